@@ -17,6 +17,7 @@ limitations under the License.
 package gce
 
 import (
+	"context"
 	"fmt"
 
 	"google.golang.org/api/cloudresourcemanager/v1"
@@ -43,6 +44,8 @@ type MockGCECloud struct {
 	region  string
 	labels  map[string]string
 
+	networkTier string
+
 	computeClient              *mockcompute.MockClient
 	dnsClient                  *mockdns.MockClient
 	iamClient                  *mockiam.MockClient
@@ -90,6 +93,19 @@ func (c *MockGCECloud) WithLabels(labels map[string]string) gce.GCECloud {
 	return i
 }
 
+// WithNetworkTier returns a copy of the MockGCECloud bound to the specified default network tier
+func (c *MockGCECloud) WithNetworkTier(networkTier string) gce.GCECloud {
+	i := &MockGCECloud{}
+	*i = *c
+	i.networkTier = networkTier
+	return i
+}
+
+// NetworkTier implements GCECloud::NetworkTier
+func (c *MockGCECloud) NetworkTier() string {
+	return c.networkTier
+}
+
 // ProviderID implements fi.Cloud::ProviderID
 func (c *MockGCECloud) ProviderID() kops.CloudProviderID {
 	return kops.CloudProviderGCE
@@ -138,6 +154,14 @@ func (c *MockGCECloud) WaitForOp(op *compute.Operation) error {
 	return nil
 }
 
+// WaitForOpContext implements GCECloud::WaitForOpContext
+func (c *MockGCECloud) WaitForOpContext(ctx context.Context, op *compute.Operation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.WaitForOp(op)
+}
+
 // FindClusterStatus implements GCECloud::FindClusterStatus
 func (c *MockGCECloud) FindClusterStatus(cluster *kops.Cluster) (*kops.ClusterStatus, error) {
 	return nil, fmt.Errorf("MockGCECloud::FindClusterStatus not implemented")
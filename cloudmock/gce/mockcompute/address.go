@@ -66,7 +66,7 @@ func (c *addressClient) Insert(project, region string, addr *compute.Address) (*
 		addrs = map[string]*compute.Address{}
 		regions[region] = addrs
 	}
-	addr.SelfLink = fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/regions/%s/addrs/%s", project, region, addr.Name)
+	addr.SelfLink = fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/regions/%s/addresses/%s", project, region, addr.Name)
 	addrs[addr.Name] = addr
 	return doneOperation(), nil
 }
@@ -38,11 +38,15 @@ type MockClient struct {
 	firewallClient         *firewallClient
 	routerClient           *routerClient
 
+	globalForwardingRuleClient *globalForwardingRuleClient
+	globalAddressClient        *globalAddressClient
+
 	instanceTemplateClient     *instanceTemplateClient
 	instanceGroupManagerClient *instanceGroupManagerClient
 	targetPoolClient           *targetPoolClient
 
-	diskClient *diskClient
+	diskClient              *diskClient
+	serviceAttachmentClient *serviceAttachmentClient
 }
 
 var _ gce.ComputeClient = &MockClient{}
@@ -64,11 +68,15 @@ func NewMockClient(project string) *MockClient {
 		firewallClient:         newFirewallClient(),
 		routerClient:           newRouterClient(),
 
+		globalForwardingRuleClient: newGlobalForwardingRuleClient(),
+		globalAddressClient:        newGlobalAddressClient(),
+
 		instanceTemplateClient:     newInstanceTemplateClient(),
 		instanceGroupManagerClient: newInstanceGroupManagerClient(),
 		targetPoolClient:           newTargetPoolClient(),
 
-		diskClient: newDiskClient(),
+		diskClient:              newDiskClient(),
+		serviceAttachmentClient: newServiceAttachmentClient(),
 	}
 }
 
@@ -83,9 +91,11 @@ func (c *MockClient) AllResources() map[string]interface{} {
 		// TODO(kenji): Fix this.
 		c.routeClient.All,
 		c.forwardingRuleClient.All,
+		c.globalForwardingRuleClient.All,
 		c.httpHealthChecksClient.All,
 		c.healthCheckClient.All,
 		c.addressClient.All,
+		c.globalAddressClient.All,
 		c.firewallClient.All,
 		c.routerClient.All,
 		c.instanceTemplateClient.All,
@@ -133,6 +143,10 @@ func (c *MockClient) ForwardingRules() gce.ForwardingRuleClient {
 	return c.forwardingRuleClient
 }
 
+func (c *MockClient) GlobalForwardingRules() gce.GlobalForwardingRuleClient {
+	return c.globalForwardingRuleClient
+}
+
 func (c *MockClient) HTTPHealthChecks() gce.HttpHealthChecksClient {
 	return c.httpHealthChecksClient
 }
@@ -149,6 +163,10 @@ func (c *MockClient) Addresses() gce.AddressClient {
 	return c.addressClient
 }
 
+func (c *MockClient) GlobalAddresses() gce.GlobalAddressClient {
+	return c.globalAddressClient
+}
+
 func (c *MockClient) Firewalls() gce.FirewallClient {
 	return c.firewallClient
 }
@@ -178,6 +196,10 @@ func (c *MockClient) Disks() gce.DiskClient {
 	return c.diskClient
 }
 
+func (c *MockClient) ServiceAttachments() gce.ServiceAttachmentClient {
+	return c.serviceAttachmentClient
+}
+
 func notFoundError() error {
 	return &googleapi.Error{
 		Code: 404,
@@ -92,6 +92,23 @@ func (c *backendServiceClient) Get(project, _, name string) (*compute.BackendSer
 	return backendService, nil
 }
 
+// Patch updates the fields of a backend service which GCE can change in place; only connectionTrackingPolicy is modelled.
+func (c *backendServiceClient) Patch(project, _, name string, patch *compute.BackendService) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	backendServices, ok := c.backendServices[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	backendService, ok := backendServices[name]
+	if !ok {
+		return nil, notFoundError()
+	}
+
+	backendService.ConnectionTrackingPolicy = patch.ConnectionTrackingPolicy
+	return doneOperation(), nil
+}
+
 func (c *backendServiceClient) List(_ context.Context, project, _ string) ([]*compute.BackendService, error) {
 	c.Lock()
 	defer c.Unlock()
@@ -91,6 +91,27 @@ func (c *forwardingRuleClient) SetLabels(ctx context.Context, project, region, n
 	return doneOperation(), nil
 }
 
+// Patch updates the fields of a forwarding rule which GCE can change in place; only allowGlobalAccess is modelled.
+func (c *forwardingRuleClient) Patch(ctx context.Context, project, region, name string, patch *compute.ForwardingRule) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	regions, ok := c.forwardingRules[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	frs, ok := regions[region]
+	if !ok {
+		return nil, notFoundError()
+	}
+	fr, ok := frs[name]
+	if !ok {
+		return nil, notFoundError()
+	}
+
+	fr.AllowGlobalAccess = patch.AllowGlobalAccess
+	return doneOperation(), nil
+}
+
 func (c *forwardingRuleClient) Delete(ctx context.Context, project, region, name string) (*compute.Operation, error) {
 	c.Lock()
 	defer c.Unlock()
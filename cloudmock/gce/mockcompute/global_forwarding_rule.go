@@ -0,0 +1,136 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockcompute
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/gce"
+)
+
+type globalForwardingRuleClient struct {
+	// forwardingRules are global forwardingRules keyed by project and forwardingRule name.
+	forwardingRules map[string]map[string]*compute.ForwardingRule
+	// labelUpdates counts the label updates, to generate a new label fingerprint for each
+	labelUpdates int
+	sync.Mutex
+}
+
+var _ gce.GlobalForwardingRuleClient = &globalForwardingRuleClient{}
+
+func newGlobalForwardingRuleClient() *globalForwardingRuleClient {
+	return &globalForwardingRuleClient{
+		forwardingRules: map[string]map[string]*compute.ForwardingRule{},
+	}
+}
+
+func (c *globalForwardingRuleClient) All() map[string]interface{} {
+	c.Lock()
+	defer c.Unlock()
+	m := map[string]interface{}{}
+	for _, frs := range c.forwardingRules {
+		for n, fr := range frs {
+			m[n] = fr
+		}
+	}
+	return m
+}
+
+func (c *globalForwardingRuleClient) Insert(ctx context.Context, project string, fr *compute.ForwardingRule) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	frs, ok := c.forwardingRules[project]
+	if !ok {
+		frs = map[string]*compute.ForwardingRule{}
+		c.forwardingRules[project] = frs
+	}
+	fr.SelfLink = fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/global/forwardingRules/%s", project, fr.Name)
+	frs[fr.Name] = fr
+	return doneOperation(), nil
+}
+
+func (c *globalForwardingRuleClient) Delete(ctx context.Context, project, name string) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	frs, ok := c.forwardingRules[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if _, ok := frs[name]; !ok {
+		return nil, notFoundError()
+	}
+	delete(frs, name)
+	return doneOperation(), nil
+}
+
+func (c *globalForwardingRuleClient) Get(ctx context.Context, project, name string) (*compute.ForwardingRule, error) {
+	c.Lock()
+	defer c.Unlock()
+	frs, ok := c.forwardingRules[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	fr, ok := frs[name]
+	if !ok {
+		return nil, notFoundError()
+	}
+	return fr, nil
+}
+
+// SetLabels replaces the labels of a global forwarding rule. As in GCE, the request must carry the current label
+// fingerprint, which changes with every update.
+func (c *globalForwardingRuleClient) SetLabels(ctx context.Context, project, name string, req *compute.GlobalSetLabelsRequest) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	frs, ok := c.forwardingRules[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	fr, ok := frs[name]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if req.LabelFingerprint != fr.LabelFingerprint {
+		return nil, &googleapi.Error{
+			Code:    412,
+			Message: fmt.Sprintf("label fingerprint %q of ForwardingRule %q is out of date", req.LabelFingerprint, name),
+		}
+	}
+
+	c.labelUpdates++
+	fr.Labels = req.Labels
+	fr.LabelFingerprint = fmt.Sprintf("fingerprint-%d", c.labelUpdates)
+	return doneOperation(), nil
+}
+
+func (c *globalForwardingRuleClient) List(ctx context.Context, project string) ([]*compute.ForwardingRule, error) {
+	c.Lock()
+	defer c.Unlock()
+	frs, ok := c.forwardingRules[project]
+	if !ok {
+		return nil, nil
+	}
+	var l []*compute.ForwardingRule
+	for _, fr := range frs {
+		l = append(l, fr)
+	}
+	return l, nil
+}
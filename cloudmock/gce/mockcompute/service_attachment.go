@@ -0,0 +1,94 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockcompute
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	compute "google.golang.org/api/compute/v1"
+	"k8s.io/kops/upup/pkg/fi/cloudup/gce"
+)
+
+type serviceAttachmentClient struct {
+	// serviceAttachments are serviceAttachments keyed by project, region, and serviceAttachment name.
+	serviceAttachments map[string]map[string]map[string]*compute.ServiceAttachment
+	sync.Mutex
+}
+
+var _ gce.ServiceAttachmentClient = &serviceAttachmentClient{}
+
+func newServiceAttachmentClient() *serviceAttachmentClient {
+	return &serviceAttachmentClient{
+		serviceAttachments: map[string]map[string]map[string]*compute.ServiceAttachment{},
+	}
+}
+
+func (c *serviceAttachmentClient) Insert(ctx context.Context, project, region string, sa *compute.ServiceAttachment) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	regions, ok := c.serviceAttachments[project]
+	if !ok {
+		regions = map[string]map[string]*compute.ServiceAttachment{}
+		c.serviceAttachments[project] = regions
+	}
+	sas, ok := regions[region]
+	if !ok {
+		sas = map[string]*compute.ServiceAttachment{}
+		regions[region] = sas
+	}
+	sa.SelfLink = fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/regions/%s/serviceAttachments/%s", project, region, sa.Name)
+	sas[sa.Name] = sa
+	return doneOperation(), nil
+}
+
+func (c *serviceAttachmentClient) Delete(ctx context.Context, project, region, name string) (*compute.Operation, error) {
+	c.Lock()
+	defer c.Unlock()
+	regions, ok := c.serviceAttachments[project]
+	if !ok {
+		return nil, notFoundError()
+	}
+	sas, ok := regions[region]
+	if !ok {
+		return nil, notFoundError()
+	}
+	if _, ok := sas[name]; !ok {
+		return nil, notFoundError()
+	}
+	delete(sas, name)
+	return doneOperation(), nil
+}
+
+func (c *serviceAttachmentClient) List(ctx context.Context, project, region string) ([]*compute.ServiceAttachment, error) {
+	c.Lock()
+	defer c.Unlock()
+	regions, ok := c.serviceAttachments[project]
+	if !ok {
+		return nil, nil
+	}
+	sas, ok := regions[region]
+	if !ok {
+		return nil, nil
+	}
+	var l []*compute.ServiceAttachment
+	for _, sa := range sas {
+		l = append(l, sa)
+	}
+	return l, nil
+}
@@ -20,10 +20,14 @@ import (
 	"net/http/httptest"
 	"sync"
 
+	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/l7policies"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
 	"k8s.io/kops/cloudmock/openstack"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
 )
 
 // MockClient represents a mocked networks (nebula) client
@@ -34,6 +38,25 @@ type MockClient struct {
 	loadbalancers map[string]loadbalancers.LoadBalancer
 	listeners     map[string]listeners.Listener
 	pools         map[string]pools.Pool
+	monitors      map[string]monitors.Monitor
+	// members are the members of the pools, by pool and member ID
+	members    map[string]map[string]pools.Member
+	l7policies map[string]l7policies.L7Policy
+	// stats are the statistics of the loadbalancers, by loadbalancer ID
+	stats             map[string]loadbalancers.Stats
+	availabilityZones []AvailabilityZone
+
+	// VipPorts, if set, is the networking mock in which the VIP ports of new loadbalancers are created, as Octavia does
+	VipPorts *mocknetworking.MockClient
+	// VipQosPolicyUnsupported makes the mock reject vip_qos_policy_id, as Octavia before Queens does
+	VipQosPolicyUnsupported bool
+	// AsyncProvisioning makes new loadbalancers PENDING_CREATE, and changes to a loadbalancer or its children leave it
+	// PENDING_UPDATE, until the loadbalancer is read again, as Octavia does while it applies them
+	AsyncProvisioning bool
+	// CascadeDeleteUnsupported makes the mock reject cascade deletes of loadbalancers, as some providers do
+	CascadeDeleteUnsupported bool
+	// MemberOperatingStatus is the operating status of new members, ONLINE if empty
+	MemberOperatingStatus string
 }
 
 // CreateClient will create a new mock networking client
@@ -44,6 +67,9 @@ func CreateClient() *MockClient {
 	m.mockListeners()
 	m.mockLoadBalancers()
 	m.mockPools()
+	m.mockMonitors()
+	m.mockL7Policies()
+	m.mockAvailabilityZones()
 	m.Server = httptest.NewServer(m.Mux)
 	return m
 }
@@ -53,6 +79,11 @@ func (m *MockClient) Reset() {
 	m.loadbalancers = make(map[string]loadbalancers.LoadBalancer)
 	m.listeners = make(map[string]listeners.Listener)
 	m.pools = make(map[string]pools.Pool)
+	m.monitors = make(map[string]monitors.Monitor)
+	m.members = make(map[string]map[string]pools.Member)
+	m.l7policies = make(map[string]l7policies.L7Policy)
+	m.stats = make(map[string]loadbalancers.Stats)
+	m.availabilityZones = nil
 }
 
 // All returns a map of all resource IDs to their resources
@@ -67,5 +98,52 @@ func (m *MockClient) All() map[string]interface{} {
 	for id, p := range m.pools {
 		all[id] = p
 	}
+	for id, monitor := range m.monitors {
+		all[id] = monitor
+	}
+	for _, members := range m.members {
+		for id, member := range members {
+			all[id] = member
+		}
+	}
+	for id, policy := range m.l7policies {
+		all[id] = policy
+	}
 	return all
 }
+
+// markPending puts the loadbalancer into PENDING_UPDATE after a change, if AsyncProvisioning is set
+func (m *MockClient) markPending(loadbalancerID string) {
+	l, ok := m.loadbalancers[loadbalancerID]
+	if !m.AsyncProvisioning || !ok || l.ProvisioningStatus == "ERROR" {
+		return
+	}
+	l.ProvisioningStatus = "PENDING_UPDATE"
+	m.loadbalancers[l.ID] = l
+}
+
+// poolLoadBalancerID returns the ID of the loadbalancer of the pool
+func (m *MockClient) poolLoadBalancerID(poolID string) string {
+	p, ok := m.pools[poolID]
+	if !ok || len(p.Loadbalancers) == 0 {
+		return ""
+	}
+	return p.Loadbalancers[0].ID
+}
+
+// listenerLoadBalancerID returns the ID of the loadbalancer of the listener
+func (m *MockClient) listenerLoadBalancerID(listenerID string) string {
+	l, ok := m.listeners[listenerID]
+	if !ok || len(l.Loadbalancers) == 0 {
+		return ""
+	}
+	return l.Loadbalancers[0].ID
+}
+
+// newID returns the given ID of a seeded resource, or a new one
+func newID(id string) string {
+	if id != "" {
+		return id
+	}
+	return uuid.New().String()
+}
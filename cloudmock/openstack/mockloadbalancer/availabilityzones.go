@@ -0,0 +1,59 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockloadbalancer
+
+import (
+	"net/http"
+)
+
+// AvailabilityZone is an Octavia availability zone, which gophercloud has no client for
+type AvailabilityZone struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type availabilityZoneListResponse struct {
+	AvailabilityZones []AvailabilityZone `json:"availability_zones"`
+}
+
+func (m *MockClient) mockAvailabilityZones() {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		w.Header().Add("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zones := make([]AvailabilityZone, 0, len(m.availabilityZones))
+		zones = append(zones, m.availabilityZones...)
+		w.WriteHeader(http.StatusOK)
+		writeJSON(w, availabilityZoneListResponse{AvailabilityZones: zones})
+	}
+	m.Mux.HandleFunc("/lbaas/availabilityzones", handler)
+}
+
+// AddAvailabilityZone adds a loadbalancer availability zone
+func (m *MockClient) AddAvailabilityZone(zone AvailabilityZone) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.availabilityZones = append(m.availabilityZones, zone)
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2026 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockloadbalancer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/l7policies"
+)
+
+type l7policyListResponse struct {
+	L7Policies []l7policies.L7Policy `json:"l7policies"`
+}
+
+type l7policyGetResponse struct {
+	L7Policy l7policies.L7Policy `json:"l7policy"`
+}
+
+type l7policyCreateRequest struct {
+	L7Policy l7policies.CreateOpts `json:"l7policy"`
+}
+
+type l7ruleListResponse struct {
+	Rules []l7policies.Rule `json:"rules"`
+}
+
+// rulesRe matches the rules subresource of an L7 policy
+var rulesRe = regexp.MustCompile(`^([^/]+)/rules/?$`)
+
+func (m *MockClient) mockL7Policies() {
+	re := regexp.MustCompile(`/lbaas/l7policies/?`)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		w.Header().Add("Content-Type", "application/json")
+
+		policyID := re.ReplaceAllString(r.URL.Path, "")
+		if match := rulesRe.FindStringSubmatch(policyID); match != nil && r.Method == http.MethodGet {
+			m.listL7Rules(w, match[1])
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if policyID == "" {
+				r.ParseForm()
+				m.listL7Policies(w, r.Form)
+			} else {
+				m.getL7Policy(w, policyID)
+			}
+		case http.MethodPost:
+			m.createL7Policy(w, r)
+		case http.MethodDelete:
+			m.deleteL7Policy(w, policyID)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	m.Mux.HandleFunc("/lbaas/l7policies/", handler)
+	m.Mux.HandleFunc("/lbaas/l7policies", handler)
+}
+
+// sortedL7Policies returns the L7 policies, sorted by ID
+func (m *MockClient) sortedL7Policies() []l7policies.L7Policy {
+	policies := make([]l7policies.L7Policy, 0, len(m.l7policies))
+	for _, policy := range m.l7policies {
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+	return policies
+}
+
+// listenerL7Policies returns the L7 policies of the listener, sorted by ID
+func (m *MockClient) listenerL7Policies(listenerID string) []l7policies.L7Policy {
+	var policies []l7policies.L7Policy
+	for _, policy := range m.sortedL7Policies() {
+		if policy.ListenerID == listenerID {
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+func (m *MockClient) listL7Policies(w http.ResponseWriter, vals url.Values) {
+	policies := make([]l7policies.L7Policy, 0)
+	for _, policy := range m.sortedL7Policies() {
+		if listenerID := vals.Get("listener_id"); listenerID != "" && listenerID != policy.ListenerID {
+			continue
+		}
+		if action := vals.Get("action"); action != "" && action != policy.Action {
+			continue
+		}
+		if name := vals.Get("name"); name != "" && name != policy.Name {
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, l7policyListResponse{L7Policies: policies})
+}
+
+func (m *MockClient) getL7Policy(w http.ResponseWriter, policyID string) {
+	policy, ok := m.l7policies[policyID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, l7policyGetResponse{L7Policy: policy})
+}
+
+func (m *MockClient) listL7Rules(w http.ResponseWriter, policyID string) {
+	policy, ok := m.l7policies[policyID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	rules := make([]l7policies.Rule, 0, len(policy.Rules))
+	rules = append(rules, policy.Rules...)
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, l7ruleListResponse{Rules: rules})
+}
+
+func (m *MockClient) createL7Policy(w http.ResponseWriter, r *http.Request) {
+	var create l7policyCreateRequest
+	err := json.NewDecoder(r.Body).Decode(&create)
+	if err != nil {
+		panic("error decoding create l7policy request")
+	}
+	if _, ok := m.listeners[create.L7Policy.ListenerID]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	policy := l7policies.L7Policy{
+		ID:                 uuid.New().String(),
+		Name:               create.L7Policy.Name,
+		ListenerID:         create.L7Policy.ListenerID,
+		Action:             string(create.L7Policy.Action),
+		Position:           create.L7Policy.Position,
+		Description:        create.L7Policy.Description,
+		RedirectPoolID:     create.L7Policy.RedirectPoolID,
+		RedirectPrefix:     create.L7Policy.RedirectPrefix,
+		RedirectURL:        create.L7Policy.RedirectURL,
+		RedirectHttpCode:   create.L7Policy.RedirectHttpCode,
+		AdminStateUp:       true,
+		ProvisioningStatus: "ACTIVE",
+		Tags:               create.L7Policy.Tags,
+	}
+	if create.L7Policy.AdminStateUp != nil {
+		policy.AdminStateUp = *create.L7Policy.AdminStateUp
+	}
+	for _, rule := range create.L7Policy.Rules {
+		policy.Rules = append(policy.Rules, l7policies.Rule{
+			ID:                 uuid.New().String(),
+			RuleType:           string(rule.RuleType),
+			CompareType:        string(rule.CompareType),
+			Value:              rule.Value,
+			Key:                rule.Key,
+			Invert:             rule.Invert,
+			AdminStateUp:       true,
+			ProvisioningStatus: "ACTIVE",
+			Tags:               rule.Tags,
+		})
+	}
+	m.l7policies[policy.ID] = policy
+	m.markPending(m.listenerLoadBalancerID(policy.ListenerID))
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, l7policyGetResponse{L7Policy: policy})
+}
+
+func (m *MockClient) deleteL7Policy(w http.ResponseWriter, policyID string) {
+	policy, ok := m.l7policies[policyID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	delete(m.l7policies, policyID)
+	m.markPending(m.listenerLoadBalancerID(policy.ListenerID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddL7Policy adds an L7 policy with its rules, and returns it with its ID. The ID of the policy is kept if it is set.
+func (m *MockClient) AddL7Policy(policy l7policies.L7Policy) l7policies.L7Policy {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	policy.ID = newID(policy.ID)
+	m.l7policies[policy.ID] = policy
+	return policy
+}
+
+// L7Policies returns the L7 policies with their rules, sorted by ID
+func (m *MockClient) L7Policies() []l7policies.L7Policy {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.sortedL7Policies()
+}
@@ -22,8 +22,10 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 
 	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/l7policies"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 )
 
@@ -35,10 +37,17 @@ type listenerGetResponse struct {
 	Listener listeners.Listener `json:"listener"`
 }
 
+// DefaultTLSCiphers are the ciphers of TERMINATED_HTTPS listeners created without TLSCiphers
+const DefaultTLSCiphers = "ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384"
+
 type listenerCreateRequest struct {
 	Listener listeners.CreateOpts `json:"listener"`
 }
 
+type listenerUpdateRequest struct {
+	Listener listeners.UpdateOpts `json:"listener"`
+}
+
 func (m *MockClient) mockListeners() {
 	re := regexp.MustCompile(`/lbaas/listeners/?`)
 
@@ -51,10 +60,16 @@ func (m *MockClient) mockListeners() {
 		listenerID := re.ReplaceAllString(r.URL.Path, "")
 		switch r.Method {
 		case http.MethodGet:
-			r.ParseForm()
-			m.listListeners(w, r.Form)
+			if listenerID == "" {
+				r.ParseForm()
+				m.listListeners(w, r.Form)
+			} else {
+				m.getListener(w, listenerID)
+			}
 		case http.MethodPost:
 			m.createListener(w, r)
+		case http.MethodPut:
+			m.updateListener(w, r, listenerID)
 		case http.MethodDelete:
 			m.deleteListener(w, listenerID)
 		default:
@@ -78,7 +93,10 @@ func (m *MockClient) listListeners(w http.ResponseWriter, vals url.Values) {
 		if id != "" && l.ID != id {
 			continue
 		}
-		listeners = append(listeners, l)
+		if lbID := vals.Get("loadbalancer_id"); lbID != "" && (len(l.Loadbalancers) == 0 || l.Loadbalancers[0].ID != lbID) {
+			continue
+		}
+		listeners = append(listeners, m.populateListener(l))
 	}
 
 	resp := listenerListResponse{
@@ -94,15 +112,45 @@ func (m *MockClient) listListeners(w http.ResponseWriter, vals url.Values) {
 	}
 }
 
+func (m *MockClient) getListener(w http.ResponseWriter, listenerID string) {
+	l, ok := m.listeners[listenerID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, listenerGetResponse{Listener: m.populateListener(l)})
+}
+
 func (m *MockClient) deleteListener(w http.ResponseWriter, listenerID string) {
 	if _, ok := m.listeners[listenerID]; ok {
-		delete(m.listeners, listenerID)
-		w.WriteHeader(http.StatusOK)
+		m.markPending(m.listenerLoadBalancerID(listenerID))
+		m.removeListener(listenerID)
+		w.WriteHeader(http.StatusNoContent)
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
+// removeListener removes the listener with its L7 policies
+func (m *MockClient) removeListener(listenerID string) {
+	for id, policy := range m.l7policies {
+		if policy.ListenerID == listenerID {
+			delete(m.l7policies, id)
+		}
+	}
+	delete(m.listeners, listenerID)
+}
+
+// populateListener returns the listener listing its L7 policies, by ID, as Octavia does
+func (m *MockClient) populateListener(l listeners.Listener) listeners.Listener {
+	l.L7Policies = nil
+	for _, policy := range m.listenerL7Policies(l.ID) {
+		l.L7Policies = append(l.L7Policies, l7policies.L7Policy{ID: policy.ID})
+	}
+	return l
+}
+
 func (m *MockClient) createListener(w http.ResponseWriter, r *http.Request) {
 	var create listenerCreateRequest
 	err := json.NewDecoder(r.Body).Decode(&create)
@@ -110,6 +158,13 @@ func (m *MockClient) createListener(w http.ResponseWriter, r *http.Request) {
 		panic("error decoding create listener request")
 	}
 
+	for _, existing := range m.listeners {
+		if m.listenerLoadBalancerID(existing.ID) == create.Listener.LoadbalancerID && existing.ProtocolPort == create.Listener.ProtocolPort {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	}
+
 	w.WriteHeader(http.StatusAccepted)
 
 	l := listeners.Listener{
@@ -120,8 +175,51 @@ func (m *MockClient) createListener(w http.ResponseWriter, r *http.Request) {
 		Protocol:      string(create.Listener.Protocol),
 		ProtocolPort:  create.Listener.ProtocolPort,
 		AllowedCIDRs:  create.Listener.AllowedCIDRs,
+		Tags:          create.Listener.Tags,
+		AdminStateUp:  true,
+
+		SniContainerRefs:        create.Listener.SniContainerRefs,
+		InsertHeaders:           create.Listener.InsertHeaders,
+		DefaultTlsContainerRef:  create.Listener.DefaultTlsContainerRef,
+		ClientAuthentication:    string(create.Listener.ClientAuthentication),
+		ClientCATLSContainerRef: create.Listener.ClientCATLSContainerRef,
+		TLSCiphers:              create.Listener.TLSCiphers,
+	}
+	if l.ClientAuthentication == "" {
+		l.ClientAuthentication = string(listeners.ClientAuthenticationNone)
+	}
+	if create.Listener.AdminStateUp != nil {
+		l.AdminStateUp = *create.Listener.AdminStateUp
+	}
+	if create.Listener.ConnLimit != nil {
+		l.ConnLimit = *create.Listener.ConnLimit
+	}
+	if create.Listener.TimeoutClientData != nil {
+		l.TimeoutClientData = *create.Listener.TimeoutClientData
+	}
+	if create.Listener.TimeoutMemberData != nil {
+		l.TimeoutMemberData = *create.Listener.TimeoutMemberData
+	}
+	if create.Listener.TimeoutMemberConnect != nil {
+		l.TimeoutMemberConnect = *create.Listener.TimeoutMemberConnect
+	}
+	if create.Listener.TimeoutTCPInspect != nil {
+		l.TimeoutTCPInspect = *create.Listener.TimeoutTCPInspect
+	}
+	for _, version := range create.Listener.TLSVersions {
+		l.TLSVersions = append(l.TLSVersions, string(version))
+	}
+	if l.Protocol == string(listeners.ProtocolTerminatedHTTPS) {
+		// Octavia applies its configured defaults to TLS listeners
+		if l.TLSCiphers == "" {
+			l.TLSCiphers = DefaultTLSCiphers
+		}
+		if l.TLSVersions == nil {
+			l.TLSVersions = []string{string(listeners.TLSVersionTLSv1_2), string(listeners.TLSVersionTLSv1_3)}
+		}
 	}
 	m.listeners[l.ID] = l
+	m.markPending(create.Listener.LoadbalancerID)
 
 	resp := listenerGetResponse{
 		Listener: l,
@@ -135,3 +233,83 @@ func (m *MockClient) createListener(w http.ResponseWriter, r *http.Request) {
 		panic("failed to write body")
 	}
 }
+
+func (m *MockClient) updateListener(w http.ResponseWriter, r *http.Request, listenerID string) {
+	l, ok := m.listeners[listenerID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var update listenerUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		panic("error decoding update listener request")
+	}
+	if update.Listener.DefaultPoolID != nil {
+		l.DefaultPoolID = *update.Listener.DefaultPoolID
+	}
+	if update.Listener.AllowedCIDRs != nil {
+		l.AllowedCIDRs = *update.Listener.AllowedCIDRs
+	}
+	if update.Listener.Tags != nil {
+		l.Tags = *update.Listener.Tags
+	}
+	if update.Listener.DefaultTlsContainerRef != nil {
+		l.DefaultTlsContainerRef = *update.Listener.DefaultTlsContainerRef
+	}
+	if update.Listener.ClientAuthentication != nil {
+		l.ClientAuthentication = string(*update.Listener.ClientAuthentication)
+	}
+	if update.Listener.ClientCATLSContainerRef != nil {
+		l.ClientCATLSContainerRef = *update.Listener.ClientCATLSContainerRef
+	}
+	if update.Listener.TLSCiphers != nil {
+		l.TLSCiphers = *update.Listener.TLSCiphers
+	}
+	if update.Listener.TLSVersions != nil {
+		l.TLSVersions = nil
+		for _, version := range *update.Listener.TLSVersions {
+			l.TLSVersions = append(l.TLSVersions, string(version))
+		}
+	}
+	m.listeners[l.ID] = l
+	m.markPending(m.listenerLoadBalancerID(l.ID))
+
+	w.WriteHeader(http.StatusOK)
+	resp := listenerGetResponse{
+		Listener: m.populateListener(l),
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
+// AddListener adds a listener, and returns it with its ID. The ID of the listener is kept if it is set.
+func (m *MockClient) AddListener(l listeners.Listener) listeners.Listener {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	l.ID = newID(l.ID)
+	l.L7Policies = nil
+	m.listeners[l.ID] = l
+	return l
+}
+
+// Listeners returns the listeners, sorted by ID
+func (m *MockClient) Listeners() []listeners.Listener {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make([]listeners.Listener, 0, len(m.listeners))
+	for _, l := range m.listeners {
+		result = append(result, m.populateListener(l))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
@@ -22,15 +22,21 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
 )
 
 type loadbalancerListResponse struct {
 	LoadBalancers []loadbalancers.LoadBalancer `json:"loadbalancers"`
+	Links         []gophercloud.Link           `json:"loadbalancers_links,omitempty"`
 }
 
 type loadbalancerGetResponse struct {
@@ -41,6 +47,14 @@ type loadbalancerCreateRequest struct {
 	LoadBalancer loadbalancers.CreateOpts `json:"loadbalancer"`
 }
 
+type loadbalancerUpdateRequest struct {
+	LoadBalancer loadbalancers.UpdateOpts `json:"loadbalancer"`
+}
+
+type loadbalancerStatsResponse struct {
+	Stats loadbalancers.Stats `json:"stats"`
+}
+
 func (m *MockClient) mockLoadBalancers() {
 	re := regexp.MustCompile(`/lbaas/loadbalancers/?`)
 
@@ -51,19 +65,25 @@ func (m *MockClient) mockLoadBalancers() {
 		w.Header().Add("Content-Type", "application/json")
 
 		loadbalancerID := re.ReplaceAllString(r.URL.Path, "")
-		// TODO: handle /members subresource
+		if id, found := strings.CutSuffix(loadbalancerID, "/stats"); found && r.Method == http.MethodGet {
+			m.getLoadBalancerStats(w, id)
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
 			if loadbalancerID == "" {
 				r.ParseForm()
-				m.listLoadBalancers(w, r.Form)
+				m.listLoadBalancers(w, r)
 			} else {
 				m.getLoadBalancer(w, loadbalancerID)
 			}
 		case http.MethodPost:
 			m.createLoadBalancer(w, r)
+		case http.MethodPut:
+			m.updateLoadBalancer(w, r, loadbalancerID)
 		case http.MethodDelete:
-			m.deleteLoadBalancer(w, loadbalancerID)
+			r.ParseForm()
+			m.deleteLoadBalancer(w, loadbalancerID, r.Form.Get("cascade") == "true")
 		default:
 			w.WriteHeader(http.StatusBadRequest)
 		}
@@ -72,7 +92,10 @@ func (m *MockClient) mockLoadBalancers() {
 	m.Mux.HandleFunc("/lbaas/loadbalancers", handler)
 }
 
-func (m *MockClient) listLoadBalancers(w http.ResponseWriter, vals url.Values) {
+// listLoadBalancers lists the loadbalancers by ID; with a limit, they are returned in pages
+// linked by the ID of the last loadbalancer of the previous page, as Octavia does
+func (m *MockClient) listLoadBalancers(w http.ResponseWriter, r *http.Request) {
+	vals := r.Form
 	w.WriteHeader(http.StatusOK)
 
 	loadbalancers := make([]loadbalancers.LoadBalancer, 0)
@@ -85,12 +108,28 @@ func (m *MockClient) listLoadBalancers(w http.ResponseWriter, vals url.Values) {
 		if id != "" && id != l.ID {
 			continue
 		}
+		if tags := vals.Get("tags"); tags != "" && !hasTags(l.Tags, strings.Split(tags, ",")) {
+			continue
+		}
+		if marker := vals.Get("marker"); marker != "" && l.ID <= marker {
+			continue
+		}
 		loadbalancers = append(loadbalancers, populateLB(l, m.pools, m.listeners))
 	}
+	sort.Slice(loadbalancers, func(i, j int) bool { return loadbalancers[i].ID < loadbalancers[j].ID })
 
 	resp := loadbalancerListResponse{
 		LoadBalancers: loadbalancers,
 	}
+	if limit, err := strconv.Atoi(vals.Get("limit")); err == nil && limit > 0 && len(loadbalancers) > limit {
+		resp.LoadBalancers = loadbalancers[:limit]
+		next := url.Values{}
+		for k, v := range vals {
+			next[k] = v
+		}
+		next.Set("marker", resp.LoadBalancers[limit-1].ID)
+		resp.Links = []gophercloud.Link{{Rel: "next", Href: "http://" + r.Host + r.URL.Path + "?" + next.Encode()}}
+	}
 	respB, err := json.Marshal(resp)
 	if err != nil {
 		panic(fmt.Sprintf("failed to marshal %+v", resp))
@@ -114,18 +153,61 @@ func (m *MockClient) getLoadBalancer(w http.ResponseWriter, loadbalancerID strin
 		if err != nil {
 			panic("failed to write body")
 		}
+		// the pending change has been applied by the time it is seen
+		if strings.HasPrefix(loadbalancer.ProvisioningStatus, "PENDING_") {
+			loadbalancer.ProvisioningStatus = "ACTIVE"
+			m.loadbalancers[loadbalancerID] = loadbalancer
+		}
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
-func (m *MockClient) deleteLoadBalancer(w http.ResponseWriter, loadbalancerID string) {
-	if _, ok := m.loadbalancers[loadbalancerID]; ok {
-		delete(m.loadbalancers, loadbalancerID)
-		w.WriteHeader(http.StatusOK)
-	} else {
+func (m *MockClient) getLoadBalancerStats(w http.ResponseWriter, loadbalancerID string) {
+	if _, ok := m.loadbalancers[loadbalancerID]; !ok {
 		w.WriteHeader(http.StatusNotFound)
+		return
 	}
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, loadbalancerStatsResponse{Stats: m.stats[loadbalancerID]})
+}
+
+// deleteLoadBalancer deletes the loadbalancer; unless the delete cascades, it must not have listeners or pools left
+func (m *MockClient) deleteLoadBalancer(w http.ResponseWriter, loadbalancerID string, cascade bool) {
+	if _, ok := m.loadbalancers[loadbalancerID]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if cascade && m.CascadeDeleteUnsupported {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var listenerIDs, poolIDs []string
+	for id := range m.listeners {
+		if m.listenerLoadBalancerID(id) == loadbalancerID {
+			listenerIDs = append(listenerIDs, id)
+		}
+	}
+	for id := range m.pools {
+		if m.poolLoadBalancerID(id) == loadbalancerID {
+			poolIDs = append(poolIDs, id)
+		}
+	}
+	if !cascade && (len(listenerIDs) != 0 || len(poolIDs) != 0) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	for _, id := range poolIDs {
+		m.removePool(id)
+	}
+	for _, id := range listenerIDs {
+		m.removeListener(id)
+	}
+	delete(m.loadbalancers, loadbalancerID)
+	delete(m.stats, loadbalancerID)
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (m *MockClient) createLoadBalancer(w http.ResponseWriter, r *http.Request) {
@@ -135,13 +217,46 @@ func (m *MockClient) createLoadBalancer(w http.ResponseWriter, r *http.Request)
 		panic("error decoding create loadbalancer request")
 	}
 
+	if create.LoadBalancer.VipAddress != "" {
+		for _, existing := range m.loadbalancers {
+			if existing.VipSubnetID == create.LoadBalancer.VipSubnetID && existing.VipAddress == create.LoadBalancer.VipAddress {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+		}
+	}
+
+	if m.VipQosPolicyUnsupported && create.LoadBalancer.VipQosPolicyID != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	w.WriteHeader(http.StatusAccepted)
 	l := loadbalancers.LoadBalancer{
 		ID:                 uuid.New().String(),
 		Name:               create.LoadBalancer.Name,
 		VipSubnetID:        create.LoadBalancer.VipSubnetID,
+		VipAddress:         create.LoadBalancer.VipAddress,
+		VipNetworkID:       create.LoadBalancer.VipNetworkID,
+		VipQosPolicyID:     create.LoadBalancer.VipQosPolicyID,
+		AvailabilityZone:   create.LoadBalancer.AvailabilityZone,
+		AdminStateUp:       true,
+		Tags:               create.LoadBalancer.Tags,
 		ProvisioningStatus: "ACTIVE",
-		// TODO: create a Port and set VipPortID
+	}
+	if create.LoadBalancer.AdminStateUp != nil {
+		l.AdminStateUp = *create.LoadBalancer.AdminStateUp
+	}
+	if m.AsyncProvisioning {
+		l.ProvisioningStatus = "PENDING_CREATE"
+	}
+	if m.VipPorts != nil {
+		port := m.VipPorts.AddPort(ports.Port{
+			Name:      "octavia-lb-" + l.ID,
+			NetworkID: l.VipNetworkID,
+			FixedIPs:  []ports.IP{{SubnetID: l.VipSubnetID, IPAddress: l.VipAddress}},
+		})
+		l.VipPortID = port.ID
 	}
 	m.loadbalancers[l.ID] = l
 
@@ -158,6 +273,52 @@ func (m *MockClient) createLoadBalancer(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (m *MockClient) updateLoadBalancer(w http.ResponseWriter, r *http.Request, loadbalancerID string) {
+	l, ok := m.loadbalancers[loadbalancerID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var update loadbalancerUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		panic("error decoding update loadbalancer request")
+	}
+	if update.LoadBalancer.Name != nil {
+		l.Name = *update.LoadBalancer.Name
+	}
+	if m.VipQosPolicyUnsupported && update.LoadBalancer.VipQosPolicyID != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if update.LoadBalancer.VipQosPolicyID != nil {
+		l.VipQosPolicyID = *update.LoadBalancer.VipQosPolicyID
+	}
+	if update.LoadBalancer.Tags != nil {
+		l.Tags = *update.LoadBalancer.Tags
+	}
+	if update.LoadBalancer.AdminStateUp != nil {
+		l.AdminStateUp = *update.LoadBalancer.AdminStateUp
+	}
+	m.loadbalancers[l.ID] = l
+	m.markPending(l.ID)
+	l = m.loadbalancers[l.ID]
+
+	w.WriteHeader(http.StatusOK)
+	resp := loadbalancerGetResponse{
+		LoadBalancer: populateLB(l, m.pools, m.listeners),
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
 func populateLB(lb loadbalancers.LoadBalancer, lbPools map[string]pools.Pool, lbListeners map[string]listeners.Listener) loadbalancers.LoadBalancer {
 	lb.Pools = make([]pools.Pool, 0)
 	for _, p := range lbPools {
@@ -187,3 +348,68 @@ func populateLB(lb loadbalancers.LoadBalancer, lbPools map[string]pools.Pool, lb
 
 	return lb
 }
+
+// hasTags returns whether all the tags are set, as the tags filter of Octavia requires
+func hasTags(tags []string, filter []string) bool {
+	for _, tag := range filter {
+		found := false
+		for _, t := range tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AddLoadBalancer adds a loadbalancer, such as one left behind by an earlier run, and returns it with its ID.
+// The ID of the loadbalancer is kept if it is set.
+func (m *MockClient) AddLoadBalancer(l loadbalancers.LoadBalancer) loadbalancers.LoadBalancer {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	l.ID = newID(l.ID)
+	if l.ProvisioningStatus == "" {
+		l.ProvisioningStatus = "ACTIVE"
+	}
+	l.Listeners = nil
+	l.Pools = nil
+	m.loadbalancers[l.ID] = l
+	return l
+}
+
+// LoadBalancers returns the loadbalancers, sorted by ID
+func (m *MockClient) LoadBalancers() []loadbalancers.LoadBalancer {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lbs := make([]loadbalancers.LoadBalancer, 0, len(m.loadbalancers))
+	for _, l := range m.loadbalancers {
+		lbs = append(lbs, populateLB(l, m.pools, m.listeners))
+	}
+	sort.Slice(lbs, func(i, j int) bool { return lbs[i].ID < lbs[j].ID })
+	return lbs
+}
+
+// SetProvisioningStatus sets the provisioning status of the loadbalancer, for example to ERROR when Octavia fails to apply a change
+func (m *MockClient) SetProvisioningStatus(loadbalancerID string, status string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if l, ok := m.loadbalancers[loadbalancerID]; ok {
+		l.ProvisioningStatus = status
+		m.loadbalancers[l.ID] = l
+	}
+}
+
+// SetStats sets the statistics of the loadbalancer
+func (m *MockClient) SetStats(loadbalancerID string, stats loadbalancers.Stats) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.stats[loadbalancerID] = stats
+}
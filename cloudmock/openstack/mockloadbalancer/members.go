@@ -0,0 +1,280 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockloadbalancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+type memberListResponse struct {
+	Members []pools.Member `json:"members"`
+}
+
+type memberGetResponse struct {
+	Member pools.Member `json:"member"`
+}
+
+type memberCreateRequest struct {
+	Member pools.CreateMemberOpts `json:"member"`
+}
+
+type memberUpdateRequest struct {
+	Member pools.UpdateMemberOpts `json:"member"`
+}
+
+type memberBatchUpdateRequest struct {
+	Members []pools.BatchUpdateMemberOpts `json:"members"`
+}
+
+// handleMembers serves the /lbaas/pools/<pool>/members subresource; memberID is empty for the collection
+func (m *MockClient) handleMembers(w http.ResponseWriter, r *http.Request, poolID string, memberID string) {
+	if _, ok := m.pools[poolID]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && memberID == "":
+		m.listMembers(w, poolID)
+	case r.Method == http.MethodGet:
+		m.getMember(w, poolID, memberID)
+	case r.Method == http.MethodPost && memberID == "":
+		m.createMember(w, r, poolID)
+	case r.Method == http.MethodPut && memberID == "":
+		m.batchUpdateMembers(w, r, poolID)
+	case r.Method == http.MethodPut:
+		m.updateMember(w, r, poolID, memberID)
+	case r.Method == http.MethodDelete && memberID != "":
+		m.deleteMember(w, poolID, memberID)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// poolMembers returns the members of the pool, sorted by ID
+func (m *MockClient) poolMembers(poolID string) []pools.Member {
+	members := make([]pools.Member, 0, len(m.members[poolID]))
+	for _, member := range m.members[poolID] {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
+
+// withMembers returns the pool listing its members, by ID, as Octavia does
+func (m *MockClient) withMembers(p pools.Pool) pools.Pool {
+	p.Members = nil
+	for _, member := range m.poolMembers(p.ID) {
+		p.Members = append(p.Members, pools.Member{ID: member.ID})
+	}
+	return p
+}
+
+func (m *MockClient) listMembers(w http.ResponseWriter, poolID string) {
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, memberListResponse{Members: m.poolMembers(poolID)})
+}
+
+func (m *MockClient) getMember(w http.ResponseWriter, poolID string, memberID string) {
+	member, ok := m.members[poolID][memberID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, memberGetResponse{Member: member})
+}
+
+func (m *MockClient) createMember(w http.ResponseWriter, r *http.Request, poolID string) {
+	var create memberCreateRequest
+	err := json.NewDecoder(r.Body).Decode(&create)
+	if err != nil {
+		panic("error decoding create member request")
+	}
+
+	member := pools.Member{
+		ID:                 uuid.New().String(),
+		Name:               create.Member.Name,
+		Address:            create.Member.Address,
+		ProtocolPort:       create.Member.ProtocolPort,
+		SubnetID:           create.Member.SubnetID,
+		Weight:             1,
+		PoolID:             poolID,
+		Tags:               create.Member.Tags,
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    m.memberOperatingStatus(),
+	}
+	if create.Member.Weight != nil {
+		member.Weight = *create.Member.Weight
+	}
+	if create.Member.Backup != nil {
+		member.Backup = *create.Member.Backup
+	}
+	if create.Member.MonitorPort != nil {
+		member.MonitorPort = *create.Member.MonitorPort
+	}
+	m.putMember(member)
+	m.markPending(m.poolLoadBalancerID(poolID))
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, memberGetResponse{Member: member})
+}
+
+func (m *MockClient) updateMember(w http.ResponseWriter, r *http.Request, poolID string, memberID string) {
+	member, ok := m.members[poolID][memberID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var update memberUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		panic("error decoding update member request")
+	}
+	if update.Member.Name != nil {
+		member.Name = *update.Member.Name
+	}
+	if update.Member.Weight != nil {
+		member.Weight = *update.Member.Weight
+	}
+	if update.Member.Backup != nil {
+		member.Backup = *update.Member.Backup
+	}
+	if update.Member.MonitorPort != nil {
+		member.MonitorPort = *update.Member.MonitorPort
+	}
+	if update.Member.Tags != nil {
+		member.Tags = update.Member.Tags
+	}
+	m.putMember(member)
+	m.markPending(m.poolLoadBalancerID(poolID))
+
+	w.WriteHeader(http.StatusOK)
+	writeJSON(w, memberGetResponse{Member: member})
+}
+
+// batchUpdateMembers replaces the members of the pool: members matching an address and port are updated with the
+// attributes that are set, others are created, and members that are not listed are removed.
+// The monitor port is always replaced.
+func (m *MockClient) batchUpdateMembers(w http.ResponseWriter, r *http.Request, poolID string) {
+	var update memberBatchUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		panic("error decoding batch update members request")
+	}
+
+	existing := make(map[string]pools.Member)
+	for _, member := range m.members[poolID] {
+		existing[fmt.Sprintf("%s:%d", member.Address, member.ProtocolPort)] = member
+	}
+	m.members[poolID] = make(map[string]pools.Member)
+	for _, opts := range update.Members {
+		member, found := existing[fmt.Sprintf("%s:%d", opts.Address, opts.ProtocolPort)]
+		if !found {
+			member = pools.Member{
+				ID:                 uuid.New().String(),
+				Address:            opts.Address,
+				ProtocolPort:       opts.ProtocolPort,
+				Weight:             1,
+				PoolID:             poolID,
+				ProvisioningStatus: "ACTIVE",
+				OperatingStatus:    m.memberOperatingStatus(),
+			}
+		}
+		if opts.Name != nil {
+			member.Name = *opts.Name
+		}
+		if opts.SubnetID != nil {
+			member.SubnetID = *opts.SubnetID
+		}
+		if opts.Weight != nil {
+			member.Weight = *opts.Weight
+		}
+		if opts.Backup != nil {
+			member.Backup = *opts.Backup
+		}
+		member.MonitorPort = fi.ValueOf(opts.MonitorPort)
+		if opts.Tags != nil {
+			member.Tags = opts.Tags
+		}
+		m.putMember(member)
+	}
+	m.markPending(m.poolLoadBalancerID(poolID))
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (m *MockClient) deleteMember(w http.ResponseWriter, poolID string, memberID string) {
+	if _, ok := m.members[poolID][memberID]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	delete(m.members[poolID], memberID)
+	m.markPending(m.poolLoadBalancerID(poolID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *MockClient) putMember(member pools.Member) {
+	if m.members[member.PoolID] == nil {
+		m.members[member.PoolID] = make(map[string]pools.Member)
+	}
+	m.members[member.PoolID][member.ID] = member
+}
+
+// memberOperatingStatus is the operating status of new members
+func (m *MockClient) memberOperatingStatus() string {
+	if m.MemberOperatingStatus != "" {
+		return m.MemberOperatingStatus
+	}
+	return "ONLINE"
+}
+
+// AddMember adds a member to the pool it names, and returns it with its ID. The ID of the member is kept if it is set.
+func (m *MockClient) AddMember(member pools.Member) pools.Member {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	member.ID = newID(member.ID)
+	m.putMember(member)
+	return member
+}
+
+// Members returns the members of the pool, sorted by ID
+func (m *MockClient) Members(poolID string) []pools.Member {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.poolMembers(poolID)
+}
+
+func writeJSON(w http.ResponseWriter, resp interface{}) {
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mockloadbalancer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+)
+
+type monitorListResponse struct {
+	Monitors []monitors.Monitor `json:"healthmonitors"`
+}
+
+type monitorGetResponse struct {
+	Monitor monitors.Monitor `json:"healthmonitor"`
+}
+
+type monitorCreateRequest struct {
+	Monitor monitors.CreateOpts `json:"healthmonitor"`
+}
+
+type monitorUpdateRequest struct {
+	Monitor monitors.UpdateOpts `json:"healthmonitor"`
+}
+
+// defaultMaxRetriesDown is the max_retries_down Octavia sets when it is not specified
+const defaultMaxRetriesDown = 3
+
+func (m *MockClient) mockMonitors() {
+	re := regexp.MustCompile(`/lbaas/healthmonitors/?`)
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		w.Header().Add("Content-Type", "application/json")
+
+		monitorID := re.ReplaceAllString(r.URL.Path, "")
+		switch r.Method {
+		case http.MethodGet:
+			if monitorID == "" {
+				r.ParseForm()
+				m.listMonitors(w, r.Form)
+			} else {
+				m.getMonitor(w, monitorID)
+			}
+		case http.MethodPost:
+			m.createMonitor(w, r)
+		case http.MethodPut:
+			m.updateMonitor(w, r, monitorID)
+		case http.MethodDelete:
+			m.deleteMonitor(w, monitorID)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	m.Mux.HandleFunc("/lbaas/healthmonitors/", handler)
+	m.Mux.HandleFunc("/lbaas/healthmonitors", handler)
+}
+
+func (m *MockClient) writeMonitor(w http.ResponseWriter, monitor monitors.Monitor) {
+	resp := monitorGetResponse{
+		Monitor: monitor,
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
+func (m *MockClient) listMonitors(w http.ResponseWriter, vals url.Values) {
+	w.WriteHeader(http.StatusOK)
+
+	monitors := make([]monitors.Monitor, 0)
+	name := vals.Get("name")
+	poolID := vals.Get("pool_id")
+	for _, monitor := range m.monitors {
+		if name != "" && name != monitor.Name {
+			continue
+		}
+		if poolID != "" && (len(monitor.Pools) == 0 || poolID != monitor.Pools[0].ID) {
+			continue
+		}
+		monitors = append(monitors, monitor)
+	}
+
+	resp := monitorListResponse{
+		Monitors: monitors,
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
+func (m *MockClient) getMonitor(w http.ResponseWriter, monitorID string) {
+	monitor, ok := m.monitors[monitorID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	m.writeMonitor(w, monitor)
+}
+
+func (m *MockClient) deleteMonitor(w http.ResponseWriter, monitorID string) {
+	if monitor, ok := m.monitors[monitorID]; ok {
+		if len(monitor.Pools) != 0 {
+			m.markPending(m.poolLoadBalancerID(monitor.Pools[0].ID))
+		}
+		delete(m.monitors, monitorID)
+		w.WriteHeader(http.StatusNoContent)
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (m *MockClient) createMonitor(w http.ResponseWriter, r *http.Request) {
+	var create monitorCreateRequest
+	err := json.NewDecoder(r.Body).Decode(&create)
+	if err != nil {
+		panic("error decoding create monitor request")
+	}
+
+	w.WriteHeader(http.StatusCreated)
+
+	monitor := monitors.Monitor{
+		ID:             uuid.New().String(),
+		Name:           create.Monitor.Name,
+		Type:           create.Monitor.Type,
+		Delay:          create.Monitor.Delay,
+		Timeout:        create.Monitor.Timeout,
+		MaxRetries:     create.Monitor.MaxRetries,
+		MaxRetriesDown: create.Monitor.MaxRetriesDown,
+		URLPath:        create.Monitor.URLPath,
+		HTTPMethod:     create.Monitor.HTTPMethod,
+		HTTPVersion:    create.Monitor.HTTPVersion,
+		ExpectedCodes:  create.Monitor.ExpectedCodes,
+		DomainName:     create.Monitor.DomainName,
+		Pools:          []monitors.PoolID{{ID: create.Monitor.PoolID}},
+	}
+	if monitor.MaxRetriesDown == 0 {
+		monitor.MaxRetriesDown = defaultMaxRetriesDown
+	}
+	m.monitors[monitor.ID] = monitor
+	m.markPending(m.poolLoadBalancerID(create.Monitor.PoolID))
+
+	m.writeMonitor(w, monitor)
+}
+
+func (m *MockClient) updateMonitor(w http.ResponseWriter, r *http.Request, monitorID string) {
+	monitor, ok := m.monitors[monitorID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var update monitorUpdateRequest
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		panic("error decoding update monitor request")
+	}
+	if update.Monitor.Delay != 0 {
+		monitor.Delay = update.Monitor.Delay
+	}
+	if update.Monitor.Timeout != 0 {
+		monitor.Timeout = update.Monitor.Timeout
+	}
+	if update.Monitor.MaxRetries != 0 {
+		monitor.MaxRetries = update.Monitor.MaxRetries
+	}
+	if update.Monitor.MaxRetriesDown != 0 {
+		monitor.MaxRetriesDown = update.Monitor.MaxRetriesDown
+	}
+	if update.Monitor.URLPath != "" {
+		monitor.URLPath = update.Monitor.URLPath
+	}
+	m.monitors[monitor.ID] = monitor
+
+	w.WriteHeader(http.StatusOK)
+	m.writeMonitor(w, monitor)
+}
+
+// AddMonitor adds a health monitor, and returns it with its ID. The ID of the monitor is kept if it is set.
+func (m *MockClient) AddMonitor(monitor monitors.Monitor) monitors.Monitor {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	monitor.ID = newID(monitor.ID)
+	m.monitors[monitor.ID] = monitor
+	return monitor
+}
@@ -19,12 +19,15 @@ package mockloadbalancer
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
 
 	"github.com/google/uuid"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/kops/upup/pkg/fi"
 )
 
 type poolListResponse struct {
@@ -39,6 +42,13 @@ type poolCreateRequest struct {
 	Pool pools.CreateOpts `json:"pool"`
 }
 
+type poolUpdateRequest struct {
+	Pool pools.UpdateOpts `json:"pool"`
+}
+
+// membersRe matches the members subresource of a pool, and the ID of a member
+var membersRe = regexp.MustCompile(`^([^/]+)/members/?([^/]*)$`)
+
 func (m *MockClient) mockPools() {
 	re := regexp.MustCompile(`/lbaas/pools/?`)
 
@@ -49,7 +59,10 @@ func (m *MockClient) mockPools() {
 		w.Header().Add("Content-Type", "application/json")
 
 		poolID := re.ReplaceAllString(r.URL.Path, "")
-		// TODO: handle /members subresource
+		if match := membersRe.FindStringSubmatch(poolID); match != nil {
+			m.handleMembers(w, r, match[1], match[2])
+			return
+		}
 		switch r.Method {
 		case http.MethodGet:
 			if poolID == "" {
@@ -60,6 +73,8 @@ func (m *MockClient) mockPools() {
 			}
 		case http.MethodPost:
 			m.createPool(w, r)
+		case http.MethodPut:
+			m.updatePool(w, r, poolID)
 		case http.MethodDelete:
 			m.deletePool(w, poolID)
 		default:
@@ -83,7 +98,10 @@ func (m *MockClient) listPools(w http.ResponseWriter, vals url.Values) {
 		if name != "" && name != p.Name {
 			continue
 		}
-		pools = append(pools, p)
+		if lbID := vals.Get("loadbalancer_id"); lbID != "" && lbID != m.poolLoadBalancerID(p.ID) {
+			continue
+		}
+		pools = append(pools, m.populatePool(p))
 	}
 
 	resp := poolListResponse{
@@ -102,7 +120,7 @@ func (m *MockClient) listPools(w http.ResponseWriter, vals url.Values) {
 func (m *MockClient) getPool(w http.ResponseWriter, poolID string) {
 	if pool, ok := m.pools[poolID]; ok {
 		resp := poolGetResponse{
-			Pool: pool,
+			Pool: m.populatePool(pool),
 		}
 		respB, err := json.Marshal(resp)
 		if err != nil {
@@ -119,13 +137,37 @@ func (m *MockClient) getPool(w http.ResponseWriter, poolID string) {
 
 func (m *MockClient) deletePool(w http.ResponseWriter, poolID string) {
 	if _, ok := m.pools[poolID]; ok {
-		delete(m.pools, poolID)
-		w.WriteHeader(http.StatusOK)
+		m.markPending(m.poolLoadBalancerID(poolID))
+		m.removePool(poolID)
+		w.WriteHeader(http.StatusNoContent)
 	} else {
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 
+// removePool removes the pool with its members and health monitor
+func (m *MockClient) removePool(poolID string) {
+	for id, monitor := range m.monitors {
+		if len(monitor.Pools) != 0 && monitor.Pools[0].ID == poolID {
+			delete(m.monitors, id)
+		}
+	}
+	delete(m.pools, poolID)
+	delete(m.members, poolID)
+}
+
+// populatePool returns the pool listing its members, by ID, and its health monitor, as Octavia does
+func (m *MockClient) populatePool(p pools.Pool) pools.Pool {
+	p = m.withMembers(p)
+	p.MonitorID = ""
+	for _, monitor := range m.monitors {
+		if len(monitor.Pools) != 0 && monitor.Pools[0].ID == p.ID {
+			p.MonitorID = monitor.ID
+		}
+	}
+	return p
+}
+
 func (m *MockClient) createPool(w http.ResponseWriter, r *http.Request) {
 	var create poolCreateRequest
 	err := json.NewDecoder(r.Body).Decode(&create)
@@ -135,14 +177,31 @@ func (m *MockClient) createPool(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusAccepted)
 
+	lbID := create.Pool.LoadbalancerID
+	if create.Pool.ListenerID != "" {
+		lbID = m.listenerLoadBalancerID(create.Pool.ListenerID)
+	}
 	p := pools.Pool{
-		ID:            uuid.New().String(),
-		Name:          create.Pool.Name,
-		LBMethod:      string(create.Pool.LBMethod),
-		Protocol:      string(create.Pool.Protocol),
-		Loadbalancers: []pools.LoadBalancerID{{ID: create.Pool.LoadbalancerID}},
+		ID:                uuid.New().String(),
+		Name:              create.Pool.Name,
+		LBMethod:          string(create.Pool.LBMethod),
+		Protocol:          string(create.Pool.Protocol),
+		Loadbalancers:     []pools.LoadBalancerID{{ID: lbID}},
+		TLSEnabled:        create.Pool.TLSEnabled,
+		CATLSContainerRef: create.Pool.CATLSContainerRef,
+		CRLContainerRef:   create.Pool.CRLContainerRef,
+		Tags:              create.Pool.Tags,
+	}
+	if create.Pool.ListenerID != "" {
+		p.Listeners = []pools.ListenerID{{ID: create.Pool.ListenerID}}
+		// the pool becomes the default pool of its listener
+		if l, ok := m.listeners[create.Pool.ListenerID]; ok {
+			l.DefaultPoolID = p.ID
+			m.listeners[l.ID] = l
+		}
 	}
 	m.pools[p.ID] = p
+	m.markPending(lbID)
 
 	resp := poolGetResponse{
 		Pool: p,
@@ -156,3 +215,76 @@ func (m *MockClient) createPool(w http.ResponseWriter, r *http.Request) {
 		panic("failed to write body")
 	}
 }
+
+func (m *MockClient) updatePool(w http.ResponseWriter, r *http.Request, poolID string) {
+	p, ok := m.pools[poolID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		panic("error reading update pool request")
+	}
+	var update poolUpdateRequest
+	// fields tells the references that are set to null, and so removed, from those that are left out
+	var fields struct {
+		Pool map[string]json.RawMessage `json:"pool"`
+	}
+	if json.Unmarshal(body, &update) != nil || json.Unmarshal(body, &fields) != nil {
+		panic("error decoding update pool request")
+	}
+	if update.Pool.TLSEnabled != nil {
+		p.TLSEnabled = *update.Pool.TLSEnabled
+	}
+	if _, found := fields.Pool["ca_tls_container_ref"]; found {
+		p.CATLSContainerRef = fi.ValueOf(update.Pool.CATLSContainerRef)
+	}
+	if _, found := fields.Pool["crl_container_ref"]; found {
+		p.CRLContainerRef = fi.ValueOf(update.Pool.CRLContainerRef)
+	}
+	if update.Pool.Tags != nil {
+		p.Tags = *update.Pool.Tags
+	}
+	m.pools[p.ID] = p
+	m.markPending(m.poolLoadBalancerID(p.ID))
+
+	w.WriteHeader(http.StatusOK)
+	resp := poolGetResponse{
+		Pool: m.populatePool(p),
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
+// AddPool adds a pool, and returns it with its ID. The ID of the pool is kept if it is set.
+func (m *MockClient) AddPool(p pools.Pool) pools.Pool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	p.ID = newID(p.ID)
+	p.Members = nil
+	p.MonitorID = ""
+	m.pools[p.ID] = p
+	return p
+}
+
+// Pools returns the pools, sorted by ID
+func (m *MockClient) Pools() []pools.Pool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make([]pools.Pool, 0, len(m.pools))
+	for _, p := range m.pools {
+		result = append(result, m.populatePool(p))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
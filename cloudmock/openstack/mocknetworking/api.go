@@ -37,6 +37,7 @@ type MockClient struct {
 
 	networks           map[string]externalNetwork
 	ports              map[string]ports.Port
+	portQosPolicies    map[string]string
 	routers            map[string]routers.Router
 	routerInterfaces   map[string][]routers.InterfaceInfo
 	securityGroups     map[string]groups.SecGroup
@@ -65,6 +66,7 @@ func CreateClient() *MockClient {
 func (m *MockClient) Reset() {
 	m.networks = make(map[string]externalNetwork)
 	m.ports = make(map[string]ports.Port)
+	m.portQosPolicies = make(map[string]string)
 	m.routers = make(map[string]routers.Router)
 	m.routerInterfaces = make(map[string][]routers.InterfaceInfo)
 	m.securityGroups = make(map[string]groups.SecGroup)
@@ -35,7 +35,13 @@ type portListResponse struct {
 }
 
 type portGetResponse struct {
-	Port ports.Port `json:"port"`
+	Port portWithQosPolicy `json:"port"`
+}
+
+// portWithQosPolicy is a port with the QoS policy of the qos extension
+type portWithQosPolicy struct {
+	ports.Port
+	QoSPolicyID string `json:"qos_policy_id,omitempty"`
 }
 
 type portCreateRequest struct {
@@ -43,7 +49,10 @@ type portCreateRequest struct {
 }
 
 type portUpdateRequest struct {
-	Port ports.UpdateOpts `json:"port"`
+	Port struct {
+		ports.UpdateOpts
+		QoSPolicyID *string `json:"qos_policy_id"`
+	} `json:"port"`
 }
 
 func (m *MockClient) mockPorts() {
@@ -123,7 +132,7 @@ func (m *MockClient) listPorts(w http.ResponseWriter, vals url.Values) {
 func (m *MockClient) getPort(w http.ResponseWriter, portID string) {
 	if port, ok := m.ports[portID]; ok {
 		resp := portGetResponse{
-			Port: port,
+			Port: portWithQosPolicy{Port: port, QoSPolicyID: m.portQosPolicies[portID]},
 		}
 		respB, err := json.Marshal(resp)
 		if err != nil {
@@ -141,6 +150,7 @@ func (m *MockClient) getPort(w http.ResponseWriter, portID string) {
 func (m *MockClient) deletePort(w http.ResponseWriter, portID string) {
 	if _, ok := m.ports[portID]; ok {
 		delete(m.ports, portID)
+		delete(m.portQosPolicies, portID)
 		w.WriteHeader(http.StatusOK)
 	} else {
 		w.WriteHeader(http.StatusNotFound)
@@ -183,7 +193,7 @@ func (m *MockClient) createPort(w http.ResponseWriter, r *http.Request) {
 	m.ports[p.ID] = p
 
 	resp := portGetResponse{
-		Port: p,
+		Port: portWithQosPolicy{Port: p},
 	}
 	respB, err := json.Marshal(resp)
 	if err != nil {
@@ -240,7 +250,34 @@ func (m *MockClient) updatePort(w http.ResponseWriter, r *http.Request) {
 	if deviceID != nil {
 		port.DeviceID = fi.ValueOf(deviceID)
 	}
+	if update.Port.QoSPolicyID != nil {
+		m.portQosPolicies[portID] = *update.Port.QoSPolicyID
+	}
+	if update.Port.SecurityGroups != nil {
+		port.SecurityGroups = *update.Port.SecurityGroups
+	}
 	m.ports[portID] = port
 
 	w.WriteHeader(http.StatusOK)
+	resp := portGetResponse{
+		Port: portWithQosPolicy{Port: port, QoSPolicyID: m.portQosPolicies[portID]},
+	}
+	respB, err := json.Marshal(resp)
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal %+v", resp))
+	}
+	_, err = w.Write(respB)
+	if err != nil {
+		panic("failed to write body")
+	}
+}
+
+// AddPort adds a port created by another service, such as the VIP port of a loadbalancer, and returns it with its ID
+func (m *MockClient) AddPort(port ports.Port) ports.Port {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	port.ID = uuid.New().String()
+	m.ports[port.ID] = port
+	return port
 }
@@ -860,6 +860,23 @@ type OpenstackLoadbalancerConfig struct {
 	EnableIngressHostname *bool   `json:"enableIngressHostname,omitempty"`
 	IngressHostnameSuffix *string `json:"ingressHostnameSuffix,omitempty"`
 	FlavorID              *string `json:"flavorID,omitempty"`
+	VipQosPolicyID        *string `json:"vipQosPolicyID,omitempty"`
+	// APIHealthCheckPath is the kube-apiserver path, one of /healthz, /livez or /readyz, checked by the health monitor of the API loadbalancer.
+	// When set, the monitor checks the path over HTTP on the kube-apiserver-healthcheck port instead of only checking the TCP connection.
+	APIHealthCheckPath *string `json:"apiHealthCheckPath,omitempty"`
+	// PreservedMemberTag keeps members carrying this tag in the API loadbalancer pool, even though they were not added by kops.
+	PreservedMemberTag *string `json:"preservedMemberTag,omitempty"`
+	// PreservedMemberNamePrefix keeps members whose name starts with this prefix in the API loadbalancer pool, even though they were not added by kops.
+	PreservedMemberNamePrefix *string `json:"preservedMemberNamePrefix,omitempty"`
+	// ProjectID is the project to create the API loadbalancer in, if it differs from the project of the credentials.
+	// Creating loadbalancers in another project requires admin rights.
+	ProjectID *string `json:"projectID,omitempty"`
+	// DeleteUnmanagedListeners deletes listeners on the API loadbalancer that were not added by kops,
+	// instead of only warning about them. Listeners tagged KopsIgnore are always left alone.
+	DeleteUnmanagedListeners *bool `json:"deleteUnmanagedListeners,omitempty"`
+	// AdditionalTags are set, as key=value tags, on the API loadbalancer and its listeners and pools, for example
+	// for chargeback. Tags kops uses to identify its resources, such as KubernetesCluster, cannot be overridden.
+	AdditionalTags map[string]string `json:"additionalTags,omitempty"`
 }
 
 type OpenstackBlockStorageConfig struct {
@@ -963,6 +980,10 @@ type CloudConfiguration struct {
 	// GCEUseStartupScript specifies enables using startup-script instead of user-data metadata.
 	// +k8s:conversion-gen=false
 	GCEUseStartupScript *bool `json:"gceUseStartupScript,omitempty"`
+	// GCENetworkTier is the default network tier (PREMIUM or STANDARD) for external load balancer
+	// forwarding rules and addresses. If unset, the project default is used.
+	// +k8s:conversion-gen=false
+	GCENetworkTier *string `json:"gceNetworkTier,omitempty"`
 	// DisableSecurityGroupIngress disables the Cloud Controller Manager's creation
 	// of an AWS Security Group for each load balancer provisioned for a Service (AWS only).
 	// +k8s:conversion-gen=false
@@ -205,6 +205,12 @@ func Convert_v1alpha2_ClusterSpec_To_kops_ClusterSpec(in *ClusterSpec, out *kops
 			}
 			out.CloudProvider.GCE.UseStartupScript = in.CloudConfig.GCEUseStartupScript
 		}
+		if in.CloudConfig.GCENetworkTier != nil {
+			if out.CloudProvider.GCE == nil {
+				return field.Forbidden(field.NewPath("spec").Child("cloudConfig", "gceNetworkTier"), "GCE network tier supports only GCE")
+			}
+			out.CloudProvider.GCE.NetworkTier = in.CloudConfig.GCENetworkTier
+		}
 		if in.CloudConfig.DisableSecurityGroupIngress != nil {
 			if out.CloudProvider.AWS == nil {
 				return field.Forbidden(field.NewPath("spec").Child("cloudConfig", "disableSecurityGroupIngress"), "disableSecurityGroupIngress supports only AWS")
@@ -553,6 +559,12 @@ func Convert_kops_ClusterSpec_To_v1alpha2_ClusterSpec(in *kops.ClusterSpec, out
 			}
 			out.CloudConfig.GCEUseStartupScript = gce.UseStartupScript
 		}
+		if gce.NetworkTier != nil {
+			if out.CloudConfig == nil {
+				out.CloudConfig = &CloudConfiguration{}
+			}
+			out.CloudConfig.GCENetworkTier = gce.NetworkTier
+		}
 		if gce.PDCSIDriver != nil {
 			if out.CloudConfig == nil {
 				out.CloudConfig = &CloudConfiguration{}
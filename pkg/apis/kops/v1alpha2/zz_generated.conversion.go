@@ -2205,6 +2205,7 @@ func autoConvert_v1alpha2_CloudConfiguration_To_kops_CloudConfiguration(in *Clou
 	// INFO: in.NodeIPFamilies opted out of conversion generation
 	// INFO: in.GCEServiceAccount opted out of conversion generation
 	// INFO: in.GCEUseStartupScript opted out of conversion generation
+	// INFO: in.GCENetworkTier opted out of conversion generation
 	// INFO: in.DisableSecurityGroupIngress opted out of conversion generation
 	// INFO: in.ElbSecurityGroup opted out of conversion generation
 	// INFO: in.VSphereUsername opted out of conversion generation
@@ -6803,6 +6804,13 @@ func autoConvert_v1alpha2_OpenstackLoadbalancerConfig_To_kops_OpenstackLoadbalan
 	out.EnableIngressHostname = in.EnableIngressHostname
 	out.IngressHostnameSuffix = in.IngressHostnameSuffix
 	out.FlavorID = in.FlavorID
+	out.VipQosPolicyID = in.VipQosPolicyID
+	out.APIHealthCheckPath = in.APIHealthCheckPath
+	out.PreservedMemberTag = in.PreservedMemberTag
+	out.PreservedMemberNamePrefix = in.PreservedMemberNamePrefix
+	out.ProjectID = in.ProjectID
+	out.DeleteUnmanagedListeners = in.DeleteUnmanagedListeners
+	out.AdditionalTags = in.AdditionalTags
 	return nil
 }
 
@@ -6823,6 +6831,13 @@ func autoConvert_kops_OpenstackLoadbalancerConfig_To_v1alpha2_OpenstackLoadbalan
 	out.EnableIngressHostname = in.EnableIngressHostname
 	out.IngressHostnameSuffix = in.IngressHostnameSuffix
 	out.FlavorID = in.FlavorID
+	out.VipQosPolicyID = in.VipQosPolicyID
+	out.APIHealthCheckPath = in.APIHealthCheckPath
+	out.PreservedMemberTag = in.PreservedMemberTag
+	out.PreservedMemberNamePrefix = in.PreservedMemberNamePrefix
+	out.ProjectID = in.ProjectID
+	out.DeleteUnmanagedListeners = in.DeleteUnmanagedListeners
+	out.AdditionalTags = in.AdditionalTags
 	return nil
 }
 
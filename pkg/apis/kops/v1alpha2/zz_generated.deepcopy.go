@@ -746,6 +746,11 @@ func (in *CloudConfiguration) DeepCopyInto(out *CloudConfiguration) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.GCENetworkTier != nil {
+		in, out := &in.GCENetworkTier, &out.GCENetworkTier
+		*out = new(string)
+		**out = **in
+	}
 	if in.DisableSecurityGroupIngress != nil {
 		in, out := &in.DisableSecurityGroupIngress, &out.DisableSecurityGroupIngress
 		*out = new(bool)
@@ -5275,6 +5280,43 @@ func (in *OpenstackLoadbalancerConfig) DeepCopyInto(out *OpenstackLoadbalancerCo
 		*out = new(string)
 		**out = **in
 	}
+	if in.VipQosPolicyID != nil {
+		in, out := &in.VipQosPolicyID, &out.VipQosPolicyID
+		*out = new(string)
+		**out = **in
+	}
+	if in.APIHealthCheckPath != nil {
+		in, out := &in.APIHealthCheckPath, &out.APIHealthCheckPath
+		*out = new(string)
+		**out = **in
+	}
+	if in.PreservedMemberTag != nil {
+		in, out := &in.PreservedMemberTag, &out.PreservedMemberTag
+		*out = new(string)
+		**out = **in
+	}
+	if in.PreservedMemberNamePrefix != nil {
+		in, out := &in.PreservedMemberNamePrefix, &out.PreservedMemberNamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProjectID != nil {
+		in, out := &in.ProjectID, &out.ProjectID
+		*out = new(string)
+		**out = **in
+	}
+	if in.DeleteUnmanagedListeners != nil {
+		in, out := &in.DeleteUnmanagedListeners, &out.DeleteUnmanagedListeners
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AdditionalTags != nil {
+		in, out := &in.AdditionalTags, &out.AdditionalTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -247,6 +247,9 @@ type GCESpec struct {
 
 	// BinariesLocation is the location of the GCE cloud provider binaries.
 	BinariesLocation *string `json:"binariesLocation,omitempty"`
+	// NetworkTier is the default network tier (PREMIUM or STANDARD) for external load balancer
+	// forwarding rules and addresses. If unset, the project default is used.
+	NetworkTier *string `json:"networkTier,omitempty"`
 }
 
 // HetznerSpec configures the Hetzner cloud provider.
@@ -4202,6 +4202,7 @@ func autoConvert_v1alpha3_GCESpec_To_kops_GCESpec(in *GCESpec, out *kops.GCESpec
 	}
 	out.UseStartupScript = in.UseStartupScript
 	out.BinariesLocation = in.BinariesLocation
+	out.NetworkTier = in.NetworkTier
 	return nil
 }
 
@@ -4227,6 +4228,7 @@ func autoConvert_kops_GCESpec_To_v1alpha3_GCESpec(in *kops.GCESpec, out *GCESpec
 	}
 	out.UseStartupScript = in.UseStartupScript
 	out.BinariesLocation = in.BinariesLocation
+	out.NetworkTier = in.NetworkTier
 	return nil
 }
 
@@ -7091,6 +7093,13 @@ func autoConvert_v1alpha3_OpenstackLoadbalancerConfig_To_kops_OpenstackLoadbalan
 	out.EnableIngressHostname = in.EnableIngressHostname
 	out.IngressHostnameSuffix = in.IngressHostnameSuffix
 	out.FlavorID = in.FlavorID
+	out.VipQosPolicyID = in.VipQosPolicyID
+	out.APIHealthCheckPath = in.APIHealthCheckPath
+	out.PreservedMemberTag = in.PreservedMemberTag
+	out.PreservedMemberNamePrefix = in.PreservedMemberNamePrefix
+	out.ProjectID = in.ProjectID
+	out.DeleteUnmanagedListeners = in.DeleteUnmanagedListeners
+	out.AdditionalTags = in.AdditionalTags
 	return nil
 }
 
@@ -7111,6 +7120,13 @@ func autoConvert_kops_OpenstackLoadbalancerConfig_To_v1alpha3_OpenstackLoadbalan
 	out.EnableIngressHostname = in.EnableIngressHostname
 	out.IngressHostnameSuffix = in.IngressHostnameSuffix
 	out.FlavorID = in.FlavorID
+	out.VipQosPolicyID = in.VipQosPolicyID
+	out.APIHealthCheckPath = in.APIHealthCheckPath
+	out.PreservedMemberTag = in.PreservedMemberTag
+	out.PreservedMemberNamePrefix = in.PreservedMemberNamePrefix
+	out.ProjectID = in.ProjectID
+	out.DeleteUnmanagedListeners = in.DeleteUnmanagedListeners
+	out.AdditionalTags = in.AdditionalTags
 	return nil
 }
 
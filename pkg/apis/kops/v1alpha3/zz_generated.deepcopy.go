@@ -2123,6 +2123,11 @@ func (in *GCESpec) DeepCopyInto(out *GCESpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.NetworkTier != nil {
+		in, out := &in.NetworkTier, &out.NetworkTier
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -5222,6 +5227,43 @@ func (in *OpenstackLoadbalancerConfig) DeepCopyInto(out *OpenstackLoadbalancerCo
 		*out = new(string)
 		**out = **in
 	}
+	if in.VipQosPolicyID != nil {
+		in, out := &in.VipQosPolicyID, &out.VipQosPolicyID
+		*out = new(string)
+		**out = **in
+	}
+	if in.APIHealthCheckPath != nil {
+		in, out := &in.APIHealthCheckPath, &out.APIHealthCheckPath
+		*out = new(string)
+		**out = **in
+	}
+	if in.PreservedMemberTag != nil {
+		in, out := &in.PreservedMemberTag, &out.PreservedMemberTag
+		*out = new(string)
+		**out = **in
+	}
+	if in.PreservedMemberNamePrefix != nil {
+		in, out := &in.PreservedMemberNamePrefix, &out.PreservedMemberNamePrefix
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProjectID != nil {
+		in, out := &in.ProjectID, &out.ProjectID
+		*out = new(string)
+		**out = **in
+	}
+	if in.DeleteUnmanagedListeners != nil {
+		in, out := &in.DeleteUnmanagedListeners, &out.DeleteUnmanagedListeners
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AdditionalTags != nil {
+		in, out := &in.AdditionalTags, &out.AdditionalTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -34,6 +34,13 @@ func gceValidateCluster(c *kops.Cluster) field.ErrorList {
 		}
 	}
 
+	if gceSpec := c.Spec.CloudProvider.GCE; gceSpec != nil && gceSpec.NetworkTier != nil {
+		// The default tier is applied to the regional, external resources of the API load balancer.
+		if err := gce.ValidateNetworkTier(*gceSpec.NetworkTier, false, "EXTERNAL"); err != nil {
+			allErrs = append(allErrs, field.Invalid(fieldSpec.Child("cloudProvider", "gce", "networkTier"), *gceSpec.NetworkTier, err.Error()))
+		}
+	}
+
 	return allErrs
 }
 
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func openstackValidateCluster(c *kops.Cluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	fieldSpec := field.NewPath("spec", "cloudProvider", "openstack")
+
+	if c.Spec.CloudProvider.Openstack == nil {
+		return allErrs
+	}
+
+	if lb := c.Spec.CloudProvider.Openstack.Loadbalancer; lb != nil && lb.APIHealthCheckPath != nil {
+		fieldPath := fieldSpec.Child("loadbalancer", "apiHealthCheckPath")
+		// the monitor checks the path through kube-apiserver-healthcheck, which only proxies these paths
+		allErrs = append(allErrs, IsValidValue(fieldPath, lb.APIHealthCheckPath, []string{"/healthz", "/livez", "/readyz"})...)
+		if fi.ValueOf(lb.Provider) == "ovn" {
+			allErrs = append(allErrs, field.Forbidden(fieldPath, "the ovn loadbalancer provider does not support HTTP health monitors"))
+		}
+	}
+
+	if lb := c.Spec.CloudProvider.Openstack.Loadbalancer; lb != nil {
+		fieldPath := fieldSpec.Child("loadbalancer", "additionalTags")
+		for _, key := range slices.Sorted(maps.Keys(lb.AdditionalTags)) {
+			if err := openstack.ValidateLoadBalancerTag(key, lb.AdditionalTags[key]); err != nil {
+				allErrs = append(allErrs, field.Invalid(fieldPath.Key(key), lb.AdditionalTags[key], err.Error()))
+			}
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func TestOpenstackValidateCluster(t *testing.T) {
+	grid := []struct {
+		Input          *kops.OpenstackLoadbalancerConfig
+		ExpectedErrors []string
+	}{
+		{
+			Input: nil,
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				APIHealthCheckPath: fi.PtrTo("/readyz"),
+			},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				APIHealthCheckPath: fi.PtrTo("/healthz"),
+				Provider:           fi.PtrTo("amphora"),
+			},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				APIHealthCheckPath: fi.PtrTo("readyz"),
+			},
+			ExpectedErrors: []string{"Unsupported value::spec.cloudProvider.openstack.loadbalancer.apiHealthCheckPath"},
+		},
+		{
+			// kube-apiserver-healthcheck does not proxy the individual checks
+			Input: &kops.OpenstackLoadbalancerConfig{
+				APIHealthCheckPath: fi.PtrTo("/readyz/etcd"),
+			},
+			ExpectedErrors: []string{"Unsupported value::spec.cloudProvider.openstack.loadbalancer.apiHealthCheckPath"},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				APIHealthCheckPath: fi.PtrTo("/livez"),
+				Provider:           fi.PtrTo("ovn"),
+			},
+			ExpectedErrors: []string{"Forbidden::spec.cloudProvider.openstack.loadbalancer.apiHealthCheckPath"},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				AdditionalTags: map[string]string{"team": "platform"},
+			},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				AdditionalTags: map[string]string{"team": "a,b"},
+			},
+			ExpectedErrors: []string{"Invalid value::spec.cloudProvider.openstack.loadbalancer.additionalTags[team]"},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				AdditionalTags: map[string]string{"team=a": "b"},
+			},
+			ExpectedErrors: []string{"Invalid value::spec.cloudProvider.openstack.loadbalancer.additionalTags[team=a]"},
+		},
+		{
+			Input: &kops.OpenstackLoadbalancerConfig{
+				AdditionalTags: map[string]string{openstack.TagClusterName: "other.k8s.local"},
+			},
+			ExpectedErrors: []string{"Invalid value::spec.cloudProvider.openstack.loadbalancer.additionalTags[" + openstack.TagClusterName + "]"},
+		},
+	}
+	for _, g := range grid {
+		cluster := &kops.Cluster{
+			Spec: kops.ClusterSpec{
+				CloudProvider: kops.CloudProviderSpec{
+					Openstack: &kops.OpenstackSpec{
+						Loadbalancer: g.Input,
+					},
+				},
+			},
+		}
+		errs := openstackValidateCluster(cluster)
+
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+		if len(errs) != len(g.ExpectedErrors) {
+			t.Errorf("expected %d errors for %v, got %v", len(g.ExpectedErrors), g.Input, errs)
+		}
+	}
+}
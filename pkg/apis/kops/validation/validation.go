@@ -72,6 +72,8 @@ func newValidateCluster(cluster *kops.Cluster, strict bool) field.ErrorList {
 		allErrs = append(allErrs, awsValidateCluster(cluster, strict)...)
 	case kops.CloudProviderGCE:
 		allErrs = append(allErrs, gceValidateCluster(cluster)...)
+	case kops.CloudProviderOpenstack:
+		allErrs = append(allErrs, openstackValidateCluster(cluster)...)
 	}
 
 	return allErrs
@@ -208,7 +208,7 @@ func (b *APILoadBalancerBuilder) createInternalLB(c *fi.CloudupModelBuilderConte
 			Subnetwork:          subnet,
 			Labels: map[string]string{
 				clusterLabel.Key: clusterLabel.Value,
-				"name":           "api-" + sn.Name,
+				"name":           gce.NormalizeLabelValue("api-" + sn.Name),
 			},
 		})
 		if b.Cluster.UsesNoneDNS() {
@@ -226,7 +226,7 @@ func (b *APILoadBalancerBuilder) createInternalLB(c *fi.CloudupModelBuilderConte
 				Subnetwork:          subnet,
 				Labels: map[string]string{
 					clusterLabel.Key: clusterLabel.Value,
-					"name":           "kops-controller-" + sn.Name,
+					"name":           gce.NormalizeLabelValue("kops-controller-" + sn.Name),
 				},
 			}
 			// We previously created a forwarding rule which was external; prune it
@@ -79,6 +79,15 @@ func (c *OpenstackModelContext) APIResourceName() string {
 	return "api." + c.ClusterName()
 }
 
+// apiHealthCheckPath returns the path checked by the health monitor of the API loadbalancer, or nil for a TCP check
+func (c *OpenstackModelContext) apiHealthCheckPath() *string {
+	osSpec := c.Cluster.Spec.CloudProvider.Openstack
+	if osSpec == nil || osSpec.Loadbalancer == nil {
+		return nil
+	}
+	return osSpec.Loadbalancer.APIHealthCheckPath
+}
+
 func (c *OpenstackModelContext) findSubnetClusterSpec(subnet string) (string, kops.SubnetType, error) {
 	for _, sp := range c.Cluster.Spec.Networking.Subnets {
 		if sp.Name == subnet {
@@ -329,6 +329,19 @@ func (b *FirewallModelBuilder) addHTTPSRules(c *fi.CloudupModelBuilderContext, s
 			}
 		}
 
+		// Allow the health monitor to reach kube-apiserver-healthcheck on the masters
+		if b.apiHealthCheckPath() != nil {
+			b.addDirectionalGroupRule(c, masterSG, nil, &openstacktasks.SecurityGroupRule{
+				Lifecycle:      b.Lifecycle,
+				Direction:      s(string(rules.DirIngress)),
+				Protocol:       s(IPProtocolTCP),
+				EtherType:      s(IPV4),
+				PortRangeMin:   i(wellknownports.KubeAPIServerHealthCheck),
+				PortRangeMax:   i(wellknownports.KubeAPIServerHealthCheck),
+				RemoteIPPrefix: s(b.Cluster.Spec.Networking.NetworkCIDR),
+			})
+		}
+
 	} else {
 		// Allow the masters to receive connections from KubernetesAPIAccess
 		for _, apiAccess := range b.Cluster.Spec.API.Access {
@@ -734,7 +747,7 @@ func (b *FirewallModelBuilder) Build(c *fi.CloudupModelBuilderContext) error {
 		} else if role == kops.InstanceGroupRoleNode {
 			sg.RemoveExtraRules = []string{"port=22", "port=10250"}
 		} else if role == kops.InstanceGroupRoleControlPlane {
-			sg.RemoveExtraRules = []string{"port=22", "port=443", "port=10250"}
+			sg.RemoveExtraRules = []string{"port=22", "port=443", "port=10250", fmt.Sprintf("port=%d", wellknownports.KubeAPIServerHealthCheck)}
 		}
 		c.AddTask(sg)
 		sgMap[groupName] = sg
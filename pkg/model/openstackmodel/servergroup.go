@@ -22,6 +22,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
 	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/pkg/apis/kops"
@@ -314,7 +315,7 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 		}
 
 		lbTask := &openstacktasks.LB{
-			Name:      fi.PtrTo(b.APIResourceName()),
+			Name:      fi.PtrTo(openstack.NormalizeLBName(b.APIResourceName())),
 			Subnet:    fi.PtrTo(lbSubnetName),
 			Lifecycle: b.Lifecycle,
 		}
@@ -322,12 +323,33 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 		if b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.FlavorID != nil {
 			lbTask.FlavorID = b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.FlavorID
 		}
+		if b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.VipQosPolicyID != nil {
+			lbTask.VipQosPolicyID = b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.VipQosPolicyID
+		}
+		if b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.ProjectID != nil {
+			lbTask.ProjectID = b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.ProjectID
+		}
+		if b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.DeleteUnmanagedListeners != nil {
+			lbTask.DeleteUnmanagedListeners = b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.DeleteUnmanagedListeners
+		}
 
 		useVIPACL := b.UseVIPACL()
 		if !useVIPACL {
 			lbTask.SecurityGroup = b.LinkToSecurityGroup(b.APIResourceName())
 		}
 
+		// Tags need Octavia API 2.5, which is available wherever VIP ACLs are
+		var lbTags []string
+		if useVIPACL {
+			ownershipTags := []string{
+				truncate.TruncateString(fmt.Sprintf("%s=%s", openstack.TagClusterName, b.ClusterName()), TRUNCATE_OPT),
+			}
+			lbTags = openstack.LoadBalancerTags(ownershipTags, b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.AdditionalTags)
+			lbTask.Tags = lbTags
+		} else if len(b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer.AdditionalTags) != 0 {
+			klog.Warningf("Octavia does not support tags, not setting the additional tags of the API loadbalancer")
+		}
+
 		c.AddTask(lbTask)
 
 		lbfipTask := &openstacktasks.FloatingIP{
@@ -340,9 +362,10 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 		lbfipTask.WellKnownServices = append(lbfipTask.WellKnownServices, wellknownservices.KubeAPIServer)
 
 		poolTask := &openstacktasks.LBPool{
-			Name:         fi.PtrTo(fmt.Sprintf("%s-https", fi.ValueOf(lbTask.Name))),
+			Name:         fi.PtrTo(openstack.NormalizeLBName(fmt.Sprintf("%s-https", fi.ValueOf(lbTask.Name)))),
 			Loadbalancer: lbTask,
 			Lifecycle:    b.Lifecycle,
+			Tags:         lbTags,
 		}
 		c.AddTask(poolTask)
 
@@ -363,6 +386,7 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 			}
 			sort.Strings(AllowedCIDRs)
 			listenerTask.AllowedCIDRs = AllowedCIDRs
+			listenerTask.Tags = lbTags
 		}
 		c.AddTask(listenerTask)
 
@@ -371,6 +395,15 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 			Pool:      poolTask,
 			Lifecycle: b.Lifecycle,
 		}
+		apiHealthCheckPath := b.apiHealthCheckPath()
+		if apiHealthCheckPath != nil {
+			// kube-apiserver rejects anonymous requests, so the path is checked
+			// through the kube-apiserver-healthcheck sidecar instead
+			monitorTask.Type = fi.PtrTo(monitors.TypeHTTP)
+			monitorTask.URLPath = apiHealthCheckPath
+		} else {
+			monitorTask.Type = fi.PtrTo(monitors.TypeTCP)
+		}
 		c.AddTask(monitorTask)
 
 		ifName, err := b.GetNetworkName()
@@ -378,6 +411,11 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 			return err
 		}
 
+		var preservedMembers *openstack.MemberPreservation
+		if preservation := openstack.NewMemberPreservation(b.Cluster.Spec.CloudProvider.Openstack.Loadbalancer); preservation != (openstack.MemberPreservation{}) {
+			preservedMembers = &preservation
+		}
+
 		for _, ig := range b.InstanceGroups {
 			if ig.Spec.Role == kops.InstanceGroupRoleControlPlane {
 				associateTask := &openstacktasks.PoolAssociation{
@@ -389,6 +427,19 @@ func (b *ServerGroupModelBuilder) Build(c *fi.CloudupModelBuilderContext) error
 					ProtocolPort:  fi.PtrTo(wellknownports.KubeAPIServer),
 					Lifecycle:     b.Lifecycle,
 					Weight:        fi.PtrTo(1),
+
+					PreservedMembers: preservedMembers,
+				}
+				if apiHealthCheckPath != nil {
+					associateTask.MonitorPort = fi.PtrTo(wellknownports.KubeAPIServerHealthCheck)
+				}
+				if v, ok := ig.ObjectMeta.Annotations[openstack.OS_ANNOTATION+openstack.LB_BACKUP_MEMBER]; ok {
+					switch v {
+					case "true", "enabled":
+						associateTask.Backup = fi.PtrTo(true)
+					default:
+						associateTask.Backup = fi.PtrTo(false)
+					}
 				}
 				c.AddTask(associateTask)
 			}
@@ -703,6 +703,8 @@ func getServerGroupModelBuilderTestInput() []serverGroupModelBuilderTestInput {
 								Method:          fi.PtrTo("ROUND_ROBIN"),
 								Provider:        fi.PtrTo("amphora"),
 								UseOctavia:      fi.PtrTo(true),
+
+								PreservedMemberTag: fi.PtrTo("kops-preserve"),
 							},
 							Monitor: &kops.OpenstackMonitor{
 								Delay:      fi.PtrTo("1m"),
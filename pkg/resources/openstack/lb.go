@@ -17,7 +17,6 @@ limitations under the License.
 package openstack
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
@@ -56,7 +55,7 @@ func (os *clusterDiscoveryOS) DeleteSubnetLBs(subnet subnets.Subnet) ([]*resourc
 	if preExistingSubnet {
 		// if we have preExistingSubnet, we cannot delete others than api LB
 		for _, lb := range lbs {
-			if lb.Name == fmt.Sprintf("api.%s", os.clusterName) {
+			if lb.Name == openstack.NormalizeLBName("api."+os.clusterName) {
 				filteredLBs = append(filteredLBs, lb)
 			}
 		}
@@ -65,6 +64,19 @@ func (os *clusterDiscoveryOS) DeleteSubnetLBs(subnet subnets.Subnet) ([]*resourc
 	}
 
 	for _, lb := range filteredLBs {
+		if os.osCloud.UseOctavia() {
+			// Octavia deletes the children with the loadbalancer, so the loadbalancers are deleted together
+			klog.V(2).Info("skipping LB Children because using Octavia")
+			resourceTrackers = append(resourceTrackers, &resources.Resource{
+				Name:         lb.Name,
+				ID:           lb.ID,
+				Type:         typeLB,
+				GroupKey:     typeLB,
+				GroupDeleter: os.deleteLBs,
+			})
+			continue
+		}
+
 		resourceTracker := &resources.Resource{
 			Name: lb.Name,
 			ID:   lb.ID,
@@ -78,11 +90,6 @@ func (os *clusterDiscoveryOS) DeleteSubnetLBs(subnet subnets.Subnet) ([]*resourc
 		}
 		resourceTrackers = append(resourceTrackers, resourceTracker)
 
-		if os.osCloud.UseOctavia() {
-			klog.V(2).Info("skipping LB Children because using Octavia")
-			continue
-		}
-
 		// Identify pools associated to this LB
 		for _, pool := range lb.Pools {
 
@@ -131,3 +138,14 @@ func (os *clusterDiscoveryOS) DeleteSubnetLBs(subnet subnets.Subnet) ([]*resourc
 
 	return resourceTrackers, nil
 }
+
+// deleteLBs deletes the loadbalancers of the trackers in parallel, with their children
+func (os *clusterDiscoveryOS) deleteLBs(cloud fi.Cloud, trackers []*resources.Resource) error {
+	var ids []string
+	for _, t := range trackers {
+		ids = append(ids, t.ID)
+	}
+	return cloud.(openstack.OpenstackCloud).DeleteClusterLoadBalancers(os.clusterName, openstack.DeleteClusterLoadBalancersOptions{
+		LoadBalancerIDs: ids,
+	})
+}
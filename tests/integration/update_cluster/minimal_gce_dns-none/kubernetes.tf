@@ -442,6 +442,7 @@ resource "google_compute_firewall" "ssh-external-to-node-minimal-gce-example-com
 
 resource "google_compute_forwarding_rule" "api-us-test1-minimal-gce-example-com" {
   backend_service = google_compute_backend_service.api-minimal-gce-example-com.id
+  depends_on      = [google_compute_backend_service.api-minimal-gce-example-com, google_compute_health_check.api-minimal-gce-example-com]
   ip_address      = google_compute_address.api-us-test1-minimal-gce-example-com.address
   ip_protocol     = "TCP"
   labels = {
@@ -457,6 +458,7 @@ resource "google_compute_forwarding_rule" "api-us-test1-minimal-gce-example-com"
 
 resource "google_compute_forwarding_rule" "kops-controller-us-test1-minimal-gce-example-com" {
   backend_service = google_compute_backend_service.api-minimal-gce-example-com.id
+  depends_on      = [google_compute_backend_service.api-minimal-gce-example-com, google_compute_health_check.api-minimal-gce-example-com]
   ip_address      = google_compute_address.api-us-test1-minimal-gce-example-com.address
   ip_protocol     = "TCP"
   labels = {
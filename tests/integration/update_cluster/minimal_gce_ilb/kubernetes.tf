@@ -426,6 +426,7 @@ resource "google_compute_firewall" "ssh-external-to-node-minimal-gce-ilb-example
 
 resource "google_compute_forwarding_rule" "api-us-test1-minimal-gce-ilb-example-com" {
   backend_service = google_compute_backend_service.api-minimal-gce-ilb-example-com.id
+  depends_on      = [google_compute_backend_service.api-minimal-gce-ilb-example-com, google_compute_health_check.api-minimal-gce-ilb-example-com]
   ip_address      = google_compute_address.api-us-test1-minimal-gce-ilb-example-com.address
   ip_protocol     = "TCP"
   labels = {
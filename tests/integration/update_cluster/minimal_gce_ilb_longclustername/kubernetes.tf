@@ -426,6 +426,7 @@ resource "google_compute_firewall" "ssh-external-to-node-minimal-gce-with-a-very
 
 resource "google_compute_forwarding_rule" "api-us-test1-minimal-gce-with-a-very-very-very-very-very-96dqvi" {
   backend_service = google_compute_backend_service.api-minimal-gce-with-a-very-very-very-very-very-long-nam-96dqvi.id
+  depends_on      = [google_compute_backend_service.api-minimal-gce-with-a-very-very-very-very-very-long-nam-96dqvi, google_compute_health_check.api-minimal-gce-with-a-very-very-very-very-very-long-nam-96dqvi]
   ip_address      = google_compute_address.api-us-test1-minimal-gce-with-a-very-very-very-very-very-96dqvi.address
   ip_protocol     = "TCP"
   labels = {
@@ -443,6 +443,7 @@ resource "google_compute_forwarding_rule" "api-minimal-gce-plb-example-com" {
 
 resource "google_compute_forwarding_rule" "api-us-test1-minimal-gce-plb-example-com" {
   backend_service = google_compute_backend_service.api-minimal-gce-plb-example-com.id
+  depends_on      = [google_compute_backend_service.api-minimal-gce-plb-example-com, google_compute_health_check.api-minimal-gce-plb-example-com]
   ip_address      = google_compute_address.api-us-test1-minimal-gce-plb-example-com.address
   ip_protocol     = "TCP"
   labels = {
@@ -723,7 +723,9 @@ func (c *ApplyClusterCmd) Run(ctx context.Context) (*ApplyResults, error) {
 	case TargetDirect:
 		switch cluster.GetCloudProvider() {
 		case kops.CloudProviderGCE:
-			target = gce.NewGCEAPITarget(cloud.(gce.GCECloud))
+			gceTarget := gce.NewGCEAPITarget(cloud.(gce.GCECloud))
+			gceTarget.BatchLabelUpdates = true
+			target = gceTarget
 		case kops.CloudProviderAWS:
 			target = awsup.NewAWSAPITarget(cloud.(awsup.AWSCloud))
 		case kops.CloudProviderDO:
@@ -810,6 +812,12 @@ func (c *ApplyClusterCmd) Run(ctx context.Context) (*ApplyResults, error) {
 
 	err = context.RunTasks(options)
 	if err != nil {
+		if gceTarget, ok := target.(*gce.GCEAPITarget); ok {
+			// the label changes of the tasks that ran were reported as applied
+			if err := gceTarget.FlushLabelUpdates(ctx); err != nil {
+				klog.Warningf("error setting labels of forwarding rules: %v", err)
+			}
+		}
 		return nil, fmt.Errorf("error running tasks: %v", err)
 	}
 
@@ -19,10 +19,18 @@ package gce
 import (
 	"context"
 	"fmt"
+	"os"
 
 	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	"k8s.io/klog/v2"
 )
 
+// computeEndpointEnvVar overrides the endpoint of the compute API, for example to reach it through
+// Private Google Access or Private Service Connect. It is the variable gcloud uses for the same purpose,
+// and takes the full base URL, such as https://compute-example.p.googleapis.com/compute/v1/.
+const computeEndpointEnvVar = "CLOUDSDK_API_ENDPOINT_OVERRIDES_COMPUTE"
+
 type ComputeClient interface {
 	Projects() ProjectClient
 	Regions() RegionClient
@@ -31,9 +39,11 @@ type ComputeClient interface {
 	Subnetworks() SubnetworkClient
 	Routes() RouteClient
 	ForwardingRules() ForwardingRuleClient
+	GlobalForwardingRules() GlobalForwardingRuleClient
 	HTTPHealthChecks() HttpHealthChecksClient
 	RegionHealthChecks() RegionHealthChecksClient
 	Addresses() AddressClient
+	GlobalAddresses() GlobalAddressClient
 	Firewalls() FirewallClient
 	Routers() RouterClient
 	Instances() InstanceClient
@@ -42,6 +52,7 @@ type ComputeClient interface {
 	TargetPools() TargetPoolClient
 	Disks() DiskClient
 	RegionBackendServices() RegionBackendServiceClient
+	ServiceAttachments() ServiceAttachmentClient
 }
 
 type computeClientImpl struct {
@@ -50,8 +61,18 @@ type computeClientImpl struct {
 
 var _ ComputeClient = &computeClientImpl{}
 
-func newComputeClientImpl(ctx context.Context) (*computeClientImpl, error) {
-	srv, err := compute.NewService(ctx)
+// computeClientOptions returns the options for the compute API client, honoring the endpoint override.
+func computeClientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if endpoint := os.Getenv(computeEndpointEnvVar); endpoint != "" {
+		klog.V(2).Infof("using compute API endpoint %s from %s", endpoint, computeEndpointEnvVar)
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	return opts
+}
+
+func newComputeClientImpl(ctx context.Context, opts ...option.ClientOption) (*computeClientImpl, error) {
+	srv, err := compute.NewService(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("error building compute API client: %v", err)
 	}
@@ -102,12 +123,24 @@ func (c *computeClientImpl) ForwardingRules() ForwardingRuleClient {
 	}
 }
 
+func (c *computeClientImpl) GlobalForwardingRules() GlobalForwardingRuleClient {
+	return &globalForwardingRuleClientImpl{
+		srv: c.srv.GlobalForwardingRules,
+	}
+}
+
 func (c *computeClientImpl) RegionBackendServices() RegionBackendServiceClient {
 	return &regionBackendServiceClientImpl{
 		srv: c.srv.RegionBackendServices,
 	}
 }
 
+func (c *computeClientImpl) ServiceAttachments() ServiceAttachmentClient {
+	return &serviceAttachmentClientImpl{
+		srv: c.srv.ServiceAttachments,
+	}
+}
+
 func (c *computeClientImpl) HTTPHealthChecks() HttpHealthChecksClient {
 	return &httpHealthCheckClientImpl{
 		srv: c.srv.HttpHealthChecks,
@@ -126,6 +159,12 @@ func (c *computeClientImpl) Addresses() AddressClient {
 	}
 }
 
+func (c *computeClientImpl) GlobalAddresses() GlobalAddressClient {
+	return &globalAddressClientImpl{
+		srv: c.srv.GlobalAddresses,
+	}
+}
+
 func (c *computeClientImpl) Firewalls() FirewallClient {
 	return &firewallClientImpl{
 		srv: c.srv.Firewalls,
@@ -302,6 +341,7 @@ type RegionBackendServiceClient interface {
 	Delete(project, region, name string) (*compute.Operation, error)
 	Get(project, region, name string) (*compute.BackendService, error)
 	List(ctx context.Context, project, region string) ([]*compute.BackendService, error)
+	Patch(project, region, name string, bs *compute.BackendService) (*compute.Operation, error)
 }
 
 type regionBackendServiceClientImpl struct {
@@ -322,6 +362,10 @@ func (c *regionBackendServiceClientImpl) Get(project, region, name string) (*com
 	return c.srv.Get(project, region, name).Do()
 }
 
+func (c *regionBackendServiceClientImpl) Patch(project, region, name string, bs *compute.BackendService) (*compute.Operation, error) {
+	return c.srv.Patch(project, region, name, bs).Do()
+}
+
 func (c *regionBackendServiceClientImpl) List(ctx context.Context, project, region string) ([]*compute.BackendService, error) {
 	var hcs []*compute.BackendService
 	if err := c.srv.List(project, region).Pages(ctx, func(p *compute.BackendServiceList) error {
@@ -367,6 +411,7 @@ type ForwardingRuleClient interface {
 	Get(ctx context.Context, project, region, name string) (*compute.ForwardingRule, error)
 	List(ctx context.Context, project, region string) ([]*compute.ForwardingRule, error)
 	SetLabels(ctx context.Context, project, region, resource string, request *compute.RegionSetLabelsRequest) (*compute.Operation, error)
+	Patch(ctx context.Context, project, region, name string, fr *compute.ForwardingRule) (*compute.Operation, error)
 }
 
 type forwardingRuleClientImpl struct {
@@ -391,6 +436,10 @@ func (c *forwardingRuleClientImpl) SetLabels(ctx context.Context, project string
 	return c.srv.SetLabels(project, region, resource, request).Context(ctx).Do()
 }
 
+func (c *forwardingRuleClientImpl) Patch(ctx context.Context, project, region, name string, fr *compute.ForwardingRule) (*compute.Operation, error) {
+	return c.srv.Patch(project, region, name, fr).Context(ctx).Do()
+}
+
 func (c *forwardingRuleClientImpl) List(ctx context.Context, project, region string) ([]*compute.ForwardingRule, error) {
 	var frs []*compute.ForwardingRule
 	if err := c.srv.List(project, region).Pages(ctx, func(p *compute.ForwardingRuleList) error {
@@ -402,6 +451,47 @@ func (c *forwardingRuleClientImpl) List(ctx context.Context, project, region str
 	return frs, nil
 }
 
+type GlobalForwardingRuleClient interface {
+	Insert(ctx context.Context, project string, fr *compute.ForwardingRule) (*compute.Operation, error)
+	Delete(ctx context.Context, project, name string) (*compute.Operation, error)
+	Get(ctx context.Context, project, name string) (*compute.ForwardingRule, error)
+	List(ctx context.Context, project string) ([]*compute.ForwardingRule, error)
+	SetLabels(ctx context.Context, project, resource string, request *compute.GlobalSetLabelsRequest) (*compute.Operation, error)
+}
+
+type globalForwardingRuleClientImpl struct {
+	srv *compute.GlobalForwardingRulesService
+}
+
+var _ GlobalForwardingRuleClient = &globalForwardingRuleClientImpl{}
+
+func (c *globalForwardingRuleClientImpl) Insert(ctx context.Context, project string, fr *compute.ForwardingRule) (*compute.Operation, error) {
+	return c.srv.Insert(project, fr).Context(ctx).Do()
+}
+
+func (c *globalForwardingRuleClientImpl) Delete(ctx context.Context, project, name string) (*compute.Operation, error) {
+	return c.srv.Delete(project, name).Context(ctx).Do()
+}
+
+func (c *globalForwardingRuleClientImpl) Get(ctx context.Context, project, name string) (*compute.ForwardingRule, error) {
+	return c.srv.Get(project, name).Context(ctx).Do()
+}
+
+func (c *globalForwardingRuleClientImpl) SetLabels(ctx context.Context, project string, resource string, request *compute.GlobalSetLabelsRequest) (*compute.Operation, error) {
+	return c.srv.SetLabels(project, resource, request).Context(ctx).Do()
+}
+
+func (c *globalForwardingRuleClientImpl) List(ctx context.Context, project string) ([]*compute.ForwardingRule, error) {
+	var frs []*compute.ForwardingRule
+	if err := c.srv.List(project).Pages(ctx, func(p *compute.ForwardingRuleList) error {
+		frs = append(frs, p.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return frs, nil
+}
+
 type RegionHealthChecksClient interface {
 	Insert(project, region string, fr *compute.HealthCheck) (*compute.Operation, error)
 	Delete(project, region, name string) (*compute.Operation, error)
@@ -519,6 +609,51 @@ func (c *addressClientImpl) ListWithFilter(project, region, filter string) ([]*c
 	return addrs.Items, nil
 }
 
+type GlobalAddressClient interface {
+	Insert(project string, addr *compute.Address) (*compute.Operation, error)
+	Delete(project, name string) (*compute.Operation, error)
+	Get(project, name string) (*compute.Address, error)
+	List(ctx context.Context, project string) ([]*compute.Address, error)
+	ListWithFilter(project, filter string) ([]*compute.Address, error)
+}
+
+type globalAddressClientImpl struct {
+	srv *compute.GlobalAddressesService
+}
+
+var _ GlobalAddressClient = &globalAddressClientImpl{}
+
+func (c *globalAddressClientImpl) Insert(project string, addr *compute.Address) (*compute.Operation, error) {
+	return c.srv.Insert(project, addr).Do()
+}
+
+func (c *globalAddressClientImpl) Delete(project, name string) (*compute.Operation, error) {
+	return c.srv.Delete(project, name).Do()
+}
+
+func (c *globalAddressClientImpl) Get(project, name string) (*compute.Address, error) {
+	return c.srv.Get(project, name).Do()
+}
+
+func (c *globalAddressClientImpl) List(ctx context.Context, project string) ([]*compute.Address, error) {
+	var addrs []*compute.Address
+	if err := c.srv.List(project).Pages(ctx, func(p *compute.AddressList) error {
+		addrs = append(addrs, p.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
+func (c *globalAddressClientImpl) ListWithFilter(project, filter string) ([]*compute.Address, error) {
+	addrs, err := c.srv.List(project).Filter(filter).Do()
+	if err != nil {
+		return nil, err
+	}
+	return addrs.Items, nil
+}
+
 type FirewallClient interface {
 	Insert(project string, fw *compute.Firewall) (*compute.Operation, error)
 	Delete(project, name string) (*compute.Operation, error)
@@ -788,6 +923,37 @@ func (c *targetPoolClientImpl) List(ctx context.Context, project, region string)
 	return tps, nil
 }
 
+type ServiceAttachmentClient interface {
+	Insert(ctx context.Context, project, region string, sa *compute.ServiceAttachment) (*compute.Operation, error)
+	Delete(ctx context.Context, project, region, name string) (*compute.Operation, error)
+	List(ctx context.Context, project, region string) ([]*compute.ServiceAttachment, error)
+}
+
+type serviceAttachmentClientImpl struct {
+	srv *compute.ServiceAttachmentsService
+}
+
+var _ ServiceAttachmentClient = &serviceAttachmentClientImpl{}
+
+func (c *serviceAttachmentClientImpl) Insert(ctx context.Context, project, region string, sa *compute.ServiceAttachment) (*compute.Operation, error) {
+	return c.srv.Insert(project, region, sa).Context(ctx).Do()
+}
+
+func (c *serviceAttachmentClientImpl) Delete(ctx context.Context, project, region, name string) (*compute.Operation, error) {
+	return c.srv.Delete(project, region, name).Context(ctx).Do()
+}
+
+func (c *serviceAttachmentClientImpl) List(ctx context.Context, project, region string) ([]*compute.ServiceAttachment, error) {
+	var sas []*compute.ServiceAttachment
+	if err := c.srv.List(project, region).Pages(ctx, func(p *compute.ServiceAttachmentList) error {
+		sas = append(sas, p.Items...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return sas, nil
+}
+
 type DiskClient interface {
 	Insert(project, zone string, disk *compute.Disk) (*compute.Operation, error)
 	Delete(project, zone, name string) (*compute.Operation, error)
@@ -0,0 +1,57 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/api/option"
+)
+
+func TestComputeEndpointOverride(t *testing.T) {
+	ctx := context.TODO()
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "api", "portRange": "443-443"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv(computeEndpointEnvVar, server.URL+"/private/compute/v1/")
+
+	client, err := newComputeClientImpl(ctx, append(computeClientOptions(), option.WithoutAuthentication())...)
+	if err != nil {
+		t.Fatalf("error building compute client: %v", err)
+	}
+
+	fr, err := client.ForwardingRules().Get(ctx, "testproject", "us-test1", "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if fr.PortRange != "443-443" {
+		t.Errorf("expected the forwarding rule served by the override, got %+v", fr)
+	}
+	expected := "GET /private/compute/v1/projects/testproject/regions/us-test1/forwardingRules/api"
+	if len(requests) != 1 || requests[0] != expected {
+		t.Errorf("expected request %q, got %v", expected, requests)
+	}
+}
@@ -17,11 +17,32 @@ limitations under the License.
 package gce
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+	compute "google.golang.org/api/compute/v1"
+	"k8s.io/klog/v2"
 	"k8s.io/kops/upup/pkg/fi"
 )
 
+// maxConcurrentLabelOperationWaits bounds the operations of batched label updates which are polled at once.
+const maxConcurrentLabelOperationWaits = 16
+
 type GCEAPITarget struct {
 	Cloud GCECloud
+
+	// BatchLabelUpdates defers the label updates of ForwardingRules to Finish,
+	// which issues them together and polls their operations concurrently.
+	BatchLabelUpdates bool
+
+	mutex                             sync.Mutex
+	pendingForwardingRuleLabels       map[string]*compute.RegionSetLabelsRequest
+	pendingGlobalForwardingRuleLabels map[string]*compute.GlobalSetLabelsRequest
 }
 
 var _ fi.CloudupTarget = &GCEAPITarget{}
@@ -33,9 +54,111 @@ func NewGCEAPITarget(cloud GCECloud) *GCEAPITarget {
 }
 
 func (t *GCEAPITarget) Finish(taskMap map[string]fi.CloudupTask) error {
-	return nil
+	return t.FlushLabelUpdates(context.Background())
 }
 
 func (t *GCEAPITarget) DefaultCheckExisting() bool {
 	return true
 }
+
+// SetForwardingRuleLabels sets the labels of the regional ForwardingRule name.
+// With BatchLabelUpdates the update is queued until Finish, otherwise it is applied immediately.
+func (t *GCEAPITarget) SetForwardingRuleLabels(ctx context.Context, name string, req *compute.RegionSetLabelsRequest) error {
+	if !t.BatchLabelUpdates {
+		op, err := t.Cloud.Compute().ForwardingRules().SetLabels(ctx, t.Cloud.Project(), t.Cloud.Region(), name, req)
+		if err != nil {
+			return fmt.Errorf("setting ForwardingRule %q labels: %w", name, err)
+		}
+		if err := t.Cloud.WaitForOpContext(ctx, op); err != nil {
+			return fmt.Errorf("setting ForwardingRule %q labels: %w", name, err)
+		}
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pendingForwardingRuleLabels == nil {
+		t.pendingForwardingRuleLabels = make(map[string]*compute.RegionSetLabelsRequest)
+	}
+	t.pendingForwardingRuleLabels[name] = req
+	return nil
+}
+
+// SetGlobalForwardingRuleLabels sets the labels of the global ForwardingRule name, which uses another API than regional rules.
+// With BatchLabelUpdates the update is queued until Finish, otherwise it is applied immediately.
+func (t *GCEAPITarget) SetGlobalForwardingRuleLabels(ctx context.Context, name string, req *compute.GlobalSetLabelsRequest) error {
+	if !t.BatchLabelUpdates {
+		op, err := t.Cloud.Compute().GlobalForwardingRules().SetLabels(ctx, t.Cloud.Project(), name, req)
+		if err != nil {
+			return fmt.Errorf("setting global ForwardingRule %q labels: %w", name, err)
+		}
+		if err := t.Cloud.WaitForOpContext(ctx, op); err != nil {
+			return fmt.Errorf("setting global ForwardingRule %q labels: %w", name, err)
+		}
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.pendingGlobalForwardingRuleLabels == nil {
+		t.pendingGlobalForwardingRuleLabels = make(map[string]*compute.GlobalSetLabelsRequest)
+	}
+	t.pendingGlobalForwardingRuleLabels[name] = req
+	return nil
+}
+
+// FlushLabelUpdates issues the queued label updates, and then waits for all of their operations concurrently.
+// It is called by Finish, and must be called when the tasks fail, as Finish is not called then.
+func (t *GCEAPITarget) FlushLabelUpdates(ctx context.Context) error {
+	t.mutex.Lock()
+	pending := t.pendingForwardingRuleLabels
+	pendingGlobal := t.pendingGlobalForwardingRuleLabels
+	t.pendingForwardingRuleLabels = nil
+	t.pendingGlobalForwardingRuleLabels = nil
+	t.mutex.Unlock()
+
+	if len(pending) == 0 && len(pendingGlobal) == 0 {
+		return nil
+	}
+
+	klog.V(2).Infof("Setting labels of %d ForwardingRules", len(pending)+len(pendingGlobal))
+
+	// ops are keyed by the description of their rule, as a regional and a global rule may share a name
+	var errs []error
+	ops := make(map[string]*compute.Operation)
+	for _, name := range slices.Sorted(maps.Keys(pending)) {
+		op, err := t.Cloud.Compute().ForwardingRules().SetLabels(ctx, t.Cloud.Project(), t.Cloud.Region(), name, pending[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("setting ForwardingRule %q labels: %w", name, err))
+			continue
+		}
+		ops[fmt.Sprintf("ForwardingRule %q", name)] = op
+	}
+	for _, name := range slices.Sorted(maps.Keys(pendingGlobal)) {
+		op, err := t.Cloud.Compute().GlobalForwardingRules().SetLabels(ctx, t.Cloud.Project(), name, pendingGlobal[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("setting global ForwardingRule %q labels: %w", name, err))
+			continue
+		}
+		ops[fmt.Sprintf("global ForwardingRule %q", name)] = op
+	}
+
+	var mutex sync.Mutex
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentLabelOperationWaits)
+	for rule, op := range ops {
+		g.Go(func() error {
+			if err := t.Cloud.WaitForOpContext(ctx, op); err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("setting %s labels: %w", rule, err))
+				mutex.Unlock()
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	return errors.Join(errs...)
+}
@@ -47,9 +47,15 @@ type GCECloud interface {
 	CloudDNS() DNSClient
 	Project() string
 	WaitForOp(op *compute.Operation) error
+	// WaitForOpContext waits for the operation to complete, returning early if the context is cancelled
+	WaitForOpContext(ctx context.Context, op *compute.Operation) error
 	Labels() map[string]string
 	Zones() ([]string, error)
 
+	// NetworkTier returns the default network tier of the cluster's external load balancer resources,
+	// or "" to use the project default
+	NetworkTier() string
+
 	// ServiceAccount returns the email for the service account that the instances will run under
 	ServiceAccount() (string, error)
 
@@ -78,6 +84,9 @@ type gceCloudImplementation struct {
 	projectInfo *compute.Project
 
 	labels map[string]string
+
+	// networkTier is the default network tier of external load balancer resources
+	networkTier string
 }
 
 var _ fi.Cloud = &gceCloudImplementation{}
@@ -136,7 +145,7 @@ func NewGCECloud(region string, project string, labels map[string]string) (GCECl
 		klog.Infof("Will load GOOGLE_APPLICATION_CREDENTIALS from %s", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"))
 	}
 
-	computeClient, err := newComputeClientImpl(ctx)
+	computeClient, err := newComputeClientImpl(ctx, computeClientOptions()...)
 	if err != nil {
 		return nil, fmt.Errorf("error building compute API client: %v", err)
 	}
@@ -192,6 +201,8 @@ func CacheGCECloudInstance(region string, project string, c GCECloud) {
 type gceCloudInternal interface {
 	// WithLabels returns a copy of the GCECloud, bound to the specified labels
 	WithLabels(labels map[string]string) GCECloud
+	// WithNetworkTier returns a copy of the GCECloud, bound to the specified default network tier
+	WithNetworkTier(networkTier string) GCECloud
 }
 
 // WithLabels returns a copy of the GCECloud, bound to the specified labels
@@ -270,6 +281,19 @@ func (c *gceCloudImplementation) FindVPCInfo(id string) (*fi.VPCInfo, error) {
 	return nil, nil
 }
 
+// WithNetworkTier returns a copy of the GCECloud, bound to the specified default network tier
+func (c *gceCloudImplementation) WithNetworkTier(networkTier string) GCECloud {
+	i := &gceCloudImplementation{}
+	*i = *c
+	i.networkTier = networkTier
+	return i
+}
+
+// NetworkTier returns the default network tier of external load balancer resources
+func (c *gceCloudImplementation) NetworkTier() string {
+	return c.networkTier
+}
+
 func (c *gceCloudImplementation) Labels() map[string]string {
 	// Defensive copy
 	tags := make(map[string]string)
@@ -312,6 +336,10 @@ func (c *gceCloudImplementation) WaitForOp(op *compute.Operation) error {
 	return WaitForOp(c.compute.srv, op)
 }
 
+func (c *gceCloudImplementation) WaitForOpContext(ctx context.Context, op *compute.Operation) error {
+	return WaitForOpContext(ctx, c.compute.srv, op)
+}
+
 func (c *gceCloudImplementation) GetApiIngressStatus(cluster *kops.Cluster) ([]fi.ApiIngressStatus, error) {
 	// TODO: Add context to GetApiIngressStatus
 
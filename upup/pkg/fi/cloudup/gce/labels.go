@@ -20,9 +20,11 @@ import (
 	"bytes"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
 
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/truncate"
 )
 
 const (
@@ -37,6 +39,51 @@ const (
 	Node                          = "node"
 )
 
+const (
+	// MaxLabels is the maximum number of labels GCE allows on a resource
+	MaxLabels = 64
+	// maxLabelLength is the maximum length of a GCE label key or value
+	maxLabelLength = 63
+)
+
+var (
+	labelKeyRegexp   = regexp.MustCompile(`^[\p{Ll}\p{Lo}][\p{Ll}\p{Lo}\p{N}_-]*$`)
+	labelValueRegexp = regexp.MustCompile(`^[\p{Ll}\p{Lo}\p{N}_-]*$`)
+	invalidLabelRune = regexp.MustCompile(`[^a-z0-9_-]`)
+)
+
+// ValidateLabel checks that key and value satisfy the GCE label constraints:
+// keys must start with a lowercase letter, both may only contain lowercase letters, digits, _ and -,
+// and neither may be longer than 63 characters.
+func ValidateLabel(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("label key must not be empty")
+	}
+	if n := len([]rune(key)); n > maxLabelLength {
+		return fmt.Errorf("label key %q is %d characters long, must be at most %d", key, n, maxLabelLength)
+	}
+	if !labelKeyRegexp.MatchString(key) {
+		return fmt.Errorf("label key %q must start with a lowercase letter and contain only lowercase letters, digits, _ and -", key)
+	}
+	if n := len([]rune(value)); n > maxLabelLength {
+		return fmt.Errorf("value of label %q is %d characters long, must be at most %d", key, n, maxLabelLength)
+	}
+	if !labelValueRegexp.MatchString(value) {
+		return fmt.Errorf("value %q of label %q must contain only lowercase letters, digits, _ and -", value, key)
+	}
+	return nil
+}
+
+// NormalizeLabelValue turns s into a valid GCE label value, for labels whose value is chosen by kOps.
+// It is lowercased, invalid characters are replaced by -, and long values are truncated with a hash suffix.
+func NormalizeLabelValue(s string) string {
+	s = invalidLabelRune.ReplaceAllString(strings.ToLower(s), "-")
+	return truncate.TruncateString(s, truncate.TruncateStringOptions{
+		MaxLength:  maxLabelLength,
+		HashLength: 6,
+	})
+}
+
 // EncodeGCELabel encodes a string into an RFC1035 compatible value, suitable for use as GCE label key or value
 // We use a URI inspired escaping, but with - instead of %.
 func EncodeGCELabel(s string) string {
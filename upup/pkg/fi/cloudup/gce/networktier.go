@@ -0,0 +1,73 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"fmt"
+)
+
+const (
+	// NetworkTierPremium routes traffic over Google's global network.
+	NetworkTierPremium = "PREMIUM"
+	// NetworkTierStandard routes traffic over the public internet; it is only available for regional resources.
+	NetworkTierStandard = "STANDARD"
+)
+
+// NetworkTiers is the list of network tiers supported by kOps.
+var NetworkTiers = []string{NetworkTierPremium, NetworkTierStandard}
+
+// ValidateNetworkTier checks that tier is a known network tier and that it can be used
+// for a resource that is either global or regional, with the given load balancing scheme.
+// An empty scheme is treated as EXTERNAL, which is the GCE default.
+func ValidateNetworkTier(tier string, global bool, loadBalancingScheme string) error {
+	switch tier {
+	case NetworkTierPremium:
+		return nil
+	case NetworkTierStandard:
+		if global {
+			return fmt.Errorf("network tier %s is not supported for global resources", tier)
+		}
+		if loadBalancingScheme != "" && loadBalancingScheme != "EXTERNAL" {
+			return fmt.Errorf("network tier %s is only supported with the EXTERNAL load balancing scheme, not %s", tier, loadBalancingScheme)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown network tier %q, must be one of %v", tier, NetworkTiers)
+	}
+}
+
+// WithDefaultNetworkTier returns a copy of the cloud that uses tier for the external load balancer resources
+// that don't set a network tier. As those are regional EXTERNAL resources, the tier is validated for them here,
+// once, rather than by every task that falls back to it.
+func WithDefaultNetworkTier(cloud GCECloud, tier string) (GCECloud, error) {
+	if err := ValidateNetworkTier(tier, false, "EXTERNAL"); err != nil {
+		return nil, fmt.Errorf("invalid default network tier: %w", err)
+	}
+	return cloud.(gceCloudInternal).WithNetworkTier(tier), nil
+}
+
+// NetworkTierFor returns the network tier of a resource: tier if it is set, else the default network tier of the
+// cloud for regional resources with the EXTERNAL load balancing scheme, and else "" for the project default.
+func NetworkTierFor(cloud GCECloud, tier *string, global bool, loadBalancingScheme string) string {
+	if tier != nil {
+		return *tier
+	}
+	if global || (loadBalancingScheme != "" && loadBalancingScheme != "EXTERNAL") {
+		return ""
+	}
+	return cloud.NetworkTier()
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"testing"
+)
+
+func TestValidateNetworkTier(t *testing.T) {
+	grid := []struct {
+		tier                string
+		global              bool
+		loadBalancingScheme string
+		expectError         bool
+	}{
+		{tier: NetworkTierPremium},
+		{tier: NetworkTierPremium, global: true},
+		{tier: NetworkTierPremium, loadBalancingScheme: "INTERNAL"},
+		{tier: NetworkTierStandard},
+		{tier: NetworkTierStandard, loadBalancingScheme: "EXTERNAL"},
+		{tier: NetworkTierStandard, global: true, expectError: true},
+		{tier: NetworkTierStandard, loadBalancingScheme: "INTERNAL", expectError: true},
+		{tier: "premium", expectError: true},
+		{tier: "", expectError: true},
+	}
+
+	for _, g := range grid {
+		err := ValidateNetworkTier(g.tier, g.global, g.loadBalancingScheme)
+		if g.expectError && err == nil {
+			t.Errorf("expected error for tier=%q global=%v scheme=%q", g.tier, g.global, g.loadBalancingScheme)
+		}
+		if !g.expectError && err != nil {
+			t.Errorf("unexpected error for tier=%q global=%v scheme=%q: %v", g.tier, g.global, g.loadBalancingScheme, err)
+		}
+	}
+}
@@ -20,6 +20,7 @@ package gce
 // The file contains functions that deal with waiting for GCE operations to complete
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -35,52 +36,57 @@ const (
 )
 
 func WaitForOp(client *compute.Service, op *compute.Operation) error {
+	return WaitForOpContext(context.Background(), client, op)
+}
+
+// WaitForOpContext waits for the operation to complete, aborting the wait if the context is cancelled.
+func WaitForOpContext(ctx context.Context, client *compute.Service, op *compute.Operation) error {
 	u, err := ParseGoogleCloudURL(op.SelfLink)
 	if err != nil {
 		return fmt.Errorf("error parsing operation URL %q: %v", op.SelfLink, err)
 	}
 
 	if u.Zone != "" {
-		return waitForZoneOp(client, op)
+		return waitForZoneOp(ctx, client, op)
 	}
 
 	if u.Region != "" {
-		return waitForRegionOp(client, op)
+		return waitForRegionOp(ctx, client, op)
 	}
 
-	return waitForGlobalOp(client, op)
+	return waitForGlobalOp(ctx, client, op)
 }
 
-func waitForZoneOp(client *compute.Service, op *compute.Operation) error {
+func waitForZoneOp(ctx context.Context, client *compute.Service, op *compute.Operation) error {
 	u, err := ParseGoogleCloudURL(op.SelfLink)
 	if err != nil {
 		return fmt.Errorf("error parsing operation URL %q: %v", op.SelfLink, err)
 	}
 
-	return waitForOp(op, func(operationName string) (*compute.Operation, error) {
-		return client.ZoneOperations.Wait(u.Project, u.Zone, operationName).Do()
+	return waitForOp(ctx, op, func(ctx context.Context, operationName string) (*compute.Operation, error) {
+		return client.ZoneOperations.Wait(u.Project, u.Zone, operationName).Context(ctx).Do()
 	})
 }
 
-func waitForRegionOp(client *compute.Service, op *compute.Operation) error {
+func waitForRegionOp(ctx context.Context, client *compute.Service, op *compute.Operation) error {
 	u, err := ParseGoogleCloudURL(op.SelfLink)
 	if err != nil {
 		return fmt.Errorf("error parsing operation URL %q: %v", op.SelfLink, err)
 	}
 
-	return waitForOp(op, func(operationName string) (*compute.Operation, error) {
-		return client.RegionOperations.Wait(u.Project, u.Region, operationName).Do()
+	return waitForOp(ctx, op, func(ctx context.Context, operationName string) (*compute.Operation, error) {
+		return client.RegionOperations.Wait(u.Project, u.Region, operationName).Context(ctx).Do()
 	})
 }
 
-func waitForGlobalOp(client *compute.Service, op *compute.Operation) error {
+func waitForGlobalOp(ctx context.Context, client *compute.Service, op *compute.Operation) error {
 	u, err := ParseGoogleCloudURL(op.SelfLink)
 	if err != nil {
 		return fmt.Errorf("error parsing operation URL %q: %v", op.SelfLink, err)
 	}
 
-	return waitForOp(op, func(operationName string) (*compute.Operation, error) {
-		return client.GlobalOperations.Wait(u.Project, operationName).Do()
+	return waitForOp(ctx, op, func(ctx context.Context, operationName string) (*compute.Operation, error) {
+		return client.GlobalOperations.Wait(u.Project, operationName).Context(ctx).Do()
 	})
 }
 
@@ -88,7 +94,7 @@ func opIsDone(op *compute.Operation) bool {
 	return op != nil && op.Status == "DONE"
 }
 
-func waitForOp(op *compute.Operation, getOperation func(operationName string) (*compute.Operation, error)) error {
+func waitForOp(ctx context.Context, op *compute.Operation, getOperation func(ctx context.Context, operationName string) (*compute.Operation, error)) error {
 	if op == nil {
 		return fmt.Errorf("operation must not be nil")
 	}
@@ -99,14 +105,14 @@ func waitForOp(op *compute.Operation, getOperation func(operationName string) (*
 
 	opStart := time.Now()
 	opName := op.Name
-	return wait.Poll(operationPollInterval, operationPollTimeoutDuration, func() (bool, error) {
+	return wait.PollUntilContextTimeout(ctx, operationPollInterval, operationPollTimeoutDuration, false, func(ctx context.Context) (bool, error) {
 		start := time.Now()
 		// gce.operationPollRateLimiter.Accept()
 		duration := time.Since(start)
 		if duration > 5*time.Second {
 			klog.Infof("pollOperation: throttled %v for %v", duration, opName)
 		}
-		pollOp, err := getOperation(opName)
+		pollOp, err := getOperation(ctx, opName)
 		if err != nil {
 			klog.Warningf("GCE poll operation %s failed: pollOp: [%v] err: [%v]", opName, pollOp, err)
 			klog.Infof("getErrorFromOp: [%v]", getErrorFromOp(pollOp))
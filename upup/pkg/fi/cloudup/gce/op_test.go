@@ -0,0 +1,83 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+)
+
+func TestWaitForOpCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	op := &compute.Operation{Name: "op-never-done", Status: "RUNNING"}
+	polls := 0
+	getOperation := func(ctx context.Context, operationName string) (*compute.Operation, error) {
+		polls++
+		return &compute.Operation{Name: operationName, Status: "RUNNING"}, nil
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	done := make(chan error)
+	go func() {
+		done <- waitForOp(ctx, op, getOperation)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatalf("expected error from cancelled wait")
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(operationPollInterval / 2):
+		t.Fatalf("wait was not aborted by the cancelled context")
+	}
+}
+
+func TestWaitForOpReturnsOperationError(t *testing.T) {
+	op := &compute.Operation{Name: "op-fails", Status: "RUNNING"}
+	getOperation := func(ctx context.Context, operationName string) (*compute.Operation, error) {
+		return &compute.Operation{
+			Name:                operationName,
+			Status:              "DONE",
+			HttpErrorStatusCode: 409,
+			Error: &compute.OperationError{
+				Errors: []*compute.OperationErrorErrors{{Message: "resource already exists"}},
+			},
+		}, nil
+	}
+
+	err := waitForOp(context.Background(), op, getOperation)
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected googleapi.Error, got %v", err)
+	}
+	if apiErr.Code != 409 || apiErr.Message != "resource already exists" {
+		t.Errorf("unexpected error: %v", apiErr)
+	}
+}
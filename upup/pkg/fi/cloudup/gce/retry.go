@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gce
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// TransientErrorBackoff is the backoff used when retrying GCE API calls that failed with a transient error.
+var TransientErrorBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// IsRetryable returns true if the error is a transient GCE API error,
+// such as a server error or rate limiting, that is worth retrying.
+func IsRetryable(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError {
+		return true
+	}
+	for _, e := range apiErr.Errors {
+		switch e.Reason {
+		case "rateLimitExceeded", "userRateLimitExceeded", "backendError":
+			return true
+		}
+	}
+	return false
+}
+
+// RetryOnTransientError calls fn until it returns an error that is not retryable, or the backoff is exhausted.
+// Errors that are not retryable, including not found errors, are returned immediately.
+func RetryOnTransientError(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		lastErr = fn()
+		if lastErr == nil {
+			return true, nil
+		}
+		if !IsRetryable(lastErr) {
+			return false, lastErr
+		}
+		klog.V(2).Infof("retrying after transient GCE API error: %v", lastErr)
+		return false, nil
+	})
+	if err != nil && lastErr != nil {
+		return lastErr
+	}
+	return err
+}
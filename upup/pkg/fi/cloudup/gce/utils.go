@@ -49,6 +49,24 @@ func IsNotReady(err error) bool {
 	return false
 }
 
+// IsUnsupportedField returns true if the API rejected the request because of the named field,
+// for example because the field is newer than the API version that serves the request.
+func IsUnsupportedField(err error, field string) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok || apiErr.Code != 400 {
+		return false
+	}
+	if strings.Contains(apiErr.Message, field) {
+		return true
+	}
+	for _, e := range apiErr.Errors {
+		if strings.Contains(e.Message, field) {
+			return true
+		}
+	}
+	return false
+}
+
 // ClusterPrefixedName returns a cluster-prefixed name, with a maxLength
 func ClusterPrefixedName(objectName string, clusterName string, maxLength int) string {
 	suffix := "-" + objectName
@@ -18,6 +18,8 @@ package gcetasks
 
 import (
 	"fmt"
+	"net"
+	"strings"
 
 	compute "google.golang.org/api/compute/v1"
 	"k8s.io/klog/v2"
@@ -37,11 +39,29 @@ type Address struct {
 	IPAddressType *string
 	Purpose       *string
 
+	// NetworkTier is the network tier (PREMIUM or STANDARD) of an EXTERNAL address.
+	// If unset, GCE uses the project default.
+	NetworkTier *string
+
 	Subnetwork *Subnet
 
+	// Global marks a global address, as used by the forwarding rules of global load balancers.
+	Global *bool
+
+	// Project is the project of an address reserved in another project than the cluster, for example a host project.
+	// Such an address is only referenced by forwarding rules, through its self link; kops does not create it.
+	Project *string
+
 	// WellKnownServices indicates which services are supported by this resource.
 	// This field is internal and is not rendered to the cloud.
 	WellKnownServices []wellknownservices.WellKnownService
+
+	// selfLink is the URL of the address.
+	// Only set on the actual resource returned by Find.
+	selfLink string
+	// ipVersion is the IP version (IPV4 or IPV6) of the address.
+	// Only set on the actual resource returned by Find.
+	ipVersion string
 }
 
 var _ fi.CompareWithID = &ForwardingRule{}
@@ -57,6 +77,10 @@ func (e *Address) Find(c *fi.CloudupContext) (*Address, error) {
 			e.IPAddress = actual.IPAddress
 		}
 
+		// Global and Project only select where the address is looked up
+		actual.Global = e.Global
+		actual.Project = e.Project
+
 		// Ignore system fields
 		actual.Lifecycle = e.Lifecycle
 		actual.WellKnownServices = e.WellKnownServices
@@ -64,21 +88,18 @@ func (e *Address) Find(c *fi.CloudupContext) (*Address, error) {
 	return actual, err
 }
 
-func findAddressByIP(cloud gce.GCECloud, ip string, subnet string) (*Address, error) {
+// findAddressByIP returns the address in the region with the IP, or nil if there is none.
+// If ipVersion is set, only addresses of that IP version are considered.
+func findAddressByIP(cloud gce.GCECloud, ip string, subnet string, ipVersion string) (*Address, error) {
 	// Technically this is a regex, but it doesn't matter, it's a prefilter
 	addrs, err := cloud.Compute().Addresses().ListWithFilter(cloud.Project(), cloud.Region(), "address eq "+ip)
 	if err != nil {
 		return nil, fmt.Errorf("error listing IP Addresses: %v", err)
 	}
 
-	var matches []*compute.Address
-	for _, addr := range addrs {
-		if subnet != "" && addr.Subnetwork != subnet {
-			continue
-		}
-		if addr.Address == ip {
-			matches = append(matches, addr)
-		}
+	matches, err := filterAddressesByIP(addrs, ip, subnet, ipVersion)
+	if err != nil {
+		return nil, err
 	}
 
 	if len(matches) == 0 {
@@ -96,17 +117,143 @@ func findAddressByIP(cloud gce.GCECloud, ip string, subnet string) (*Address, er
 	actual.IPAddressType = &addr.AddressType
 	actual.Purpose = &addr.Purpose
 	actual.Name = &addr.Name
+	if addr.NetworkTier != "" {
+		actual.NetworkTier = &addr.NetworkTier
+	}
 	if addr.Subnetwork != "" {
 		actual.Subnetwork = &Subnet{
 			Name: fi.PtrTo(lastComponent(addr.Subnetwork)),
 		}
 	}
+	actual.ipVersion = addressIPVersion(addr)
 
 	return actual, nil
 }
 
+// findGlobalAddressByIP is the equivalent of findAddressByIP for global addresses,
+// as used by the forwarding rules of global load balancers.
+func findGlobalAddressByIP(cloud gce.GCECloud, ip string, ipVersion string) (*Address, error) {
+	addrs, err := cloud.Compute().GlobalAddresses().ListWithFilter(cloud.Project(), "address eq "+ip)
+	if err != nil {
+		return nil, fmt.Errorf("error listing global IP Addresses: %v", err)
+	}
+
+	matches, err := filterAddressesByIP(addrs, ip, "", ipVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("found multiple global Addresses matching %q", ip)
+	}
+
+	return globalAddressFromCompute(matches[0]), nil
+}
+
+// filterAddressesByIP returns the addresses with the IP, in the subnet if it is set.
+// If ipVersion is set, addresses of the other IP version are skipped, and it is an error if only those match.
+func filterAddressesByIP(addrs []*compute.Address, ip string, subnet string, ipVersion string) ([]*compute.Address, error) {
+	var matches []*compute.Address
+	var otherVersion []string
+	for _, addr := range addrs {
+		if subnet != "" && addr.Subnetwork != subnet {
+			continue
+		}
+		if addr.Address != ip {
+			continue
+		}
+		if ipVersion != "" && addressIPVersion(addr) != ipVersion {
+			otherVersion = append(otherVersion, addr.Name)
+			continue
+		}
+		matches = append(matches, addr)
+	}
+
+	if len(matches) == 0 && len(otherVersion) != 0 {
+		return nil, fmt.Errorf("no %s Address found with IP %q, only Addresses of another IP version: %s", ipVersion, ip, strings.Join(otherVersion, ", "))
+	}
+	return matches, nil
+}
+
+// addressIPVersion returns the IP version of the address; GCE may omit it for IPV4 addresses.
+func addressIPVersion(addr *compute.Address) string {
+	if addr.IpVersion != "" {
+		return addr.IpVersion
+	}
+	return ipVersionOf(addr.Address)
+}
+
+// ipVersionOf returns the IP version of an IP, which is IPV4 unless it parses as an IPv6 address.
+func ipVersionOf(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return ipVersionIPv6
+	}
+	return ipVersionIPv4
+}
+
+func globalAddressFromCompute(addr *compute.Address) *Address {
+	actual := &Address{}
+	actual.IPAddress = &addr.Address
+	actual.IPAddressType = &addr.AddressType
+	actual.Purpose = &addr.Purpose
+	actual.Name = &addr.Name
+	if addr.NetworkTier != "" {
+		actual.NetworkTier = &addr.NetworkTier
+	}
+	actual.Global = fi.PtrTo(true)
+	actual.selfLink = addr.SelfLink
+	actual.ipVersion = addressIPVersion(addr)
+	return actual
+}
+
+// project returns the project of the address, which defaults to the project of the cluster.
+func (e *Address) project(cloud gce.GCECloud) string {
+	if e.Project != nil {
+		return *e.Project
+	}
+	return cloud.Project()
+}
+
+// isCrossProject returns true if the address is reserved in another project than the cluster.
+func (e *Address) isCrossProject(cloud gce.GCECloud) bool {
+	return e.Project != nil && *e.Project != cloud.Project()
+}
+
+// URL returns the URL of the address, in the given region unless it is global.
+func (e *Address) URL(project string, region string) string {
+	u := gce.GoogleCloudURL{
+		Version: "v1",
+		Project: project,
+		Name:    *e.Name,
+		Type:    "addresses",
+	}
+	if fi.ValueOf(e.Global) {
+		u.Global = true
+	} else {
+		u.Region = region
+	}
+	return u.BuildURL()
+}
+
 func (e *Address) find(cloud gce.GCECloud) (*Address, error) {
-	r, err := cloud.Compute().Addresses().Get(cloud.Project(), cloud.Region(), *e.Name)
+	project := e.project(cloud)
+	if fi.ValueOf(e.Global) {
+		r, err := cloud.Compute().GlobalAddresses().Get(project, *e.Name)
+		if err != nil {
+			if gce.IsNotFound(err) {
+				return nil, nil
+			}
+
+			return nil, fmt.Errorf("error getting global IP Address: %v", err)
+		}
+		return globalAddressFromCompute(r), nil
+	}
+
+	r, err := cloud.Compute().Addresses().Get(project, cloud.Region(), *e.Name)
 	if err != nil {
 		if gce.IsNotFound(err) {
 			return nil, nil
@@ -120,6 +267,11 @@ func (e *Address) find(cloud gce.GCECloud) (*Address, error) {
 	actual.IPAddressType = &r.AddressType
 	actual.Purpose = &r.Purpose
 	actual.Name = &r.Name
+	actual.selfLink = r.SelfLink
+	actual.ipVersion = addressIPVersion(r)
+	if r.NetworkTier != "" {
+		actual.NetworkTier = &r.NetworkTier
+	}
 	if e.Subnetwork != nil {
 		actual.Subnetwork = &Subnet{
 			Name: fi.PtrTo(lastComponent(r.Subnetwork)),
@@ -160,18 +312,41 @@ func (_ *Address) CheckChanges(a, e, changes *Address) error {
 		if changes.IPAddress != nil {
 			return fi.CannotChangeField("Address")
 		}
+		if changes.NetworkTier != nil {
+			return fi.CannotChangeField("NetworkTier")
+		}
+		if changes.Global != nil {
+			return fi.CannotChangeField("Global")
+		}
+	}
+	if e.Project != nil && *e.Project == "" {
+		return fmt.Errorf("Project cannot be empty on Address %q", fi.ValueOf(e.Name))
+	}
+	if fi.ValueOf(e.Global) && e.Subnetwork != nil {
+		return fmt.Errorf("Subnetwork cannot be set on global Address %q", fi.ValueOf(e.Name))
+	}
+	if e.NetworkTier != nil {
+		if err := gce.ValidateNetworkTier(*e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.IPAddressType)); err != nil {
+			return fmt.Errorf("invalid NetworkTier for Address %q: %w", fi.ValueOf(e.Name), err)
+		}
 	}
 	return nil
 }
 
 func (_ *Address) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Address) error {
 	cloud := t.Cloud
+	if a == nil && e.isCrossProject(cloud) {
+		return fmt.Errorf("Address %q was not found in project %s; addresses in other projects are not created by kops", fi.ValueOf(e.Name), *e.Project)
+	}
 	addr := &compute.Address{
 		Name:        *e.Name,
 		Address:     fi.ValueOf(e.IPAddress),
 		AddressType: fi.ValueOf(e.IPAddressType),
 		Purpose:     fi.ValueOf(e.Purpose),
-		Region:      cloud.Region(),
+		NetworkTier: gce.NetworkTierFor(cloud, e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.IPAddressType)),
+	}
+	if !fi.ValueOf(e.Global) {
+		addr.Region = cloud.Region()
 	}
 
 	if e.Subnetwork != nil {
@@ -181,7 +356,13 @@ func (_ *Address) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Address) error {
 	if a == nil {
 		klog.V(2).Infof("Creating Address: %q", addr.Name)
 
-		op, err := cloud.Compute().Addresses().Insert(cloud.Project(), cloud.Region(), addr)
+		var op *compute.Operation
+		var err error
+		if fi.ValueOf(e.Global) {
+			op, err = cloud.Compute().GlobalAddresses().Insert(cloud.Project(), addr)
+		} else {
+			op, err = cloud.Compute().Addresses().Insert(cloud.Project(), cloud.Region(), addr)
+		}
 		if err != nil {
 			return fmt.Errorf("error creating IP Address: %v", err)
 		}
@@ -200,23 +381,40 @@ type terraformAddress struct {
 	Name        *string                  `cty:"name"`
 	AddressType *string                  `cty:"address_type"`
 	Purpose     *string                  `cty:"purpose"`
+	NetworkTier *string                  `cty:"network_tier"`
 	Subnetwork  *terraformWriter.Literal `cty:"subnetwork"`
 }
 
 func (_ *Address) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *Address) error {
+	if e.Project != nil && *e.Project != t.Project {
+		// Only referenced by forwarding rules, through its URL
+		return nil
+	}
+
 	tf := &terraformAddress{
 		Name:        e.Name,
 		AddressType: e.IPAddressType,
 		Purpose:     e.Purpose,
 	}
+	if networkTier := gce.NetworkTierFor(t.Cloud.(gce.GCECloud), e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.IPAddressType)); networkTier != "" {
+		tf.NetworkTier = fi.PtrTo(networkTier)
+	}
 	if e.Subnetwork != nil {
 		tf.Subnetwork = e.Subnetwork.TerraformLink()
 	}
-	return t.RenderResource("google_compute_address", *e.Name, tf)
+	return t.RenderResource(e.terraformResourceType(), *e.Name, tf)
+}
+
+// terraformResourceType returns the terraform resource type of the address, which differs for global addresses.
+func (e *Address) terraformResourceType() string {
+	if fi.ValueOf(e.Global) {
+		return "google_compute_global_address"
+	}
+	return "google_compute_address"
 }
 
 func (e *Address) TerraformAddress() *terraformWriter.Literal {
 	name := fi.ValueOf(e.Name)
 
-	return terraformWriter.LiteralProperty("google_compute_address", name, "address")
+	return terraformWriter.LiteralProperty(e.terraformResourceType(), name, "address")
 }
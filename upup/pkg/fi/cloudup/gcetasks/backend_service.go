@@ -18,6 +18,8 @@ package gcetasks
 
 import (
 	"fmt"
+	"reflect"
+	"slices"
 	"strings"
 
 	compute "google.golang.org/api/compute/v1"
@@ -37,6 +39,11 @@ type BackendService struct {
 	Protocol              *string
 	InstanceGroupManagers []*InstanceGroupManager
 
+	// ConnectionPersistenceOnUnhealthyBackends controls whether passthrough load balancers keep sending the packets of
+	// established connections to a backend that became unhealthy: DEFAULT_FOR_PROTOCOL, NEVER_PERSIST or ALWAYS_PERSIST.
+	// If unset, GCE uses DEFAULT_FOR_PROTOCOL. It can be changed in place.
+	ConnectionPersistenceOnUnhealthyBackends *string
+
 	Lifecycle    fi.Lifecycle
 	ForAPIServer bool
 }
@@ -86,6 +93,12 @@ func (e *BackendService) find(cloud gce.GCECloud) (*BackendService, error) {
 		igms = append(igms, &InstanceGroupManager{Name: &nameParts[len(nameParts)-1]})
 	}
 	actual.InstanceGroupManagers = igms
+	if r.ConnectionTrackingPolicy != nil && r.ConnectionTrackingPolicy.ConnectionPersistenceOnUnhealthyBackends != "" {
+		actual.ConnectionPersistenceOnUnhealthyBackends = fi.PtrTo(r.ConnectionTrackingPolicy.ConnectionPersistenceOnUnhealthyBackends)
+	} else {
+		// GCE omits the policy until it is set
+		actual.ConnectionPersistenceOnUnhealthyBackends = fi.PtrTo(connectionPersistenceDefault)
+	}
 
 	return actual, nil
 }
@@ -94,12 +107,27 @@ func (e *BackendService) Run(c *fi.CloudupContext) error {
 	return fi.CloudupDefaultDeltaRunMethod(e, c)
 }
 
+const connectionPersistenceDefault = "DEFAULT_FOR_PROTOCOL"
+
+// connectionPersistenceValues are the values of ConnectionPersistenceOnUnhealthyBackends accepted by GCE
+var connectionPersistenceValues = []string{connectionPersistenceDefault, "NEVER_PERSIST", "ALWAYS_PERSIST"}
+
 func (_ *BackendService) CheckChanges(a, e, changes *BackendService) error {
 	if a != nil {
 		if changes.Name != nil {
 			return fi.CannotChangeField("Name")
 		}
 	}
+	if e.ConnectionPersistenceOnUnhealthyBackends != nil {
+		if !slices.Contains(connectionPersistenceValues, *e.ConnectionPersistenceOnUnhealthyBackends) {
+			return fmt.Errorf("invalid ConnectionPersistenceOnUnhealthyBackends %q for BackendService %q, must be one of %s",
+				*e.ConnectionPersistenceOnUnhealthyBackends, fi.ValueOf(e.Name), strings.Join(connectionPersistenceValues, ", "))
+		}
+		// connection tracking only applies to passthrough load balancers
+		if scheme := fi.ValueOf(e.LoadBalancingScheme); scheme != "INTERNAL" && scheme != "EXTERNAL" {
+			return fmt.Errorf("ConnectionPersistenceOnUnhealthyBackends is not supported on BackendService %q with the %s load balancing scheme", fi.ValueOf(e.Name), scheme)
+		}
+	}
 	return nil
 }
 
@@ -122,6 +150,11 @@ func (_ *BackendService) RenderGCE(t *gce.GCEAPITarget, a, e, changes *BackendSe
 		LoadBalancingScheme: *e.LoadBalancingScheme,
 		Backends:            backends,
 	}
+	if e.ConnectionPersistenceOnUnhealthyBackends != nil {
+		bs.ConnectionTrackingPolicy = &compute.BackendServiceConnectionTrackingPolicy{
+			ConnectionPersistenceOnUnhealthyBackends: *e.ConnectionPersistenceOnUnhealthyBackends,
+		}
+	}
 
 	if a == nil {
 		klog.V(2).Infof("Creating BackendService: %q", bs.Name)
@@ -135,7 +168,25 @@ func (_ *BackendService) RenderGCE(t *gce.GCEAPITarget, a, e, changes *BackendSe
 			return fmt.Errorf("error waiting for backend service: %v", err)
 		}
 	} else {
-		return fmt.Errorf("cannot apply changes to backend service: %v", changes)
+		inPlace := *changes
+		inPlace.ConnectionPersistenceOnUnhealthyBackends = nil
+		if !reflect.DeepEqual(&inPlace, &BackendService{}) {
+			return fmt.Errorf("cannot apply changes to backend service: %v", changes)
+		}
+
+		if changes.ConnectionPersistenceOnUnhealthyBackends != nil {
+			klog.V(2).Infof("Setting connection persistence of BackendService %q to %s", bs.Name, *changes.ConnectionPersistenceOnUnhealthyBackends)
+			patch := &compute.BackendService{
+				ConnectionTrackingPolicy: bs.ConnectionTrackingPolicy,
+			}
+			op, err := cloud.Compute().RegionBackendServices().Patch(cloud.Project(), cloud.Region(), bs.Name, patch)
+			if err != nil {
+				return fmt.Errorf("error setting connection persistence of backend service %q: %w", bs.Name, err)
+			}
+			if err := cloud.WaitForOp(op); err != nil {
+				return fmt.Errorf("error setting connection persistence of backend service %q: %w", bs.Name, err)
+			}
+		}
 	}
 
 	return nil
@@ -158,6 +209,12 @@ type terraformBackendService struct {
 	LoadBalancingScheme *string                    `cty:"load_balancing_scheme"`
 	Protocol            *string                    `cty:"protocol"`
 	Backend             []terraformBackend         `cty:"backend"`
+
+	ConnectionTrackingPolicy *terraformConnectionTrackingPolicy `cty:"connection_tracking_policy"`
+}
+
+type terraformConnectionTrackingPolicy struct {
+	ConnectionPersistenceOnUnhealthyBackends *string `cty:"connection_persistence_on_unhealthy_backends"`
 }
 
 func (_ *BackendService) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *BackendService) error {
@@ -185,6 +242,12 @@ func (_ *BackendService) RenderTerraform(t *terraform.TerraformTarget, a, e, cha
 	}
 	tf.HealthChecks = hcs
 
+	if e.ConnectionPersistenceOnUnhealthyBackends != nil {
+		tf.ConnectionTrackingPolicy = &terraformConnectionTrackingPolicy{
+			ConnectionPersistenceOnUnhealthyBackends: e.ConnectionPersistenceOnUnhealthyBackends,
+		}
+	}
+
 	return t.RenderResource("google_compute_backend_service", *e.Name, tf)
 }
 
@@ -193,3 +256,15 @@ func (e *BackendService) TerraformAddress() *terraformWriter.Literal {
 
 	return terraformWriter.LiteralProperty("google_compute_backend_service", name, "id")
 }
+
+// TerraformDependencies returns the resources a reference to the backend service depends on:
+// the backend service itself, and its health checks, which must exist before it can serve.
+func (e *BackendService) TerraformDependencies() []*terraformWriter.Literal {
+	dependencies := []*terraformWriter.Literal{
+		terraformWriter.LiteralResource("google_compute_backend_service", fi.ValueOf(e.Name)),
+	}
+	for _, hc := range e.HealthChecks {
+		dependencies = append(dependencies, terraformWriter.LiteralResource("google_compute_health_check", fi.ValueOf(hc.Name)))
+	}
+	return dependencies
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcetasks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	gcemock "k8s.io/kops/cloudmock/gce"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestBackendServiceConnectionPersistence(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	bs := &BackendService{
+		Name:                                     fi.PtrTo("api"),
+		Lifecycle:                                fi.LifecycleSync,
+		Protocol:                                 fi.PtrTo("TCP"),
+		LoadBalancingScheme:                      fi.PtrTo("INTERNAL"),
+		ConnectionPersistenceOnUnhealthyBackends: fi.PtrTo("ALWAYS_PERSIST"),
+	}
+	allTasks := map[string]fi.CloudupTask{*bs.Name: bs}
+
+	persistence := func() string {
+		r, err := cloud.Compute().RegionBackendServices().Get(project, region, "api")
+		if err != nil {
+			t.Fatalf("error getting backend service: %v", err)
+		}
+		if r.ConnectionTrackingPolicy == nil {
+			return ""
+		}
+		return r.ConnectionTrackingPolicy.ConnectionPersistenceOnUnhealthyBackends
+	}
+
+	runTasks(t, ctx, cloud, allTasks)
+	if actual := persistence(); actual != "ALWAYS_PERSIST" {
+		t.Errorf("expected the backend service to be created with ALWAYS_PERSIST, got %q", actual)
+	}
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	// The policy is changed in place
+	bs.ConnectionPersistenceOnUnhealthyBackends = fi.PtrTo("NEVER_PERSIST")
+	checkHasChanges(t, ctx, cloud, allTasks)
+	runTasks(t, ctx, cloud, allTasks)
+	if actual := persistence(); actual != "NEVER_PERSIST" {
+		t.Errorf("expected the backend service to be updated to NEVER_PERSIST, got %q", actual)
+	}
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	invalid := []*BackendService{
+		{Name: fi.PtrTo("api"), LoadBalancingScheme: fi.PtrTo("INTERNAL"), ConnectionPersistenceOnUnhealthyBackends: fi.PtrTo("SOMETIMES")},
+		{Name: fi.PtrTo("api"), LoadBalancingScheme: fi.PtrTo("INTERNAL_MANAGED"), ConnectionPersistenceOnUnhealthyBackends: fi.PtrTo("NEVER_PERSIST")},
+	}
+	for _, e := range invalid {
+		if err := (&BackendService{}).CheckChanges(nil, e, e); err == nil || !strings.Contains(err.Error(), "ConnectionPersistenceOnUnhealthyBackends") {
+			t.Errorf("expected error for %s with the %s scheme, got %v", *e.ConnectionPersistenceOnUnhealthyBackends, *e.LoadBalancingScheme, err)
+		}
+	}
+}
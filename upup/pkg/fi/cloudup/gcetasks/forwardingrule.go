@@ -19,14 +19,23 @@ package gcetasks
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	compute "google.golang.org/api/compute/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/truncate"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/gce"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraformWriter"
+	"k8s.io/kops/util/pkg/reflectutils"
 )
 
 // ForwardingRule represents a GCE ForwardingRule
@@ -35,32 +44,127 @@ type ForwardingRule struct {
 	Name      *string
 	Lifecycle fi.Lifecycle
 
-	PortRange  *string
-	Ports      []string
+	// PortRange and Ports are the ports the rule accepts traffic on. Setting PortRange to "" or Ports to an
+	// empty (non-nil) list clears them, whereas leaving them nil means they are not managed.
+	PortRange *string
+	Ports     []string
+	// AllPorts forwards traffic on all ports, as used by internal passthrough load balancers.
+	// It requires a BackendService and the INTERNAL load balancing scheme, and excludes PortRange and Ports.
+	AllPorts   *bool
 	TargetPool *TargetPool
 	// An IP address can be specified either in dotted decimal
 	// or by reference to an address object.  The following two
 	// fields are mutually exclusive.
 	IPAddress     *Address
 	RuleIPAddress *string
+	// IPCollection is a reference to a PublicDelegatedPrefix sub-block, from which a
+	// bring-your-own-IP address is allocated.  It is exclusive with the fields above.
+	IPCollection *string
 
 	IPProtocol          string
 	LoadBalancingScheme *string
 	Network             *Network
 	Subnetwork          *Subnet
 	BackendService      *BackendService
+	// Target is the URL of a target of a type kops does not model, such as a target proxy, URL map or backend bucket.
+	// It is read-only, and only set on the actual resource returned by Find; kops refuses to change the target of such a rule.
+	Target *string
+
+	// NoAutomateDNSZone disables the automatic creation of a DNS zone for the internal forwarding rule.
+	// Only applies to INTERNAL load balancing schemes.
+	NoAutomateDNSZone *bool
+
+	// AllowGlobalAccess allows clients in any region to reach an internal forwarding rule.
+	// Only applies to INTERNAL load balancing schemes; if unset, GCE only allows clients in the region of the rule.
+	AllowGlobalAccess *bool
+
+	// Global marks the forwarding rule of a global load balancer, which is a global resource
+	// whose IPAddress refers to a global address.
+	Global *bool
+
+	// NetworkTier is the network tier (PREMIUM or STANDARD) of the forwarding rule.
+	// If unset, GCE uses the project default.
+	NetworkTier *string
+
+	// IPVersion is the IP version (IPV4 or IPV6) of the forwarding rule.
+	// If unset, GCE creates an IPV4 rule. In dual-stack clusters it keeps the task from
+	// adopting or pruning a rule of the other IP version.
+	IPVersion *string
 
 	// Labels to set on the resource.
 	Labels map[string]string
+	// ManagedLabelPrefix limits the labels kops manages to those whose key starts with the prefix, so that the
+	// labels set by other tools on an adopted rule are kept. Labels must then only have keys with the prefix.
+	// It is not supported by terraform, which manages all the labels of a rule.
+	ManagedLabelPrefix *string
+
+	// TerraformIgnoreIPAddressChanges makes terraform ignore changes to the IP address of a rule that
+	// uses an ephemeral or rule-managed IP, so that terraform doesn't try to reset an IP assigned by GCE.
+	// It has no effect when the rule uses an Address, or outside of terraform.
+	TerraformIgnoreIPAddressChanges *bool
+
+	// TerraformName is the name of the terraform resource of the rule, defaulting to Name.
+	// Keeping it when Name changes keeps the terraform address of the rule stable, so the rule is
+	// replaced in place rather than destroyed and created as an unrelated resource.
+	TerraformName *string
+
+	// ForceRecreate allows changes that GCE cannot apply in place, by deleting the rule and creating it again.
+	ForceRecreate *bool
+	// PreserveIP keeps the ephemeral IP of the rule across a forced recreation, by promoting it to a
+	// reserved Address which the new rule reuses, so that DNS records pointing at the rule stay valid.
+	PreserveIP *bool
+	// ReleasePreservedIP releases the Address reserved by PreserveIP once the new rule has been created.
+	ReleasePreservedIP *bool
+
+	// WaitForServing makes the creation of the rule wait until its IP accepts TCP connections on its first port,
+	// so that the apply only completes once the rule is programmed. It is only supported on external TCP rules.
+	WaitForServing *bool
+
+	// ReadPscConnectedEndpoints makes Find look up the Private Service Connect endpoints connected to an internal rule,
+	// returned by PscConnectedEndpoints. This lists the service attachments of the region, which requires the
+	// compute.serviceAttachments.list permission.
+	ReadPscConnectedEndpoints *bool
+
+	// PscConnectionID and PscConnectionStatus describe the Private Service Connect connection of the rule.
+	// They are read-only, and only set on the actual resource returned by Find.
+	PscConnectionID     *string
+	PscConnectionStatus *string
 
 	// Fingerprint of the labels, used to avoid race-conditions on updates.
 	// Only set on the actual resource returned by Find.
 	labelFingerprint string
 
+	// unmanagedLabels are the labels of the rule which ManagedLabelPrefix excludes, and which updates must keep.
+	// Only set on the actual resource returned by Find.
+	unmanagedLabels map[string]string
+
+	// resourceID and creationTimestamp are assigned by GCE, for tracking the rule.
+	// Only set on the actual resource returned by Find.
+	resourceID        uint64
+	creationTimestamp string
+
+	// ipAddress is the IP address of the rule, also when it is ephemeral.
+	// Only set on the actual resource returned by Find.
+	ipAddress string
+
+	// pscConnectedEndpoints are the consumer endpoints connected to the service attachments of an internal rule.
+	// Only set on the actual resource returned by Find.
+	pscConnectedEndpoints []PscConnectedEndpoint
+
 	// pruneForwardingRules will prune any forwarding rules found with the specified names
 	pruneForwardingRules []forwardingRulePruneSpec
 }
 
+// PscConnectedEndpoint is a Private Service Connect endpoint of a consumer, connected to a service attachment.
+type PscConnectedEndpoint struct {
+	// Endpoint is the URL of the consumer forwarding rule
+	Endpoint        string
+	PscConnectionID string
+	// Status is the status of the connection, such as ACCEPTED or PENDING
+	Status          string
+	ConsumerNetwork string
+}
+
 type forwardingRulePruneSpec struct {
 	Name string
 }
@@ -71,6 +175,33 @@ func (e *ForwardingRule) CompareWithID() *string {
 	return e.Name
 }
 
+// ResourceID returns the ID assigned to the rule by GCE.
+// It is only known for the actual rule returned by Find, and is 0 otherwise.
+func (e *ForwardingRule) ResourceID() uint64 {
+	return e.resourceID
+}
+
+// CreatedAt returns when the rule was created in GCE.
+// It is only known for the actual rule returned by Find, and is the zero time otherwise.
+func (e *ForwardingRule) CreatedAt() time.Time {
+	if e.creationTimestamp == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339, e.creationTimestamp)
+	if err != nil {
+		klog.Warningf("ignoring invalid creation timestamp %q of ForwardingRule %q: %v", e.creationTimestamp, fi.ValueOf(e.Name), err)
+		return time.Time{}
+	}
+	return t
+}
+
+// PscConnectedEndpoints returns the Private Service Connect endpoints of consumers connected to the rule,
+// for producers publishing an internal rule with a service attachment.
+// They are only known for the actual rule returned by Find with ReadPscConnectedEndpoints, and are nil otherwise.
+func (e *ForwardingRule) PscConnectedEndpoints() []PscConnectedEndpoint {
+	return e.pscConnectedEndpoints
+}
+
 func (e *ForwardingRule) PruneForwardingRulesWithName(name string) {
 	e.pruneForwardingRules = append(e.pruneForwardingRules, forwardingRulePruneSpec{Name: name})
 }
@@ -81,7 +212,18 @@ func (e *ForwardingRule) Find(c *fi.CloudupContext) (*ForwardingRule, error) {
 	cloud := c.T.Cloud.(gce.GCECloud)
 	name := fi.ValueOf(e.Name)
 
-	r, err := cloud.Compute().ForwardingRules().Get(ctx, cloud.Project(), cloud.Region(), name)
+	global := fi.ValueOf(e.Global)
+
+	var r *compute.ForwardingRule
+	err := gce.RetryOnTransientError(ctx, gce.TransientErrorBackoff, func() error {
+		var err error
+		if global {
+			r, err = cloud.Compute().GlobalForwardingRules().Get(ctx, cloud.Project(), name)
+		} else {
+			r, err = cloud.Compute().ForwardingRules().Get(ctx, cloud.Project(), cloud.Region(), name)
+		}
+		return err
+	})
 	if err != nil {
 		if gce.IsNotFound(err) {
 			return nil, nil
@@ -91,22 +233,51 @@ func (e *ForwardingRule) Find(c *fi.CloudupContext) (*ForwardingRule, error) {
 
 	actual := &ForwardingRule{
 		Name:       fi.PtrTo(r.Name),
-		IPProtocol: r.IPProtocol,
+		IPProtocol: normalizeIPProtocol(r.IPProtocol),
+		Global:     e.Global,
 	}
 	if r.PortRange != "" {
 		actual.PortRange = &r.PortRange
+	} else if e.PortRange != nil {
+		// GCE omits an unset port range, which matches one explicitly cleared
+		actual.PortRange = fi.PtrTo("")
 	}
 	if len(r.Ports) > 0 {
 		actual.Ports = r.Ports
+	} else if e.Ports != nil {
+		actual.Ports = []string{}
 	}
+	actual.AllPorts = fi.PtrTo(r.AllPorts)
 
 	if r.Target != "" {
-		actual.TargetPool = &TargetPool{
-			Name: fi.PtrTo(lastComponent(r.Target)),
+		switch resourceType := targetResourceType(r.Target); resourceType {
+		case "targetPools", "":
+			actual.TargetPool = &TargetPool{
+				Name: fi.PtrTo(lastComponent(r.Target)),
+			}
+		case "backendServices":
+			if r.BackendService == "" {
+				r.BackendService = r.Target
+			}
+		default:
+			// We don't model other targets, such as targetTcpProxies, so they must not show up as a TargetPool
+			klog.V(2).Infof("ForwardingRule %q has target %q of unsupported type %s", name, r.Target, resourceType)
+			actual.Target = fi.PtrTo(r.Target)
 		}
 	}
-	if r.IPAddress != "" {
-		address, err := findAddressByIP(cloud, r.IPAddress, r.Subnetwork)
+	if r.IpCollection != "" {
+		actual.IPCollection = fi.PtrTo(r.IpCollection)
+	}
+	// An address allocated from an IP collection is not a reserved Address
+	if r.IPAddress != "" && r.IpCollection == "" {
+		var address *Address
+		if e.IPAddress != nil && e.IPAddress.isCrossProject(cloud) {
+			address, err = findCrossProjectAddress(cloud, e.IPAddress, r.IPAddress)
+		} else if global {
+			address, err = findGlobalAddressByIP(cloud, r.IPAddress, forwardingRuleIPVersion(r))
+		} else {
+			address, err = findAddressByIP(cloud, r.IPAddress, r.Subnetwork, forwardingRuleIPVersion(r))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("error finding Address with IP=%q: %w", r.IPAddress, err)
 		}
@@ -116,6 +287,9 @@ func (e *ForwardingRule) Find(c *fi.CloudupContext) (*ForwardingRule, error) {
 		actual.BackendService = &BackendService{
 			Name: fi.PtrTo(lastComponent(r.BackendService)),
 		}
+		if klog.V(4).Enabled() {
+			logConnectionTrackingPolicy(cloud, name, r.BackendService)
+		}
 	}
 	if r.LoadBalancingScheme != "" {
 		actual.LoadBalancingScheme = fi.PtrTo(r.LoadBalancingScheme)
@@ -131,30 +305,569 @@ func (e *ForwardingRule) Find(c *fi.CloudupContext) (*ForwardingRule, error) {
 		}
 	}
 
+	actual.NoAutomateDNSZone = fi.PtrTo(r.NoAutomateDnsZone)
+	actual.AllowGlobalAccess = fi.PtrTo(r.AllowGlobalAccess)
+	if r.NetworkTier != "" {
+		actual.NetworkTier = fi.PtrTo(r.NetworkTier)
+	}
+	actual.IPVersion = fi.PtrTo(forwardingRuleIPVersion(r))
+
+	if r.PscConnectionId != 0 || r.PscConnectionStatus != "" {
+		actual.PscConnectionID = fi.PtrTo(strconv.FormatUint(r.PscConnectionId, 10))
+		actual.PscConnectionStatus = fi.PtrTo(r.PscConnectionStatus)
+		klog.V(2).Infof("ForwardingRule %q has Private Service Connect connection %s with status %s", name, *actual.PscConnectionID, r.PscConnectionStatus)
+	}
+	if fi.ValueOf(e.ReadPscConnectedEndpoints) && !global && isInternalLoadBalancingScheme(r.LoadBalancingScheme) {
+		// This is only observed, so failing to read it (for example for lack of permissions) is not fatal
+		endpoints, err := findPscConnectedEndpoints(ctx, cloud, r.Name)
+		if err != nil {
+			klog.V(2).Infof("unable to read the Private Service Connect endpoints of ForwardingRule %q: %v", name, err)
+		}
+		actual.pscConnectedEndpoints = endpoints
+	}
+
 	actual.Labels = r.Labels
+	if e.ManagedLabelPrefix != nil {
+		actual.Labels, actual.unmanagedLabels = splitManagedLabels(r.Labels, *e.ManagedLabelPrefix)
+	}
 	actual.labelFingerprint = r.LabelFingerprint
+	actual.resourceID = r.Id
+	actual.creationTimestamp = r.CreationTimestamp
+	actual.ipAddress = r.IPAddress
 
 	// Ignore "system" fields
 	actual.Lifecycle = e.Lifecycle
+	actual.TerraformIgnoreIPAddressChanges = e.TerraformIgnoreIPAddressChanges
+	actual.TerraformName = e.TerraformName
+	actual.ForceRecreate = e.ForceRecreate
+	actual.PreserveIP = e.PreserveIP
+	actual.ReleasePreservedIP = e.ReleasePreservedIP
+	actual.WaitForServing = e.WaitForServing
+	actual.ReadPscConnectedEndpoints = e.ReadPscConnectedEndpoints
+	actual.ManagedLabelPrefix = e.ManagedLabelPrefix
+
+	actual.clearServerDefaults(e)
 
 	return actual, nil
 }
 
+// findCrossProjectAddress returns the address in another project referenced by e, if the rule uses it.
+// The IP address of the rule is matched against both the IP and the self link of the address.
+func findCrossProjectAddress(cloud gce.GCECloud, e *Address, ip string) (*Address, error) {
+	address, err := e.find(cloud)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, nil
+	}
+	if ip != fi.ValueOf(address.IPAddress) && ip != address.selfLink {
+		return nil, nil
+	}
+	address.Project = e.Project
+	address.Global = e.Global
+	return address, nil
+}
+
+// isInternalLoadBalancingScheme returns true for the schemes of internal load balancers, which can be published with Private Service Connect.
+func isInternalLoadBalancingScheme(scheme string) bool {
+	return scheme == "INTERNAL" || scheme == "INTERNAL_MANAGED"
+}
+
+// findPscConnectedEndpoints returns the endpoints connected to the service attachments targeting the regional rule name.
+func findPscConnectedEndpoints(ctx context.Context, cloud gce.GCECloud, name string) ([]PscConnectedEndpoint, error) {
+	serviceAttachments, err := cloud.Compute().ServiceAttachments().List(ctx, cloud.Project(), cloud.Region())
+	if err != nil {
+		return nil, fmt.Errorf("listing ServiceAttachments: %w", err)
+	}
+
+	var endpoints []PscConnectedEndpoint
+	for _, sa := range serviceAttachments {
+		if targetResourceType(sa.TargetService) != "forwardingRules" || lastComponent(sa.TargetService) != name {
+			continue
+		}
+		for _, endpoint := range sa.ConnectedEndpoints {
+			endpoints = append(endpoints, PscConnectedEndpoint{
+				Endpoint:        endpoint.Endpoint,
+				PscConnectionID: strconv.FormatUint(endpoint.PscConnectionId, 10),
+				Status:          endpoint.Status,
+				ConsumerNetwork: endpoint.ConsumerNetwork,
+			})
+		}
+	}
+	return endpoints, nil
+}
+
+// clearServerDefaults clears the fields of the actual rule that GCE populates when they were not specified,
+// and that e leaves unset, so that an adopted rule does not show changes for them.
+// Pointer fields don't need this, because a nil expected value already means "don't care".
+func (a *ForwardingRule) clearServerDefaults(e *ForwardingRule) {
+	if e.IPProtocol == "" {
+		// GCE defaults the protocol to TCP
+		a.IPProtocol = ""
+	}
+	if e.Ports == nil {
+		a.Ports = nil
+	}
+	if e.Labels == nil {
+		// Labels may be added by GCE or other tools; labelFingerprint is kept for updates
+		a.Labels = nil
+	}
+}
+
+// splitManagedLabels splits labels into those whose key starts with prefix, which kops manages, and the others.
+func splitManagedLabels(labels map[string]string, prefix string) (managed, unmanaged map[string]string) {
+	for k, v := range labels {
+		if strings.HasPrefix(k, prefix) {
+			if managed == nil {
+				managed = make(map[string]string)
+			}
+			managed[k] = v
+		} else {
+			if unmanaged == nil {
+				unmanaged = make(map[string]string)
+			}
+			unmanaged[k] = v
+		}
+	}
+	return managed, unmanaged
+}
+
+// targetResourceType returns the resource type of a (possibly partial) target URL,
+// for example targetPools for ".../regions/us-test1/targetPools/api", or "" if it is a bare name.
+func targetResourceType(target string) string {
+	tokens := strings.Split(strings.Trim(target, "/"), "/")
+	if len(tokens) < 2 {
+		return ""
+	}
+	return tokens[len(tokens)-2]
+}
+
+// logConnectionTrackingPolicy logs the effective connection tracking policy of the backend service behind a forwarding rule,
+// which helps diagnosing dropped long-lived or asymmetrically routed connections through internal load balancers.
+// It is best-effort, and never fails the reconcile.
+func logConnectionTrackingPolicy(cloud gce.GCECloud, ruleName string, backendServiceURL string) {
+	if !strings.Contains(backendServiceURL, "/regions/") {
+		klog.V(4).Infof("not looking up connection tracking policy of global backend service %q of ForwardingRule %q", backendServiceURL, ruleName)
+		return
+	}
+	backendServiceName := lastComponent(backendServiceURL)
+	bs, err := cloud.Compute().RegionBackendServices().Get(cloud.Project(), cloud.Region(), backendServiceName)
+	if err != nil {
+		klog.V(4).Infof("unable to get BackendService %q of ForwardingRule %q: %v", backendServiceName, ruleName, err)
+		return
+	}
+	klog.V(4).Infof("ForwardingRule %q uses BackendService %q with connection tracking policy %s", ruleName, backendServiceName, describeConnectionTrackingPolicy(bs.ConnectionTrackingPolicy))
+}
+
+// describeConnectionTrackingPolicy returns a human-readable description of a connection tracking policy
+func describeConnectionTrackingPolicy(policy *compute.BackendServiceConnectionTrackingPolicy) string {
+	if policy == nil {
+		return "<default>"
+	}
+	return fmt.Sprintf("trackingMode=%s connectionPersistenceOnUnhealthyBackends=%s idleTimeoutSec=%d enableStrongAffinity=%t",
+		valueOrDefault(policy.TrackingMode), valueOrDefault(policy.ConnectionPersistenceOnUnhealthyBackends), policy.IdleTimeoutSec, policy.EnableStrongAffinity)
+}
+
+// valueOrDefault returns s, or "<default>" if GCE left it unset
+func valueOrDefault(s string) string {
+	if s == "" {
+		return "<default>"
+	}
+	return s
+}
+
+var _ fi.CloudupTaskNormalize = &ForwardingRule{}
+
+// Normalize resolves service names such as "https" in PortRange and Ports to port numbers,
+// which is the only form GCE accepts, and uppercases IPProtocol as GCE reports it.
+func (e *ForwardingRule) Normalize(c *fi.CloudupContext) error {
+	e.IPProtocol = normalizeIPProtocol(e.IPProtocol)
+	if fi.ValueOf(e.PortRange) != "" {
+		portRange, err := resolvePortSpec(*e.PortRange)
+		if err != nil {
+			return fmt.Errorf("invalid PortRange for ForwardingRule %q: %w", fi.ValueOf(e.Name), err)
+		}
+		if !strings.Contains(portRange, "-") {
+			// GCE reports a single port as a range, so use the same form to avoid spurious changes
+			portRange = portRange + "-" + portRange
+		}
+		e.PortRange = &portRange
+	}
+	for i, port := range e.Ports {
+		resolved, err := resolvePortSpec(port)
+		if err != nil {
+			return fmt.Errorf("invalid Ports for ForwardingRule %q: %w", fi.ValueOf(e.Name), err)
+		}
+		e.Ports[i] = resolved
+	}
+	return nil
+}
+
+// normalizeIPProtocol returns the canonical uppercase form of an IP protocol;
+// GCE accepts either case, but always reports it uppercase.
+func normalizeIPProtocol(protocol string) string {
+	return strings.ToUpper(protocol)
+}
+
 func (e *ForwardingRule) Run(c *fi.CloudupContext) error {
 	return fi.CloudupDefaultDeltaRunMethod(e, c)
 }
 
+// ForwardingRuleFieldChange is a field of a ForwardingRule that applying the task would change.
+type ForwardingRuleFieldChange struct {
+	Field string
+	// Actual is the current value of the field in GCE, and Expected the value it would be set to;
+	// either is empty when the field is unset.
+	Actual   string
+	Expected string
+}
+
+// Kind returns whether the field would be "added", "removed" or "changed".
+func (c *ForwardingRuleFieldChange) Kind() string {
+	switch {
+	case c.Actual == "":
+		return "added"
+	case c.Expected == "":
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+// ForwardingRuleDiff is the difference between a ForwardingRule task and the rule in GCE, as returned by Diff.
+type ForwardingRuleDiff struct {
+	Name string
+	// Create is true if the rule does not exist yet, and would be created with all the fields of the task
+	Create bool
+	// Changes are the fields of an existing rule that differ from the task, sorted by field name
+	Changes []ForwardingRuleFieldChange
+}
+
+// HasChanges returns true if applying the task would create or change the rule.
+func (d *ForwardingRuleDiff) HasChanges() bool {
+	return d.Create || len(d.Changes) != 0
+}
+
+// String returns a human-readable summary of the changes, one field per line.
+func (d *ForwardingRuleDiff) String() string {
+	var b strings.Builder
+	switch {
+	case d.Create:
+		fmt.Fprintf(&b, "ForwardingRule %q would be created\n", d.Name)
+	case len(d.Changes) == 0:
+		fmt.Fprintf(&b, "ForwardingRule %q has no changes\n", d.Name)
+	default:
+		fmt.Fprintf(&b, "ForwardingRule %q would be changed:\n", d.Name)
+	}
+	for _, change := range d.Changes {
+		switch change.Kind() {
+		case "added":
+			fmt.Fprintf(&b, "  %s: added %s\n", change.Field, change.Expected)
+		case "removed":
+			fmt.Fprintf(&b, "  %s: removed %s\n", change.Field, change.Actual)
+		default:
+			fmt.Fprintf(&b, "  %s: changed %s -> %s\n", change.Field, change.Actual, change.Expected)
+		}
+	}
+	return b.String()
+}
+
+// Diff compares the task with the rule in GCE, without changing anything in GCE, and returns what applying it would change.
+// It works the same way as applying the task directly, so it also covers changes that terraform does not plan,
+// and returns the error CheckChanges would fail the apply with.
+func (e *ForwardingRule) Diff(c *fi.CloudupContext) (*ForwardingRuleDiff, error) {
+	if err := e.Normalize(c); err != nil {
+		return nil, err
+	}
+	a, err := e.Find(c)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ForwardingRuleDiff{Name: fi.ValueOf(e.Name)}
+	if a == nil {
+		diff.Create = true
+		a = &ForwardingRule{}
+	}
+	changes := &ForwardingRule{}
+	if !fi.BuildChanges(a, e, changes) {
+		return diff, nil
+	}
+	if err := e.CheckChanges(a, e, changes); err != nil {
+		return nil, err
+	}
+	if diff.Create {
+		return diff, nil
+	}
+
+	valA := reflect.ValueOf(a).Elem()
+	valE := reflect.ValueOf(e).Elem()
+	valC := reflect.ValueOf(changes).Elem()
+	for i := 0; i < valC.NumField(); i++ {
+		field := valC.Type().Field(i)
+		if !field.IsExported() || valC.Field(i).IsZero() {
+			continue
+		}
+		diff.Changes = append(diff.Changes, ForwardingRuleFieldChange{
+			Field:    field.Name,
+			Actual:   describeForwardingRuleField(valA.Field(i)),
+			Expected: describeForwardingRuleField(valE.Field(i)),
+		})
+	}
+	slices.SortFunc(diff.Changes, func(x, y ForwardingRuleFieldChange) int { return strings.Compare(x.Field, y.Field) })
+	return diff, nil
+}
+
+// describeForwardingRuleField returns the value of a field of a ForwardingRule for a diff, or "" if it is unset.
+// Referenced tasks are described by their name, and labels in key order.
+func describeForwardingRuleField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	if id, ok := v.Interface().(fi.CompareWithID); ok {
+		return fi.ValueOf(id.CompareWithID())
+	}
+	switch value := v.Interface().(type) {
+	case *string:
+		return *value
+	case []string:
+		if len(value) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(value, ", ") + "]"
+	case map[string]string:
+		var pairs []string
+		for _, k := range slices.Sorted(maps.Keys(value)) {
+			pairs = append(pairs, k+"="+value[k])
+		}
+		return "{" + strings.Join(pairs, ", ") + "}"
+	}
+	return reflectutils.ValueAsString(v)
+}
+
+// checkForwardingRuleIPSources checks that the IP of the rule comes from at most one of IPAddress, RuleIPAddress and IPCollection.
+func checkForwardingRuleIPSources(e *ForwardingRule) error {
+	if e.IPAddress != nil && e.RuleIPAddress != nil {
+		return fmt.Errorf("specified both IP Address and rule-managed IP address on ForwardingRule %q: Address %q, %v",
+			fi.ValueOf(e.Name), fi.ValueOf(e.IPAddress.Name), *e.RuleIPAddress)
+	}
+	var sources []string
+	if e.IPAddress != nil {
+		sources = append(sources, "IPAddress")
+	}
+	if e.RuleIPAddress != nil {
+		sources = append(sources, "RuleIPAddress")
+	}
+	if e.IPCollection != nil {
+		sources = append(sources, "IPCollection")
+	}
+	if len(sources) > 1 {
+		return fmt.Errorf("at most one of IPAddress, RuleIPAddress and IPCollection can be set on ForwardingRule %q, got %s", fi.ValueOf(e.Name), strings.Join(sources, " and "))
+	}
+	return nil
+}
+
 func (_ *ForwardingRule) CheckChanges(a, e, changes *ForwardingRule) error {
 	if fi.ValueOf(e.Name) == "" {
 		return fi.RequiredField("Name")
 	}
+	if e.PscConnectionID != nil || e.PscConnectionStatus != nil {
+		return fmt.Errorf("PscConnectionID and PscConnectionStatus are read-only and cannot be set on ForwardingRule %q", fi.ValueOf(e.Name))
+	}
+	if e.Target != nil {
+		return fmt.Errorf("Target is read-only and cannot be set on ForwardingRule %q", fi.ValueOf(e.Name))
+	}
+	if a != nil && a.Target != nil && (changes.TargetPool != nil || changes.BackendService != nil) {
+		return fmt.Errorf("ForwardingRule %q targets %q of type %s, which kops does not support managing; change or delete the rule manually",
+			fi.ValueOf(e.Name), *a.Target, targetResourceType(*a.Target))
+	}
+	switch scheme := fi.ValueOf(e.LoadBalancingScheme); scheme {
+	case "INTERNAL":
+		if e.Network == nil || e.Subnetwork == nil {
+			return fmt.Errorf("ForwardingRule %q with the INTERNAL load balancing scheme requires Network and Subnetwork", fi.ValueOf(e.Name))
+		}
+	case "EXTERNAL", "EXTERNAL_MANAGED":
+		if e.Subnetwork != nil {
+			return fmt.Errorf("Subnetwork cannot be set on ForwardingRule %q with the %s load balancing scheme", fi.ValueOf(e.Name), scheme)
+		}
+	}
+	if e.NoAutomateDNSZone != nil && fi.ValueOf(e.LoadBalancingScheme) != "INTERNAL" {
+		return fmt.Errorf("NoAutomateDNSZone can only be set on forwarding rules with the INTERNAL load balancing scheme")
+	}
+	if e.NetworkTier != nil {
+		if err := gce.ValidateNetworkTier(*e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.LoadBalancingScheme)); err != nil {
+			return fmt.Errorf("invalid NetworkTier for ForwardingRule %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
+	if fi.ValueOf(e.Global) && e.IPAddress != nil && !fi.ValueOf(e.IPAddress.Global) {
+		return fmt.Errorf("global ForwardingRule %q must use a global Address", fi.ValueOf(e.Name))
+	}
+	if err := checkForwardingRuleIPSources(e); err != nil {
+		return err
+	}
+	if e.IPCollection != nil {
+		if fi.ValueOf(e.Global) {
+			return fmt.Errorf("IPCollection is not supported on global ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+	}
+	if a != nil && changes.IPCollection != nil {
+		return fi.CannotChangeField("IPCollection")
+	}
+	if len(e.Labels) > gce.MaxLabels {
+		return fmt.Errorf("ForwardingRule %q has %d labels, must have at most %d", fi.ValueOf(e.Name), len(e.Labels), gce.MaxLabels)
+	}
+	for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+		if err := gce.ValidateLabel(k, e.Labels[k]); err != nil {
+			return fmt.Errorf("invalid label on ForwardingRule %q: %w", fi.ValueOf(e.Name), err)
+		}
+	}
+	if e.ManagedLabelPrefix != nil {
+		if *e.ManagedLabelPrefix == "" {
+			return fmt.Errorf("ManagedLabelPrefix cannot be empty on ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+		for _, k := range slices.Sorted(maps.Keys(e.Labels)) {
+			if !strings.HasPrefix(k, *e.ManagedLabelPrefix) {
+				return fmt.Errorf("label %q on ForwardingRule %q does not have the managed prefix %q", k, fi.ValueOf(e.Name), *e.ManagedLabelPrefix)
+			}
+		}
+	}
+	if a != nil && changes.Global != nil {
+		return fi.CannotChangeField("Global")
+	}
+	if fi.ValueOf(e.AllPorts) {
+		if fi.ValueOf(e.PortRange) != "" || len(e.Ports) != 0 {
+			return fmt.Errorf("AllPorts cannot be combined with PortRange or Ports on ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+		if e.BackendService == nil || e.TargetPool != nil {
+			return fmt.Errorf("AllPorts requires a BackendService target on ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+		if fi.ValueOf(e.LoadBalancingScheme) != "INTERNAL" {
+			return fmt.Errorf("AllPorts can only be set on forwarding rules with the INTERNAL load balancing scheme")
+		}
+	}
+	if a != nil && changes.AllPorts != nil {
+		return fi.CannotChangeField("AllPorts")
+	}
+	if fi.ValueOf(e.AllowGlobalAccess) && fi.ValueOf(e.LoadBalancingScheme) != "INTERNAL" {
+		return fmt.Errorf("AllowGlobalAccess can only be set on forwarding rules with the INTERNAL load balancing scheme")
+	}
+	if e.IPVersion != nil && *e.IPVersion != ipVersionIPv4 && *e.IPVersion != ipVersionIPv6 {
+		return fmt.Errorf("invalid IPVersion %q for ForwardingRule %q, must be %s or %s", *e.IPVersion, fi.ValueOf(e.Name), ipVersionIPv4, ipVersionIPv6)
+	}
+	if a != nil && changes.IPVersion != nil {
+		return fmt.Errorf("ForwardingRule %q exists with IP version %s, but %s was expected; it may belong to the load balancer of the other IP version",
+			fi.ValueOf(e.Name), fi.ValueOf(a.IPVersion), fi.ValueOf(e.IPVersion))
+	}
+	if a != nil && changes.NetworkTier != nil && !isAdoptedLifecycle(e.Lifecycle) {
+		return fi.CannotChangeField("NetworkTier")
+	}
+	if e.TerraformName != nil && *e.TerraformName == "" {
+		return fmt.Errorf("TerraformName cannot be empty on ForwardingRule %q", fi.ValueOf(e.Name))
+	}
+	if fi.ValueOf(e.PreserveIP) && !fi.ValueOf(e.ForceRecreate) {
+		return fmt.Errorf("PreserveIP requires ForceRecreate on ForwardingRule %q", fi.ValueOf(e.Name))
+	}
+	if fi.ValueOf(e.ReleasePreservedIP) && !fi.ValueOf(e.PreserveIP) {
+		return fmt.Errorf("ReleasePreservedIP requires PreserveIP on ForwardingRule %q", fi.ValueOf(e.Name))
+	}
+	if fi.ValueOf(e.WaitForServing) {
+		if e.IPProtocol != "TCP" {
+			return fmt.Errorf("WaitForServing requires the TCP protocol on ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+		if isInternalLoadBalancingScheme(fi.ValueOf(e.LoadBalancingScheme)) {
+			return fmt.Errorf("WaitForServing is not supported on ForwardingRule %q with the %s load balancing scheme", fi.ValueOf(e.Name), fi.ValueOf(e.LoadBalancingScheme))
+		}
+		if forwardingRuleServingPort(e) == "" {
+			return fmt.Errorf("WaitForServing requires PortRange or Ports on ForwardingRule %q", fi.ValueOf(e.Name))
+		}
+	}
+	if migratesTargetPoolToBackendService(a, e) {
+		if !fi.ValueOf(e.ForceRecreate) {
+			return fmt.Errorf("ForwardingRule %q targets TargetPool %q; moving it to BackendService %q requires ForceRecreate",
+				fi.ValueOf(e.Name), fi.ValueOf(a.TargetPool.Name), fi.ValueOf(e.BackendService.Name))
+		}
+		if fi.ValueOf(e.PreserveIP) && changes.LoadBalancingScheme != nil {
+			return fmt.Errorf("cannot preserve the IP of ForwardingRule %q while changing its load balancing scheme from %s to %s",
+				fi.ValueOf(e.Name), fi.ValueOf(a.LoadBalancingScheme), fi.ValueOf(e.LoadBalancingScheme))
+		}
+	}
 	return nil
 }
 
-func (_ *ForwardingRule) RenderGCE(t *gce.GCEAPITarget, a, e, changes *ForwardingRule) error {
-	ctx := context.TODO()
+// migratesTargetPoolToBackendService returns true if the existing rule a targets a TargetPool, while e targets a BackendService.
+// GCE cannot change the target type of a rule, so it has to be recreated.
+func migratesTargetPoolToBackendService(a, e *ForwardingRule) bool {
+	return a != nil && a.TargetPool != nil && e.TargetPool == nil && e.BackendService != nil
+}
+
+const (
+	ipVersionIPv4 = "IPV4"
+	ipVersionIPv6 = "IPV6"
+)
+
+// forwardingRuleIPVersion returns the IP version of the forwarding rule; GCE may omit it for IPV4 rules.
+func forwardingRuleIPVersion(fr *compute.ForwardingRule) string {
+	if fr.IpVersion == "" {
+		return ipVersionIPv4
+	}
+	return fr.IpVersion
+}
+
+// checkAddressIPVersion returns an error if the rule has an IPVersion, and its address is of the other IP version.
+// GCE would reject the rule, so this fails with a clearer error first.
+func checkAddressIPVersion(e *ForwardingRule, addressIPVersion string) error {
+	if e.IPVersion == nil || addressIPVersion == "" || addressIPVersion == *e.IPVersion {
+		return nil
+	}
+	return fmt.Errorf("Address %q is %s, so it cannot be used by %s ForwardingRule %q",
+		fi.ValueOf(e.IPAddress.Name), addressIPVersion, *e.IPVersion, fi.ValueOf(e.Name))
+}
+
+// isAdoptedLifecycle returns true if the lifecycle only adopts an existing forwarding rule,
+// which may be managed by another process and so must not be changed or pruned by us.
+func isAdoptedLifecycle(lifecycle fi.Lifecycle) bool {
+	switch lifecycle {
+	case fi.LifecycleExistsAndValidates, fi.LifecycleExistsAndWarnIfChanges, fi.LifecycleReferenceOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+var _ fi.SupportsReferenceOnly = &ForwardingRule{}
+
+// SupportsReferenceOnly implements fi.SupportsReferenceOnly.
+// A ReferenceOnly forwarding rule is rendered to terraform as a data source.
+func (e *ForwardingRule) SupportsReferenceOnly() bool {
+	return true
+}
+
+func (_ *ForwardingRule) RenderGCE(c *fi.CloudupContext, t *gce.GCEAPITarget, a, e, changes *ForwardingRule) error {
+	ctx := c.Context()
 
 	name := fi.ValueOf(e.Name)
+	global := fi.ValueOf(e.Global)
+
+	if e.Lifecycle == fi.LifecycleReferenceOnly {
+		if a == nil {
+			return fmt.Errorf("ForwardingRule %q has lifecycle %s but was not found", name, e.Lifecycle)
+		}
+		klog.V(2).Infof("not applying changes to ForwardingRule %q with lifecycle %s", name, e.Lifecycle)
+		return nil
+	}
+
+	// The task framework does not render adopted rules, but guard against mutating them anyway.
+	if a != nil && isAdoptedLifecycle(e.Lifecycle) {
+		if e.Lifecycle == fi.LifecycleExistsAndValidates {
+			return fmt.Errorf("ForwardingRule %q has lifecycle %s but does not match: %v", name, e.Lifecycle, changes)
+		}
+		klog.Warningf("not applying changes to ForwardingRule %q with lifecycle %s: %v", name, e.Lifecycle, changes)
+		return nil
+	}
 
 	o := &compute.ForwardingRule{
 		Name:       name,
@@ -166,10 +879,24 @@ func (_ *ForwardingRule) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Forwardin
 	if len(e.Ports) > 0 {
 		o.Ports = e.Ports
 	}
+	if e.AllPorts != nil {
+		o.AllPorts = *e.AllPorts
+	}
 
 	if e.LoadBalancingScheme != nil {
 		o.LoadBalancingScheme = *e.LoadBalancingScheme
 	}
+	if e.NoAutomateDNSZone != nil {
+		o.NoAutomateDnsZone = *e.NoAutomateDNSZone
+	}
+	if e.AllowGlobalAccess != nil {
+		o.AllowGlobalAccess = *e.AllowGlobalAccess
+	}
+	o.NetworkTier = gce.NetworkTierFor(t.Cloud, e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.LoadBalancingScheme))
+	if e.IPVersion != nil {
+		o.IpVersion = *e.IPVersion
+	}
+	o.ForceSendFields = explicitZeroFields(e)
 
 	if e.TargetPool != nil {
 		o.Target = e.TargetPool.URL(t.Cloud)
@@ -182,9 +909,28 @@ func (_ *ForwardingRule) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Forwardin
 		o.BackendService = e.BackendService.URL(t.Cloud)
 	}
 
-	if e.IPAddress != nil {
+	// crossProjectIP is the IP of an address in another project, which the rule references by its self link
+	crossProjectIP := ""
+	if e.IPAddress != nil && e.IPAddress.isCrossProject(t.Cloud) {
+		// An address in another project cannot be found by its IP, so it is referenced by its self link
+		addr, err := e.IPAddress.find(t.Cloud)
+		if err != nil {
+			return fmt.Errorf("error finding Address %q in project %s: %w", fi.ValueOf(e.IPAddress.Name), *e.IPAddress.Project, err)
+		}
+		if addr == nil {
+			return fmt.Errorf("Address %q was not found in project %s", fi.ValueOf(e.IPAddress.Name), *e.IPAddress.Project)
+		}
+		if err := checkAddressIPVersion(e, addr.ipVersion); err != nil {
+			return err
+		}
+		o.IPAddress = addr.selfLink
+		crossProjectIP = fi.ValueOf(addr.IPAddress)
+	} else if e.IPAddress != nil {
 		o.IPAddress = fi.ValueOf(e.IPAddress.IPAddress)
+		ipVersion := ipVersionOf(o.IPAddress)
 		if o.IPAddress == "" {
+			// The Address task is a dependency of this task (inferred from the IPAddress field),
+			// so it has already been created, but only the cloud knows the IP it was allocated.
 			addr, err := e.IPAddress.find(t.Cloud)
 			if err != nil {
 				return fmt.Errorf("error finding Address %q: %v", e.IPAddress, err)
@@ -197,14 +943,18 @@ func (_ *ForwardingRule) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Forwardin
 			if o.IPAddress == "" {
 				return fmt.Errorf("Address had no IP: %v", e.IPAddress)
 			}
+			ipVersion = addr.ipVersion
+		}
+		if err := checkAddressIPVersion(e, ipVersion); err != nil {
+			return err
 		}
-	}
-	if o.IPAddress != "" && e.RuleIPAddress != nil {
-		return fmt.Errorf("Specified both IP Address and rule-managed IP address: %v, %v", e.IPAddress, *e.RuleIPAddress)
 	}
 	if e.RuleIPAddress != nil {
 		o.IPAddress = *e.RuleIPAddress
 	}
+	if e.IPCollection != nil {
+		o.IpCollection = *e.IPCollection
+	}
 
 	if e.Network != nil {
 		project := t.Cloud.Project()
@@ -222,91 +972,555 @@ func (_ *ForwardingRule) RenderGCE(t *gce.GCEAPITarget, a, e, changes *Forwardin
 		o.Subnetwork = e.Subnetwork.URL(project, t.Cloud.Region())
 	}
 
+	// ip is the IP of the rule as GCE reports it on the rules that use it
+	ip := o.IPAddress
+	if crossProjectIP != "" && e.RuleIPAddress == nil {
+		ip = crossProjectIP
+	}
+
 	if a == nil {
-		klog.V(4).Infof("Creating ForwardingRule %q", o.Name)
+		return createForwardingRule(ctx, t, e, o, ip, global)
+	}
 
-		op, err := t.Cloud.Compute().ForwardingRules().Insert(ctx, t.Cloud.Project(), t.Cloud.Region(), o)
+	if !reflect.DeepEqual(withoutInPlaceChanges(changes), &ForwardingRule{}) {
+		if !fi.ValueOf(e.ForceRecreate) {
+			return fmt.Errorf("cannot apply changes to ForwardingRule: %v", changes)
+		}
+		if migratesTargetPoolToBackendService(a, e) {
+			klog.Warningf("Migrating ForwardingRule %q from TargetPool %q to BackendService %q; it does not serve traffic until it is recreated",
+				name, fi.ValueOf(a.TargetPool.Name), fi.ValueOf(e.BackendService.Name))
+		}
+		return recreateForwardingRule(ctx, t, a, e, changes, o, ip, global)
+	}
+
+	if changes.AllowGlobalAccess != nil {
+		patch := &compute.ForwardingRule{
+			AllowGlobalAccess: o.AllowGlobalAccess,
+			// Send false explicitly, as it is otherwise omitted and global access not disabled
+			ForceSendFields: []string{"AllowGlobalAccess"},
+		}
+		op, err := t.Cloud.Compute().ForwardingRules().Patch(ctx, t.Cloud.Project(), t.Cloud.Region(), o.Name, patch)
 		if err != nil {
-			return fmt.Errorf("error creating ForwardingRule %q: %v", o.Name, err)
+			return fmt.Errorf("setting ForwardingRule %q global access: %w", o.Name, err)
 		}
 
-		if err := t.Cloud.WaitForOp(op); err != nil {
-			return fmt.Errorf("error creating forwarding rule: %v", err)
+		if err := t.Cloud.WaitForOpContext(ctx, op); err != nil {
+			return fmt.Errorf("setting ForwardingRule %q global access: %w", o.Name, err)
 		}
+	}
 
-		if e.Labels != nil {
-			// We can't set labels on creation; we have to read the object to get the fingerprint
-			// TODO: We could get it from the operation!
-			r, err := t.Cloud.Compute().ForwardingRules().Get(ctx, t.Cloud.Project(), t.Cloud.Region(), name)
-			if err != nil {
-				return fmt.Errorf("reading created ForwardingRule %q: %v", name, err)
+	if changes.Labels != nil {
+		labels := e.Labels
+		if len(a.unmanagedLabels) != 0 {
+			labels = maps.Clone(a.unmanagedLabels)
+			maps.Copy(labels, e.Labels)
+			if len(labels) > gce.MaxLabels {
+				return fmt.Errorf("ForwardingRule %q would have %d labels with the %d labels kops does not manage, must have at most %d",
+					name, len(labels), len(a.unmanagedLabels), gce.MaxLabels)
 			}
+		}
+		if err := setForwardingRuleLabels(ctx, t, o.Name, a.labelFingerprint, labels, global); err != nil {
+			return err
+		}
+	}
 
-			req := compute.RegionSetLabelsRequest{
-				LabelFingerprint: r.LabelFingerprint,
-				Labels:           e.Labels,
-			}
-			op, err := t.Cloud.Compute().ForwardingRules().SetLabels(ctx, t.Cloud.Project(), t.Cloud.Region(), o.Name, &req)
-			if err != nil {
-				return fmt.Errorf("setting ForwardingRule labels: %w", err)
-			}
+	return nil
+}
 
-			if err := t.Cloud.WaitForOp(op); err != nil {
-				return fmt.Errorf("setting ForwardRule labels: %w", err)
-			}
+// setForwardingRuleLabels sets the labels of the rule, given the fingerprint of its current labels.
+// Global rules have their own SetLabels API, which takes a GlobalSetLabelsRequest instead of a RegionSetLabelsRequest.
+func setForwardingRuleLabels(ctx context.Context, t *gce.GCEAPITarget, name string, fingerprint string, labels map[string]string, global bool) error {
+	if global {
+		req := compute.GlobalSetLabelsRequest{
+			LabelFingerprint: fingerprint,
+			Labels:           labels,
+		}
+		return t.SetGlobalForwardingRuleLabels(ctx, name, &req)
+	}
+	req := compute.RegionSetLabelsRequest{
+		LabelFingerprint: fingerprint,
+		Labels:           labels,
+	}
+	return t.SetForwardingRuleLabels(ctx, name, &req)
+}
+
+// explicitZeroFields returns the names of the compute fields which e explicitly sets to their zero value, such as a
+// cleared PortRange. They must be force-sent, as the compute API otherwise omits them, and so cannot tell them from unset.
+func explicitZeroFields(e *ForwardingRule) []string {
+	var fields []string
+	if e.PortRange != nil && *e.PortRange == "" {
+		fields = append(fields, "PortRange")
+	}
+	if e.Ports != nil && len(e.Ports) == 0 {
+		fields = append(fields, "Ports")
+	}
+	if e.AllPorts != nil && !*e.AllPorts {
+		fields = append(fields, "AllPorts")
+	}
+	if e.NoAutomateDNSZone != nil && !*e.NoAutomateDNSZone {
+		fields = append(fields, "NoAutomateDnsZone")
+	}
+	if e.AllowGlobalAccess != nil && !*e.AllowGlobalAccess {
+		fields = append(fields, "AllowGlobalAccess")
+	}
+	return fields
+}
+
+// withoutInPlaceChanges returns a copy of changes without the labels and global access, which can be updated in place.
+func withoutInPlaceChanges(changes *ForwardingRule) *ForwardingRule {
+	c := *changes
+	c.Labels = nil
+	c.AllowGlobalAccess = nil
+	return &c
+}
+
+// createForwardingRule creates the forwarding rule o, and then sets the labels of e.
+// ip is the IP of the rule, which differs from o.IPAddress for an address referenced by its self link.
+func createForwardingRule(ctx context.Context, t *gce.GCEAPITarget, e *ForwardingRule, o *compute.ForwardingRule, ip string, global bool) error {
+	cloud := t.Cloud
+
+	if ip != "" {
+		if err := checkForwardingRuleIPAvailable(ctx, cloud, o, ip, global); err != nil {
+			return err
+		}
+	}
+
+	if e.TargetPool != nil {
+		if err := checkForwardingRuleTargetPoolExists(cloud, e.TargetPool, o.Name); err != nil {
+			return err
+		}
+	}
+
+	klog.V(4).Infof("Creating ForwardingRule %q", o.Name)
+
+	var op *compute.Operation
+	var err error
+	if global {
+		op, err = cloud.Compute().GlobalForwardingRules().Insert(ctx, cloud.Project(), o)
+	} else {
+		op, err = cloud.Compute().ForwardingRules().Insert(ctx, cloud.Project(), cloud.Region(), o)
+	}
+	if err != nil {
+		if o.IpCollection != "" && gce.IsUnsupportedField(err, "ipCollection") {
+			return fmt.Errorf("error creating ForwardingRule %q: ipCollection is not supported by the compute API: %w", o.Name, err)
+		}
+		return fmt.Errorf("error creating ForwardingRule %q: %v", o.Name, err)
+	}
+
+	if err := cloud.WaitForOpContext(ctx, op); err != nil {
+		return fmt.Errorf("error creating forwarding rule: %v", err)
+	}
+
+	if e.Labels != nil {
+		// We can't set labels on creation; we have to read the object to get the fingerprint
+		// TODO: We could get it from the operation!
+		var r *compute.ForwardingRule
+		if global {
+			r, err = cloud.Compute().GlobalForwardingRules().Get(ctx, cloud.Project(), o.Name)
+		} else {
+			r, err = cloud.Compute().ForwardingRules().Get(ctx, cloud.Project(), cloud.Region(), o.Name)
+		}
+		if err != nil {
+			return fmt.Errorf("reading created ForwardingRule %q: %v", o.Name, err)
+		}
+
+		if err := setForwardingRuleLabels(ctx, t, o.Name, r.LabelFingerprint, e.Labels, global); err != nil {
+			return err
+		}
+	}
+
+	if fi.ValueOf(e.WaitForServing) {
+		if err := waitForRuleServing(ctx, cloud, o.Name, forwardingRuleServingPort(e), global); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+var (
+	// forwardingRuleServingTimeout is how long waitForRuleServing waits for a new rule to accept connections
+	forwardingRuleServingTimeout = 5 * time.Minute
+	// forwardingRuleServingPollInterval is how often waitForRuleServing dials a new rule
+	forwardingRuleServingPollInterval = 5 * time.Second
+	// dialForwardingRule dials the IP and port of a rule, and is replaced in tests
+	dialForwardingRule = (&net.Dialer{Timeout: 5 * time.Second}).DialContext
+)
+
+// forwardingRuleServingPort returns the port probed by WaitForServing, which is the first port of the rule.
+func forwardingRuleServingPort(e *ForwardingRule) string {
+	if len(e.Ports) != 0 {
+		return e.Ports[0]
+	}
+	if e.PortRange != nil {
+		port, _, _ := strings.Cut(*e.PortRange, "-")
+		return port
+	}
+	return ""
+}
+
+// waitForRuleServing dials the IP of the rule name on port until it accepts a TCP connection.
+// GCE completes the insert operation before the rule is programmed in the data plane, so this is the signal that it is serving.
+func waitForRuleServing(ctx context.Context, cloud gce.GCECloud, name string, port string, global bool) error {
+	var r *compute.ForwardingRule
+	var err error
+	if global {
+		r, err = cloud.Compute().GlobalForwardingRules().Get(ctx, cloud.Project(), name)
 	} else {
-		if changes.Labels != nil {
-			req := compute.RegionSetLabelsRequest{
-				LabelFingerprint: a.labelFingerprint,
-				Labels:           e.Labels,
-			}
-			op, err := t.Cloud.Compute().ForwardingRules().SetLabels(ctx, t.Cloud.Project(), t.Cloud.Region(), o.Name, &req)
-			if err != nil {
-				return fmt.Errorf("setting ForwardingRule labels: %w", err)
-			}
+		r, err = cloud.Compute().ForwardingRules().Get(ctx, cloud.Project(), cloud.Region(), name)
+	}
+	if err != nil {
+		return fmt.Errorf("reading created ForwardingRule %q: %w", name, err)
+	}
+	if r.IPAddress == "" {
+		return fmt.Errorf("created ForwardingRule %q has no IP address to probe", name)
+	}
 
-			if err := t.Cloud.WaitForOp(op); err != nil {
-				return fmt.Errorf("setting ForwardRule labels: %w", err)
-			}
+	address := net.JoinHostPort(r.IPAddress, port)
+	klog.V(2).Infof("Waiting for ForwardingRule %q to serve on %s", name, address)
+
+	var lastErr error
+	err = wait.PollUntilContextTimeout(ctx, forwardingRuleServingPollInterval, forwardingRuleServingTimeout, true, func(ctx context.Context) (bool, error) {
+		conn, err := dialForwardingRule(ctx, "tcp", address)
+		if err != nil {
+			klog.V(4).Infof("ForwardingRule %q is not serving on %s yet: %v", name, address, err)
+			lastErr = err
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			return fmt.Errorf("ForwardingRule %q is not serving on %s: %w (last error: %v)", name, address, err, lastErr)
+		}
+		return fmt.Errorf("ForwardingRule %q is not serving on %s: %w", name, address, err)
+	}
+	return nil
+}
+
+// recreateForwardingRule applies changes which GCE cannot apply in place, by deleting the rule and creating o.
+// With PreserveIP, an ephemeral IP of the rule is first promoted to a reserved Address, which o then reuses.
+func recreateForwardingRule(ctx context.Context, t *gce.GCEAPITarget, a, e, changes *ForwardingRule, o *compute.ForwardingRule, ip string, global bool) error {
+	cloud := t.Cloud
 
-			changes.Labels = nil
+	klog.Infof("Recreating ForwardingRule %q to apply changes: %v", o.Name, changes)
+
+	preservedAddress := ""
+	if fi.ValueOf(e.PreserveIP) && o.IPAddress == "" && a.ipAddress != "" && a.IPAddress == nil && a.IPCollection == nil {
+		addressName, err := promoteForwardingRuleIP(ctx, cloud, o, a.ipAddress, global)
+		if err != nil {
+			return err
 		}
+		preservedAddress = addressName
+		o.IPAddress = a.ipAddress
+		ip = a.ipAddress
+	}
 
-		if !reflect.DeepEqual(changes, &ForwardingRule{}) {
-			return fmt.Errorf("cannot apply changes to ForwardingRule: %v", changes)
+	var op *compute.Operation
+	var err error
+	if global {
+		op, err = cloud.Compute().GlobalForwardingRules().Delete(ctx, cloud.Project(), o.Name)
+	} else {
+		op, err = cloud.Compute().ForwardingRules().Delete(ctx, cloud.Project(), cloud.Region(), o.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting ForwardingRule %q for recreation: %w", o.Name, err)
+	}
+	if err := cloud.WaitForOpContext(ctx, op); err != nil {
+		return fmt.Errorf("error deleting ForwardingRule %q for recreation: %w", o.Name, err)
+	}
+
+	if err := createForwardingRule(ctx, t, e, o, ip, global); err != nil {
+		return err
+	}
+
+	if preservedAddress != "" && fi.ValueOf(e.ReleasePreservedIP) {
+		klog.V(2).Infof("Releasing Address %q reserved for ForwardingRule %q", preservedAddress, o.Name)
+		if global {
+			op, err = cloud.Compute().GlobalAddresses().Delete(cloud.Project(), preservedAddress)
+		} else {
+			op, err = cloud.Compute().Addresses().Delete(cloud.Project(), cloud.Region(), preservedAddress)
+		}
+		if err != nil {
+			return fmt.Errorf("error releasing Address %q of ForwardingRule %q: %w", preservedAddress, o.Name, err)
+		}
+		if err := cloud.WaitForOpContext(ctx, op); err != nil {
+			return fmt.Errorf("error releasing Address %q of ForwardingRule %q: %w", preservedAddress, o.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// promoteForwardingRuleIP reserves the ephemeral IP of the forwarding rule o as an Address, so that it is not
+// released when the rule is deleted. It returns the name of the Address.
+func promoteForwardingRuleIP(ctx context.Context, cloud gce.GCECloud, o *compute.ForwardingRule, ip string, global bool) (string, error) {
+	addr := &compute.Address{
+		Name:        truncate.TruncateString(o.Name+"-ip", truncate.TruncateStringOptions{MaxLength: 63}),
+		Address:     ip,
+		NetworkTier: o.NetworkTier,
+		IpVersion:   o.IpVersion,
+	}
+	if o.LoadBalancingScheme == "INTERNAL" {
+		addr.AddressType = "INTERNAL"
+		addr.Subnetwork = o.Subnetwork
+	}
+
+	klog.V(2).Infof("Reserving IP %s of ForwardingRule %q as Address %q", ip, o.Name, addr.Name)
+	var op *compute.Operation
+	var err error
+	if global {
+		op, err = cloud.Compute().GlobalAddresses().Insert(cloud.Project(), addr)
+	} else {
+		op, err = cloud.Compute().Addresses().Insert(cloud.Project(), cloud.Region(), addr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reserving IP %s of ForwardingRule %q: %w", ip, o.Name, err)
+	}
+	if err := cloud.WaitForOpContext(ctx, op); err != nil {
+		return "", fmt.Errorf("error reserving IP %s of ForwardingRule %q: %w", ip, o.Name, err)
+	}
+	return addr.Name, nil
+}
+
+// checkForwardingRuleTargetPoolExists checks that the target pool of a rule exists before the rule is created.
+// The TargetPool task is normally a dependency of the rule, but a pool which is not part of the tasks,
+// or failed to be created, would otherwise only surface as an unhelpful 400 from GCE.
+func checkForwardingRuleTargetPoolExists(cloud gce.GCECloud, targetPool *TargetPool, ruleName string) error {
+	poolName := fi.ValueOf(targetPool.Name)
+	_, err := cloud.Compute().TargetPools().Get(cloud.Project(), cloud.Region(), poolName)
+	if err != nil {
+		if gce.IsNotFound(err) {
+			return fmt.Errorf("TargetPool %q of ForwardingRule %q does not exist in region %s", poolName, ruleName, cloud.Region())
+		}
+		return fmt.Errorf("error getting TargetPool %q of ForwardingRule %q: %w", poolName, ruleName, err)
+	}
+	return nil
+}
+
+// checkForwardingRuleIPAvailable verifies that the IP address of the forwarding rule isn't already serving
+// the same ports through another forwarding rule, for example because the rule was deleted out-of-band
+// and its reserved address was reused elsewhere. ip is the IP of the rule, as GCE reports it on the other rules.
+func checkForwardingRuleIPAvailable(ctx context.Context, cloud gce.GCECloud, o *compute.ForwardingRule, ip string, global bool) error {
+	var forwardingRules []*compute.ForwardingRule
+	var err error
+	if global {
+		forwardingRules, err = cloud.Compute().GlobalForwardingRules().List(ctx, cloud.Project())
+	} else {
+		forwardingRules, err = cloud.Compute().ForwardingRules().List(ctx, cloud.Project(), cloud.Region())
+	}
+	if err != nil {
+		return fmt.Errorf("listing ForwardingRules: %w", err)
+	}
+
+	for _, fr := range forwardingRules {
+		if fr.Name == o.Name || fr.IPAddress != ip {
+			continue
+		}
+		if !strings.EqualFold(fr.IPProtocol, o.IPProtocol) || !forwardingRulePortsOverlap(fr, o) {
+			continue
+		}
+
+		addressName := ip
+		var addr *Address
+		if global {
+			addr, err = findGlobalAddressByIP(cloud, ip, o.IpVersion)
+		} else {
+			addr, err = findAddressByIP(cloud, ip, o.Subnetwork, o.IpVersion)
+		}
+		if err != nil {
+			return err
+		}
+		if addr != nil {
+			addressName = fmt.Sprintf("%s (%s)", fi.ValueOf(addr.Name), ip)
 		}
+		return fmt.Errorf("cannot create ForwardingRule %q: address %s is already in use by ForwardingRule %q; delete that ForwardingRule or choose another address", o.Name, addressName, fr.Name)
 	}
 
 	return nil
 }
 
+// forwardingRulePortsOverlap returns true if the two forwarding rules accept traffic on a common port.
+func forwardingRulePortsOverlap(a, b *compute.ForwardingRule) bool {
+	for _, ra := range forwardingRulePortRanges(a) {
+		for _, rb := range forwardingRulePortRanges(b) {
+			if ra[0] <= rb[1] && rb[0] <= ra[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forwardingRulePortRanges returns the port ranges served by the forwarding rule, as inclusive [low, high] pairs.
+func forwardingRulePortRanges(fr *compute.ForwardingRule) [][2]int {
+	allPorts := [][2]int{{1, 65535}}
+	if fr.AllPorts {
+		return allPorts
+	}
+
+	var specs []string
+	if fr.PortRange != "" {
+		specs = append(specs, fr.PortRange)
+	}
+	specs = append(specs, fr.Ports...)
+	if len(specs) == 0 {
+		return allPorts
+	}
+
+	var ranges [][2]int
+	for _, spec := range specs {
+		low, high, found := strings.Cut(spec, "-")
+		if !found {
+			high = low
+		}
+		l, err := strconv.Atoi(low)
+		if err != nil {
+			return allPorts
+		}
+		h, err := strconv.Atoi(high)
+		if err != nil {
+			return allPorts
+		}
+		ranges = append(ranges, [2]int{l, h})
+	}
+	return ranges
+}
+
+// wellKnownServicePorts maps the service names accepted in place of port numbers to their ports.
+// It is built in, rather than read from /etc/services, so that the result doesn't depend on the machine running kops.
+var wellKnownServicePorts = map[string]int{
+	"ftp":        21,
+	"ssh":        22,
+	"telnet":     23,
+	"smtp":       25,
+	"dns":        53,
+	"domain":     53,
+	"http":       80,
+	"pop3":       110,
+	"imap":       143,
+	"ldap":       389,
+	"https":      443,
+	"smtps":      465,
+	"submission": 587,
+	"ldaps":      636,
+	"imaps":      993,
+	"pop3s":      995,
+	"mysql":      3306,
+	"postgresql": 5432,
+	"http-alt":   8080,
+}
+
+// resolvePortSpec resolves the service names in a port or port range, such as "https" or "http-https", to port numbers.
+// Numeric ports are returned unchanged; unknown service names are an error.
+func resolvePortSpec(spec string) (string, error) {
+	if port, found := wellKnownServicePorts[strings.ToLower(spec)]; found {
+		return strconv.Itoa(port), nil
+	}
+
+	low, high, isRange := strings.Cut(spec, "-")
+	resolvePort := func(s string) (string, error) {
+		if _, err := strconv.Atoi(s); err == nil {
+			return s, nil
+		}
+		if port, found := wellKnownServicePorts[strings.ToLower(s)]; found {
+			return strconv.Itoa(port), nil
+		}
+		return "", fmt.Errorf("unknown service name %q in port %q", s, spec)
+	}
+	l, err := resolvePort(low)
+	if err != nil {
+		return "", err
+	}
+	if !isRange {
+		return l, nil
+	}
+	h, err := resolvePort(high)
+	if err != nil {
+		return "", err
+	}
+	return l + "-" + h, nil
+}
+
 type terraformForwardingRule struct {
 	Name                string                   `cty:"name"`
 	PortRange           *string                  `cty:"port_range"`
 	Ports               []string                 `cty:"ports"`
+	AllPorts            *bool                    `cty:"all_ports"`
 	Target              *terraformWriter.Literal `cty:"target"`
 	IPAddress           *terraformWriter.Literal `cty:"ip_address"`
+	IPCollection        *string                  `cty:"ip_collection"`
 	IPProtocol          string                   `cty:"ip_protocol"`
 	LoadBalancingScheme *string                  `cty:"load_balancing_scheme"`
 	Network             *terraformWriter.Literal `cty:"network"`
 	Subnetwork          *terraformWriter.Literal `cty:"subnetwork"`
 	BackendService      *terraformWriter.Literal `cty:"backend_service"`
+	NoAutomateDNSZone   *bool                    `cty:"no_automate_dns_zone"`
+	AllowGlobalAccess   *bool                    `cty:"allow_global_access"`
+	NetworkTier         *string                  `cty:"network_tier"`
+	IPVersion           *string                  `cty:"ip_version"`
 	Labels              map[string]string        `cty:"labels"`
+	Region              *string                  `cty:"region"`
+	Project             *string                  `cty:"project"`
+	Lifecycle           *terraform.Lifecycle     `cty:"lifecycle"`
+	// DependsOn orders the rule after its backend service is ready, which the reference alone does not guarantee
+	DependsOn []*terraformWriter.Literal `cty:"depends_on"`
+}
+
+// terraformForwardingRuleData is the data source used to reference a forwarding rule managed outside of kops
+type terraformForwardingRuleData struct {
+	Name    string  `cty:"name"`
+	Region  *string `cty:"region"`
+	Project *string `cty:"project"`
 }
 
 func (_ *ForwardingRule) RenderTerraform(t *terraform.TerraformTarget, a, e, changes *ForwardingRule) error {
 	name := fi.ValueOf(e.Name)
 
+	if e.Lifecycle == fi.LifecycleReferenceOnly {
+		tf := &terraformForwardingRuleData{
+			Name: name,
+		}
+		if region := t.Cloud.Region(); region != t.ProviderRegion() && !fi.ValueOf(e.Global) {
+			tf.Region = fi.PtrTo(region)
+		}
+		if project := t.Project; project != t.ProviderProject() {
+			tf.Project = fi.PtrTo(project)
+		}
+		return t.RenderDataSource(e.terraformResourceType(), e.terraformName(), tf)
+	}
+
+	if e.ManagedLabelPrefix != nil {
+		klog.Warningf("ignoring ManagedLabelPrefix of ForwardingRule %q, as terraform manages all its labels", name)
+	}
+
 	tf := &terraformForwardingRule{
 		Name:                name,
 		IPProtocol:          e.IPProtocol,
 		LoadBalancingScheme: e.LoadBalancingScheme,
+		NoAutomateDNSZone:   e.NoAutomateDNSZone,
+		AllowGlobalAccess:   e.AllowGlobalAccess,
+		IPVersion:           e.IPVersion,
 		Ports:               e.Ports,
+		AllPorts:            e.AllPorts,
 		PortRange:           e.PortRange,
 		Labels:              e.Labels,
 	}
 
+	if networkTier := gce.NetworkTierFor(t.Cloud.(gce.GCECloud), e.NetworkTier, fi.ValueOf(e.Global), fi.ValueOf(e.LoadBalancingScheme)); networkTier != "" {
+		tf.NetworkTier = fi.PtrTo(networkTier)
+	}
+
+	// Only emit region and project when they differ from the provider defaults,
+	// so that existing configurations don't see spurious changes.
+	if region := t.Cloud.Region(); region != t.ProviderRegion() && !fi.ValueOf(e.Global) {
+		tf.Region = fi.PtrTo(region)
+	}
+	if project := t.Project; project != t.ProviderProject() {
+		tf.Project = fi.PtrTo(project)
+	}
+
 	if e.TargetPool != nil {
 		tf.Target = e.TargetPool.TerraformLink()
 	}
@@ -321,21 +1535,51 @@ func (_ *ForwardingRule) RenderTerraform(t *terraform.TerraformTarget, a, e, cha
 
 	if e.BackendService != nil {
 		tf.BackendService = e.BackendService.TerraformAddress()
+		tf.DependsOn = e.BackendService.TerraformDependencies()
 	}
 
-	if e.IPAddress != nil {
+	if e.IPAddress != nil && e.IPAddress.Project != nil && *e.IPAddress.Project != t.Project {
+		// An address in another project is not managed by this configuration
+		tf.IPAddress = terraformWriter.LiteralFromStringValue(e.IPAddress.URL(*e.IPAddress.Project, t.Cloud.Region()))
+	} else if e.IPAddress != nil {
 		tf.IPAddress = e.IPAddress.TerraformAddress()
 	} else if e.RuleIPAddress != nil {
 		tf.IPAddress = terraformWriter.LiteralFromStringValue(*e.RuleIPAddress)
 	}
+	tf.IPCollection = e.IPCollection
+
+	if fi.ValueOf(e.TerraformIgnoreIPAddressChanges) && e.IPAddress == nil {
+		tf.Lifecycle = &terraform.Lifecycle{
+			IgnoreChanges: []*terraformWriter.Literal{{String: "ip_address"}},
+		}
+	}
 
-	return t.RenderResource("google_compute_forwarding_rule", name, tf)
+	return t.RenderResource(e.terraformResourceType(), e.terraformName(), tf)
+}
+
+// terraformName returns the name of the terraform resource of the rule, which is independent of the GCE name when TerraformName is set.
+func (e *ForwardingRule) terraformName() string {
+	if e.TerraformName != nil {
+		return *e.TerraformName
+	}
+	return fi.ValueOf(e.Name)
+}
+
+// terraformResourceType returns the terraform resource type of the forwarding rule, which differs for global rules.
+func (e *ForwardingRule) terraformResourceType() string {
+	if fi.ValueOf(e.Global) {
+		return "google_compute_global_forwarding_rule"
+	}
+	return "google_compute_forwarding_rule"
 }
 
 func (e *ForwardingRule) TerraformLink() *terraformWriter.Literal {
-	name := fi.ValueOf(e.Name)
+	name := e.terraformName()
 
-	return terraformWriter.LiteralSelfLink("google_compute_forwarding_rule", name)
+	if e.Lifecycle == fi.LifecycleReferenceOnly {
+		return terraformWriter.LiteralData(e.terraformResourceType(), name, "self_link")
+	}
+	return terraformWriter.LiteralSelfLink(e.terraformResourceType(), name)
 }
 
 var _ fi.CloudupProducesDeletions = &ForwardingRule{}
@@ -344,6 +1588,11 @@ var _ fi.CloudupProducesDeletions = &ForwardingRule{}
 func (e *ForwardingRule) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeletion, error) {
 	var removals []fi.CloudupDeletion
 
+	if len(e.pruneForwardingRules) != 0 && isAdoptedLifecycle(e.Lifecycle) {
+		klog.V(2).Infof("not pruning forwarding rules for ForwardingRule %q with lifecycle %s", fi.ValueOf(e.Name), e.Lifecycle)
+		return nil, nil
+	}
+
 	if len(e.pruneForwardingRules) != 0 {
 		ctx := c.Context()
 		cloud := c.T.Cloud.(gce.GCECloud)
@@ -361,6 +1610,11 @@ func (e *ForwardingRule) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeleti
 					prune = true
 				}
 			}
+			if prune && e.IPVersion != nil && forwardingRuleIPVersion(forwardingRule) != *e.IPVersion {
+				klog.Warningf("not pruning ForwardingRule %q of IP version %s from ForwardingRule %q of IP version %s",
+					forwardingRule.Name, forwardingRuleIPVersion(forwardingRule), fi.ValueOf(e.Name), *e.IPVersion)
+				prune = false
+			}
 
 			if prune {
 				removals = append(removals, &deleteForwardingRule{forwardingRule: forwardingRule})
@@ -0,0 +1,2879 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcetasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
+	"k8s.io/apimachinery/pkg/util/wait"
+	gcemock "k8s.io/kops/cloudmock/gce"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/gce"
+	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
+)
+
+func TestForwardingRuleTerraformRegionAndProject(t *testing.T) {
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	tests := []struct {
+		desc            string
+		providerConfig  map[string]string
+		expectedRegion  *string
+		expectedProject *string
+	}{
+		{
+			desc: "provider defaults match the cloud",
+		},
+		{
+			desc:           "provider region differs",
+			providerConfig: map[string]string{"region": "us-test2"},
+			expectedRegion: fi.PtrTo(region),
+		},
+		{
+			desc:            "provider project differs",
+			providerConfig:  map[string]string{"project": "otherproject"},
+			expectedProject: fi.PtrTo(project),
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			targetSpec := &kops.TargetSpec{
+				Terraform: &kops.TerraformSpec{
+					ProviderExtraConfig: testCase.providerConfig,
+				},
+			}
+			target := terraform.NewTerraformTarget(cloud, project, "", targetSpec)
+
+			e := &ForwardingRule{
+				Name:       fi.PtrTo("api"),
+				IPProtocol: "TCP",
+				PortRange:  fi.PtrTo("443-443"),
+			}
+			if err := e.RenderTerraform(target, nil, e, e); err != nil {
+				t.Fatalf("unexpected error rendering terraform: %v", err)
+			}
+
+			resources, err := target.GetResourcesByType()
+			if err != nil {
+				t.Fatalf("unexpected error getting resources: %v", err)
+			}
+			tf := resources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRule)
+			if fi.ValueOf(tf.Region) != fi.ValueOf(testCase.expectedRegion) {
+				t.Errorf("expected region %q, got %q", fi.ValueOf(testCase.expectedRegion), fi.ValueOf(tf.Region))
+			}
+			if fi.ValueOf(tf.Project) != fi.ValueOf(testCase.expectedProject) {
+				t.Errorf("expected project %q, got %q", fi.ValueOf(testCase.expectedProject), fi.ValueOf(tf.Project))
+			}
+		})
+	}
+}
+
+func TestForwardingRuleTerraformIgnoreIPAddressChanges(t *testing.T) {
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	tests := []struct {
+		desc          string
+		rule          *ForwardingRule
+		expectIgnored bool
+	}{
+		{
+			desc: "rule-managed IP",
+			rule: &ForwardingRule{
+				RuleIPAddress:                   fi.PtrTo("10.0.0.1"),
+				TerraformIgnoreIPAddressChanges: fi.PtrTo(true),
+			},
+			expectIgnored: true,
+		},
+		{
+			desc: "ephemeral IP",
+			rule: &ForwardingRule{
+				TerraformIgnoreIPAddressChanges: fi.PtrTo(true),
+			},
+			expectIgnored: true,
+		},
+		{
+			desc: "option not set",
+			rule: &ForwardingRule{
+				RuleIPAddress: fi.PtrTo("10.0.0.1"),
+			},
+		},
+		{
+			desc: "fixed Address",
+			rule: &ForwardingRule{
+				IPAddress:                       &Address{Name: fi.PtrTo("api")},
+				TerraformIgnoreIPAddressChanges: fi.PtrTo(true),
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+
+			e := testCase.rule
+			e.Name = fi.PtrTo("api")
+			e.IPProtocol = "TCP"
+			if err := e.RenderTerraform(target, nil, e, e); err != nil {
+				t.Fatalf("unexpected error rendering terraform: %v", err)
+			}
+
+			resources, err := target.GetResourcesByType()
+			if err != nil {
+				t.Fatalf("unexpected error getting resources: %v", err)
+			}
+			tf := resources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRule)
+			ignored := tf.Lifecycle != nil && len(tf.Lifecycle.IgnoreChanges) == 1 && tf.Lifecycle.IgnoreChanges[0].String == "ip_address"
+			if ignored != testCase.expectIgnored {
+				t.Errorf("expected ip_address ignored=%v, got lifecycle %+v", testCase.expectIgnored, tf.Lifecycle)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleTerraformReferenceOnly(t *testing.T) {
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+	target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+
+	e := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		Lifecycle:  fi.LifecycleReferenceOnly,
+		IPProtocol: "TCP",
+		PortRange:  fi.PtrTo("443-443"),
+	}
+	if err := e.RenderTerraform(target, nil, e, e); err != nil {
+		t.Fatalf("unexpected error rendering terraform: %v", err)
+	}
+
+	resources, err := target.GetResourcesByType()
+	if err != nil {
+		t.Fatalf("unexpected error getting resources: %v", err)
+	}
+	if _, found := resources["google_compute_forwarding_rule"]["api"]; found {
+		t.Errorf("expected no managed resource for a ReferenceOnly forwarding rule")
+	}
+
+	dataSources, err := target.GetDataSourcesByType()
+	if err != nil {
+		t.Fatalf("unexpected error getting data sources: %v", err)
+	}
+	tf, ok := dataSources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRuleData)
+	if !ok {
+		t.Fatalf("expected google_compute_forwarding_rule data source, got %v", dataSources)
+	}
+	if tf.Name != "api" {
+		t.Errorf("expected data source name %q, got %q", "api", tf.Name)
+	}
+
+	link := e.TerraformLink()
+	if link == nil || link.String != "data.google_compute_forwarding_rule.api.self_link" {
+		t.Errorf("expected link to the data source, got %v", link)
+	}
+}
+
+func TestForwardingRuleTerraformDependsOnBackendService(t *testing.T) {
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+	outDir := t.TempDir()
+	target := terraform.NewTerraformTarget(cloud, project, outDir, &kops.TargetSpec{})
+
+	backendService := &BackendService{
+		Name:         fi.PtrTo("api-example-com"),
+		HealthChecks: []*HealthCheck{{Name: fi.PtrTo("api-example-com")}},
+	}
+	rules := []*ForwardingRule{
+		{
+			Name:                fi.PtrTo("api-example-com"),
+			IPProtocol:          "TCP",
+			Ports:               []string{"443"},
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+			BackendService:      backendService,
+		},
+		{
+			Name:       fi.PtrTo("kops-controller-example-com"),
+			IPProtocol: "TCP",
+			PortRange:  fi.PtrTo("3988-3988"),
+		},
+	}
+	for _, e := range rules {
+		if err := e.RenderTerraform(target, nil, e, e); err != nil {
+			t.Fatalf("unexpected error rendering terraform: %v", err)
+		}
+	}
+	if err := target.Finish(nil); err != nil {
+		t.Fatalf("unexpected error writing terraform: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(outDir, "kubernetes.tf"))
+	if err != nil {
+		t.Fatalf("unexpected error reading terraform: %v", err)
+	}
+
+	expected := `resource "google_compute_forwarding_rule" "api-example-com" {
+  backend_service = google_compute_backend_service.api-example-com.id
+  depends_on      = [google_compute_backend_service.api-example-com, google_compute_health_check.api-example-com]
+  ip_protocol     = "TCP"
+  load_balancing_scheme = "INTERNAL"
+  name                  = "api-example-com"
+  ports                 = ["443"]
+}
+
+resource "google_compute_forwarding_rule" "kops-controller-example-com" {
+  ip_protocol = "TCP"
+  name       = "kops-controller-example-com"
+  port_range = "3988-3988"
+}
+`
+	if !strings.Contains(string(b), expected) {
+		t.Errorf("expected terraform to contain:\n%s\ngot:\n%s", expected, string(b))
+	}
+}
+
+func TestForwardingRuleTerraformName(t *testing.T) {
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	tests := []struct {
+		desc         string
+		rule         *ForwardingRule
+		expectedKey  string
+		expectedName string
+	}{
+		{
+			desc:         "defaults to the name",
+			rule:         &ForwardingRule{Name: fi.PtrTo("api")},
+			expectedKey:  "api",
+			expectedName: "api",
+		},
+		{
+			desc:         "renamed rule keeps its terraform name",
+			rule:         &ForwardingRule{Name: fi.PtrTo("api-v2"), TerraformName: fi.PtrTo("api")},
+			expectedKey:  "api",
+			expectedName: "api-v2",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+
+			e := testCase.rule
+			e.IPProtocol = "TCP"
+			if err := e.RenderTerraform(target, nil, e, e); err != nil {
+				t.Fatalf("unexpected error rendering terraform: %v", err)
+			}
+
+			resources, err := target.GetResourcesByType()
+			if err != nil {
+				t.Fatalf("unexpected error getting resources: %v", err)
+			}
+			tf, ok := resources["google_compute_forwarding_rule"][testCase.expectedKey].(*terraformForwardingRule)
+			if !ok {
+				t.Fatalf("expected google_compute_forwarding_rule.%s, got %v", testCase.expectedKey, resources)
+			}
+			if tf.Name != testCase.expectedName {
+				t.Errorf("expected GCE name %q, got %q", testCase.expectedName, tf.Name)
+			}
+
+			expectedLink := "google_compute_forwarding_rule." + testCase.expectedKey + ".self_link"
+			if link := e.TerraformLink(); link == nil || link.String != expectedLink {
+				t.Errorf("expected link %q, got %v", expectedLink, link)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleIPAddressInUse(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().Addresses().Insert(project, region, &compute.Address{Name: "api-address", Address: "1.2.3.4"}); err != nil {
+		t.Fatalf("error creating address: %v", err)
+	}
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:       "other",
+		IPAddress:  "1.2.3.4",
+		IPProtocol: "TCP",
+		PortRange:  "443-443",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	tests := []struct {
+		desc          string
+		rule          *compute.ForwardingRule
+		expectedError string
+	}{
+		{
+			desc:          "same port range",
+			rule:          &compute.ForwardingRule{Name: "api", IPAddress: "1.2.3.4", IPProtocol: "TCP", PortRange: "443-443"},
+			expectedError: `address api-address (1.2.3.4) is already in use by ForwardingRule "other"`,
+		},
+		{
+			desc:          "overlapping ports",
+			rule:          &compute.ForwardingRule{Name: "api", IPAddress: "1.2.3.4", IPProtocol: "TCP", Ports: []string{"80", "443"}},
+			expectedError: `already in use by ForwardingRule "other"`,
+		},
+		{
+			desc:          "all ports",
+			rule:          &compute.ForwardingRule{Name: "api", IPAddress: "1.2.3.4", IPProtocol: "TCP"},
+			expectedError: `already in use by ForwardingRule "other"`,
+		},
+		{
+			desc: "shared address on other ports",
+			rule: &compute.ForwardingRule{Name: "kops-controller", IPAddress: "1.2.3.4", IPProtocol: "TCP", Ports: []string{"3988"}},
+		},
+		{
+			desc: "other protocol",
+			rule: &compute.ForwardingRule{Name: "api", IPAddress: "1.2.3.4", IPProtocol: "UDP", PortRange: "443-443"},
+		},
+		{
+			desc: "other address",
+			rule: &compute.ForwardingRule{Name: "api", IPAddress: "1.2.3.5", IPProtocol: "TCP", PortRange: "443-443"},
+		},
+		{
+			desc: "recreating the same rule",
+			rule: &compute.ForwardingRule{Name: "other", IPAddress: "1.2.3.4", IPProtocol: "TCP", PortRange: "443-443"},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			err := checkForwardingRuleIPAvailable(ctx, cloud, testCase.rule, testCase.rule.IPAddress, false)
+			if testCase.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error containing %q, got nil", testCase.expectedError)
+			}
+			if !strings.Contains(err.Error(), testCase.expectedError) {
+				t.Errorf("expected error containing %q, got %v", testCase.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleMissingTargetPool(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// The rule references a pool which is not one of the tasks, so nothing creates it
+	rule := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		Lifecycle:  fi.LifecycleSync,
+		PortRange:  fi.PtrTo("443-443"),
+		IPProtocol: "TCP",
+		TargetPool: &TargetPool{Name: fi.PtrTo("api")},
+	}
+	allTasks := map[string]fi.CloudupTask{"forwardingRule/api": rule}
+
+	target := gce.NewGCEAPITarget(cloud)
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	err = (&ForwardingRule{}).RenderGCE(c, target, nil, rule, rule)
+	expectedError := `TargetPool "api" of ForwardingRule "api" does not exist in region us-test1`
+	if err == nil || err.Error() != expectedError {
+		t.Fatalf("expected error %q, got %v", expectedError, err)
+	}
+	if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api"); !gce.IsNotFound(err) {
+		t.Errorf("expected forwarding rule not to be created, got %v", err)
+	}
+
+	if _, err := cloud.Compute().TargetPools().Insert(project, region, &compute.TargetPool{Name: "api"}); err != nil {
+		t.Fatalf("error creating target pool: %v", err)
+	}
+	if err := (&ForwardingRule{}).RenderGCE(c, target, nil, rule, rule); err != nil {
+		t.Fatalf("unexpected error creating forwarding rule: %v", err)
+	}
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if lastComponent(r.Target) != "api" {
+		t.Errorf("unexpected Target %q on the forwarding rule", r.Target)
+	}
+}
+
+func TestForwardingRuleNoAutomateDNSZone(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		network := &Network{Name: fi.PtrTo("default"), Lifecycle: fi.LifecycleIgnore}
+		subnet := &Subnet{Name: fi.PtrTo("us-test1"), Lifecycle: fi.LifecycleIgnore, Network: network}
+		rule := &ForwardingRule{
+			Name:      fi.PtrTo("api-internal"),
+			Lifecycle: fi.LifecycleSync,
+
+			Ports:               []string{"443"},
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+			NoAutomateDNSZone:   fi.PtrTo(true),
+			Network:             network,
+			Subnetwork:          subnet,
+		}
+
+		return map[string]fi.CloudupTask{
+			"network/" + *network.Name: network,
+			"subnet/" + *subnet.Name:   subnet,
+			*rule.Name:                 rule,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkHasChanges(t, ctx, cloud, allTasks)
+	}
+
+	{
+		allTasks := buildTasks()
+		runTasks(t, ctx, cloud, allTasks)
+	}
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-internal")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if !r.NoAutomateDnsZone {
+		t.Errorf("expected NoAutomateDnsZone to be set on the forwarding rule")
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	external := &ForwardingRule{
+		Name:                fi.PtrTo("api"),
+		LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+		NoAutomateDNSZone:   fi.PtrTo(true),
+	}
+	if err := (&ForwardingRule{}).CheckChanges(nil, external, external); err == nil {
+		t.Errorf("expected error setting NoAutomateDNSZone on an EXTERNAL forwarding rule")
+	}
+}
+
+func TestForwardingRuleSchemeSubnetwork(t *testing.T) {
+	network := &Network{Name: fi.PtrTo("default")}
+	subnet := &Subnet{Name: fi.PtrTo("us-test1"), Network: network}
+
+	grid := []struct {
+		scheme        string
+		network       *Network
+		subnetwork    *Subnet
+		expectedError string
+	}{
+		{scheme: "INTERNAL", network: network, subnetwork: subnet},
+		{scheme: "INTERNAL", network: network, expectedError: "requires Network and Subnetwork"},
+		{scheme: "INTERNAL", subnetwork: subnet, expectedError: "requires Network and Subnetwork"},
+		{scheme: "INTERNAL", expectedError: "requires Network and Subnetwork"},
+		{scheme: "EXTERNAL"},
+		{scheme: "EXTERNAL", network: network},
+		{scheme: "EXTERNAL", network: network, subnetwork: subnet, expectedError: "Subnetwork cannot be set"},
+		{scheme: "EXTERNAL_MANAGED", subnetwork: subnet, expectedError: "Subnetwork cannot be set"},
+		{scheme: "INTERNAL_MANAGED", network: network, subnetwork: subnet},
+		{},
+	}
+	for _, g := range grid {
+		e := &ForwardingRule{
+			Name:       fi.PtrTo("api"),
+			IPProtocol: "TCP",
+			Ports:      []string{"443"},
+			Network:    g.network,
+			Subnetwork: g.subnetwork,
+		}
+		if g.scheme != "" {
+			e.LoadBalancingScheme = fi.PtrTo(g.scheme)
+		}
+		desc := fmt.Sprintf("scheme=%q network=%v subnetwork=%v", g.scheme, g.network != nil, g.subnetwork != nil)
+		err := e.CheckChanges(nil, e, e)
+		if g.expectedError == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", desc, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+			t.Errorf("%s: expected error containing %q, got %v", desc, g.expectedError, err)
+		}
+	}
+}
+
+func TestForwardingRulePscConnection(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "psc-endpoint",
+		IPProtocol:          "TCP",
+		PscConnectionId:     1234567890,
+		PscConnectionStatus: "ACCEPTED",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	e := &ForwardingRule{
+		Name:       fi.PtrTo("psc-endpoint"),
+		Lifecycle:  fi.LifecycleSync,
+		IPProtocol: "TCP",
+	}
+	allTasks := map[string]fi.CloudupTask{
+		*e.Name: e,
+	}
+
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	actual, err := e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error finding forwarding rule: %v", err)
+	}
+	if fi.ValueOf(actual.PscConnectionID) != "1234567890" {
+		t.Errorf("expected PscConnectionID 1234567890, got %q", fi.ValueOf(actual.PscConnectionID))
+	}
+	if fi.ValueOf(actual.PscConnectionStatus) != "ACCEPTED" {
+		t.Errorf("expected PscConnectionStatus ACCEPTED, got %q", fi.ValueOf(actual.PscConnectionStatus))
+	}
+
+	// The read-only fields must not show up as changes
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	e.PscConnectionStatus = fi.PtrTo("REJECTED")
+	if err := (&ForwardingRule{}).CheckChanges(actual, e, &ForwardingRule{}); err == nil {
+		t.Errorf("expected error setting read-only PscConnectionStatus")
+	}
+}
+
+func TestForwardingRulePscConnectedEndpoints(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "internal-service",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "INTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	// A service attachment as returned by the compute API, publishing the rule to two consumers
+	response := `{
+		"name": "internal-service",
+		"targetService": "https://www.googleapis.com/compute/v1/projects/testproject/regions/us-test1/forwardingRules/internal-service",
+		"connectedEndpoints": [
+			{
+				"endpoint": "https://www.googleapis.com/compute/v1/projects/consumer-a/regions/us-test1/forwardingRules/psc-endpoint",
+				"pscConnectionId": "1234567890",
+				"status": "ACCEPTED",
+				"consumerNetwork": "https://www.googleapis.com/compute/v1/projects/consumer-a/global/networks/default"
+			},
+			{
+				"endpoint": "https://www.googleapis.com/compute/v1/projects/consumer-b/regions/us-test1/forwardingRules/psc-endpoint",
+				"pscConnectionId": "1234567891",
+				"status": "PENDING",
+				"consumerNetwork": "https://www.googleapis.com/compute/v1/projects/consumer-b/global/networks/default"
+			}
+		]
+	}`
+	serviceAttachment := &compute.ServiceAttachment{}
+	if err := json.Unmarshal([]byte(response), serviceAttachment); err != nil {
+		t.Fatalf("error parsing service attachment: %v", err)
+	}
+	if _, err := cloud.Compute().ServiceAttachments().Insert(ctx, project, region, serviceAttachment); err != nil {
+		t.Fatalf("error creating service attachment: %v", err)
+	}
+	// A service attachment of another rule is ignored
+	if _, err := cloud.Compute().ServiceAttachments().Insert(ctx, project, region, &compute.ServiceAttachment{
+		Name:               "other",
+		TargetService:      "https://www.googleapis.com/compute/v1/projects/testproject/regions/us-test1/forwardingRules/other",
+		ConnectedEndpoints: []*compute.ServiceAttachmentConnectedEndpoint{{Endpoint: "other", Status: "ACCEPTED"}},
+	}); err != nil {
+		t.Fatalf("error creating service attachment: %v", err)
+	}
+
+	e := &ForwardingRule{
+		Name:                fi.PtrTo("internal-service"),
+		Lifecycle:           fi.LifecycleSync,
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+	}
+	allTasks := map[string]fi.CloudupTask{
+		*e.Name: e,
+	}
+
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	// The service attachments are only listed on request
+	actual, err := e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error finding forwarding rule: %v", err)
+	}
+	if actual.PscConnectedEndpoints() != nil {
+		t.Errorf("expected no connected endpoints without ReadPscConnectedEndpoints, got %+v", actual.PscConnectedEndpoints())
+	}
+
+	e.ReadPscConnectedEndpoints = fi.PtrTo(true)
+	actual, err = e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error finding forwarding rule: %v", err)
+	}
+	expected := []PscConnectedEndpoint{
+		{
+			Endpoint:        "https://www.googleapis.com/compute/v1/projects/consumer-a/regions/us-test1/forwardingRules/psc-endpoint",
+			PscConnectionID: "1234567890",
+			Status:          "ACCEPTED",
+			ConsumerNetwork: "https://www.googleapis.com/compute/v1/projects/consumer-a/global/networks/default",
+		},
+		{
+			Endpoint:        "https://www.googleapis.com/compute/v1/projects/consumer-b/regions/us-test1/forwardingRules/psc-endpoint",
+			PscConnectionID: "1234567891",
+			Status:          "PENDING",
+			ConsumerNetwork: "https://www.googleapis.com/compute/v1/projects/consumer-b/global/networks/default",
+		},
+	}
+	if !reflect.DeepEqual(actual.PscConnectedEndpoints(), expected) {
+		t.Errorf("expected connected endpoints %+v, got %+v", expected, actual.PscConnectedEndpoints())
+	}
+
+	// The connected endpoints must not show up as changes
+	checkNoChanges(t, ctx, cloud, allTasks)
+}
+
+func TestForwardingRuleAdoptionLifecycles(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	buildRule := func(lifecycle fi.Lifecycle, owner string) *ForwardingRule {
+		rule := &ForwardingRule{
+			Name:      fi.PtrTo("api"),
+			Lifecycle: lifecycle,
+
+			PortRange:           fi.PtrTo("443-443"),
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			Labels:              map[string]string{"owner": owner},
+		}
+		rule.PruneForwardingRulesWithName("api-old")
+		return rule
+	}
+
+	// Tasks with an error are retried, so fail fast
+	runOptions := fi.RunTasksOptions{
+		MaxTaskDuration:         200 * time.Millisecond,
+		WaitAfterAllTasksFailed: 10 * time.Millisecond,
+	}
+
+	grid := []struct {
+		lifecycle   fi.Lifecycle
+		matching    bool
+		expectError bool
+		expectOwner string
+		expectPrune bool
+	}{
+		{lifecycle: fi.LifecycleSync, matching: true, expectOwner: "old", expectPrune: true},
+		{lifecycle: fi.LifecycleSync, matching: false, expectOwner: "kops", expectPrune: true},
+		{lifecycle: fi.LifecycleWarnIfInsufficientAccess, matching: true, expectOwner: "old", expectPrune: true},
+		{lifecycle: fi.LifecycleWarnIfInsufficientAccess, matching: false, expectOwner: "kops", expectPrune: true},
+		{lifecycle: fi.LifecycleIgnore, matching: true, expectOwner: "old"},
+		{lifecycle: fi.LifecycleIgnore, matching: false, expectOwner: "old"},
+		{lifecycle: fi.LifecycleExistsAndValidates, matching: true, expectOwner: "old"},
+		{lifecycle: fi.LifecycleExistsAndValidates, matching: false, expectError: true, expectOwner: "old"},
+		{lifecycle: fi.LifecycleExistsAndWarnIfChanges, matching: true, expectOwner: "old"},
+		{lifecycle: fi.LifecycleExistsAndWarnIfChanges, matching: false, expectOwner: "old"},
+	}
+
+	for _, g := range grid {
+		desc := fmt.Sprintf("%s/matching=%v", g.lifecycle, g.matching)
+		t.Run(desc, func(t *testing.T) {
+			cloud := gcemock.InstallMockGCECloud(region, project)
+
+			// The rule created by the old process, and a rule it left behind
+			for _, name := range []string{"api", "api-old"} {
+				if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+					Name:                name,
+					PortRange:           "443-443",
+					IPProtocol:          "TCP",
+					LoadBalancingScheme: "EXTERNAL",
+					Labels:              map[string]string{"owner": "old"},
+				}); err != nil {
+					t.Fatalf("error creating forwarding rule %q: %v", name, err)
+				}
+			}
+
+			owner := "old"
+			if !g.matching {
+				owner = "kops"
+			}
+			rule := buildRule(g.lifecycle, owner)
+			allTasks := map[string]fi.CloudupTask{*rule.Name: rule}
+
+			target := gce.NewGCEAPITarget(cloud)
+			context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			err = context.RunTasks(runOptions)
+			if g.expectError && err == nil {
+				t.Errorf("expected error during Run")
+			}
+			if !g.expectError && err != nil {
+				t.Errorf("unexpected error during Run: %v", err)
+			}
+
+			r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+			if err != nil {
+				t.Fatalf("error getting forwarding rule: %v", err)
+			}
+			if got := r.Labels["owner"]; got != g.expectOwner {
+				t.Errorf("expected owner label %q, got %q", g.expectOwner, got)
+			}
+
+			_, err = cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-old")
+			pruned := gce.IsNotFound(err)
+			if err != nil && !pruned {
+				t.Fatalf("error getting forwarding rule: %v", err)
+			}
+			if pruned != g.expectPrune {
+				t.Errorf("expected pruned=%v, got %v", g.expectPrune, pruned)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleGlobalAddress(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().GlobalAddresses().Insert(project, &compute.Address{
+		Name:        "api-global",
+		Address:     "203.0.113.10",
+		AddressType: "EXTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating global address: %v", err)
+	}
+	// A regional address with the same IP must not be picked up for a global rule
+	if _, err := cloud.Compute().Addresses().Insert(project, region, &compute.Address{
+		Name:        "api-regional",
+		Address:     "203.0.113.10",
+		AddressType: "EXTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating address: %v", err)
+	}
+	if _, err := cloud.Compute().GlobalForwardingRules().Insert(ctx, project, &compute.ForwardingRule{
+		Name:       "api-global",
+		IPProtocol: "TCP",
+		PortRange:  "443-443",
+		IPAddress:  "203.0.113.10",
+	}); err != nil {
+		t.Fatalf("error creating global forwarding rule: %v", err)
+	}
+
+	address := &Address{
+		Name:      fi.PtrTo("api-global"),
+		Lifecycle: fi.LifecycleSync,
+		Global:    fi.PtrTo(true),
+	}
+	e := &ForwardingRule{
+		Name:       fi.PtrTo("api-global"),
+		Lifecycle:  fi.LifecycleSync,
+		IPProtocol: "TCP",
+		PortRange:  fi.PtrTo("443-443"),
+		Global:     fi.PtrTo(true),
+		IPAddress:  address,
+	}
+	allTasks := map[string]fi.CloudupTask{
+		"address/" + *address.Name: address,
+		*e.Name:                    e,
+	}
+
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	actual, err := e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error finding forwarding rule: %v", err)
+	}
+	if actual == nil {
+		t.Fatalf("expected to find global forwarding rule")
+	}
+	if actual.IPAddress == nil || fi.ValueOf(actual.IPAddress.Name) != "api-global" {
+		t.Errorf("expected IPAddress to resolve to global address %q, got %v", "api-global", actual.IPAddress)
+	}
+
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	// Creating a global rule resolves the referenced address against global addresses
+	created := &ForwardingRule{
+		Name:       fi.PtrTo("api-global-2"),
+		Lifecycle:  fi.LifecycleSync,
+		IPProtocol: "TCP",
+		PortRange:  fi.PtrTo("8443-8443"),
+		Global:     fi.PtrTo(true),
+		IPAddress:  address,
+	}
+	createTasks := map[string]fi.CloudupTask{
+		"address/" + *address.Name: address,
+		*created.Name:              created,
+	}
+	runTasks(t, ctx, cloud, createTasks)
+
+	fr, err := cloud.Compute().GlobalForwardingRules().Get(ctx, project, "api-global-2")
+	if err != nil {
+		t.Fatalf("error getting created global forwarding rule: %v", err)
+	}
+	if fr.IPAddress != "203.0.113.10" {
+		t.Errorf("expected IP address %q, got %q", "203.0.113.10", fr.IPAddress)
+	}
+	if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-global-2"); !gce.IsNotFound(err) {
+		t.Errorf("expected global rule not to be created as a regional rule, got error %v", err)
+	}
+
+	checkNoChanges(t, ctx, cloud, createTasks)
+}
+
+// flakyForwardingRuleCloud wraps a GCE cloud, failing the first Get calls on forwarding rules.
+type flakyForwardingRuleCloud struct {
+	gce.GCECloud
+	compute *flakyForwardingRuleCompute
+}
+
+func (c *flakyForwardingRuleCloud) Compute() gce.ComputeClient {
+	return c.compute
+}
+
+type flakyForwardingRuleCompute struct {
+	gce.ComputeClient
+	forwardingRules *flakyForwardingRuleClient
+}
+
+func (c *flakyForwardingRuleCompute) ForwardingRules() gce.ForwardingRuleClient {
+	return c.forwardingRules
+}
+
+type flakyForwardingRuleClient struct {
+	gce.ForwardingRuleClient
+	errs  []error
+	calls int
+}
+
+func (c *flakyForwardingRuleClient) Get(ctx context.Context, project, region, name string) (*compute.ForwardingRule, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return c.ForwardingRuleClient.Get(ctx, project, region, name)
+}
+
+func TestForwardingRuleFindRetriesTransientErrors(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	defer func(backoff wait.Backoff) {
+		gce.TransientErrorBackoff = backoff
+	}(gce.TransientErrorBackoff)
+	gce.TransientErrorBackoff.Duration = time.Millisecond
+
+	mockCloud := gcemock.InstallMockGCECloud(region, project)
+	if _, err := mockCloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:       "api",
+		IPProtocol: "TCP",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	tests := []struct {
+		desc          string
+		name          string
+		errs          []error
+		expectFound   bool
+		expectErr     bool
+		expectedCalls int
+	}{
+		{
+			desc:          "retryable error followed by success",
+			name:          "api",
+			errs:          []error{&googleapi.Error{Code: http.StatusServiceUnavailable}},
+			expectFound:   true,
+			expectedCalls: 2,
+		},
+		{
+			desc: "rate limited followed by success",
+			name: "api",
+			errs: []error{&googleapi.Error{
+				Code:   http.StatusForbidden,
+				Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+			}},
+			expectFound:   true,
+			expectedCalls: 2,
+		},
+		{
+			desc:          "not found is not retried",
+			name:          "missing",
+			expectedCalls: 1,
+		},
+		{
+			desc:          "non-retryable error is not retried",
+			name:          "api",
+			errs:          []error{&googleapi.Error{Code: http.StatusForbidden}},
+			expectErr:     true,
+			expectedCalls: 1,
+		},
+		{
+			desc: "retries are bounded",
+			name: "api",
+			errs: []error{
+				&googleapi.Error{Code: http.StatusInternalServerError},
+				&googleapi.Error{Code: http.StatusInternalServerError},
+				&googleapi.Error{Code: http.StatusInternalServerError},
+				&googleapi.Error{Code: http.StatusInternalServerError},
+				&googleapi.Error{Code: http.StatusInternalServerError},
+				&googleapi.Error{Code: http.StatusInternalServerError},
+			},
+			expectErr:     true,
+			expectedCalls: gce.TransientErrorBackoff.Steps,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			client := &flakyForwardingRuleClient{
+				ForwardingRuleClient: mockCloud.Compute().ForwardingRules(),
+				errs:                 testCase.errs,
+			}
+			cloud := &flakyForwardingRuleCloud{
+				GCECloud: mockCloud,
+				compute: &flakyForwardingRuleCompute{
+					ComputeClient:   mockCloud.Compute(),
+					forwardingRules: client,
+				},
+			}
+
+			e := &ForwardingRule{
+				Name:       fi.PtrTo(testCase.name),
+				Lifecycle:  fi.LifecycleSync,
+				IPProtocol: "TCP",
+			}
+			allTasks := map[string]fi.CloudupTask{
+				testCase.name: e,
+			}
+			c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+
+			actual, err := e.Find(c)
+			if testCase.expectErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !testCase.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if testCase.expectFound && actual == nil {
+				t.Errorf("expected to find forwarding rule")
+			}
+			if !testCase.expectFound && actual != nil {
+				t.Errorf("expected not to find forwarding rule, got %v", actual)
+			}
+			if client.calls != testCase.expectedCalls {
+				t.Errorf("expected %d calls to Get, got %d", testCase.expectedCalls, client.calls)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleIPCollection(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+	ipCollection := "projects/testproject/regions/us-test1/publicDelegatedPrefixes/byoip-sub"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	e := &ForwardingRule{
+		Name:         fi.PtrTo("api"),
+		Lifecycle:    fi.LifecycleSync,
+		IPProtocol:   "TCP",
+		PortRange:    fi.PtrTo("443-443"),
+		IPCollection: fi.PtrTo(ipCollection),
+	}
+	allTasks := map[string]fi.CloudupTask{
+		*e.Name: e,
+	}
+
+	checkHasChanges(t, ctx, cloud, allTasks)
+	runTasks(t, ctx, cloud, allTasks)
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if r.IpCollection != ipCollection {
+		t.Errorf("expected ipCollection %q, got %q", ipCollection, r.IpCollection)
+	}
+
+	if err := (&ForwardingRule{}).CheckChanges(nil, &ForwardingRule{
+		Name:          fi.PtrTo("api"),
+		IPCollection:  fi.PtrTo(ipCollection),
+		RuleIPAddress: fi.PtrTo("10.0.0.1"),
+	}, nil); err == nil {
+		t.Errorf("expected error combining IPCollection with RuleIPAddress")
+	}
+
+	target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+	if err := e.RenderTerraform(target, nil, e, e); err != nil {
+		t.Fatalf("unexpected error rendering terraform: %v", err)
+	}
+	resources, err := target.GetResourcesByType()
+	if err != nil {
+		t.Fatalf("unexpected error getting resources: %v", err)
+	}
+	tf := resources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRule)
+	if fi.ValueOf(tf.IPCollection) != ipCollection {
+		t.Errorf("expected terraform ip_collection %q, got %q", ipCollection, fi.ValueOf(tf.IPCollection))
+	}
+}
+
+func TestForwardingRuleIPSources(t *testing.T) {
+	address := &Address{Name: fi.PtrTo("api"), IPAddress: fi.PtrTo("10.0.0.1")}
+	ruleIPAddress := fi.PtrTo("10.0.0.2")
+	ipCollection := fi.PtrTo("projects/testproject/regions/us-test1/publicDelegatedPrefixes/byoip-sub")
+
+	grid := []struct {
+		ipAddress     *Address
+		ruleIPAddress *string
+		ipCollection  *string
+		expectedError string
+	}{
+		{},
+		{ipAddress: address},
+		{ruleIPAddress: ruleIPAddress},
+		{ipCollection: ipCollection},
+		{ipAddress: address, ruleIPAddress: ruleIPAddress, expectedError: `specified both IP Address and rule-managed IP address on ForwardingRule "api": Address "api", 10.0.0.2`},
+		{ipAddress: address, ipCollection: ipCollection, expectedError: "got IPAddress and IPCollection"},
+		{ruleIPAddress: ruleIPAddress, ipCollection: ipCollection, expectedError: "got RuleIPAddress and IPCollection"},
+		{ipAddress: address, ruleIPAddress: ruleIPAddress, ipCollection: ipCollection, expectedError: "specified both IP Address and rule-managed IP address"},
+	}
+	for _, g := range grid {
+		e := &ForwardingRule{
+			Name:          fi.PtrTo("api"),
+			IPProtocol:    "TCP",
+			Ports:         []string{"443"},
+			IPAddress:     g.ipAddress,
+			RuleIPAddress: g.ruleIPAddress,
+			IPCollection:  g.ipCollection,
+		}
+		desc := fmt.Sprintf("IPAddress=%v RuleIPAddress=%v IPCollection=%v", g.ipAddress != nil, g.ruleIPAddress != nil, g.ipCollection != nil)
+		// the sources are checked when planning, before RenderGCE is reached
+		err := e.CheckChanges(nil, e, e)
+		if g.expectedError == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", desc, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), g.expectedError) {
+			t.Errorf("%s: expected error containing %q, got %v", desc, g.expectedError, err)
+		}
+	}
+}
+
+func TestForwardingRuleLabelValidation(t *testing.T) {
+	tooManyLabels := map[string]string{}
+	for i := 0; i <= gce.MaxLabels; i++ {
+		tooManyLabels[fmt.Sprintf("label-%d", i)] = "value"
+	}
+
+	tests := []struct {
+		labels      map[string]string
+		expectError string
+	}{
+		{labels: map[string]string{"k8s-io-cluster-name": "my-cluster-example-com", "name": "api_1"}},
+		{labels: map[string]string{"empty-value": ""}},
+		{labels: map[string]string{"Name": "api"}, expectError: `label key "Name"`},
+		{labels: map[string]string{"1name": "api"}, expectError: `label key "1name"`},
+		{labels: map[string]string{"": "api"}, expectError: "label key must not be empty"},
+		{labels: map[string]string{strings.Repeat("k", 64): "api"}, expectError: "64 characters long"},
+		{labels: map[string]string{"name": "API"}, expectError: `value "API" of label "name"`},
+		{labels: map[string]string{"name": "api.example.com"}, expectError: `value "api.example.com" of label "name"`},
+		{labels: map[string]string{"name": strings.Repeat("v", 64)}, expectError: `value of label "name" is 64 characters long`},
+		{labels: tooManyLabels, expectError: "must have at most 64"},
+	}
+
+	for _, test := range tests {
+		err := (&ForwardingRule{}).CheckChanges(nil, &ForwardingRule{
+			Name:   fi.PtrTo("api"),
+			Labels: test.labels,
+		}, nil)
+		if test.expectError == "" {
+			if err != nil {
+				t.Errorf("unexpected error for labels %v: %v", test.labels, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectError) {
+			t.Errorf("expected error containing %q for labels %v, got %v", test.expectError, test.labels, err)
+		}
+	}
+
+	for input, expected := range map[string]string{
+		"api-us-test1a": "api-us-test1a",
+		"api-US.Test1A": "api-us-test1a",
+		"kops-controller-" + strings.Repeat("a", 60): "kops-controller-" + strings.Repeat("a", 40) + "-",
+	} {
+		actual := gce.NormalizeLabelValue(input)
+		if err := gce.ValidateLabel("name", actual); err != nil {
+			t.Errorf("normalized value of %q is not valid: %v", input, err)
+		}
+		if !strings.HasPrefix(actual, expected) {
+			t.Errorf("expected normalized value of %q to start with %q, got %q", input, expected, actual)
+		}
+	}
+}
+
+func TestForwardingRuleManagedLabelPrefix(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// A rule labelled by kops and by another tool
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "api",
+		PortRange:           "443-443",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "EXTERNAL",
+		Labels:              map[string]string{"kops-k8s-io-role": "old", "team": "network"},
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	rule := &ForwardingRule{
+		Name:                fi.PtrTo("api"),
+		Lifecycle:           fi.LifecycleSync,
+		PortRange:           fi.PtrTo("443-443"),
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+		Labels:              map[string]string{"kops-k8s-io-role": "api"},
+		ManagedLabelPrefix:  fi.PtrTo("kops-k8s-io-"),
+	}
+	allTasks := map[string]fi.CloudupTask{*rule.Name: rule}
+	checkHasChanges(t, ctx, cloud, allTasks)
+	runTasks(t, ctx, cloud, allTasks)
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	expected := map[string]string{"kops-k8s-io-role": "api", "team": "network"}
+	if !reflect.DeepEqual(r.Labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, r.Labels)
+	}
+
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	unprefixed := &ForwardingRule{
+		Name:               fi.PtrTo("api"),
+		Labels:             map[string]string{"team": "kops"},
+		ManagedLabelPrefix: fi.PtrTo("kops-k8s-io-"),
+	}
+	if err := (&ForwardingRule{}).CheckChanges(nil, unprefixed, unprefixed); err == nil || !strings.Contains(err.Error(), "managed prefix") {
+		t.Errorf("expected error setting a label without the managed prefix, got %v", err)
+	}
+}
+
+func TestForwardingRuleDependsOnAddress(t *testing.T) {
+	address := &Address{
+		Name: fi.PtrTo("api"),
+	}
+	targetPool := &TargetPool{
+		Name: fi.PtrTo("api"),
+	}
+	withAddress := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		TargetPool: targetPool,
+		IPAddress:  address,
+	}
+	withoutAddress := &ForwardingRule{
+		Name:          fi.PtrTo("api-static"),
+		TargetPool:    targetPool,
+		RuleIPAddress: fi.PtrTo("10.0.0.1"),
+	}
+
+	tasks := map[string]fi.CloudupTask{
+		"Address/api":               address,
+		"TargetPool/api":            targetPool,
+		"ForwardingRule/api":        withAddress,
+		"ForwardingRule/api-static": withoutAddress,
+	}
+	deps := fi.FindTaskDependencies(tasks)
+
+	if actual := deps["ForwardingRule/api"]; !slices.Contains(actual, "Address/api") {
+		t.Errorf("expected ForwardingRule to depend on its Address, got %v", actual)
+	}
+	if actual := deps["ForwardingRule/api-static"]; slices.Contains(actual, "Address/api") {
+		t.Errorf("unexpected dependency on Address for ForwardingRule without IPAddress: %v", actual)
+	}
+	if actual := deps["Address/api"]; len(actual) != 0 {
+		t.Errorf("unexpected dependencies for Address: %v", actual)
+	}
+}
+
+func TestDescribeConnectionTrackingPolicy(t *testing.T) {
+	tests := []struct {
+		policy   *compute.BackendServiceConnectionTrackingPolicy
+		expected string
+	}{
+		{
+			expected: "<default>",
+		},
+		{
+			policy:   &compute.BackendServiceConnectionTrackingPolicy{},
+			expected: "trackingMode=<default> connectionPersistenceOnUnhealthyBackends=<default> idleTimeoutSec=0 enableStrongAffinity=false",
+		},
+		{
+			policy: &compute.BackendServiceConnectionTrackingPolicy{
+				TrackingMode:                             "PER_SESSION",
+				ConnectionPersistenceOnUnhealthyBackends: "NEVER_PERSIST",
+				IdleTimeoutSec:                           600,
+				EnableStrongAffinity:                     true,
+			},
+			expected: "trackingMode=PER_SESSION connectionPersistenceOnUnhealthyBackends=NEVER_PERSIST idleTimeoutSec=600 enableStrongAffinity=true",
+		},
+	}
+
+	for _, testCase := range tests {
+		if actual := describeConnectionTrackingPolicy(testCase.policy); actual != testCase.expected {
+			t.Errorf("expected %q, got %q", testCase.expected, actual)
+		}
+	}
+}
+
+func TestForwardingRuleFindTargetTypes(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	tests := []struct {
+		desc                   string
+		target                 string
+		expectedTargetPool     string
+		expectedBackendService string
+		// expectedTargetType is set for targets kops does not model
+		expectedTargetType string
+	}{
+		{
+			desc:               "target pool",
+			target:             "https://www.googleapis.com/compute/v1/projects/testproject/regions/us-test1/targetPools/api",
+			expectedTargetPool: "api",
+		},
+		{
+			desc:               "partial target pool URL",
+			target:             "regions/us-test1/targetPools/api",
+			expectedTargetPool: "api",
+		},
+		{
+			desc:               "bare target pool name",
+			target:             "api",
+			expectedTargetPool: "api",
+		},
+		{
+			desc:                   "backend service",
+			target:                 "https://www.googleapis.com/compute/v1/projects/testproject/regions/us-test1/backendServices/api",
+			expectedBackendService: "api",
+		},
+		{
+			desc:               "target TCP proxy",
+			target:             "https://www.googleapis.com/compute/v1/projects/testproject/global/targetTcpProxies/api",
+			expectedTargetType: "targetTcpProxies",
+		},
+		{
+			desc:               "backend bucket",
+			target:             "https://www.googleapis.com/compute/v1/projects/testproject/global/backendBuckets/static",
+			expectedTargetType: "backendBuckets",
+		},
+		{
+			desc:               "URL map",
+			target:             "https://www.googleapis.com/compute/v1/projects/testproject/global/urlMaps/web",
+			expectedTargetType: "urlMaps",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			cloud := gcemock.InstallMockGCECloud(region, project)
+
+			if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+				Name:       "api",
+				IPProtocol: "TCP",
+				Target:     testCase.target,
+			}); err != nil {
+				t.Fatalf("error creating forwarding rule: %v", err)
+			}
+
+			e := &ForwardingRule{
+				Name:       fi.PtrTo("api"),
+				Lifecycle:  fi.LifecycleSync,
+				IPProtocol: "TCP",
+			}
+			allTasks := map[string]fi.CloudupTask{
+				*e.Name: e,
+			}
+			c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+			if err != nil {
+				t.Fatalf("error building context: %v", err)
+			}
+			actual, err := e.Find(c)
+			if err != nil {
+				t.Fatalf("unexpected error finding forwarding rule: %v", err)
+			}
+
+			var targetPool, backendService string
+			if actual.TargetPool != nil {
+				targetPool = fi.ValueOf(actual.TargetPool.Name)
+			}
+			if actual.BackendService != nil {
+				backendService = fi.ValueOf(actual.BackendService.Name)
+			}
+			if targetPool != testCase.expectedTargetPool {
+				t.Errorf("expected TargetPool %q, got %q", testCase.expectedTargetPool, targetPool)
+			}
+			if backendService != testCase.expectedBackendService {
+				t.Errorf("expected BackendService %q, got %q", testCase.expectedBackendService, backendService)
+			}
+
+			if testCase.expectedTargetType == "" {
+				if actual.Target != nil {
+					t.Errorf("expected no unsupported Target, got %q", *actual.Target)
+				}
+				return
+			}
+			if fi.ValueOf(actual.Target) != testCase.target {
+				t.Errorf("expected Target %q, got %q", testCase.target, fi.ValueOf(actual.Target))
+			}
+
+			// Leaving the target alone is fine, but kops cannot point the rule at a target pool
+			changes := &ForwardingRule{}
+			fi.BuildChanges(actual, e, changes)
+			if err := e.CheckChanges(actual, e, changes); err != nil {
+				t.Errorf("unexpected error leaving the target alone: %v", err)
+			}
+			e.TargetPool = &TargetPool{Name: fi.PtrTo("api")}
+			changes = &ForwardingRule{}
+			fi.BuildChanges(actual, e, changes)
+			err = e.CheckChanges(actual, e, changes)
+			if err == nil || !strings.Contains(err.Error(), "of type "+testCase.expectedTargetType+",") {
+				t.Errorf("expected error naming target type %s, got %v", testCase.expectedTargetType, err)
+			}
+		})
+	}
+}
+
+func TestForwardingRuleNormalizeServiceNames(t *testing.T) {
+	grid := []struct {
+		PortRange         string
+		Ports             []string
+		ExpectedPortRange string
+		ExpectedPorts     []string
+		ExpectedError     string
+	}{
+		{PortRange: "https", ExpectedPortRange: "443-443"},
+		{PortRange: "443", ExpectedPortRange: "443-443"},
+		{PortRange: "443-443", ExpectedPortRange: "443-443"},
+		{PortRange: "http-https", ExpectedPortRange: "80-443"},
+		{PortRange: "HTTP-alt", ExpectedPortRange: "8080-8080"},
+		{Ports: []string{"ssh", "443", "dns"}, ExpectedPorts: []string{"22", "443", "53"}},
+		{PortRange: "gopher", ExpectedError: `unknown service name "gopher"`},
+		{Ports: []string{"80", "https-nope"}, ExpectedError: `unknown service name "nope"`},
+	}
+	for _, g := range grid {
+		e := &ForwardingRule{Name: fi.PtrTo("api"), Ports: g.Ports}
+		if g.PortRange != "" {
+			e.PortRange = fi.PtrTo(g.PortRange)
+		}
+		err := e.Normalize(nil)
+		if g.ExpectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), g.ExpectedError) {
+				t.Errorf("%q %v: expected error containing %q, got %v", g.PortRange, g.Ports, g.ExpectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q %v: unexpected error: %v", g.PortRange, g.Ports, err)
+			continue
+		}
+		if fi.ValueOf(e.PortRange) != g.ExpectedPortRange {
+			t.Errorf("%q: expected PortRange %q, got %q", g.PortRange, g.ExpectedPortRange, fi.ValueOf(e.PortRange))
+		}
+		if !slices.Equal(e.Ports, g.ExpectedPorts) {
+			t.Errorf("%v: expected Ports %v, got %v", g.Ports, g.ExpectedPorts, e.Ports)
+		}
+	}
+}
+
+func TestForwardingRuleIPProtocolCase(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:       "api",
+		PortRange:  "443-443",
+		IPProtocol: "TCP",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:       "dns",
+		PortRange:  "53-53",
+		IPProtocol: "udp",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	api := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		Lifecycle:  fi.LifecycleSync,
+		PortRange:  fi.PtrTo("443"),
+		IPProtocol: "tcp",
+	}
+	dns := &ForwardingRule{
+		Name:       fi.PtrTo("dns"),
+		Lifecycle:  fi.LifecycleSync,
+		PortRange:  fi.PtrTo("53"),
+		IPProtocol: "UDP",
+	}
+	allTasks := map[string]fi.CloudupTask{*api.Name: api, *dns.Name: dns}
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	for _, e := range []*ForwardingRule{api, dns} {
+		if err := e.Normalize(c); err != nil {
+			t.Fatalf("unexpected error from Normalize: %v", err)
+		}
+		a, err := e.Find(c)
+		if err != nil {
+			t.Fatalf("unexpected error from Find: %v", err)
+		}
+		if a == nil {
+			t.Fatalf("expected to find forwarding rule %q", *e.Name)
+		}
+		if a.IPProtocol != e.IPProtocol {
+			t.Errorf("expected IPProtocol %q for %q, got %q", e.IPProtocol, *e.Name, a.IPProtocol)
+		}
+		changes := &ForwardingRule{}
+		if fi.BuildChanges(a, e, changes) {
+			t.Errorf("expected no changes for %q, got %v", *e.Name, fi.DebugAsJsonString(changes))
+		}
+	}
+}
+
+func TestForwardingRuleIPVersion(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	// Tasks with an error are retried, so fail fast
+	runOptions := fi.RunTasksOptions{
+		MaxTaskDuration:         200 * time.Millisecond,
+		WaitAfterAllTasksFailed: 10 * time.Millisecond,
+	}
+
+	run := func(cloud gce.GCECloud, rule *ForwardingRule) error {
+		allTasks := map[string]fi.CloudupTask{*rule.Name: rule}
+		c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		return c.RunTasks(runOptions)
+	}
+
+	insert := func(cloud gce.GCECloud, name, ipVersion string) {
+		if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+			Name:                name,
+			PortRange:           "443-443",
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: "EXTERNAL",
+			IpVersion:           ipVersion,
+		}); err != nil {
+			t.Fatalf("error creating forwarding rule %q: %v", name, err)
+		}
+	}
+
+	newRule := func(name string) *ForwardingRule {
+		return &ForwardingRule{
+			Name:                fi.PtrTo(name),
+			Lifecycle:           fi.LifecycleSync,
+			PortRange:           fi.PtrTo("443-443"),
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			IPVersion:           fi.PtrTo("IPV4"),
+		}
+	}
+
+	t.Run("does not adopt a rule of the other version", func(t *testing.T) {
+		cloud := gcemock.InstallMockGCECloud(region, project)
+		insert(cloud, "api", "IPV6")
+
+		err := run(cloud, newRule("api"))
+		if err == nil {
+			t.Fatalf("expected error adopting an IPV6 rule for an IPV4 task")
+		}
+
+		r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+		if err != nil {
+			t.Fatalf("error getting forwarding rule: %v", err)
+		}
+		if r.IpVersion != "IPV6" {
+			t.Errorf("expected IPV6 rule to be left alone, got %q", r.IpVersion)
+		}
+	})
+
+	t.Run("only prunes rules of its own version", func(t *testing.T) {
+		cloud := gcemock.InstallMockGCECloud(region, project)
+		// GCE may omit the version of IPV4 rules
+		insert(cloud, "api-old", "")
+		insert(cloud, "api-ipv6", "IPV6")
+
+		rule := newRule("api-ipv4")
+		rule.PruneForwardingRulesWithName("api-old")
+		rule.PruneForwardingRulesWithName("api-ipv6")
+		if err := run(cloud, rule); err != nil {
+			t.Fatalf("unexpected error during Run: %v", err)
+		}
+
+		r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-ipv4")
+		if err != nil {
+			t.Fatalf("error getting forwarding rule: %v", err)
+		}
+		if r.IpVersion != "IPV4" {
+			t.Errorf("expected IPV4 rule to be created, got %q", r.IpVersion)
+		}
+		if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-old"); !gce.IsNotFound(err) {
+			t.Errorf("expected api-old to be pruned, got %v", err)
+		}
+		if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-ipv6"); err != nil {
+			t.Errorf("expected api-ipv6 to be kept, got %v", err)
+		}
+	})
+}
+
+func TestForwardingRuleIPv6OnlyAddress(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// GCE may omit the version of IPV4 addresses
+	if _, err := cloud.Compute().Addresses().Insert(project, region, &compute.Address{
+		Name:        "api-ipv4",
+		Address:     "203.0.113.10",
+		AddressType: "EXTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating address: %v", err)
+	}
+	if _, err := cloud.Compute().Addresses().Insert(project, region, &compute.Address{
+		Name:        "api-ipv6",
+		Address:     "2600:1900:4000::a",
+		AddressType: "EXTERNAL",
+		IpVersion:   "IPV6",
+	}); err != nil {
+		t.Fatalf("error creating address: %v", err)
+	}
+
+	// An IPv6 rule must not resolve its IP to an IPv4 address
+	if _, err := findAddressByIP(cloud, "203.0.113.10", "", ipVersionIPv6); err == nil || !strings.Contains(err.Error(), "no IPV6 Address found") {
+		t.Errorf("expected error finding an IPV6 address with an IPv4 IP, got %v", err)
+	}
+	if address, err := findAddressByIP(cloud, "2600:1900:4000::a", "", ipVersionIPv6); err != nil || address == nil || fi.ValueOf(address.Name) != "api-ipv6" {
+		t.Errorf("expected to find IPV6 address %q, got %v (error %v)", "api-ipv6", address, err)
+	}
+
+	newTasks := func(address *Address) map[string]fi.CloudupTask {
+		rule := &ForwardingRule{
+			Name:                fi.PtrTo("api"),
+			Lifecycle:           fi.LifecycleSync,
+			PortRange:           fi.PtrTo("443-443"),
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			IPVersion:           fi.PtrTo("IPV6"),
+			IPAddress:           address,
+		}
+		return map[string]fi.CloudupTask{
+			"address/" + *address.Name: address,
+			*rule.Name:                 rule,
+		}
+	}
+
+	t.Run("IPv4 address", func(t *testing.T) {
+		allTasks := newTasks(&Address{Name: fi.PtrTo("api-ipv4"), Lifecycle: fi.LifecycleSync})
+
+		c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		// Tasks with an error are retried, so fail fast
+		err = c.RunTasks(fi.RunTasksOptions{MaxTaskDuration: 200 * time.Millisecond, WaitAfterAllTasksFailed: 10 * time.Millisecond})
+		if err == nil {
+			t.Fatalf("expected error using an IPV4 address for an IPV6 rule")
+		}
+		if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api"); !gce.IsNotFound(err) {
+			t.Errorf("expected the rule not to be created, got error %v", err)
+		}
+	})
+
+	t.Run("IPv6 address", func(t *testing.T) {
+		allTasks := newTasks(&Address{Name: fi.PtrTo("api-ipv6"), Lifecycle: fi.LifecycleSync})
+		runTasks(t, ctx, cloud, allTasks)
+
+		fr, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+		if err != nil {
+			t.Fatalf("error getting forwarding rule: %v", err)
+		}
+		if fr.IPAddress != "2600:1900:4000::a" {
+			t.Errorf("expected the rule to use the IPV6 address, got %q", fr.IPAddress)
+		}
+		checkNoChanges(t, ctx, cloud, allTasks)
+	})
+}
+
+func TestForwardingRuleFindClearsServerDefaults(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// A rule created outside of kops, with the fields that GCE fills in
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "api",
+		PortRange:           "443-443",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "EXTERNAL",
+		NetworkTier:         "PREMIUM",
+		Labels:              map[string]string{"goog-managed": "true"},
+		LabelFingerprint:    "fingerprint",
+		CreationTimestamp:   "2024-01-01T00:00:00.000-07:00",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	e := &ForwardingRule{
+		Name:                fi.PtrTo("api"),
+		Lifecycle:           fi.LifecycleSync,
+		PortRange:           fi.PtrTo("443-443"),
+		LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+	}
+	allTasks := map[string]fi.CloudupTask{*e.Name: e}
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	a, err := e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error from Find: %v", err)
+	}
+	if a == nil {
+		t.Fatalf("expected to find forwarding rule")
+	}
+
+	changes := &ForwardingRule{}
+	if fi.BuildChanges(a, e, changes) {
+		t.Errorf("expected no changes adopting the rule, got %v", fi.DebugAsJsonString(changes))
+	}
+
+	// Fields that are specified are still compared
+	e.IPProtocol = "UDP"
+	if !fi.BuildChanges(a, e, &ForwardingRule{}) {
+		t.Errorf("expected a change of IPProtocol")
+	}
+}
+
+func TestForwardingRuleFindResourceTracking(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:              "api",
+		PortRange:         "443-443",
+		IPProtocol:        "TCP",
+		Id:                1234567890,
+		CreationTimestamp: "2024-03-01T10:20:30.000-08:00",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	e := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		Lifecycle:  fi.LifecycleSync,
+		PortRange:  fi.PtrTo("443-443"),
+		IPProtocol: "TCP",
+	}
+	allTasks := map[string]fi.CloudupTask{*e.Name: e}
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+
+	a, err := e.Find(c)
+	if err != nil {
+		t.Fatalf("unexpected error from Find: %v", err)
+	}
+	if a == nil {
+		t.Fatalf("expected to find forwarding rule")
+	}
+
+	if a.ResourceID() != 1234567890 {
+		t.Errorf("expected resource ID 1234567890, got %d", a.ResourceID())
+	}
+	expectedCreatedAt := time.Date(2024, 3, 1, 18, 20, 30, 0, time.UTC)
+	if !a.CreatedAt().Equal(expectedCreatedAt) {
+		t.Errorf("expected creation time %v, got %v", expectedCreatedAt, a.CreatedAt())
+	}
+
+	// The expected rule doesn't know them, which must not be a change
+	if e.ResourceID() != 0 || !e.CreatedAt().IsZero() {
+		t.Errorf("expected no resource tracking on the expected rule, got %d and %v", e.ResourceID(), e.CreatedAt())
+	}
+	if fi.BuildChanges(a, e, &ForwardingRule{}) {
+		t.Errorf("expected no changes from resource tracking fields")
+	}
+}
+
+func TestForwardingRuleAllPorts(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func() map[string]fi.CloudupTask {
+		network := &Network{Name: fi.PtrTo("default"), Lifecycle: fi.LifecycleIgnore}
+		subnet := &Subnet{Name: fi.PtrTo("us-test1"), Lifecycle: fi.LifecycleIgnore, Network: network}
+		backendService := &BackendService{
+			Name:                fi.PtrTo("api-passthrough"),
+			Lifecycle:           fi.LifecycleSync,
+			Protocol:            fi.PtrTo("TCP"),
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+		}
+		rule := &ForwardingRule{
+			Name:      fi.PtrTo("api-passthrough"),
+			Lifecycle: fi.LifecycleSync,
+
+			AllPorts:            fi.PtrTo(true),
+			BackendService:      backendService,
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+			Network:             network,
+			Subnetwork:          subnet,
+		}
+
+		return map[string]fi.CloudupTask{
+			"network/" + *network.Name:               network,
+			"subnet/" + *subnet.Name:                 subnet,
+			"backendService/" + *backendService.Name: backendService,
+			"forwardingRule/" + *rule.Name:           rule,
+		}
+	}
+
+	{
+		allTasks := buildTasks()
+		checkHasChanges(t, ctx, cloud, allTasks)
+	}
+
+	{
+		allTasks := buildTasks()
+		runTasks(t, ctx, cloud, allTasks)
+	}
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-passthrough")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if !r.AllPorts {
+		t.Errorf("expected AllPorts to be set on the forwarding rule")
+	}
+	if lastComponent(r.BackendService) != "api-passthrough" {
+		t.Errorf("unexpected BackendService %q on the forwarding rule", r.BackendService)
+	}
+
+	{
+		allTasks := buildTasks()
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	grid := []struct {
+		name string
+		rule *ForwardingRule
+	}{
+		{
+			name: "with port range",
+			rule: &ForwardingRule{
+				Name:                fi.PtrTo("api"),
+				AllPorts:            fi.PtrTo(true),
+				PortRange:           fi.PtrTo("443-443"),
+				BackendService:      &BackendService{Name: fi.PtrTo("api")},
+				LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+				Network:             &Network{Name: fi.PtrTo("default")},
+				Subnetwork:          &Subnet{Name: fi.PtrTo("us-test1")},
+			},
+		},
+		{
+			name: "without backend service",
+			rule: &ForwardingRule{
+				Name:                fi.PtrTo("api"),
+				AllPorts:            fi.PtrTo(true),
+				TargetPool:          &TargetPool{Name: fi.PtrTo("api")},
+				LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+				Network:             &Network{Name: fi.PtrTo("default")},
+				Subnetwork:          &Subnet{Name: fi.PtrTo("us-test1")},
+			},
+		},
+		{
+			name: "external scheme",
+			rule: &ForwardingRule{
+				Name:                fi.PtrTo("api"),
+				AllPorts:            fi.PtrTo(true),
+				BackendService:      &BackendService{Name: fi.PtrTo("api")},
+				LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			},
+		},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			if err := (&ForwardingRule{}).CheckChanges(nil, g.rule, g.rule); err == nil {
+				t.Errorf("expected error from CheckChanges")
+			}
+		})
+	}
+}
+
+func TestForwardingRuleAllowGlobalAccess(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(allowGlobalAccess bool) map[string]fi.CloudupTask {
+		network := &Network{Name: fi.PtrTo("default"), Lifecycle: fi.LifecycleIgnore}
+		subnet := &Subnet{Name: fi.PtrTo("us-test1"), Lifecycle: fi.LifecycleIgnore, Network: network}
+		backendService := &BackendService{
+			Name:                fi.PtrTo("api-internal"),
+			Lifecycle:           fi.LifecycleSync,
+			Protocol:            fi.PtrTo("TCP"),
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+		}
+		rule := &ForwardingRule{
+			Name:      fi.PtrTo("api-internal"),
+			Lifecycle: fi.LifecycleSync,
+
+			Ports:               []string{"443"},
+			BackendService:      backendService,
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+			AllowGlobalAccess:   fi.PtrTo(allowGlobalAccess),
+			Network:             network,
+			Subnetwork:          subnet,
+		}
+
+		return map[string]fi.CloudupTask{
+			"network/" + *network.Name:               network,
+			"subnet/" + *subnet.Name:                 subnet,
+			"backendService/" + *backendService.Name: backendService,
+			"forwardingRule/" + *rule.Name:           rule,
+		}
+	}
+
+	{
+		allTasks := buildTasks(true)
+		runTasks(t, ctx, cloud, allTasks)
+	}
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-internal")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if !r.AllowGlobalAccess {
+		t.Errorf("expected AllowGlobalAccess to be set on the forwarding rule")
+	}
+
+	{
+		allTasks := buildTasks(true)
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	// Global access is updated in place, without ForceRecreate
+	{
+		allTasks := buildTasks(false)
+		checkHasChanges(t, ctx, cloud, allTasks)
+		runTasks(t, ctx, cloud, allTasks)
+	}
+
+	r, err = cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-internal")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if r.AllowGlobalAccess {
+		t.Errorf("expected AllowGlobalAccess to be cleared on the forwarding rule")
+	}
+
+	{
+		allTasks := buildTasks(false)
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	t.Run("external scheme", func(t *testing.T) {
+		rule := &ForwardingRule{
+			Name:                fi.PtrTo("api"),
+			AllowGlobalAccess:   fi.PtrTo(true),
+			TargetPool:          &TargetPool{Name: fi.PtrTo("api")},
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+		}
+		if err := (&ForwardingRule{}).CheckChanges(nil, rule, rule); err == nil {
+			t.Errorf("expected error from CheckChanges")
+		}
+	})
+
+	t.Run("terraform", func(t *testing.T) {
+		target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+
+		e := buildTasks(true)["forwardingRule/api-internal"].(*ForwardingRule)
+		if err := e.RenderTerraform(target, nil, e, e); err != nil {
+			t.Fatalf("unexpected error rendering terraform: %v", err)
+		}
+
+		resources, err := target.GetResourcesByType()
+		if err != nil {
+			t.Fatalf("unexpected error getting resources: %v", err)
+		}
+		tf, ok := resources["google_compute_forwarding_rule"]["api-internal"].(*terraformForwardingRule)
+		if !ok {
+			t.Fatalf("expected google_compute_forwarding_rule.api-internal, got %v", resources)
+		}
+		if !fi.ValueOf(tf.AllowGlobalAccess) {
+			t.Errorf("expected allow_global_access to be set")
+		}
+	})
+}
+
+func TestForwardingRuleDiff(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "api",
+		IPAddress:           "203.0.113.10",
+		PortRange:           "443-443",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "EXTERNAL",
+		Labels:              map[string]string{"cluster": "example"},
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	newRule := func(name, portRange string) *ForwardingRule {
+		return &ForwardingRule{
+			Name:                fi.PtrTo(name),
+			Lifecycle:           fi.LifecycleSync,
+			PortRange:           fi.PtrTo(portRange),
+			IPProtocol:          "tcp",
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			Labels:              map[string]string{"cluster": "example"},
+		}
+	}
+	diff := func(rule *ForwardingRule) *ForwardingRuleDiff {
+		c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, gce.NewGCEAPITarget(cloud), nil, cloud, nil, nil, nil, map[string]fi.CloudupTask{*rule.Name: rule})
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		d, err := rule.Diff(c)
+		if err != nil {
+			t.Fatalf("unexpected error computing diff: %v", err)
+		}
+		return d
+	}
+
+	t.Run("changed port range", func(t *testing.T) {
+		d := diff(newRule("api", "8443"))
+
+		expected := []ForwardingRuleFieldChange{{Field: "PortRange", Actual: "443-443", Expected: "8443-8443"}}
+		if !reflect.DeepEqual(d.Changes, expected) {
+			t.Errorf("expected changes %v, got %v", expected, d.Changes)
+		}
+		summary := d.String()
+		if !strings.Contains(summary, "PortRange: changed 443-443 -> 8443-8443") {
+			t.Errorf("expected the summary to list the changed PortRange, got:\n%s", summary)
+		}
+		for _, unchanged := range []string{"IPProtocol", "LoadBalancingScheme", "Labels", "Name"} {
+			if strings.Contains(summary, unchanged) {
+				t.Errorf("expected the summary to omit unchanged field %s, got:\n%s", unchanged, summary)
+			}
+		}
+
+		r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+		if err != nil {
+			t.Fatalf("error getting forwarding rule: %v", err)
+		}
+		if r.PortRange != "443-443" {
+			t.Errorf("expected the forwarding rule not to be changed, got port range %q", r.PortRange)
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		d := diff(newRule("api", "443"))
+		if d.HasChanges() {
+			t.Errorf("expected no changes, got:\n%s", d)
+		}
+	})
+
+	t.Run("new rule", func(t *testing.T) {
+		d := diff(newRule("api-2", "443"))
+		if !d.Create || len(d.Changes) != 0 {
+			t.Errorf("expected the rule to be created, got:\n%s", d)
+		}
+		if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api-2"); !gce.IsNotFound(err) {
+			t.Errorf("expected the forwarding rule not to be created, got error %v", err)
+		}
+	})
+}
+
+func TestForwardingRuleRecreatePreservesIP(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	for _, release := range []bool{false, true} {
+		t.Run(fmt.Sprintf("release=%v", release), func(t *testing.T) {
+			cloud := gcemock.InstallMockGCECloud(region, project)
+
+			// A rule with an ephemeral IP assigned by GCE
+			if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+				Name:                "api",
+				IPAddress:           "203.0.113.10",
+				PortRange:           "443-443",
+				IPProtocol:          "TCP",
+				LoadBalancingScheme: "EXTERNAL",
+			}); err != nil {
+				t.Fatalf("error creating forwarding rule: %v", err)
+			}
+
+			rule := &ForwardingRule{
+				Name:                fi.PtrTo("api"),
+				Lifecycle:           fi.LifecycleSync,
+				PortRange:           fi.PtrTo("8443-8443"),
+				IPProtocol:          "TCP",
+				LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+				ForceRecreate:       fi.PtrTo(true),
+				PreserveIP:          fi.PtrTo(true),
+				ReleasePreservedIP:  fi.PtrTo(release),
+			}
+			runTasks(t, ctx, cloud, map[string]fi.CloudupTask{*rule.Name: rule})
+
+			r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+			if err != nil {
+				t.Fatalf("error getting forwarding rule: %v", err)
+			}
+			if r.PortRange != "8443-8443" {
+				t.Errorf("expected the forwarding rule to be recreated with the new port range, got %q", r.PortRange)
+			}
+			if r.IPAddress != "203.0.113.10" {
+				t.Errorf("expected the recreated forwarding rule to keep IP 203.0.113.10, got %q", r.IPAddress)
+			}
+
+			addr, err := cloud.Compute().Addresses().Get(project, region, "api-ip")
+			if release {
+				if !gce.IsNotFound(err) {
+					t.Errorf("expected the reserved address to be released, got %v, %v", addr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the IP to be reserved as an address: %v", err)
+			}
+			if addr.Address != "203.0.113.10" {
+				t.Errorf("expected the reserved address to have IP 203.0.113.10, got %q", addr.Address)
+			}
+		})
+	}
+
+	preserveOnly := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		PreserveIP: fi.PtrTo(true),
+	}
+	if err := (&ForwardingRule{}).CheckChanges(nil, preserveOnly, preserveOnly); err == nil {
+		t.Errorf("expected error setting PreserveIP without ForceRecreate")
+	}
+}
+
+func TestForwardingRuleClearPortRange(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "api",
+		PortRange:           "443-443",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "EXTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	rule := &ForwardingRule{
+		Name:                fi.PtrTo("api"),
+		Lifecycle:           fi.LifecycleSync,
+		PortRange:           fi.PtrTo(""),
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+		ForceRecreate:       fi.PtrTo(true),
+	}
+	allTasks := map[string]fi.CloudupTask{*rule.Name: rule}
+	checkHasChanges(t, ctx, cloud, allTasks)
+	runTasks(t, ctx, cloud, allTasks)
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if r.PortRange != "" {
+		t.Errorf("expected the port range to be cleared, got %q", r.PortRange)
+	}
+	// The mock keeps the request, so check that it sends the cleared field rather than omitting it
+	body, err := r.MarshalJSON()
+	if err != nil {
+		t.Fatalf("error marshalling forwarding rule: %v", err)
+	}
+	if !strings.Contains(string(body), `"portRange":""`) {
+		t.Errorf("expected the cleared port range to be sent, got %s", body)
+	}
+
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	// An unset port range is not sent
+	if fields := explicitZeroFields(&ForwardingRule{Ports: []string{}}); !slices.Equal(fields, []string{"Ports"}) {
+		t.Errorf("expected only Ports to be force-sent, got %v", fields)
+	}
+}
+
+func TestForwardingRuleMigrateTargetPoolToBackendService(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	// A legacy rule targeting a pool, with an ephemeral IP assigned by GCE
+	if _, err := cloud.Compute().TargetPools().Insert(project, region, &compute.TargetPool{Name: "api"}); err != nil {
+		t.Fatalf("error creating target pool: %v", err)
+	}
+	if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+		Name:                "api",
+		IPAddress:           "203.0.113.10",
+		PortRange:           "443-443",
+		IPProtocol:          "TCP",
+		LoadBalancingScheme: "EXTERNAL",
+		Target:              "https://www.googleapis.com/compute/v1/projects/testproject/regions/us-test1/targetPools/api",
+	}); err != nil {
+		t.Fatalf("error creating forwarding rule: %v", err)
+	}
+
+	// We define a function so we can rebuild the tasks, because we modify in-place when running
+	buildTasks := func(forceRecreate bool) map[string]fi.CloudupTask {
+		backendService := &BackendService{
+			Name:                fi.PtrTo("api"),
+			Lifecycle:           fi.LifecycleSync,
+			Protocol:            fi.PtrTo("TCP"),
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+		}
+		rule := &ForwardingRule{
+			Name:                fi.PtrTo("api"),
+			Lifecycle:           fi.LifecycleSync,
+			PortRange:           fi.PtrTo("443-443"),
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo("EXTERNAL"),
+			BackendService:      backendService,
+		}
+		if forceRecreate {
+			rule.ForceRecreate = fi.PtrTo(true)
+			rule.PreserveIP = fi.PtrTo(true)
+		}
+		return map[string]fi.CloudupTask{
+			"backendService/" + *backendService.Name: backendService,
+			"forwardingRule/" + *rule.Name:           rule,
+		}
+	}
+
+	{
+		allTasks := buildTasks(false)
+		rule := allTasks["forwardingRule/api"].(*ForwardingRule)
+		a := &ForwardingRule{Name: rule.Name, TargetPool: &TargetPool{Name: fi.PtrTo("api")}}
+		err := (&ForwardingRule{}).CheckChanges(a, rule, &ForwardingRule{BackendService: rule.BackendService})
+		if err == nil || !strings.Contains(err.Error(), "requires ForceRecreate") {
+			t.Errorf("expected error migrating without ForceRecreate, got %v", err)
+		}
+	}
+
+	{
+		allTasks := buildTasks(true)
+		runTasks(t, ctx, cloud, allTasks)
+	}
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if r.Target != "" {
+		t.Errorf("expected the migrated forwarding rule not to target the pool, got %q", r.Target)
+	}
+	if lastComponent(r.BackendService) != "api" {
+		t.Errorf("expected the migrated forwarding rule to use BackendService api, got %q", r.BackendService)
+	}
+	if r.IPAddress != "203.0.113.10" {
+		t.Errorf("expected the migrated forwarding rule to keep IP 203.0.113.10, got %q", r.IPAddress)
+	}
+
+	{
+		allTasks := buildTasks(true)
+		checkNoChanges(t, ctx, cloud, allTasks)
+	}
+
+	t.Run("scheme change with PreserveIP", func(t *testing.T) {
+		a := &ForwardingRule{Name: fi.PtrTo("api"), TargetPool: &TargetPool{Name: fi.PtrTo("api")}, LoadBalancingScheme: fi.PtrTo("EXTERNAL")}
+		e := &ForwardingRule{
+			Name:                fi.PtrTo("api"),
+			BackendService:      &BackendService{Name: fi.PtrTo("api")},
+			LoadBalancingScheme: fi.PtrTo("INTERNAL"),
+			ForceRecreate:       fi.PtrTo(true),
+			PreserveIP:          fi.PtrTo(true),
+		}
+		changes := &ForwardingRule{BackendService: e.BackendService, LoadBalancingScheme: e.LoadBalancingScheme}
+		if err := (&ForwardingRule{}).CheckChanges(a, e, changes); err == nil {
+			t.Errorf("expected error preserving the IP across a scheme change")
+		}
+	})
+}
+
+func TestForwardingRuleBatchLabelUpdates(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	buildTasks := func(role string) map[string]fi.CloudupTask {
+		pool := &TargetPool{
+			Name:      fi.PtrTo("api"),
+			Lifecycle: fi.LifecycleSync,
+		}
+		allTasks := map[string]fi.CloudupTask{
+			"targetPool/api": pool,
+		}
+		for _, name := range []string{"api-1", "api-2", "api-3"} {
+			allTasks["forwardingRule/"+name] = &ForwardingRule{
+				Name:       fi.PtrTo(name),
+				Lifecycle:  fi.LifecycleSync,
+				Ports:      []string{"443"},
+				TargetPool: pool,
+				IPProtocol: "TCP",
+				Labels:     map[string]string{"role": role},
+			}
+		}
+		return allTasks
+	}
+
+	runBatchedTasks := func(allTasks map[string]fi.CloudupTask) *gce.GCEAPITarget {
+		target := gce.NewGCEAPITarget(cloud)
+		target.BatchLabelUpdates = true
+
+		context, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		if err := context.RunTasks(testRunTasksOptions); err != nil {
+			t.Fatalf("unexpected error during Run: %v", err)
+		}
+		return target
+	}
+
+	checkLabels := func(role string) {
+		t.Helper()
+		for _, name := range []string{"api-1", "api-2", "api-3"} {
+			r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, name)
+			if err != nil {
+				t.Fatalf("error getting forwarding rule %q: %v", name, err)
+			}
+			if r.Labels["role"] != role {
+				t.Errorf("unexpected labels on forwarding rule %q: %v", name, r.Labels)
+			}
+		}
+	}
+
+	// The labels of created rules are only set when the target is finished
+	target := runBatchedTasks(buildTasks("api"))
+	checkLabels("")
+	if err := target.Finish(nil); err != nil {
+		t.Fatalf("unexpected error finishing target: %v", err)
+	}
+	checkLabels("api")
+	checkNoChanges(t, ctx, cloud, buildTasks("api"))
+
+	// Likewise for labels updated in place
+	target = runBatchedTasks(buildTasks("control-plane"))
+	checkLabels("api")
+	if err := target.Finish(nil); err != nil {
+		t.Fatalf("unexpected error finishing target: %v", err)
+	}
+	checkLabels("control-plane")
+	checkNoChanges(t, ctx, cloud, buildTasks("control-plane"))
+
+	// Queued label updates can be flushed without finishing the target, as when the tasks fail
+	target = runBatchedTasks(buildTasks("api"))
+	checkLabels("control-plane")
+	if err := target.FlushLabelUpdates(ctx); err != nil {
+		t.Fatalf("unexpected error flushing label updates: %v", err)
+	}
+	checkLabels("api")
+
+	// Failed label updates are reported by Finish
+	target = gce.NewGCEAPITarget(cloud)
+	target.BatchLabelUpdates = true
+	if err := target.SetForwardingRuleLabels(ctx, "api-missing", &compute.RegionSetLabelsRequest{Labels: map[string]string{"role": "api"}}); err != nil {
+		t.Fatalf("unexpected error queueing label update: %v", err)
+	}
+	if err := target.Finish(nil); err == nil || !strings.Contains(err.Error(), "api-missing") {
+		t.Errorf("expected error setting labels of api-missing, got %v", err)
+	}
+}
+
+// slowOperationCloud wraps a GCE cloud, adding a fixed latency to waiting for operations.
+type slowOperationCloud struct {
+	gce.GCECloud
+	latency time.Duration
+}
+
+func (c *slowOperationCloud) WaitForOpContext(ctx context.Context, op *compute.Operation) error {
+	time.Sleep(c.latency)
+	return c.GCECloud.WaitForOpContext(ctx, op)
+}
+
+func BenchmarkForwardingRuleLabelUpdates(b *testing.B) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+	ruleCount := 30
+
+	mockCloud := gcemock.InstallMockGCECloud(region, project)
+	cloud := &slowOperationCloud{GCECloud: mockCloud, latency: 5 * time.Millisecond}
+
+	var names []string
+	for i := 0; i < ruleCount; i++ {
+		name := fmt.Sprintf("api-%d", i)
+		if _, err := mockCloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{Name: name}); err != nil {
+			b.Fatalf("error creating forwarding rule: %v", err)
+		}
+		names = append(names, name)
+	}
+
+	for _, batch := range []bool{false, true} {
+		desc := "serial"
+		if batch {
+			desc = "batched"
+		}
+		b.Run(desc, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				target := gce.NewGCEAPITarget(cloud)
+				target.BatchLabelUpdates = batch
+				for _, name := range names {
+					req := &compute.RegionSetLabelsRequest{Labels: map[string]string{"revision": fmt.Sprintf("%d", i)}}
+					if err := target.SetForwardingRuleLabels(ctx, name, req); err != nil {
+						b.Fatalf("error setting labels: %v", err)
+					}
+				}
+				if err := target.Finish(nil); err != nil {
+					b.Fatalf("error finishing target: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardingRuleWaitForServing(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	defer func(timeout, interval time.Duration, dial func(ctx context.Context, network, address string) (net.Conn, error)) {
+		forwardingRuleServingTimeout = timeout
+		forwardingRuleServingPollInterval = interval
+		dialForwardingRule = dial
+	}(forwardingRuleServingTimeout, forwardingRuleServingPollInterval, dialForwardingRule)
+	forwardingRuleServingTimeout = 100 * time.Millisecond
+	forwardingRuleServingPollInterval = time.Millisecond
+
+	buildTasks := func() map[string]fi.CloudupTask {
+		address := &Address{
+			Name:      fi.PtrTo("api"),
+			Lifecycle: fi.LifecycleSync,
+		}
+		pool := &TargetPool{
+			Name:      fi.PtrTo("api"),
+			Lifecycle: fi.LifecycleSync,
+		}
+		rule := &ForwardingRule{
+			Name:           fi.PtrTo("api"),
+			Lifecycle:      fi.LifecycleSync,
+			PortRange:      fi.PtrTo("443-443"),
+			TargetPool:     pool,
+			IPAddress:      address,
+			IPProtocol:     "TCP",
+			WaitForServing: fi.PtrTo(true),
+		}
+		return map[string]fi.CloudupTask{
+			"address/api":        address,
+			"targetPool/api":     pool,
+			"forwardingRule/api": rule,
+		}
+	}
+
+	t.Run("serves after a few dials", func(t *testing.T) {
+		cloud := gcemock.InstallMockGCECloud(region, project)
+		if _, err := cloud.Compute().Addresses().Insert(project, region, &compute.Address{
+			Name:        "api",
+			Address:     "203.0.113.10",
+			AddressType: "EXTERNAL",
+		}); err != nil {
+			t.Fatalf("error creating address: %v", err)
+		}
+
+		var dialed []string
+		dialForwardingRule = func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			if len(dialed) < 3 {
+				return nil, fmt.Errorf("connection refused")
+			}
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+
+		runTasks(t, ctx, cloud, buildTasks())
+
+		if len(dialed) != 3 {
+			t.Fatalf("expected 3 dials, got %v", dialed)
+		}
+		if dialed[0] != "203.0.113.10:443" {
+			t.Errorf("expected to dial the IP and first port of the rule, got %q", dialed[0])
+		}
+		checkNoChanges(t, ctx, cloud, buildTasks())
+	})
+
+	t.Run("never serves", func(t *testing.T) {
+		cloud := gcemock.InstallMockGCECloud(region, project)
+		if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+			Name:       "api",
+			IPProtocol: "TCP",
+			PortRange:  "443-443",
+			IPAddress:  "203.0.113.10",
+		}); err != nil {
+			t.Fatalf("error creating forwarding rule: %v", err)
+		}
+
+		dialForwardingRule = func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, fmt.Errorf("connection refused")
+		}
+
+		err := waitForRuleServing(ctx, cloud, "api", "443", false)
+		if err == nil || !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("expected error with the last dial error, got %v", err)
+		}
+	})
+
+	t.Run("requires TCP", func(t *testing.T) {
+		e := buildTasks()["forwardingRule/api"].(*ForwardingRule)
+		e.IPProtocol = "UDP"
+		if err := (&ForwardingRule{}).CheckChanges(nil, e, e); err == nil {
+			t.Errorf("expected error from CheckChanges")
+		}
+	})
+}
+
+func TestForwardingRuleCrossProjectAddress(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	hostProject := "hostproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	if _, err := cloud.Compute().Addresses().Insert(hostProject, region, &compute.Address{
+		Name:        "shared-api",
+		Address:     "203.0.113.20",
+		AddressType: "EXTERNAL",
+	}); err != nil {
+		t.Fatalf("error creating address: %v", err)
+	}
+	selfLink := "https://www.googleapis.com/compute/v1/projects/hostproject/regions/us-test1/addresses/shared-api"
+
+	buildTasks := func() map[string]fi.CloudupTask {
+		address := &Address{
+			Name:      fi.PtrTo("shared-api"),
+			Lifecycle: fi.LifecycleExistsAndWarnIfChanges,
+			Project:   fi.PtrTo(hostProject),
+		}
+		pool := &TargetPool{
+			Name:      fi.PtrTo("api"),
+			Lifecycle: fi.LifecycleSync,
+		}
+		rule := &ForwardingRule{
+			Name:       fi.PtrTo("api"),
+			Lifecycle:  fi.LifecycleSync,
+			PortRange:  fi.PtrTo("443-443"),
+			TargetPool: pool,
+			IPAddress:  address,
+			IPProtocol: "TCP",
+		}
+		return map[string]fi.CloudupTask{
+			"address/shared-api": address,
+			"targetPool/api":     pool,
+			"forwardingRule/api": rule,
+		}
+	}
+
+	runTasks(t, ctx, cloud, buildTasks())
+
+	r, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api")
+	if err != nil {
+		t.Fatalf("error getting forwarding rule: %v", err)
+	}
+	if r.IPAddress != selfLink {
+		t.Errorf("expected forwarding rule to reference the address by its self link, got %q", r.IPAddress)
+	}
+	if _, err := cloud.Compute().Addresses().Get(project, region, "shared-api"); !gce.IsNotFound(err) {
+		t.Errorf("expected the address not to be created in the cluster project, got error %v", err)
+	}
+
+	checkNoChanges(t, ctx, cloud, buildTasks())
+
+	t.Run("GCE reports the IP of the address", func(t *testing.T) {
+		e := buildTasks()["address/shared-api"].(*Address)
+		address, err := findCrossProjectAddress(cloud, e, "203.0.113.20")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if address == nil || fi.ValueOf(address.Name) != "shared-api" {
+			t.Errorf("expected to find the address by its IP, got %v", address)
+		}
+
+		address, err = findCrossProjectAddress(cloud, e, "203.0.113.21")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if address != nil {
+			t.Errorf("expected no address for another IP, got %v", address)
+		}
+	})
+
+	t.Run("terraform", func(t *testing.T) {
+		target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+
+		for _, task := range buildTasks() {
+			var err error
+			switch task := task.(type) {
+			case *Address:
+				err = task.RenderTerraform(target, nil, task, task)
+			case *ForwardingRule:
+				err = task.RenderTerraform(target, nil, task, task)
+			}
+			if err != nil {
+				t.Fatalf("unexpected error rendering terraform: %v", err)
+			}
+		}
+
+		resources, err := target.GetResourcesByType()
+		if err != nil {
+			t.Fatalf("unexpected error getting resources: %v", err)
+		}
+		if _, found := resources["google_compute_address"]; found {
+			t.Errorf("expected no terraform resource for the address in another project")
+		}
+		tf, ok := resources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRule)
+		if !ok {
+			t.Fatalf("expected google_compute_forwarding_rule.api, got %v", resources)
+		}
+		if tf.IPAddress.String != fmt.Sprintf("%q", selfLink) {
+			t.Errorf("expected ip_address to be the URL of the address, got %s", tf.IPAddress.String)
+		}
+	})
+
+	t.Run("address in use by another rule", func(t *testing.T) {
+		// GCE reports the IP of the address on the rules that use it, not its self link
+		if _, err := cloud.Compute().ForwardingRules().Insert(ctx, project, region, &compute.ForwardingRule{
+			Name:       "other",
+			IPAddress:  "203.0.113.20",
+			IPProtocol: "TCP",
+			PortRange:  "443-443",
+		}); err != nil {
+			t.Fatalf("error creating forwarding rule: %v", err)
+		}
+
+		allTasks := buildTasks()
+		rule := allTasks["forwardingRule/api"].(*ForwardingRule)
+		rule.Name = fi.PtrTo("api-2")
+		target := gce.NewGCEAPITarget(cloud)
+		c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		err = rule.RenderGCE(c, target, nil, rule, rule)
+		if err == nil || !strings.Contains(err.Error(), `already in use by ForwardingRule "other"`) {
+			t.Errorf("expected the address to be in use by ForwardingRule other, got %v", err)
+		}
+	})
+}
+
+func TestForwardingRuleGlobalLabels(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	cloud := gcemock.InstallMockGCECloud(region, project)
+
+	e := &ForwardingRule{
+		Name:       fi.PtrTo("api"),
+		Lifecycle:  fi.LifecycleSync,
+		IPProtocol: "TCP",
+		PortRange:  fi.PtrTo("443-443"),
+		Global:     fi.PtrTo(true),
+		Labels:     map[string]string{"k8s-io-cluster-name": "test"},
+	}
+	allTasks := map[string]fi.CloudupTask{*e.Name: e}
+
+	checkLabels := func(expected map[string]string) {
+		t.Helper()
+		r, err := cloud.Compute().GlobalForwardingRules().Get(ctx, project, "api")
+		if err != nil {
+			t.Fatalf("error getting global forwarding rule: %v", err)
+		}
+		if !reflect.DeepEqual(r.Labels, expected) {
+			t.Errorf("expected labels %v on the global forwarding rule, got %v", expected, r.Labels)
+		}
+	}
+
+	// The labels are set through the global API, which requires the fingerprint of the global rule
+	runTasks(t, ctx, cloud, allTasks)
+	checkLabels(map[string]string{"k8s-io-cluster-name": "test"})
+	if _, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, "api"); err == nil {
+		t.Errorf("expected no regional forwarding rule to be created")
+	}
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	// Changing the labels uses the fingerprint found on the global rule
+	e.Labels = map[string]string{"k8s-io-cluster-name": "test", "k8s-io-role-control-plane": "1"}
+	checkHasChanges(t, ctx, cloud, allTasks)
+	runTasks(t, ctx, cloud, allTasks)
+	checkLabels(map[string]string{"k8s-io-cluster-name": "test", "k8s-io-role-control-plane": "1"})
+	checkNoChanges(t, ctx, cloud, allTasks)
+
+	// Batched label updates of global rules are applied when the target is finished
+	e.Labels = map[string]string{"k8s-io-cluster-name": "other"}
+	target := gce.NewGCEAPITarget(cloud)
+	target.BatchLabelUpdates = true
+	c, err := fi.NewCloudupContext(ctx, fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, allTasks)
+	if err != nil {
+		t.Fatalf("error building context: %v", err)
+	}
+	if err := c.RunTasks(testRunTasksOptions); err != nil {
+		t.Fatalf("unexpected error during Run: %v", err)
+	}
+	checkLabels(map[string]string{"k8s-io-cluster-name": "test", "k8s-io-role-control-plane": "1"})
+	if err := target.Finish(nil); err != nil {
+		t.Fatalf("unexpected error finishing target: %v", err)
+	}
+	checkLabels(map[string]string{"k8s-io-cluster-name": "other"})
+	checkNoChanges(t, ctx, cloud, allTasks)
+}
+
+func TestForwardingRuleDefaultNetworkTier(t *testing.T) {
+	ctx := context.TODO()
+
+	project := "testproject"
+	region := "us-test1"
+
+	if _, err := gce.WithDefaultNetworkTier(gcemock.InstallMockGCECloud(region, project), "standard"); err == nil {
+		t.Errorf("expected error for an unknown default network tier")
+	}
+
+	cloud, err := gce.WithDefaultNetworkTier(gcemock.InstallMockGCECloud(region, project), gce.NetworkTierStandard)
+	if err != nil {
+		t.Fatalf("unexpected error setting the default network tier: %v", err)
+	}
+
+	newRule := func(name string, scheme string, networkTier *string) *ForwardingRule {
+		return &ForwardingRule{
+			Name:                fi.PtrTo(name),
+			Lifecycle:           fi.LifecycleSync,
+			PortRange:           fi.PtrTo("443-443"),
+			IPProtocol:          "TCP",
+			LoadBalancingScheme: fi.PtrTo(scheme),
+			NetworkTier:         networkTier,
+		}
+	}
+
+	t.Run("RenderGCE", func(t *testing.T) {
+		allTasks := map[string]fi.CloudupTask{
+			"api":     newRule("api", "EXTERNAL", nil),
+			"premium": newRule("premium", "EXTERNAL", fi.PtrTo(gce.NetworkTierPremium)),
+		}
+		runTasks(t, ctx, cloud, allTasks)
+
+		for name, expected := range map[string]string{"api": gce.NetworkTierStandard, "premium": gce.NetworkTierPremium} {
+			fr, err := cloud.Compute().ForwardingRules().Get(ctx, project, region, name)
+			if err != nil {
+				t.Fatalf("error getting forwarding rule %q: %v", name, err)
+			}
+			if fr.NetworkTier != expected {
+				t.Errorf("expected forwarding rule %q to use network tier %q, got %q", name, expected, fr.NetworkTier)
+			}
+		}
+		checkNoChanges(t, ctx, cloud, allTasks)
+	})
+
+	t.Run("RenderTerraform", func(t *testing.T) {
+		target := terraform.NewTerraformTarget(cloud, project, "", &kops.TargetSpec{})
+		for _, e := range []*ForwardingRule{newRule("api", "EXTERNAL", nil), newRule("internal", "INTERNAL", nil)} {
+			if err := e.RenderTerraform(target, nil, e, e); err != nil {
+				t.Fatalf("unexpected error rendering terraform: %v", err)
+			}
+		}
+
+		resources, err := target.GetResourcesByType()
+		if err != nil {
+			t.Fatalf("unexpected error getting resources: %v", err)
+		}
+		if tf := resources["google_compute_forwarding_rule"]["api"].(*terraformForwardingRule); fi.ValueOf(tf.NetworkTier) != gce.NetworkTierStandard {
+			t.Errorf("expected the external rule to use the default network tier, got %q", fi.ValueOf(tf.NetworkTier))
+		}
+		if tf := resources["google_compute_forwarding_rule"]["internal"].(*terraformForwardingRule); tf.NetworkTier != nil {
+			t.Errorf("expected the internal rule to use the project default network tier, got %q", *tf.NetworkTier)
+		}
+	})
+}
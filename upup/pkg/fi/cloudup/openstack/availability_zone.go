@@ -74,3 +74,42 @@ func getStorageAZFromCompute(c OpenstackCloud, computeAZ string) (*az.Availabili
 	}
 	return nil, fmt.Errorf("no decernable storage availability zone could be mapped to compute availability zone %s", computeAZ)
 }
+
+// LBAvailabilityZone is an Octavia availability zone, in which loadbalancers can be created.
+type LBAvailabilityZone struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+func (c *openstackCloud) ListLBAvailabilityZones() ([]LBAvailabilityZone, error) {
+	return listLBAvailabilityZones(c)
+}
+
+func listLBAvailabilityZones(c OpenstackCloud) ([]LBAvailabilityZone, error) {
+	client := c.LoadBalancerClient()
+	if client == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	var zones []LBAvailabilityZone
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		// gophercloud has no client for Octavia availability zones
+		var body struct {
+			AvailabilityZones []LBAvailabilityZone `json:"availability_zones"`
+		}
+		_, err := client.Get(context.TODO(), client.ServiceURL("lbaas", "availabilityzones"), &body, nil)
+		if err != nil {
+			return false, fmt.Errorf("failed to list loadbalancer availability zones: %w", err)
+		}
+		zones = body.AvailabilityZones
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return zones, err
+	}
+	return zones, nil
+}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/blang/semver/v4"
@@ -38,6 +39,7 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/recordsets"
 	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/zones"
 	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/apiversions"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
@@ -71,6 +73,7 @@ const (
 	TagKopsNetwork           = "KopsNetwork"
 	TagKopsName              = "KopsName"
 	TagKopsRole              = "KopsRole"
+	TagKopsIgnore            = "KopsIgnore"
 	ResourceTypePort         = "ports"
 	ResourceTypeNetwork      = "networks"
 	ResourceTypeSubnet       = "subnets"
@@ -230,6 +233,9 @@ type OpenstackCloud interface {
 	// ListPorts will return the Neutron ports which match the options
 	ListPorts(opt ports.ListOptsBuilder) ([]ports.Port, error)
 
+	// DetectVIPSubnet returns the single subnet that all the servers have a port in, as a default loadbalancer VIP subnet
+	DetectVIPSubnet(serverIDs []string) (string, error)
+
 	// DeletePort will delete a neutron port
 	DeletePort(portID string) error
 
@@ -257,19 +263,80 @@ type OpenstackCloud interface {
 
 	GetLB(loadbalancerID string) (*loadbalancers.LoadBalancer, error)
 	GetLBStats(loadbalancerID string) (*loadbalancers.Stats, error)
+
+	// EstimatedPoolLoad returns an estimate of the active connections of each member of a pool, by member ID;
+	// Octavia does not measure connections per member
+	EstimatedPoolLoad(poolID string) (map[string]int, error)
+
+	// SetPoolTrafficSplit sets the weights of the members of a pool, by member ID, in a single batch update
+	SetPoolTrafficSplit(poolID string, weights map[string]int) error
+
+	// GetPoolTrafficSplit returns the weights of the members of a pool, by member ID
+	GetPoolTrafficSplit(poolID string) (map[string]int, error)
 	CreateLB(opt loadbalancers.CreateOptsBuilder) (*loadbalancers.LoadBalancer, error)
+
+	// CreateLBAndWait will create a loadbalancer and wait for it to be ACTIVE, deleting it again if it goes into ERROR
+	CreateLBAndWait(opt loadbalancers.CreateOptsBuilder, timeout time.Duration) (*loadbalancers.LoadBalancer, error)
+
+	// UpdateLB will update the loadbalancer
+	UpdateLB(loadbalancerID string, opt loadbalancers.UpdateOpts) (*loadbalancers.LoadBalancer, error)
 	ListLBs(opt loadbalancers.ListOptsBuilder) ([]loadbalancers.LoadBalancer, error)
+
+	// ListLBsByStatus will list the loadbalancers in the given provisioning status
+	ListLBsByStatus(status string) ([]loadbalancers.LoadBalancer, error)
 	UpdateMemberInPool(poolID string, memberID string, opts v2pools.UpdateMemberOptsBuilder) (*v2pools.Member, error)
 	ListPoolMembers(poolID string, opts v2pools.ListMembersOpts) ([]v2pools.Member, error)
 
+	// ListPoolMembersMap returns the members of a pool keyed by PoolMemberKey
+	ListPoolMembersMap(poolID string) (map[string]v2pools.Member, error)
+
+	// CheckPoolCapacity returns an error if adding members to the pool would exceed the limit of its loadbalancer flavor
+	CheckPoolCapacity(poolID string, addingMembers int) error
+
+	// ReconcilePoolMembers converges the members of a pool to the desired set with as few pool locks as possible,
+	// keeping the undesired members selected by preservation
+	ReconcilePoolMembers(poolID string, desired []MemberSpec, preservation MemberPreservation) error
+
+	// ReplacePoolMembers makes the members of a pool exactly match the desired set in a single batch update,
+	// falling back to incremental changes if the batch API is not supported
+	ReplacePoolMembers(poolID string, desired []v2pools.BatchUpdateMemberOpts) error
+
+	// ReplaceMemberForNode replaces the members of the pool for a replaced node with members for its replacement,
+	// in a single batch update so that the pool never counts both
+	ReplaceMemberForNode(poolID string, oldServerAddr string, newServerAddr string, opts ReplaceMemberOpts) error
+
 	// DeleteLB will delete loadbalancer
 	DeleteLB(lbID string, opt loadbalancers.DeleteOpts) error
 
+	// GetLBByName returns the loadbalancer with the given kops name, or nil if there is none
+	GetLBByName(name string) (*loadbalancers.LoadBalancer, error)
+
+	// EnsureLoadBalancer creates or updates the loadbalancer to match the spec, and returns it once it is ACTIVE
+	EnsureLoadBalancer(spec LoadBalancerSpec) (*loadbalancers.LoadBalancer, error)
+
+	// DeleteLBByName cascade deletes the loadbalancer with the given kops name, if confirm returns true for it
+	DeleteLBByName(name string, confirm func(lb *loadbalancers.LoadBalancer) bool) error
+
+	// DeleteLBOrdered deletes a loadbalancer and its children one by one in dependency order, for providers without cascade delete
+	DeleteLBOrdered(lbID string) error
+
+	// DeleteClusterLoadBalancers deletes the loadbalancers of a cluster, several at a time
+	DeleteClusterLoadBalancers(clusterName string, opts DeleteClusterLoadBalancersOptions) error
+
+	// ListAmphoraeForLB returns the amphorae implementing the loadbalancer, which requires the Octavia admin role
+	ListAmphoraeForLB(lbID string) ([]amphorae.Amphora, error)
+
+	// DrainLBMembers sets the weight of every member of the loadbalancer to 0, and waits for their connections to finish
+	DrainLBMembers(ctx context.Context, lbID string, drain time.Duration) error
+
 	// DefaultInstanceType determines a suitable instance type for the specified instance group
 	DefaultInstanceType(cluster *kops.Cluster, ig *kops.InstanceGroup) (string, error)
 
 	// Returns the availability zones for the service client passed (compute, volume, network)
 	ListAvailabilityZones(serviceClient *gophercloud.ServiceClient) ([]az.AvailabilityZone, error)
+
+	// ListLBAvailabilityZones returns the availability zones loadbalancers can be created in
+	ListLBAvailabilityZones() ([]LBAvailabilityZone, error)
 	AssociateToPool(server *servers.Server, poolID string, opts v2pools.CreateMemberOpts) (*v2pools.Member, error)
 	CreatePool(opts v2pools.CreateOpts) (*v2pools.Pool, error)
 	CreatePoolMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error)
@@ -280,14 +347,39 @@ type OpenstackCloud interface {
 	// ListMonitors will list HealthMonitors matching the provided options
 	ListMonitors(monitors.ListOpts) ([]monitors.Monitor, error)
 
+	// UpdateMonitor will update a Pool resources Health Monitor
+	UpdateMonitor(monitorID string, opts monitors.UpdateOpts) (*monitors.Monitor, error)
+
 	// DeleteMonitor will delete a Pool resources Health Monitor
 	DeleteMonitor(monitorID string) error
 
+	// AttachMonitorToPool will create a Health Monitor for a Pool without one, and wait until the Pool references it
+	AttachMonitorToPool(poolID string, opts monitors.CreateOpts) (*monitors.Monitor, error)
+
+	// DetachMonitorFromPool will delete the Health Monitor of a Pool, and wait until the Pool no longer references it
+	DetachMonitorFromPool(poolID string) error
+
+	// UpdatePool will update a loadbalancer pool
+	UpdatePool(poolID string, opts v2pools.UpdateOpts) (*v2pools.Pool, error)
+
 	// DeletePool will delete loadbalancer pool
 	DeletePool(poolID string) error
 	ListListeners(opts listeners.ListOpts) ([]listeners.Listener, error)
 	CreateListener(opts listeners.CreateOpts) (*listeners.Listener, error)
 
+	// UpdateListener will update the loadbalancer listener
+	UpdateListener(listenerID string, opts listeners.UpdateOpts) (*listeners.Listener, error)
+
+	// SwapListenerDefaultPool points the listener at another pool, moving traffic to its members at once
+	SwapListenerDefaultPool(listenerID, newPoolID string) error
+
+	// ShadowSwapListener migrates the listener to another protocol and pool through a shadow listener validated on another port
+	ShadowSwapListener(listenerID string, opts ShadowSwapOptions) (*listeners.Listener, error)
+
+	// EnsureHTTPToHTTPSRedirect redirects all requests to an HTTP listener to https://<host>, keeping their path.
+	// It does nothing if the listener already has an equivalent L7 policy.
+	EnsureHTTPToHTTPSRedirect(httpListenerID, host string) error
+
 	// DeleteListener will delete loadbalancer listener
 	DeleteListener(listenerID string) error
 	GetStorageAZFromCompute(azName string) (*az.AvailabilityZone, error)
@@ -299,7 +391,44 @@ type OpenstackCloud interface {
 	CreateL3FloatingIP(opts l3floatingip.CreateOpts) (fip *l3floatingip.FloatingIP, err error)
 	DeleteFloatingIP(id string) error
 	DeleteL3FloatingIP(id string) error
+
+	// AssociateFloatingIPToLB associates the floating IP with the VIP port of the loadbalancer
+	AssociateFloatingIPToLB(lbID string, floatingIPID string) error
+
+	// DisassociateFloatingIPFromLB removes the association of the floating IP with the VIP port of the loadbalancer
+	DisassociateFloatingIPFromLB(lbID string, floatingIPID string) error
+
+	// EnsureLBVIPSecurityGroups sets the security groups of the VIP port of the loadbalancer, replacing any others
+	EnsureLBVIPSecurityGroups(lbID string, sgIDs []string) error
+
 	UseLoadBalancerVIPACL() (bool, error)
+
+	// UseLoadBalancerTLSPolicy returns whether the loadbalancer API supports the TLS versions and ciphers of listeners
+	UseLoadBalancerTLSPolicy() (bool, error)
+
+	// UseLoadBalancerVIPQosPolicy returns whether the loadbalancer API is known to support the QoS policy of the VIP
+	UseLoadBalancerVIPQosPolicy() (bool, error)
+
+	// LBEventSink returns the sink notified of loadbalancer changes, or nil if there is none
+	LBEventSink() LBEventSink
+
+	// SetLBEventSink sets the sink notified of loadbalancer changes; nil disables notifications
+	SetLBEventSink(sink LBEventSink)
+
+	// LBMetricsRecorder returns the recorder of loadbalancer operations, which does nothing unless one was set
+	LBMetricsRecorder() LBMetricsRecorder
+
+	// SetLBMetricsRecorder sets the recorder of loadbalancer operations; nil disables recording
+	SetLBMetricsRecorder(recorder LBMetricsRecorder)
+
+	// GetLBHealthSummary returns the status of the loadbalancer together with its listeners, pools and members
+	GetLBHealthSummary(lbID string) (*LBHealth, error)
+
+	// WaitForMembersOnline waits for all members of the pool to be ONLINE, returning a MembersNotOnlineError on timeout
+	WaitForMembersOnline(poolID string, timeout time.Duration) error
+
+	// FindUnreachableMemberAddresses returns the addresses that are in none of the subnets the loadbalancer of the pool can reach
+	FindUnreachableMemberAddresses(poolID string, addresses []string) ([]string, error)
 }
 
 type openstackCloud struct {
@@ -307,7 +436,6 @@ type openstackCloud struct {
 	neutronClient   *gophercloud.ServiceClient
 	novaClient      *gophercloud.ServiceClient
 	dnsClient       *gophercloud.ServiceClient
-	lbClient        *gophercloud.ServiceClient
 	glanceClient    *gophercloud.ServiceClient
 	extNetworkName  *string
 	extSubnetName   *string
@@ -317,7 +445,24 @@ type openstackCloud struct {
 	useOctavia      bool
 	zones           []string
 	floatingEnabled bool
-	useVIPACL       *bool
+
+	// lbMutex guards lbClient, lbEventSink and lbMetricsRecorder, which are read by concurrent reconcile goroutines
+	lbMutex           sync.RWMutex
+	lbClient          *gophercloud.ServiceClient
+	lbEventSink       LBEventSink
+	lbMetricsRecorder LBMetricsRecorder
+
+	// vipACLMutex guards useVIPACL, which is looked up lazily on first use
+	vipACLMutex sync.Mutex
+	useVIPACL   *bool
+
+	// tlsPolicyMutex guards useTLSPolicy, which is looked up lazily on first use
+	tlsPolicyMutex sync.Mutex
+	useTLSPolicy   *bool
+
+	// vipQosPolicyMutex guards useVIPQosPolicy, which is looked up lazily on first use
+	vipQosPolicyMutex sync.Mutex
+	useVIPQosPolicy   *bool
 }
 
 var _ fi.Cloud = &openstackCloud{}
@@ -512,6 +657,8 @@ func buildLoadBalancerClient(c *openstackCloud, spec *kops.OpenstackSpec, provid
 		}
 		lbClient = client
 	}
+	c.lbMutex.Lock()
+	defer c.lbMutex.Unlock()
 	c.lbClient = lbClient
 	return nil
 }
@@ -537,10 +684,40 @@ func (c *openstackCloud) NetworkingClient() *gophercloud.ServiceClient {
 	return c.neutronClient
 }
 
+// LoadBalancerClient returns the load balancer client; it is safe for concurrent use.
 func (c *openstackCloud) LoadBalancerClient() *gophercloud.ServiceClient {
+	c.lbMutex.RLock()
+	defer c.lbMutex.RUnlock()
 	return c.lbClient
 }
 
+func (c *openstackCloud) LBEventSink() LBEventSink {
+	c.lbMutex.RLock()
+	defer c.lbMutex.RUnlock()
+	return c.lbEventSink
+}
+
+func (c *openstackCloud) SetLBEventSink(sink LBEventSink) {
+	c.lbMutex.Lock()
+	defer c.lbMutex.Unlock()
+	c.lbEventSink = sink
+}
+
+func (c *openstackCloud) LBMetricsRecorder() LBMetricsRecorder {
+	c.lbMutex.RLock()
+	defer c.lbMutex.RUnlock()
+	if c.lbMetricsRecorder == nil {
+		return noopLBMetricsRecorder{}
+	}
+	return c.lbMetricsRecorder
+}
+
+func (c *openstackCloud) SetLBMetricsRecorder(recorder LBMetricsRecorder) {
+	c.lbMutex.Lock()
+	defer c.lbMutex.Unlock()
+	c.lbMetricsRecorder = recorder
+}
+
 func (c *openstackCloud) DNSClient() *gophercloud.ServiceClient {
 	return c.dnsClient
 }
@@ -700,6 +877,9 @@ func (c *openstackCloud) GetCloudTags() map[string]string {
 }
 
 func (c *openstackCloud) UseLoadBalancerVIPACL() (bool, error) {
+	c.vipACLMutex.Lock()
+	defer c.vipACLMutex.Unlock()
+
 	if c.useVIPACL != nil {
 		return *c.useVIPACL, nil
 	}
@@ -715,23 +895,85 @@ func useLoadBalancerVIPACL(c OpenstackCloud) (bool, error) {
 	if c.LoadBalancerClient() == nil {
 		return false, nil
 	}
-	allPages, err := apiversions.List(c.LoadBalancerClient()).AllPages(context.TODO())
+	ver, err := latestLoadBalancerAPIVersion(c)
 	if err != nil {
 		return false, err
 	}
-	versions, err := apiversions.ExtractAPIVersions(allPages)
+	// https://github.com/kubernetes/cloud-provider-openstack/blob/721615aa256bbddbd481cfb4a887c3ab180c5563/pkg/util/openstack/loadbalancer.go#L108
+	return ver.Compare(semver.MustParse("2.12.0")) > 0, nil
+}
+
+func (c *openstackCloud) UseLoadBalancerTLSPolicy() (bool, error) {
+	c.tlsPolicyMutex.Lock()
+	defer c.tlsPolicyMutex.Unlock()
+
+	if c.useTLSPolicy != nil {
+		return *c.useTLSPolicy, nil
+	}
+	use, err := useLoadBalancerTLSPolicy(c)
 	if err != nil {
 		return false, err
 	}
-	if len(versions) == 0 {
-		return false, fmt.Errorf("loadbalancer API versions not found")
+	c.useTLSPolicy = &use
+	return use, nil
+}
+
+// useLoadBalancerTLSPolicy returns whether the loadbalancer API accepts the tls_ciphers and tls_versions of listeners,
+// which were added in Octavia API 2.17. Older versions reject them.
+func useLoadBalancerTLSPolicy(c OpenstackCloud) (bool, error) {
+	if c.LoadBalancerClient() == nil {
+		return false, nil
 	}
-	ver, err := semver.ParseTolerant(versions[len(versions)-1].ID)
+	ver, err := latestLoadBalancerAPIVersion(c)
 	if err != nil {
 		return false, err
 	}
-	// https://github.com/kubernetes/cloud-provider-openstack/blob/721615aa256bbddbd481cfb4a887c3ab180c5563/pkg/util/openstack/loadbalancer.go#L108
-	return ver.Compare(semver.MustParse("2.12.0")) > 0, nil
+	return ver.GTE(semver.MustParse("2.17.0")), nil
+}
+
+func (c *openstackCloud) UseLoadBalancerVIPQosPolicy() (bool, error) {
+	c.vipQosPolicyMutex.Lock()
+	defer c.vipQosPolicyMutex.Unlock()
+
+	if c.useVIPQosPolicy != nil {
+		return *c.useVIPQosPolicy, nil
+	}
+	use, err := useLoadBalancerVIPQosPolicy(c)
+	if err != nil {
+		return false, err
+	}
+	c.useVIPQosPolicy = &use
+	return use, nil
+}
+
+// useLoadBalancerVIPQosPolicy returns whether the loadbalancer API accepts the vip_qos_policy_id of loadbalancers.
+// It was added in Queens, which still reports API 2.0 like Pike, which rejects it; so only from 2.1 (Rocky) on
+// is it known to be supported.
+func useLoadBalancerVIPQosPolicy(c OpenstackCloud) (bool, error) {
+	if c.LoadBalancerClient() == nil {
+		return false, nil
+	}
+	ver, err := latestLoadBalancerAPIVersion(c)
+	if err != nil {
+		return false, err
+	}
+	return ver.GTE(semver.MustParse("2.1.0")), nil
+}
+
+// latestLoadBalancerAPIVersion returns the most recent version of the loadbalancer API supported by the cloud
+func latestLoadBalancerAPIVersion(c OpenstackCloud) (semver.Version, error) {
+	allPages, err := apiversions.List(c.LoadBalancerClient()).AllPages(context.TODO())
+	if err != nil {
+		return semver.Version{}, err
+	}
+	versions, err := apiversions.ExtractAPIVersions(allPages)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	if len(versions) == 0 {
+		return semver.Version{}, fmt.Errorf("loadbalancer API versions not found")
+	}
+	return semver.ParseTolerant(versions[len(versions)-1].ID)
 }
 
 type Address struct {
@@ -758,6 +1000,7 @@ func getLoadBalancerIngressStatus(c OpenstackCloud, cluster *kops.Cluster) ([]fi
 		lbName = cluster.Spec.API.PublicName
 	}
 	// Note that this must match OpenstackModel lb name
+	lbName = NormalizeLBName(lbName)
 	klog.V(2).Infof("Querying Openstack to find Loadbalancers for API (%q)", cluster.Name)
 	lbList, err := c.ListLBs(loadbalancers.ListOpts{
 		Name: lbName,
@@ -17,12 +17,18 @@ limitations under the License.
 package openstack
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"regexp"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/gophercloud/gophercloud/v2"
@@ -30,6 +36,7 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
 	l3floatingips "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/layer3/floatingips"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/vfs"
@@ -113,8 +120,10 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 		l3FloatingIPs        []l3floatingips.FloatingIP
 		instances            serverList
 		cloudFloatingEnabled bool
-		expectedAPIIngress   []fi.ApiIngressStatus
-		expectedError        error
+		// expectedLBName, if set, is the name the loadbalancers must be listed by
+		expectedLBName     string
+		expectedAPIIngress []fi.ApiIngressStatus
+		expectedError      error
 	}{
 		{
 			desc: "Loadbalancer configured master public name set",
@@ -215,6 +224,39 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 			},
 			expectedAPIIngress: nil,
 		},
+		{
+			desc: "Loadbalancer configured with a truncated name",
+			cluster: &kops.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: strings.Repeat("a", 250) + ".k8s.local",
+				},
+				Spec: kops.ClusterSpec{
+					CloudProvider: kops.CloudProviderSpec{
+						Openstack: &kops.OpenstackSpec{
+							Loadbalancer: &kops.OpenstackLoadbalancerConfig{},
+						},
+					},
+				},
+			},
+			loadbalancers: []loadbalancers.LoadBalancer{
+				{
+					ID:        "lb_id",
+					Name:      NormalizeLBName("api." + strings.Repeat("a", 250) + ".k8s.local"),
+					VipPortID: "vip_port_id",
+				},
+			},
+			l3FloatingIPs: []l3floatingips.FloatingIP{
+				{
+					ID:         "id",
+					PortID:     "vip_port_id",
+					FloatingIP: "8.8.8.8",
+				},
+			},
+			expectedLBName: NormalizeLBName("api." + strings.Repeat("a", 250) + ".k8s.local"),
+			expectedAPIIngress: []fi.ApiIngressStatus{
+				{IP: "8.8.8.8"},
+			},
+		},
 		{
 			desc: "No Loadbalancer configured no floating enabled",
 			cluster: &kops.Cluster{
@@ -388,17 +430,18 @@ func Test_OpenstackCloud_GetApiIngressStatus(t *testing.T) {
 					http.StatusOK,
 				)
 			}
-			fixture(
-				mux,
-				"/lbaas/loadbalancers",
-				http.MethodGet,
-				string(mustJSONMarshal(json.Marshal(
+			mux.HandleFunc("/lbaas/loadbalancers", func(w http.ResponseWriter, r *http.Request) {
+				if name := r.URL.Query().Get("name"); testCase.expectedLBName != "" && name != testCase.expectedLBName {
+					t.Errorf("expected loadbalancers to be listed by name %q, got %q", testCase.expectedLBName, name)
+				}
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprint(w, string(mustJSONMarshal(json.Marshal(
 					struct{ LoadBalancers []loadbalancers.LoadBalancer }{
 						LoadBalancers: testCase.loadbalancers,
 					},
-				))),
-				http.StatusOK,
-			)
+				))))
+			})
 			mux.HandleFunc("/floatingips", func(w http.ResponseWriter, r *http.Request) {
 				w.Header().Add("Content-Type", "application/json")
 				w.WriteHeader(http.StatusOK)
@@ -521,6 +564,89 @@ func mustJSONMarshal(data []byte, err error) []byte {
 	return data
 }
 
+// generatedIDRe matches the IDs the mock generates, so that requests can be compared
+var generatedIDRe = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+
+// mockOctavia serves the Octavia API of mockloadbalancer, recording the requests made to it as "METHOD path",
+// with generated IDs replaced by {id}. Polls of a loadbalancer are not recorded, as their number depends on timing.
+type mockOctavia struct {
+	*mockloadbalancer.MockClient
+
+	server   *httptest.Server
+	mutex    sync.Mutex
+	requests []string
+	// bodies are the bodies of the requests, by request; a repeated request keeps the last body
+	bodies map[string]string
+	// intercept, if set, is called with every request before the mock, and answers it instead by returning true
+	intercept func(w http.ResponseWriter, r *http.Request, request string) bool
+}
+
+func newMockOctavia(t testing.TB) *mockOctavia {
+	o := &mockOctavia{
+		MockClient: mockloadbalancer.CreateClient(),
+		bodies:     make(map[string]string),
+	}
+	o.server = httptest.NewServer(o)
+	t.Cleanup(func() {
+		o.server.Close()
+		o.MockClient.Server.Close()
+	})
+	return o
+}
+
+func (o *mockOctavia) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request := r.Method + " " + generatedIDRe.ReplaceAllString(r.URL.Path, "{id}")
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	o.mutex.Lock()
+	if r.Method != http.MethodGet || strings.Count(r.URL.Path, "/") != 3 || !strings.HasPrefix(r.URL.Path, "/lbaas/loadbalancers/") {
+		o.requests = append(o.requests, request)
+	}
+	o.bodies[request] = string(body)
+	intercept := o.intercept
+	o.mutex.Unlock()
+
+	if intercept != nil && intercept(w, r, request) {
+		return
+	}
+	o.Mux.ServeHTTP(w, r)
+}
+
+// cloud returns a cloud using the mock as its loadbalancer API
+func (o *mockOctavia) cloud() *openstackCloud {
+	return &openstackCloud{
+		lbClient: serviceClient(o.server.URL),
+	}
+}
+
+// recorded returns the requests made so far
+func (o *mockOctavia) recorded() []string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return slices.Clone(o.requests)
+}
+
+// count returns how often the request was made
+func (o *mockOctavia) count(request string) int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	count := 0
+	for _, r := range o.requests {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+// body returns the body of the last such request
+func (o *mockOctavia) body(request string) string {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.bodies[request]
+}
+
 func compareErrors(t *testing.T, actual, expected error) {
 	t.Helper()
 	if pointersAreBothNil(t, "errors", actual, expected) {
@@ -662,3 +788,42 @@ func Test_BuildClients(t *testing.T) {
 		})
 	}
 }
+
+func Test_UseLoadBalancerTLSPolicy(t *testing.T) {
+	tests := []struct {
+		desc     string
+		versions string
+		expected bool
+	}{
+		{
+			desc:     "Octavia API 2.15",
+			versions: `{"versions": [{"id": "v2.0", "status": "SUPPORTED"}, {"id": "v2.15", "status": "CURRENT"}]}`,
+			expected: false,
+		},
+		{
+			desc:     "Octavia API 2.17",
+			versions: `{"versions": [{"id": "v2.0", "status": "SUPPORTED"}, {"id": "v2.17", "status": "CURRENT"}]}`,
+			expected: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			mux := http.NewServeMux()
+			fixture(mux, "/", http.MethodGet, testCase.versions, http.StatusOK)
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+			use, err := cloud.UseLoadBalancerTLSPolicy()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if use != testCase.expected {
+				t.Errorf("expected %v, got %v", testCase.expected, use)
+			}
+		})
+	}
+}
@@ -19,9 +19,11 @@ package openstack
 import (
 	"context"
 	"fmt"
+	"time"
 
 	l3floatingip "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/layer3/floatingips"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
 	"k8s.io/kops/util/pkg/vfs"
 )
 
@@ -130,3 +132,98 @@ func deleteL3FloatingIP(c OpenstackCloud, id string) (err error) {
 	}
 	return err
 }
+
+// loadbalancerVIPPortTimeout is how long to wait for a loadbalancer to report its VIP port
+var loadbalancerVIPPortTimeout = 5 * time.Minute
+
+// getLBVIPPortID polls the loadbalancer until Octavia has allocated its VIP port
+func getLBVIPPortID(c OpenstackCloud, lbID string) (string, error) {
+	var portID string
+	err := wait.PollUntilContextTimeout(context.TODO(), loadbalancerActivePollInterval, loadbalancerVIPPortTimeout, true, func(ctx context.Context) (bool, error) {
+		lb, err := c.GetLB(lbID)
+		if err != nil {
+			return false, err
+		}
+		if lb.ProvisioningStatus == "ERROR" {
+			return false, fmt.Errorf("%w: loadbalancer %s has gone into ERROR state", ErrLoadBalancerProvisioningFailed, lbID)
+		}
+		portID = lb.VipPortID
+		if portID == "" {
+			klog.V(4).Infof("Waiting for loadbalancer %s to have a VIP port", lbID)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting VIP port of loadbalancer %s: %w", lbID, err)
+	}
+	return portID, nil
+}
+
+func updateL3FloatingIPPort(c OpenstackCloud, floatingIPID string, portID string) error {
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		_, err := l3floatingip.Update(context.TODO(), c.NetworkingClient(), floatingIPID, l3floatingip.UpdateOpts{
+			PortID: &portID,
+		}).Extract()
+		if err != nil {
+			return false, fmt.Errorf("failed to update port of L3 floating ip %s: %v", floatingIPID, err)
+		}
+		return true, nil
+	})
+	if !done && err == nil {
+		err = wait.ErrWaitTimeout
+	}
+	return err
+}
+
+func (c *openstackCloud) AssociateFloatingIPToLB(lbID string, floatingIPID string) error {
+	return associateFloatingIPToLB(c, lbID, floatingIPID)
+}
+
+func associateFloatingIPToLB(c OpenstackCloud, lbID string, floatingIPID string) error {
+	portID, err := getLBVIPPortID(c, lbID)
+	if err != nil {
+		return err
+	}
+
+	fip, err := c.GetL3FloatingIP(floatingIPID)
+	if err != nil {
+		return err
+	}
+	if fip.PortID == portID {
+		klog.V(2).Infof("Floating IP %s is already associated with loadbalancer %s", floatingIPID, lbID)
+		return nil
+	}
+	if fip.PortID != "" {
+		return fmt.Errorf("floating IP %s is associated with port %s, not with the VIP port %s of loadbalancer %s", floatingIPID, fip.PortID, portID, lbID)
+	}
+
+	klog.V(2).Infof("Associating floating IP %s with VIP port %s of loadbalancer %s", floatingIPID, portID, lbID)
+	return updateL3FloatingIPPort(c, floatingIPID, portID)
+}
+
+func (c *openstackCloud) DisassociateFloatingIPFromLB(lbID string, floatingIPID string) error {
+	return disassociateFloatingIPFromLB(c, lbID, floatingIPID)
+}
+
+func disassociateFloatingIPFromLB(c OpenstackCloud, lbID string, floatingIPID string) error {
+	portID, err := getLBVIPPortID(c, lbID)
+	if err != nil {
+		return err
+	}
+
+	fip, err := c.GetL3FloatingIP(floatingIPID)
+	if err != nil {
+		return err
+	}
+	if fip.PortID == "" {
+		klog.V(2).Infof("Floating IP %s is not associated with any port", floatingIPID)
+		return nil
+	}
+	if fip.PortID != portID {
+		return fmt.Errorf("floating IP %s is associated with port %s, not with the VIP port %s of loadbalancer %s", floatingIPID, fip.PortID, portID, lbID)
+	}
+
+	klog.V(2).Infof("Disassociating floating IP %s from loadbalancer %s", floatingIPID, lbID)
+	return updateL3FloatingIPPort(c, floatingIPID, "")
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFloatingIP serves a loadbalancer and a floating IP that can be associated with ports
+type fakeFloatingIP struct {
+	mutex sync.Mutex
+	// lbPolls is how often the loadbalancer is returned without a VIP port
+	lbPolls int
+	portID  string
+	updates []string
+}
+
+func (f *fakeFloatingIP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	w.Header().Add("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "GET /lbaas/loadbalancers/lb":
+		vipPortID := "vip-port"
+		if f.lbPolls > 0 {
+			f.lbPolls--
+			vipPortID = ""
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"loadbalancer": {"id": "lb", "provisioning_status": "ACTIVE", "vip_port_id": %q}}`, vipPortID)
+	case "GET /floatingips/fip":
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"floatingip": {"id": "fip", "port_id": %q}}`, f.portID)
+	case "PUT /floatingips/fip":
+		var body struct {
+			FloatingIP struct {
+				PortID *string `json:"port_id"`
+			} `json:"floatingip"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.portID = ""
+		if body.FloatingIP.PortID != nil {
+			f.portID = *body.FloatingIP.PortID
+		}
+		f.updates = append(f.updates, f.portID)
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"floatingip": {"id": "fip", "port_id": %q}}`, f.portID)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func Test_AssociateFloatingIPToLB(t *testing.T) {
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+
+	fake := &fakeFloatingIP{lbPolls: 2}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient:      serviceClient(testServer.URL),
+		neutronClient: serviceClient(testServer.URL),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cloud.AssociateFloatingIPToLB("lb", "fip"); err != nil {
+			t.Fatalf("unexpected error associating floating IP: %v", err)
+		}
+	}
+	if fake.portID != "vip-port" {
+		t.Errorf("expected floating IP to be associated with the VIP port, got %q", fake.portID)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cloud.DisassociateFloatingIPFromLB("lb", "fip"); err != nil {
+			t.Fatalf("unexpected error disassociating floating IP: %v", err)
+		}
+	}
+	if fake.portID != "" {
+		t.Errorf("expected floating IP to be disassociated, got port %q", fake.portID)
+	}
+
+	// each change is only made once
+	if len(fake.updates) != 2 || fake.updates[0] != "vip-port" || fake.updates[1] != "" {
+		t.Errorf("unexpected floating IP updates %q", fake.updates)
+	}
+
+	fake.portID = "other-port"
+	if err := cloud.AssociateFloatingIPToLB("lb", "fip"); err == nil {
+		t.Errorf("expected error associating a floating IP that is in use")
+	}
+	if err := cloud.DisassociateFloatingIPFromLB("lb", "fip"); err == nil {
+		t.Errorf("expected error disassociating a floating IP from another port")
+	}
+	if len(fake.updates) != 2 {
+		t.Errorf("expected floating IP in use to be left unchanged, got updates %q", fake.updates)
+	}
+}
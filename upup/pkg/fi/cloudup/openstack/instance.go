@@ -47,6 +47,7 @@ const (
 	SERVER_GROUP_AFFINITY     = "serverGroupAffinity"
 	ALLOWED_ADDRESS_PAIR      = "allowedAddressPair"
 	SERVER_GROUP_NAME         = "serverGroupName"
+	LB_BACKUP_MEMBER          = "loadbalancerBackupMember"
 
 	defaultActiveTimeout = time.Second * 120
 	activeStatus         = "ACTIVE"
@@ -0,0 +1,71 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// ErrAmphoraeForbidden is returned, wrapping the API error, when the credentials are not allowed to use the
+// Octavia amphora API, which by default requires the admin role.
+var ErrAmphoraeForbidden = errors.New("listing amphorae requires the Octavia admin role")
+
+func (c *openstackCloud) ListAmphoraeForLB(lbID string) ([]amphorae.Amphora, error) {
+	return listAmphoraeForLB(c, lbID)
+}
+
+// listAmphoraeForLB returns the amphorae, the service VMs of the amphora provider, which implement the loadbalancer.
+// Their compute IDs, roles (STANDALONE, or MASTER and BACKUP in active-standby topologies) and statuses help to
+// diagnose failures that don't show on the loadbalancer itself, such as a failed failover.
+// A loadbalancer of another provider, such as OVN, has no amphorae.
+func listAmphoraeForLB(c OpenstackCloud, lbID string) ([]amphorae.Amphora, error) {
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	var list []amphorae.Amphora
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		allPages, err := amphorae.List(c.LoadBalancerClient(), amphorae.ListOpts{LoadbalancerID: lbID}).AllPages(context.TODO())
+		if err != nil {
+			// retrying won't grant the role
+			if gophercloud.ResponseCodeIs(err, http.StatusForbidden) || gophercloud.ResponseCodeIs(err, http.StatusUnauthorized) {
+				return true, fmt.Errorf("%w: %w", ErrAmphoraeForbidden, err)
+			}
+			return false, fmt.Errorf("failed to list amphorae of loadbalancer %s: %w", lbID, err)
+		}
+		list, err = amphorae.ExtractAmphorae(allPages)
+		if err != nil {
+			return false, fmt.Errorf("failed to extract amphorae of loadbalancer %s: %w", lbID, err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return list, err
+	}
+	if !done {
+		return list, wait.ErrWaitTimeout
+	}
+	return list, nil
+}
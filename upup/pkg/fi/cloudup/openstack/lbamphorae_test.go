@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_ListAmphoraeForLB(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		readBackoff = backoff
+	}(readBackoff)
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	tests := []struct {
+		desc          string
+		status        int
+		body          string
+		expectedRoles map[string]string
+		expectedError error
+	}{
+		{
+			desc:   "active-standby amphorae",
+			status: http.StatusOK,
+			body: `{"amphorae": [
+				{"id": "a1", "compute_id": "vm1", "role": "MASTER", "status": "ALLOCATED", "loadbalancer_id": "lb1"},
+				{"id": "a2", "compute_id": "vm2", "role": "BACKUP", "status": "ALLOCATED", "loadbalancer_id": "lb1"}
+			]}`,
+			expectedRoles: map[string]string{"vm1": "MASTER", "vm2": "BACKUP"},
+		},
+		{
+			desc:          "forbidden without admin role",
+			status:        http.StatusForbidden,
+			body:          `{"faultcode": "Client", "faultstring": "Policy does not allow this request to be performed."}`,
+			expectedError: ErrAmphoraeForbidden,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			requests := 0
+			mux := http.NewServeMux()
+			mux.HandleFunc("/octavia/amphorae", func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if lbID := r.URL.Query().Get("loadbalancer_id"); lbID != "lb1" {
+					t.Errorf("expected amphorae to be filtered by loadbalancer lb1, got %q", lbID)
+				}
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(testCase.status)
+				w.Write([]byte(testCase.body))
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			list, err := cloud.ListAmphoraeForLB("lb1")
+			if testCase.expectedError != nil {
+				if !errors.Is(err, testCase.expectedError) {
+					t.Fatalf("expected error %v, got %v", testCase.expectedError, err)
+				}
+				if requests != 1 {
+					t.Errorf("expected the forbidden request not to be retried, got %d requests", requests)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			roles := map[string]string{}
+			for _, amphora := range list {
+				roles[amphora.ComputeID] = amphora.Role
+			}
+			if len(roles) != len(testCase.expectedRoles) {
+				t.Fatalf("expected amphorae %v, got %v", testCase.expectedRoles, roles)
+			}
+			for computeID, role := range testCase.expectedRoles {
+				if roles[computeID] != role {
+					t.Errorf("expected amphora on %s to have role %s, got %q", computeID, role, roles[computeID])
+				}
+			}
+		})
+	}
+}
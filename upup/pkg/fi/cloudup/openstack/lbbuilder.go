@@ -0,0 +1,325 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/klog/v2"
+)
+
+// defaultLBBuilderTimeout is how long LBBuilder waits for the loadbalancer to become ACTIVE after each step
+const defaultLBBuilderTimeout = 5 * time.Minute
+
+// LBTopology holds the objects created by LBBuilder.
+// Objects that were not requested are nil.
+type LBTopology struct {
+	LoadBalancer *loadbalancers.LoadBalancer
+	Listener     *listeners.Listener
+	Pool         *v2pools.Pool
+	Monitor      *monitors.Monitor
+	Members      []v2pools.Member
+
+	// AvailabilityZone is the zone the loadbalancer was created in, if WithAvailabilityZones was used
+	AvailabilityZone string
+}
+
+// LBBuilder creates a loadbalancer together with its listener, pool, monitor and members.
+// Octavia rejects changes to a loadbalancer that is not ACTIVE, so every step waits for the
+// loadbalancer to become ACTIVE again before the next one, and the IDs of the created objects
+// are passed on to their children. Names are shortened with NormalizeLBName.
+type LBBuilder struct {
+	cloud        OpenstackCloud
+	timeout      time.Duration
+	pollInterval time.Duration
+
+	lbOpts            loadbalancers.CreateOpts
+	availabilityZones []string
+	listenerOpts      *listeners.CreateOpts
+	poolOpts          *v2pools.CreateOpts
+	monitorSpec       *MonitorSpec
+	members           []MemberSpec
+}
+
+// NewLBBuilder returns a builder for a loadbalancer created with opts.
+func NewLBBuilder(c OpenstackCloud, opts loadbalancers.CreateOpts) *LBBuilder {
+	opts.Name = NormalizeLBName(opts.Name)
+	return &LBBuilder{
+		cloud:   c,
+		timeout: defaultLBBuilderTimeout,
+		lbOpts:  opts,
+	}
+}
+
+// WithTimeout sets how long to wait for the loadbalancer to become ACTIVE after each step.
+func (b *LBBuilder) WithTimeout(timeout time.Duration) *LBBuilder {
+	b.timeout = timeout
+	return b
+}
+
+// WithPollInterval sets how often the provisioning status of the loadbalancer is checked while waiting, defaulting to 2 seconds.
+func (b *LBBuilder) WithPollInterval(interval time.Duration) *LBBuilder {
+	b.pollInterval = interval
+	return b
+}
+
+// waitOptions returns how long and how often each step waits for the loadbalancer
+func (b *LBBuilder) waitOptions() lbWaitOptions {
+	return lbWaitOptions{Timeout: b.timeout, PollInterval: b.pollInterval}
+}
+
+// WithAvailabilityZones creates the loadbalancer in the first of the zones where it can be created,
+// so that a zone without capacity falls back to the next one.
+func (b *LBBuilder) WithAvailabilityZones(zones ...string) *LBBuilder {
+	b.availabilityZones = append(b.availabilityZones, zones...)
+	return b
+}
+
+// WithListener adds a listener to the loadbalancer; its LoadbalancerID is set by Build.
+func (b *LBBuilder) WithListener(opts listeners.CreateOpts) *LBBuilder {
+	opts.Name = NormalizeLBName(opts.Name)
+	b.listenerOpts = &opts
+	return b
+}
+
+// WithPool adds a pool, which becomes the default pool of the listener if there is one.
+// Its ListenerID or LoadbalancerID is set by Build.
+func (b *LBBuilder) WithPool(opts v2pools.CreateOpts) *LBBuilder {
+	opts.Name = NormalizeLBName(opts.Name)
+	b.poolOpts = &opts
+	return b
+}
+
+// WithMonitor adds a health monitor to the pool; its PoolID is set by Build.
+func (b *LBBuilder) WithMonitor(spec MonitorSpec) *LBBuilder {
+	spec.Name = NormalizeLBName(spec.Name)
+	b.monitorSpec = &spec
+	return b
+}
+
+// WithMembers adds members to the pool.
+func (b *LBBuilder) WithMembers(members ...MemberSpec) *LBBuilder {
+	b.members = append(b.members, members...)
+	return b
+}
+
+// validate checks the requested topology before anything is created
+func (b *LBBuilder) validate() error {
+	if b.lbOpts.AvailabilityZone != "" && len(b.availabilityZones) != 0 {
+		return fmt.Errorf("only one of AvailabilityZone and WithAvailabilityZones can be set")
+	}
+	if b.poolOpts == nil {
+		if b.monitorSpec != nil {
+			return fmt.Errorf("cannot add a monitor without a pool")
+		}
+		if len(b.members) != 0 {
+			return fmt.Errorf("cannot add members without a pool")
+		}
+	}
+	return ValidateLBTopology(b.topologySpec())
+}
+
+// topologySpec returns the requested topology, for ValidateLBTopology
+func (b *LBBuilder) topologySpec() LBTopologySpec {
+	spec := LBTopologySpec{
+		LoadBalancer: b.lbOpts,
+	}
+	if b.listenerOpts != nil {
+		spec.Listeners = append(spec.Listeners, *b.listenerOpts)
+	}
+	if b.poolOpts != nil {
+		pool := LBPoolSpec{
+			Pool:    *b.poolOpts,
+			Monitor: b.monitorSpec,
+			Members: b.members,
+		}
+		if b.listenerOpts != nil {
+			pool.Listener = b.listenerOpts.Name
+		}
+		spec.Pools = append(spec.Pools, pool)
+	}
+	return spec
+}
+
+// Build creates the loadbalancer and its children in order, and returns the created topology.
+// If any step fails, the loadbalancer is deleted again together with everything created so far.
+func (b *LBBuilder) Build(ctx context.Context) (*LBTopology, error) {
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+
+	topology := &LBTopology{}
+	lb, err := b.createLoadBalancer(topology)
+	if err != nil {
+		if lb != nil {
+			return nil, b.rollback(lb.ID, err)
+		}
+		return nil, err
+	}
+	topology.LoadBalancer = lb
+
+	if err := b.buildChildren(ctx, topology); err != nil {
+		return nil, b.rollback(lb.ID, err)
+	}
+	return topology, nil
+}
+
+// createLoadBalancer creates the loadbalancer, trying each of the availability zones in order if there are any.
+// A loadbalancer that is returned with an error was not cleaned up, and must be rolled back.
+func (b *LBBuilder) createLoadBalancer(topology *LBTopology) (*loadbalancers.LoadBalancer, error) {
+	if len(b.availabilityZones) == 0 {
+		return createLBAndWait(b.cloud, b.lbOpts, b.waitOptions())
+	}
+
+	zones, err := b.cloud.ListLBAvailabilityZones()
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool)
+	for _, zone := range zones {
+		enabled[zone.Name] = zone.Enabled
+	}
+
+	var errs []error
+	for _, zone := range b.availabilityZones {
+		isEnabled, exists := enabled[zone]
+		if !exists {
+			errs = append(errs, fmt.Errorf("loadbalancer availability zone %q does not exist", zone))
+			continue
+		}
+		if !isEnabled {
+			errs = append(errs, fmt.Errorf("loadbalancer availability zone %q is disabled", zone))
+			continue
+		}
+
+		opts := b.lbOpts
+		opts.AvailabilityZone = zone
+		lb, err := createLBAndWait(b.cloud, opts, b.waitOptions())
+		if err == nil {
+			klog.V(2).Infof("created loadbalancer %s in availability zone %q", lb.ID, zone)
+			topology.AvailabilityZone = zone
+			return lb, nil
+		}
+		if lb != nil {
+			// the loadbalancer may still become ACTIVE, so it is not safe to create another one
+			return lb, err
+		}
+		klog.Warningf("failed to create loadbalancer in availability zone %q, trying the next one: %v", zone, err)
+		errs = append(errs, fmt.Errorf("availability zone %q: %w", zone, err))
+	}
+	return nil, fmt.Errorf("failed to create loadbalancer in any of the availability zones %v: %w", b.availabilityZones, errors.Join(errs...))
+}
+
+// buildChildren creates the children of the loadbalancer in topology
+func (b *LBBuilder) buildChildren(ctx context.Context, topology *LBTopology) error {
+	lbID := topology.LoadBalancer.ID
+
+	if b.listenerOpts != nil {
+		opts := *b.listenerOpts
+		opts.LoadbalancerID = lbID
+		listener, err := b.cloud.CreateListener(opts)
+		if err != nil {
+			return fmt.Errorf("error creating listener: %w", err)
+		}
+		topology.Listener = listener
+		if err := b.waitForActive(ctx, topology); err != nil {
+			return err
+		}
+	}
+
+	if b.poolOpts != nil {
+		opts := *b.poolOpts
+		if topology.Listener != nil {
+			opts.ListenerID = topology.Listener.ID
+		} else {
+			opts.LoadbalancerID = lbID
+		}
+		pool, err := b.cloud.CreatePool(opts)
+		if err != nil {
+			return fmt.Errorf("error creating pool: %w", err)
+		}
+		topology.Pool = pool
+		if err := b.waitForActive(ctx, topology); err != nil {
+			return err
+		}
+	}
+
+	if b.monitorSpec != nil {
+		spec := *b.monitorSpec
+		spec.PoolID = topology.Pool.ID
+		var err error
+		spec.Type, err = MonitorTypeForPoolProtocol(topology.Pool.Protocol, spec.Type)
+		if err != nil {
+			return err
+		}
+		opts, err := BuildMonitorCreateOpts(spec)
+		if err != nil {
+			return fmt.Errorf("invalid monitor: %w", err)
+		}
+		monitor, err := b.cloud.CreatePoolMonitor(opts)
+		if err != nil {
+			return fmt.Errorf("error creating monitor: %w", err)
+		}
+		topology.Monitor = monitor
+		if err := b.waitForActive(ctx, topology); err != nil {
+			return err
+		}
+	}
+
+	if len(b.members) != 0 {
+		poolID := topology.Pool.ID
+		if err := b.cloud.ReconcilePoolMembers(poolID, b.members, MemberPreservation{}); err != nil {
+			return fmt.Errorf("error adding members to pool %s: %w", poolID, err)
+		}
+		if err := b.waitForActive(ctx, topology); err != nil {
+			return err
+		}
+		members, err := b.cloud.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return fmt.Errorf("error listing members of pool %s: %w", poolID, err)
+		}
+		topology.Members = members
+	}
+
+	return nil
+}
+
+// waitForActive waits for the loadbalancer to become ACTIVE again, and records its refreshed state
+func (b *LBBuilder) waitForActive(ctx context.Context, topology *LBTopology) error {
+	lb, err := waitForLoadBalancerActive(ctx, b.cloud, topology.LoadBalancer.ID, b.waitOptions())
+	if lb != nil {
+		topology.LoadBalancer = lb
+	}
+	return err
+}
+
+// rollback deletes the loadbalancer after a failed build, returning err together with any error deleting it
+func (b *LBBuilder) rollback(lbID string, err error) error {
+	klog.Warningf("deleting loadbalancer %s after failed build: %v", lbID, err)
+	if deleteErr := b.cloud.DeleteLB(lbID, loadbalancers.DeleteOpts{Cascade: true}); deleteErr != nil {
+		klog.Errorf("failed to delete loadbalancer %s after failed build, it must be deleted manually: %v", lbID, deleteErr)
+		return fmt.Errorf("%w (deleting loadbalancer %s also failed: %v)", err, lbID, deleteErr)
+	}
+	return err
+}
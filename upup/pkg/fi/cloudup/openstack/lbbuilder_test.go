@@ -0,0 +1,309 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+)
+
+func Test_LBBuilder(t *testing.T) {
+	defer func(interval time.Duration, backoff wait.Backoff) {
+		loadbalancerActivePollInterval = interval
+		deleteBackoff = backoff
+	}(loadbalancerActivePollInterval, deleteBackoff)
+	loadbalancerActivePollInterval = time.Millisecond
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	newBuilder := func(cloud OpenstackCloud) *LBBuilder {
+		return NewLBBuilder(cloud, loadbalancers.CreateOpts{Name: "lb"}).
+			WithTimeout(time.Second).
+			WithListener(listeners.CreateOpts{Name: "listener", Protocol: listeners.ProtocolHTTP, ProtocolPort: 443}).
+			WithPool(v2pools.CreateOpts{Name: "pool", Protocol: v2pools.ProtocolHTTP, LBMethod: v2pools.LBMethodRoundRobin}).
+			WithMonitor(MonitorSpec{Name: "monitor", Delay: 10, Timeout: 5, MaxRetries: 3, URLPath: "/healthz"}).
+			WithMembers(MemberSpec{Name: "node", Address: "10.0.0.1", ProtocolPort: 443})
+	}
+	// every change puts the loadbalancer into PENDING_UPDATE until it is polled again
+	newOctavia := func(t *testing.T) *mockOctavia {
+		octavia := newMockOctavia(t)
+		octavia.AsyncProvisioning = true
+		return octavia
+	}
+	// failAfter puts the loadbalancers into ERROR after the request
+	failAfter := func(octavia *mockOctavia, failing string) {
+		octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+			if request != failing {
+				return false
+			}
+			octavia.Mux.ServeHTTP(w, r)
+			for _, lb := range octavia.LoadBalancers() {
+				octavia.SetProvisioningStatus(lb.ID, "ERROR")
+			}
+			return true
+		}
+	}
+	// reject answers the request with 400 Bad Request
+	reject := func(w http.ResponseWriter) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"faultstring": "Invalid input"}`)
+	}
+	// failZone makes an availability zone without capacity, where new loadbalancers go into ERROR
+	failZone := func(octavia *mockOctavia, zone string) {
+		octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+			if request != "POST /lbaas/loadbalancers" {
+				return false
+			}
+			octavia.Mux.ServeHTTP(w, r)
+			for _, lb := range octavia.LoadBalancers() {
+				if lb.AvailabilityZone == zone && lb.ProvisioningStatus == "PENDING_CREATE" {
+					octavia.SetProvisioningStatus(lb.ID, "ERROR")
+				}
+			}
+			return true
+		}
+	}
+
+	t.Run("creates the topology in order", func(t *testing.T) {
+		octavia := newOctavia(t)
+
+		topology, err := newBuilder(octavia.cloud()).Build(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expectedRequests := []string{
+			"POST /lbaas/loadbalancers",
+			"POST /lbaas/listeners",
+			"POST /lbaas/pools",
+			"GET /lbaas/pools/{id}",
+			"POST /lbaas/healthmonitors",
+			"GET /lbaas/pools/{id}/members",
+			"GET /lbaas/pools/{id}",
+			"PUT /lbaas/pools/{id}/members",
+			"GET /lbaas/pools/{id}/members",
+		}
+		if actual, expected := strings.Join(octavia.recorded(), "\n"), strings.Join(expectedRequests, "\n"); actual != expected {
+			t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+		}
+
+		// the IDs of the created objects are passed on to their children
+		for request, expected := range map[string]string{
+			"POST /lbaas/listeners":      fmt.Sprintf(`"loadbalancer_id":%q`, topology.LoadBalancer.ID),
+			"POST /lbaas/pools":          fmt.Sprintf(`"listener_id":%q`, topology.Listener.ID),
+			"POST /lbaas/healthmonitors": fmt.Sprintf(`"pool_id":%q`, topology.Pool.ID),
+		} {
+			if body := octavia.body(request); !strings.Contains(body, expected) {
+				t.Errorf("expected %s to contain %s, got %s", request, expected, body)
+			}
+		}
+		if body := octavia.body("POST /lbaas/healthmonitors"); !strings.Contains(body, `"type":"HTTP"`) {
+			t.Errorf("expected monitor type to default from the pool protocol, got %s", body)
+		}
+
+		if topology.LoadBalancer.ProvisioningStatus != "ACTIVE" {
+			t.Errorf("expected ACTIVE loadbalancer, got %q", topology.LoadBalancer.ProvisioningStatus)
+		}
+		pools := octavia.Pools()
+		if len(pools) != 1 || pools[0].ID != topology.Pool.ID || pools[0].MonitorID != topology.Monitor.ID {
+			t.Errorf("unexpected topology %+v, pools %+v", topology, pools)
+		}
+		if len(topology.Members) != 1 || topology.Members[0].Name != "node" {
+			t.Errorf("expected member node, got %v", topology.Members)
+		}
+	})
+
+	t.Run("rolls back on failure", func(t *testing.T) {
+		octavia := newOctavia(t)
+		failAfter(octavia, "POST /lbaas/pools")
+
+		topology, err := newBuilder(octavia.cloud()).Build(context.TODO())
+		if err == nil {
+			t.Fatalf("expected error, got topology %+v", topology)
+		}
+		if !strings.Contains(err.Error(), "ERROR state") {
+			t.Errorf("expected loadbalancer ERROR state, got %v", err)
+		}
+
+		// the delete is repeated until the loadbalancer is gone
+		expectedRequests := []string{
+			"POST /lbaas/loadbalancers",
+			"POST /lbaas/listeners",
+			"POST /lbaas/pools",
+			"DELETE /lbaas/loadbalancers/{id}",
+			"DELETE /lbaas/loadbalancers/{id}",
+		}
+		if actual, expected := strings.Join(octavia.recorded(), "\n"), strings.Join(expectedRequests, "\n"); actual != expected {
+			t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+		}
+		if lbs := octavia.LoadBalancers(); len(lbs) != 0 {
+			t.Errorf("expected the loadbalancer to be deleted, got %v", lbs)
+		}
+	})
+
+	t.Run("deletes the loadbalancer when the listener is rejected", func(t *testing.T) {
+		octavia := newOctavia(t)
+		octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+			if request == "POST /lbaas/listeners" {
+				reject(w)
+				return true
+			}
+			return false
+		}
+
+		topology, err := newBuilder(octavia.cloud()).Build(context.TODO())
+		if err == nil {
+			t.Fatalf("expected error, got topology %+v", topology)
+		}
+		if !strings.Contains(err.Error(), "error creating listener") {
+			t.Errorf("expected the listener error to be returned, got %v", err)
+		}
+
+		expectedRequests := []string{
+			"POST /lbaas/loadbalancers",
+			"POST /lbaas/listeners",
+			"DELETE /lbaas/loadbalancers/{id}",
+			"DELETE /lbaas/loadbalancers/{id}",
+		}
+		if actual, expected := strings.Join(octavia.recorded(), "\n"), strings.Join(expectedRequests, "\n"); actual != expected {
+			t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+		}
+	})
+
+	t.Run("keeps the root cause when the rollback fails", func(t *testing.T) {
+		octavia := newOctavia(t)
+		octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+			switch request {
+			case "POST /lbaas/listeners":
+				reject(w)
+			case "DELETE /lbaas/loadbalancers/{id}":
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				return false
+			}
+			return true
+		}
+
+		_, err := newBuilder(octavia.cloud()).Build(context.TODO())
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		var rejected gophercloud.ErrUnexpectedResponseCode
+		if !errors.As(err, &rejected) || rejected.Actual != http.StatusBadRequest {
+			t.Errorf("expected the listener error to be the root cause, got %v", err)
+		}
+		lbs := octavia.LoadBalancers()
+		if len(lbs) != 1 || !strings.Contains(err.Error(), fmt.Sprintf("deleting loadbalancer %s also failed", lbs[0].ID)) {
+			t.Errorf("expected the failed rollback of %v to be reported, got %v", lbs, err)
+		}
+	})
+
+	t.Run("falls back to the next availability zone", func(t *testing.T) {
+		octavia := newOctavia(t)
+		octavia.AddAvailabilityZone(mockloadbalancer.AvailabilityZone{Name: "az1", Enabled: true})
+		octavia.AddAvailabilityZone(mockloadbalancer.AvailabilityZone{Name: "az2", Enabled: true})
+		octavia.AddAvailabilityZone(mockloadbalancer.AvailabilityZone{Name: "az3", Enabled: false})
+		failZone(octavia, "az1")
+
+		topology, err := NewLBBuilder(octavia.cloud(), loadbalancers.CreateOpts{Name: "lb"}).
+			WithTimeout(time.Second).
+			WithAvailabilityZones("az1", "az2").
+			Build(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if topology.AvailabilityZone != "az2" {
+			t.Errorf("expected loadbalancer in az2, got %q", topology.AvailabilityZone)
+		}
+
+		expectedRequests := []string{
+			"GET /lbaas/availabilityzones",
+			"POST /lbaas/loadbalancers",
+			"DELETE /lbaas/loadbalancers/{id}",
+			"DELETE /lbaas/loadbalancers/{id}",
+			"POST /lbaas/loadbalancers",
+		}
+		if actual, expected := strings.Join(octavia.recorded(), "\n"), strings.Join(expectedRequests, "\n"); actual != expected {
+			t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+		}
+		if lbs := octavia.LoadBalancers(); len(lbs) != 1 || lbs[0].AvailabilityZone != "az2" {
+			t.Errorf("expected a single loadbalancer in az2, got %v", lbs)
+		}
+	})
+
+	t.Run("reports every availability zone that failed", func(t *testing.T) {
+		octavia := newOctavia(t)
+		octavia.AddAvailabilityZone(mockloadbalancer.AvailabilityZone{Name: "az1", Enabled: true})
+		octavia.AddAvailabilityZone(mockloadbalancer.AvailabilityZone{Name: "az3", Enabled: false})
+		failZone(octavia, "az1")
+
+		topology, err := NewLBBuilder(octavia.cloud(), loadbalancers.CreateOpts{Name: "lb"}).
+			WithTimeout(time.Second).
+			WithAvailabilityZones("az1", "az2", "az3").
+			Build(context.TODO())
+		if err == nil {
+			t.Fatalf("expected error, got topology %+v", topology)
+		}
+		for _, expected := range []string{`availability zone "az1"`, "ERROR state", `"az2" does not exist`, `"az3" is disabled`} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("expected error containing %q, got %v", expected, err)
+			}
+		}
+		if !errors.Is(err, ErrLoadBalancerProvisioningFailed) {
+			t.Errorf("expected the provisioning failure to be wrapped, got %v", err)
+		}
+	})
+
+	t.Run("rejects an invalid topology before creating anything", func(t *testing.T) {
+		octavia := newOctavia(t)
+		cloud := octavia.cloud()
+
+		builders := map[string]*LBBuilder{
+			"monitor without pool": NewLBBuilder(cloud, loadbalancers.CreateOpts{}).
+				WithMonitor(MonitorSpec{Type: monitors.TypeTCP, Delay: 10, Timeout: 5, MaxRetries: 3}),
+			"members without pool": NewLBBuilder(cloud, loadbalancers.CreateOpts{}).
+				WithMembers(MemberSpec{Address: "10.0.0.1", ProtocolPort: 443}),
+			"invalid monitor": NewLBBuilder(cloud, loadbalancers.CreateOpts{}).
+				WithPool(v2pools.CreateOpts{Protocol: v2pools.ProtocolTCP}).
+				WithMonitor(MonitorSpec{Type: monitors.TypeHTTP, Delay: 10, Timeout: 5, MaxRetries: 3}),
+			"subnet and network": NewLBBuilder(cloud, loadbalancers.CreateOpts{VipSubnetID: "subnet", VipNetworkID: "network"}),
+			"network and port":   NewLBBuilder(cloud, loadbalancers.CreateOpts{VipNetworkID: "network", VipPortID: "port"}),
+			"zone and zones":     NewLBBuilder(cloud, loadbalancers.CreateOpts{AvailabilityZone: "az1"}).WithAvailabilityZones("az2"),
+		}
+		for desc, builder := range builders {
+			if _, err := builder.Build(context.TODO()); err == nil {
+				t.Errorf("%s: expected error", desc)
+			}
+		}
+		if requests := octavia.recorded(); len(requests) != 0 {
+			t.Errorf("expected no requests, got %v", requests)
+		}
+	})
+}
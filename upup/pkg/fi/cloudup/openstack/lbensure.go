@@ -0,0 +1,155 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"k8s.io/klog/v2"
+)
+
+// LoadBalancerSpec is the desired state of a loadbalancer, as converged by EnsureLoadBalancer.
+type LoadBalancerSpec struct {
+	// Name of the loadbalancer, which is shortened with NormalizeLBName.
+	Name string
+	// IdentityTag identifies the loadbalancer independently of its name, so that it is found again
+	// and renamed if its name changes. It is added to Tags.
+	IdentityTag string
+	// Tags are the tags of the loadbalancer, which replace any other tags.
+	Tags []string
+	// AdminStateUp is the administrative state of the loadbalancer, defaulting to up.
+	AdminStateUp *bool
+	// CreateOpts are used when the loadbalancer is created, for the fields that cannot be updated,
+	// such as the VIP. Their Name, Tags and AdminStateUp are set from the spec.
+	CreateOpts loadbalancers.CreateOpts
+	// Timeout is how long to wait for the loadbalancer to become ACTIVE, defaulting to 5 minutes.
+	Timeout time.Duration
+	// PollInterval is how often the provisioning status is checked while waiting, defaulting to 2 seconds.
+	PollInterval time.Duration
+}
+
+// tags returns the sorted tags of the loadbalancer, including the identity tag.
+func (s *LoadBalancerSpec) tags() []string {
+	tags := slices.Clone(s.Tags)
+	if s.IdentityTag != "" {
+		tags = append(tags, s.IdentityTag)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+func (c *openstackCloud) EnsureLoadBalancer(spec LoadBalancerSpec) (*loadbalancers.LoadBalancer, error) {
+	return ensureLoadBalancer(c, spec)
+}
+
+// ensureLoadBalancer creates the loadbalancer of the spec if it does not exist, or updates its name, tags and
+// administrative state if they differ, and then returns the loadbalancer once it is ACTIVE.
+// Calling it again with the same spec makes no further changes.
+func ensureLoadBalancer(c OpenstackCloud, spec LoadBalancerSpec) (*loadbalancers.LoadBalancer, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("loadbalancer name is required")
+	}
+	name := NormalizeLBName(spec.Name)
+	tags := spec.tags()
+	adminStateUp := true
+	if spec.AdminStateUp != nil {
+		adminStateUp = *spec.AdminStateUp
+	}
+	waitOpts := lbWaitOptions{Timeout: spec.Timeout, PollInterval: spec.PollInterval}
+	if waitOpts.Timeout == 0 {
+		waitOpts.Timeout = defaultLBBuilderTimeout
+	}
+
+	lb, err := findLoadBalancerForSpec(c, name, spec.IdentityTag)
+	if err != nil {
+		return nil, err
+	}
+
+	if lb == nil {
+		opts := spec.CreateOpts
+		opts.Name = name
+		opts.Tags = tags
+		opts.AdminStateUp = &adminStateUp
+		klog.V(2).Infof("Creating loadbalancer %q", name)
+		return createLBAndWait(c, opts, waitOpts)
+	}
+
+	var update loadbalancers.UpdateOpts
+	changed := false
+	if lb.Name != name {
+		update.Name = &name
+		changed = true
+	}
+	if current := slices.Sorted(slices.Values(lb.Tags)); !slices.Equal(slices.Compact(current), tags) {
+		update.Tags = &tags
+		changed = true
+	}
+	if lb.AdminStateUp != adminStateUp {
+		update.AdminStateUp = &adminStateUp
+		changed = true
+	}
+	if !changed {
+		if lb.ProvisioningStatus == activeStatus {
+			return lb, nil
+		}
+		return waitForLoadBalancerActive(context.TODO(), c, lb.ID, waitOpts)
+	}
+
+	// Octavia rejects updates to a loadbalancer that is not ACTIVE
+	if lb.ProvisioningStatus != activeStatus {
+		if _, err := waitForLoadBalancerActive(context.TODO(), c, lb.ID, waitOpts); err != nil {
+			return nil, err
+		}
+	}
+	klog.V(2).Infof("Updating loadbalancer %s to name %q, tags %v and admin state up %v", lb.ID, name, tags, adminStateUp)
+	if _, err := c.UpdateLB(lb.ID, update); err != nil {
+		return nil, err
+	}
+	return waitForLoadBalancerActive(context.TODO(), c, lb.ID, waitOpts)
+}
+
+// findLoadBalancerForSpec returns the loadbalancer with the name, or else the one with the identity tag, or nil if there is none.
+func findLoadBalancerForSpec(c OpenstackCloud, name string, identityTag string) (*loadbalancers.LoadBalancer, error) {
+	lb, err := c.GetLBByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if lb != nil || identityTag == "" {
+		return lb, nil
+	}
+
+	lbs, err := c.ListLBs(loadbalancers.ListOpts{Tags: []string{identityTag}})
+	if err != nil {
+		return nil, err
+	}
+	// filter again, in case the tag filter is not supported
+	lbs = slices.DeleteFunc(lbs, func(lb loadbalancers.LoadBalancer) bool {
+		return !slices.Contains(lb.Tags, identityTag)
+	})
+	switch len(lbs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &lbs[0], nil
+	default:
+		return nil, fmt.Errorf("found %d loadbalancers with tag %q", len(lbs), identityTag)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func Test_EnsureLoadBalancer(t *testing.T) {
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+
+	tests := []struct {
+		desc            string
+		existing        []loadbalancers.LoadBalancer
+		spec            LoadBalancerSpec
+		expectedCreates int
+		expectedUpdates int
+		// expected is the loadbalancer; a created one is expected with an empty ID
+		expected loadbalancers.LoadBalancer
+	}{
+		{
+			desc: "creates missing loadbalancer",
+			spec: LoadBalancerSpec{
+				Name:        "api.cluster.example.com",
+				IdentityTag: "KopsName=api",
+				Tags:        []string{"KubernetesCluster=cluster.example.com"},
+			},
+			expectedCreates: 1,
+			expected: loadbalancers.LoadBalancer{
+				Name:         "api.cluster.example.com",
+				Tags:         []string{"KopsName=api", "KubernetesCluster=cluster.example.com"},
+				AdminStateUp: true,
+			},
+		},
+		{
+			desc: "leaves matching loadbalancer alone",
+			existing: []loadbalancers.LoadBalancer{
+				{ID: "existing", Name: "api.cluster.example.com", Tags: []string{"KubernetesCluster=cluster.example.com", "KopsName=api"}, AdminStateUp: true, ProvisioningStatus: "ACTIVE"},
+			},
+			spec: LoadBalancerSpec{
+				Name:        "api.cluster.example.com",
+				IdentityTag: "KopsName=api",
+				Tags:        []string{"KubernetesCluster=cluster.example.com"},
+			},
+			expected: loadbalancers.LoadBalancer{
+				ID:           "existing",
+				Name:         "api.cluster.example.com",
+				Tags:         []string{"KubernetesCluster=cluster.example.com", "KopsName=api"},
+				AdminStateUp: true,
+			},
+		},
+		{
+			desc: "updates tags and admin state",
+			existing: []loadbalancers.LoadBalancer{
+				{ID: "existing", Name: "api.cluster.example.com", Tags: []string{"KopsName=api", "owner=team-a"}, AdminStateUp: true, ProvisioningStatus: "ACTIVE"},
+			},
+			spec: LoadBalancerSpec{
+				Name:         "api.cluster.example.com",
+				IdentityTag:  "KopsName=api",
+				Tags:         []string{"owner=team-b"},
+				AdminStateUp: fi.PtrTo(false),
+			},
+			expectedUpdates: 1,
+			expected: loadbalancers.LoadBalancer{
+				ID:           "existing",
+				Name:         "api.cluster.example.com",
+				Tags:         []string{"KopsName=api", "owner=team-b"},
+				AdminStateUp: false,
+			},
+		},
+		{
+			desc: "renames loadbalancer found by identity tag",
+			existing: []loadbalancers.LoadBalancer{
+				{ID: "existing", Name: "api.old.example.com", Tags: []string{"KopsName=api"}, AdminStateUp: true, ProvisioningStatus: "PENDING_UPDATE"},
+			},
+			spec: LoadBalancerSpec{
+				Name:        "api.cluster.example.com",
+				IdentityTag: "KopsName=api",
+			},
+			expectedUpdates: 1,
+			expected: loadbalancers.LoadBalancer{
+				ID:           "existing",
+				Name:         "api.cluster.example.com",
+				Tags:         []string{"KopsName=api"},
+				AdminStateUp: true,
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMockOctavia(t)
+			octavia.AsyncProvisioning = true
+			for _, lb := range testCase.existing {
+				octavia.AddLoadBalancer(lb)
+			}
+			cloud := octavia.cloud()
+			testCase.spec.Timeout = time.Second
+
+			expectedID := testCase.expected.ID
+
+			// the second call must find the loadbalancer converged, and change nothing
+			for i := 0; i < 2; i++ {
+				lb, err := cloud.EnsureLoadBalancer(testCase.spec)
+				if err != nil {
+					t.Fatalf("unexpected error on call %d: %v", i+1, err)
+				}
+				if lb.ProvisioningStatus != "ACTIVE" {
+					t.Errorf("expected ACTIVE loadbalancer on call %d, got %q", i+1, lb.ProvisioningStatus)
+				}
+				if expectedID == "" {
+					// the loadbalancer created by the first call
+					expectedID = lb.ID
+				}
+				if lb.ID != expectedID || lb.Name != testCase.expected.Name || lb.AdminStateUp != testCase.expected.AdminStateUp || !slices.Equal(lb.Tags, testCase.expected.Tags) {
+					t.Errorf("expected loadbalancer %s %q with tags %v and admin state up %v on call %d, got %s %q with tags %v and admin state up %v",
+						expectedID, testCase.expected.Name, testCase.expected.Tags, testCase.expected.AdminStateUp, i+1,
+						lb.ID, lb.Name, lb.Tags, lb.AdminStateUp)
+				}
+			}
+
+			if creates := octavia.count("POST /lbaas/loadbalancers"); creates != testCase.expectedCreates {
+				t.Errorf("expected %d creates, got %d", testCase.expectedCreates, creates)
+			}
+			if updates := octavia.count("PUT /lbaas/loadbalancers/existing"); updates != testCase.expectedUpdates {
+				t.Errorf("expected %d updates, got %d", testCase.expectedUpdates, updates)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+// The resource types passed to an LBEventSink
+const (
+	LBResourceLoadBalancer = "loadbalancer"
+	LBResourceListener     = "listener"
+	LBResourcePool         = "pool"
+)
+
+// LBEventSink is notified of the loadbalancers, listeners and pools created and deleted by kOps,
+// for example to record them in an audit or change-management system.
+// The callbacks are invoked after the change succeeded; name is empty when it is not known, as for deletions.
+type LBEventSink interface {
+	OnCreate(resourceType, id, name string)
+	OnDelete(resourceType, id, name string)
+}
+
+// notifyLBCreate tells the LBEventSink of the cloud, if any, that an object was created
+func notifyLBCreate(c OpenstackCloud, resourceType, id, name string) {
+	if sink := c.LBEventSink(); sink != nil {
+		sink.OnCreate(resourceType, id, name)
+	}
+}
+
+// notifyLBDelete tells the LBEventSink of the cloud, if any, that an object was deleted
+func notifyLBDelete(c OpenstackCloud, resourceType, id string) {
+	if sink := c.LBEventSink(); sink != nil {
+		sink.OnDelete(resourceType, id, "")
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// recordingLBEventSink records the events it receives as "op type id name"
+type recordingLBEventSink struct {
+	events []string
+}
+
+func (s *recordingLBEventSink) OnCreate(resourceType, id, name string) {
+	s.events = append(s.events, strings.Join([]string{"create", resourceType, id, name}, " "))
+}
+
+func (s *recordingLBEventSink) OnDelete(resourceType, id, name string) {
+	s.events = append(s.events, strings.Join([]string{"delete", resourceType, id, name}, " "))
+}
+
+func Test_LBEventSink(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		deleteBackoff = backoff
+	}(deleteBackoff)
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	mux := http.NewServeMux()
+	fixture(mux, "/lbaas/loadbalancers", http.MethodPost, `{"loadbalancer": {"id": "lb", "name": "api"}}`, http.StatusCreated)
+	fixture(mux, "/lbaas/listeners", http.MethodPost, `{"listener": {"id": "listener", "name": "api"}}`, http.StatusCreated)
+	fixture(mux, "/lbaas/pools", http.MethodPost, `{"pool": {"id": "pool", "name": "api-https"}}`, http.StatusCreated)
+	fixture(mux, "/lbaas/pools/gone", http.MethodDelete, ``, http.StatusNotFound)
+	// objects are deleted by the first request, and are gone afterwards
+	for _, path := range []string{"/lbaas/pools/pool", "/lbaas/listeners/listener", "/lbaas/loadbalancers/lb"} {
+		deleted := false
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodDelete {
+				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if deleted {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			deleted = true
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	sink := &recordingLBEventSink{}
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+	cloud.SetLBEventSink(sink)
+
+	if _, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"}); err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	if _, err := cloud.CreateListener(listeners.CreateOpts{Name: "api", Protocol: listeners.ProtocolTCP, ProtocolPort: 443}); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+	if _, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", Protocol: v2pools.ProtocolTCP, LBMethod: v2pools.LBMethodRoundRobin}); err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	if err := cloud.DeletePool("pool"); err != nil {
+		t.Fatalf("error deleting pool: %v", err)
+	}
+	// a pool that was already gone was not deleted by us
+	if err := cloud.DeletePool("gone"); err != nil {
+		t.Fatalf("error deleting pool: %v", err)
+	}
+	if err := cloud.DeleteListener("listener"); err != nil {
+		t.Fatalf("error deleting listener: %v", err)
+	}
+	if err := cloud.DeleteLB("lb", loadbalancers.DeleteOpts{}); err != nil {
+		t.Fatalf("error deleting loadbalancer: %v", err)
+	}
+
+	expected := []string{
+		"create loadbalancer lb api",
+		"create listener listener api",
+		"create pool pool api-https",
+		"delete pool pool ",
+		"delete listener listener ",
+		"delete loadbalancer lb ",
+	}
+	if actual := strings.Join(sink.events, "\n"); actual != strings.Join(expected, "\n") {
+		t.Errorf("unexpected events:\n%s\nexpected:\n%s", actual, strings.Join(expected, "\n"))
+	}
+
+	// without a sink, nothing is notified
+	cloud.SetLBEventSink(nil)
+	if _, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"}); err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	if len(sink.events) != len(expected) {
+		t.Errorf("unexpected events after removing the sink: %v", sink.events[len(expected):])
+	}
+}
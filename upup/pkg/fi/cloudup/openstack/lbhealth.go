@@ -0,0 +1,236 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+)
+
+// Operating statuses reported by Octavia
+const (
+	operatingStatusOnline = "ONLINE"
+	operatingStatusDown   = "DOWN"
+	operatingStatusError  = "ERROR"
+	// operatingStatusNoMonitor is reported for members of a pool without a health monitor, which receive traffic
+	operatingStatusNoMonitor = "NO_MONITOR"
+)
+
+// LBHealth is a snapshot of the status of a loadbalancer and its listeners, pools and members.
+// Its String method summarizes it on a single line, for logging.
+type LBHealth struct {
+	ID                 string
+	Name               string
+	ProvisioningStatus string
+	OperatingStatus    string
+	Listeners          []LBListenerHealth
+	Pools              []LBPoolHealth
+}
+
+// LBListenerHealth is the status of a listener of the loadbalancer
+type LBListenerHealth struct {
+	ID                 string
+	Name               string
+	ProvisioningStatus string
+	OperatingStatus    string
+}
+
+// LBPoolHealth is the status of a pool of the loadbalancer, with its members counted by operating status
+type LBPoolHealth struct {
+	ID                 string
+	Name               string
+	ProvisioningStatus string
+	OperatingStatus    string
+	MembersOnline      int
+	MembersDown        int
+	MembersError       int
+	// MembersOther counts members in any other operating status, such as NO_MONITOR or DRAINING
+	MembersOther int
+}
+
+// Healthy returns true if the loadbalancer and all its listeners and pools are ONLINE, and no member is DOWN or in ERROR.
+func (h *LBHealth) Healthy() bool {
+	if h.OperatingStatus != operatingStatusOnline {
+		return false
+	}
+	for _, listener := range h.Listeners {
+		if listener.OperatingStatus != operatingStatusOnline {
+			return false
+		}
+	}
+	for _, pool := range h.Pools {
+		if pool.OperatingStatus != operatingStatusOnline || pool.MembersDown != 0 || pool.MembersError != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *LBHealth) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "loadbalancer %s (%s) %s/%s", h.Name, h.ID, h.ProvisioningStatus, h.OperatingStatus)
+	for _, listener := range h.Listeners {
+		fmt.Fprintf(&b, "; listener %s %s/%s", listener.Name, listener.ProvisioningStatus, listener.OperatingStatus)
+	}
+	for _, pool := range h.Pools {
+		fmt.Fprintf(&b, "; pool %s %s/%s members online=%d down=%d error=%d other=%d",
+			pool.Name, pool.ProvisioningStatus, pool.OperatingStatus,
+			pool.MembersOnline, pool.MembersDown, pool.MembersError, pool.MembersOther)
+	}
+	return b.String()
+}
+
+func (c *openstackCloud) GetLBHealthSummary(lbID string) (*LBHealth, error) {
+	return getLBHealthSummary(c, lbID)
+}
+
+func getLBHealthSummary(c OpenstackCloud, lbID string) (*LBHealth, error) {
+	lb, err := c.GetLB(lbID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting loadbalancer %s: %v", lbID, err)
+	}
+	health := &LBHealth{
+		ID:                 lb.ID,
+		Name:               lb.Name,
+		ProvisioningStatus: lb.ProvisioningStatus,
+		OperatingStatus:    lb.OperatingStatus,
+	}
+
+	listenerList, err := c.ListListeners(listeners.ListOpts{LoadbalancerID: lbID})
+	if err != nil {
+		return nil, fmt.Errorf("error listing listeners of loadbalancer %s: %v", lbID, err)
+	}
+	for _, listener := range listenerList {
+		health.Listeners = append(health.Listeners, LBListenerHealth{
+			ID:                 listener.ID,
+			Name:               listener.Name,
+			ProvisioningStatus: listener.ProvisioningStatus,
+			OperatingStatus:    listener.OperatingStatus,
+		})
+	}
+
+	poolList, err := c.ListPools(v2pools.ListOpts{LoadbalancerID: lbID})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pools of loadbalancer %s: %v", lbID, err)
+	}
+	for _, pool := range poolList {
+		poolHealth := LBPoolHealth{
+			ID:                 pool.ID,
+			Name:               pool.Name,
+			ProvisioningStatus: pool.ProvisioningStatus,
+			OperatingStatus:    pool.OperatingStatus,
+		}
+		members, err := c.ListPoolMembers(pool.ID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return nil, fmt.Errorf("error listing members of pool %s: %v", pool.ID, err)
+		}
+		for _, member := range members {
+			switch member.OperatingStatus {
+			case operatingStatusOnline:
+				poolHealth.MembersOnline++
+			case operatingStatusDown:
+				poolHealth.MembersDown++
+			case operatingStatusError:
+				poolHealth.MembersError++
+			default:
+				poolHealth.MembersOther++
+			}
+		}
+		health.Pools = append(health.Pools, poolHealth)
+	}
+
+	return health, nil
+}
+
+// membersOnlinePollInterval is how often WaitForMembersOnline checks the operating status of the members
+var membersOnlinePollInterval = 5 * time.Second
+
+// MembersNotOnlineError is returned when the members of a pool do not come ONLINE in time.
+// It lists the members that were not online in the last snapshot, to pinpoint the unhealthy nodes.
+type MembersNotOnlineError struct {
+	PoolID  string
+	Elapsed time.Duration
+	// Members maps the PoolMemberKey of each member that is not online to its operating status
+	Members map[string]string
+}
+
+func (e *MembersNotOnlineError) Error() string {
+	keys := make([]string, 0, len(e.Members))
+	for key := range e.Members {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	statuses := make([]string, 0, len(keys))
+	for _, key := range keys {
+		statuses = append(statuses, fmt.Sprintf("%s=%s", key, e.Members[key]))
+	}
+	return fmt.Sprintf("members of pool %s did not come ONLINE after %s: %s",
+		e.PoolID, e.Elapsed.Round(time.Second), strings.Join(statuses, ", "))
+}
+
+// Unwrap allows callers to keep checking for wait.ErrWaitTimeout
+func (e *MembersNotOnlineError) Unwrap() error {
+	return wait.ErrWaitTimeout
+}
+
+func (c *openstackCloud) WaitForMembersOnline(poolID string, timeout time.Duration) error {
+	return waitForMembersOnline(c, poolID, timeout)
+}
+
+// waitForMembersOnline waits for all members of the pool to be ONLINE, or NO_MONITOR if the pool has no health monitor.
+// On timeout it returns a MembersNotOnlineError built from the last list of members.
+func waitForMembersOnline(c OpenstackCloud, poolID string, timeout time.Duration) error {
+	notOnline := map[string]string{}
+	start := time.Now()
+	err := wait.PollUntilContextTimeout(context.TODO(), membersOnlinePollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		members, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return false, err
+		}
+		notOnline = map[string]string{}
+		for _, member := range members {
+			if member.OperatingStatus != operatingStatusOnline && member.OperatingStatus != operatingStatusNoMonitor {
+				notOnline[PoolMemberKey(member.Address, member.ProtocolPort)] = member.OperatingStatus
+			}
+		}
+		if len(notOnline) != 0 {
+			klog.V(4).Infof("Waiting for %d of %d members of pool %s to be ONLINE", len(notOnline), len(members), poolID)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		if wait.Interrupted(err) {
+			return &MembersNotOnlineError{
+				PoolID:  poolID,
+				Elapsed: time.Since(start),
+				Members: notOnline,
+			}
+		}
+		return fmt.Errorf("error waiting for members of pool %s to be ONLINE: %w", poolID, err)
+	}
+	return nil
+}
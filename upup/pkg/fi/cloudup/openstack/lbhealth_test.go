@@ -0,0 +1,143 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_GetLBHealthSummary(t *testing.T) {
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	fixture(mux, "/lbaas/loadbalancers/lb", http.MethodGet,
+		`{"loadbalancer": {"id": "lb", "name": "api", "provisioning_status": "ACTIVE", "operating_status": "DEGRADED"}}`, http.StatusOK)
+	fixture(mux, "/lbaas/listeners", http.MethodGet,
+		`{"listeners": [{"id": "listener", "name": "api-https", "provisioning_status": "ACTIVE", "operating_status": "ONLINE"}]}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools", http.MethodGet,
+		`{"pools": [{"id": "pool", "name": "api-https", "provisioning_status": "ACTIVE", "operating_status": "DEGRADED"}]}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, `{"members": [
+		{"id": "a", "operating_status": "ONLINE"},
+		{"id": "b", "operating_status": "ONLINE"},
+		{"id": "c", "operating_status": "DOWN"},
+		{"id": "d", "operating_status": "ERROR"},
+		{"id": "e", "operating_status": "NO_MONITOR"}
+	]}`, http.StatusOK)
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	health, err := cloud.GetLBHealthSummary("lb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := &LBHealth{
+		ID:                 "lb",
+		Name:               "api",
+		ProvisioningStatus: "ACTIVE",
+		OperatingStatus:    "DEGRADED",
+		Listeners: []LBListenerHealth{
+			{ID: "listener", Name: "api-https", ProvisioningStatus: "ACTIVE", OperatingStatus: "ONLINE"},
+		},
+		Pools: []LBPoolHealth{
+			{ID: "pool", Name: "api-https", ProvisioningStatus: "ACTIVE", OperatingStatus: "DEGRADED", MembersOnline: 2, MembersDown: 1, MembersError: 1, MembersOther: 1},
+		},
+	}
+	if !reflect.DeepEqual(health, expected) {
+		t.Errorf("expected %+v, got %+v", expected, health)
+	}
+	if health.Healthy() {
+		t.Errorf("expected degraded loadbalancer not to be healthy")
+	}
+
+	expectedString := "loadbalancer api (lb) ACTIVE/DEGRADED; listener api-https ACTIVE/ONLINE; pool api-https ACTIVE/DEGRADED members online=2 down=1 error=1 other=1"
+	if health.String() != expectedString {
+		t.Errorf("expected %q, got %q", expectedString, health.String())
+	}
+}
+
+func Test_LBHealth_Healthy(t *testing.T) {
+	health := &LBHealth{
+		OperatingStatus: "ONLINE",
+		Listeners:       []LBListenerHealth{{OperatingStatus: "ONLINE"}},
+		Pools:           []LBPoolHealth{{OperatingStatus: "ONLINE", MembersOnline: 3, MembersOther: 1}},
+	}
+	if !health.Healthy() {
+		t.Errorf("expected %v to be healthy", health)
+	}
+
+	health.Pools[0].MembersDown = 1
+	if health.Healthy() {
+		t.Errorf("expected %v not to be healthy", health)
+	}
+}
+
+func Test_WaitForMembersOnline(t *testing.T) {
+	defer func(interval time.Duration) {
+		membersOnlinePollInterval = interval
+	}(membersOnlinePollInterval)
+	membersOnlinePollInterval = time.Millisecond
+
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	fixture(mux, "/lbaas/pools/online/members", http.MethodGet, `{"members": [
+		{"id": "a", "address": "10.0.0.1", "protocol_port": 443, "operating_status": "ONLINE"},
+		{"id": "b", "address": "10.0.0.2", "protocol_port": 443, "operating_status": "NO_MONITOR"}
+	]}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools/degraded/members", http.MethodGet, `{"members": [
+		{"id": "a", "address": "10.0.0.1", "protocol_port": 443, "operating_status": "ONLINE"},
+		{"id": "b", "address": "10.0.0.2", "protocol_port": 443, "operating_status": "DOWN"},
+		{"id": "c", "address": "10.0.0.3", "protocol_port": 443, "operating_status": "ERROR"}
+	]}`, http.StatusOK)
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	if err := cloud.WaitForMembersOnline("online", 50*time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := cloud.WaitForMembersOnline("degraded", 50*time.Millisecond)
+	var notOnline *MembersNotOnlineError
+	if !errors.As(err, &notOnline) {
+		t.Fatalf("expected MembersNotOnlineError, got %v", err)
+	}
+	if !wait.Interrupted(err) {
+		t.Errorf("expected error to be a wait timeout, got %v", err)
+	}
+	expectedMembers := map[string]string{"10.0.0.2:443": "DOWN", "10.0.0.3:443": "ERROR"}
+	if !reflect.DeepEqual(notOnline.Members, expectedMembers) {
+		t.Errorf("expected members %v, got %v", expectedMembers, notOnline.Members)
+	}
+	if !strings.Contains(err.Error(), "10.0.0.2:443=DOWN, 10.0.0.3:443=ERROR") {
+		t.Errorf("expected error to list the members that are not online, got %q", err.Error())
+	}
+}
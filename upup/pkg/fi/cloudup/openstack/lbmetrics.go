@@ -0,0 +1,248 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+var (
+	lbStatsLabels = []string{"loadbalancer_id", "loadbalancer_name"}
+
+	lbBytesInDesc = prometheus.NewDesc("kops_openstack_loadbalancer_bytes_in_total",
+		"Total bytes received by the OpenStack loadbalancer.", lbStatsLabels, nil)
+	lbBytesOutDesc = prometheus.NewDesc("kops_openstack_loadbalancer_bytes_out_total",
+		"Total bytes sent by the OpenStack loadbalancer.", lbStatsLabels, nil)
+	lbActiveConnectionsDesc = prometheus.NewDesc("kops_openstack_loadbalancer_active_connections",
+		"Connections currently active on the OpenStack loadbalancer.", lbStatsLabels, nil)
+	lbTotalConnectionsDesc = prometheus.NewDesc("kops_openstack_loadbalancer_connections_total",
+		"Total connections handled by the OpenStack loadbalancer.", lbStatsLabels, nil)
+)
+
+// lbStatsSample is the last statistics fetched for a loadbalancer
+type lbStatsSample struct {
+	name  string
+	stats loadbalancers.Stats
+}
+
+// LBStatsCollector is a prometheus.Collector exporting the statistics of OpenStack loadbalancers.
+// The statistics are fetched by Refresh or Run, so scrapes never call the OpenStack API.
+// Register it with prometheus.DefaultRegisterer to serve it next to the other kOps metrics.
+type LBStatsCollector struct {
+	cloud OpenstackCloud
+
+	mutex   sync.Mutex
+	samples map[string]lbStatsSample
+}
+
+var _ prometheus.Collector = &LBStatsCollector{}
+
+// NewLBStatsCollector returns a collector that fetches loadbalancer statistics from c.
+func NewLBStatsCollector(c OpenstackCloud) *LBStatsCollector {
+	return &LBStatsCollector{
+		cloud:   c,
+		samples: make(map[string]lbStatsSample),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (s *LBStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- lbBytesInDesc
+	ch <- lbBytesOutDesc
+	ch <- lbActiveConnectionsDesc
+	ch <- lbTotalConnectionsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (s *LBStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id, sample := range s.samples {
+		stats := sample.stats
+		ch <- prometheus.MustNewConstMetric(lbBytesInDesc, prometheus.CounterValue, float64(stats.BytesIn), id, sample.name)
+		ch <- prometheus.MustNewConstMetric(lbBytesOutDesc, prometheus.CounterValue, float64(stats.BytesOut), id, sample.name)
+		ch <- prometheus.MustNewConstMetric(lbActiveConnectionsDesc, prometheus.GaugeValue, float64(stats.ActiveConnections), id, sample.name)
+		ch <- prometheus.MustNewConstMetric(lbTotalConnectionsDesc, prometheus.CounterValue, float64(stats.TotalConnections), id, sample.name)
+	}
+}
+
+// Refresh fetches the statistics of the loadbalancers matching opts.
+// Loadbalancers that no longer exist stop being exported; a loadbalancer whose statistics
+// cannot be fetched keeps its previous sample, and the errors are returned together.
+func (s *LBStatsCollector) Refresh(opts loadbalancers.ListOptsBuilder) error {
+	lbs, err := s.cloud.ListLBs(opts)
+	if err != nil {
+		return fmt.Errorf("error listing loadbalancers: %w", err)
+	}
+
+	s.mutex.Lock()
+	previous := s.samples
+	s.mutex.Unlock()
+
+	samples := make(map[string]lbStatsSample, len(lbs))
+	var errs []error
+	for _, lb := range lbs {
+		stats, err := s.cloud.GetLBStats(lb.ID)
+		if err != nil || stats == nil {
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error getting statistics of loadbalancer %s: %w", lb.ID, err))
+			}
+			if sample, found := previous[lb.ID]; found {
+				samples[lb.ID] = sample
+			}
+			continue
+		}
+		samples[lb.ID] = lbStatsSample{
+			name:  lb.Name,
+			stats: *stats,
+		}
+	}
+
+	s.mutex.Lock()
+	s.samples = samples
+	s.mutex.Unlock()
+
+	return errors.Join(errs...)
+}
+
+// Run refreshes the statistics of the loadbalancers matching opts every interval, until ctx is cancelled.
+// Errors are logged and the next refresh is attempted as usual.
+func (s *LBStatsCollector) Run(ctx context.Context, opts loadbalancers.ListOptsBuilder, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(opts); err != nil {
+			klog.Warningf("error refreshing OpenStack loadbalancer statistics: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *openstackCloud) EstimatedPoolLoad(poolID string) (map[string]int, error) {
+	return estimatedPoolLoad(c, poolID)
+}
+
+// estimatedPoolLoad returns an estimate of the active connections of each member of the pool, by member ID, as a signal
+// for scaling the backend. It is not a measurement: Octavia only reports connections per listener, so the active
+// connections of the listeners of the pool are shared among the members that receive traffic in proportion to their
+// weight, as round robin balancing would. Uneven connection lengths, session persistence or least connections
+// balancing make the real load differ. A listener that also sends traffic to other pools through L7 policies has
+// all its connections counted in every one of them.
+// Members that receive no traffic, because they are down, drained or backups, have no connections.
+func estimatedPoolLoad(c OpenstackCloud, poolID string) (map[string]int, error) {
+	pool, err := c.GetPool(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pool %s: %w", poolID, err)
+	}
+	members, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing members of pool %s: %w", poolID, err)
+	}
+
+	connections := 0
+	for _, listener := range pool.Listeners {
+		stats, err := getListenerStats(c, listener.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting statistics of listener %s: %w", listener.ID, err)
+		}
+		if stats != nil {
+			connections += stats.ActiveConnections
+		}
+	}
+
+	load := make(map[string]int, len(members))
+	var serving []v2pools.Member
+	totalWeight := 0
+	for _, member := range members {
+		load[member.ID] = 0
+		if memberReceivesTraffic(member) {
+			serving = append(serving, member)
+			totalWeight += member.Weight
+		}
+	}
+	if totalWeight == 0 {
+		return load, nil
+	}
+
+	// hand out what is left after rounding down one connection at a time, so that the shares add up
+	slices.SortFunc(serving, func(a, b v2pools.Member) int { return strings.Compare(a.ID, b.ID) })
+	remaining := connections
+	for _, member := range serving {
+		share := connections * member.Weight / totalWeight
+		load[member.ID] = share
+		remaining -= share
+	}
+	for i := 0; remaining > 0; i = (i + 1) % len(serving) {
+		load[serving[i].ID]++
+		remaining--
+	}
+	return load, nil
+}
+
+// memberReceivesTraffic returns whether the loadbalancer sends new connections to the member
+func memberReceivesTraffic(member v2pools.Member) bool {
+	if !member.AdminStateUp || member.Weight == 0 || member.Backup {
+		return false
+	}
+	switch member.OperatingStatus {
+	case "ERROR", "OFFLINE", "DRAINING":
+		return false
+	}
+	return true
+}
+
+func getListenerStats(c OpenstackCloud, listenerID string) (stats *listeners.Stats, err error) {
+	if c.LoadBalancerClient() == nil {
+		return stats, nil
+	}
+
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		stats, err = listeners.GetStats(context.TODO(), c.LoadBalancerClient(), listenerID).Extract()
+		if err != nil {
+			return false, fmt.Errorf("error getting listener stats: %w", err)
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return stats, err
+	}
+	return stats, nil
+}
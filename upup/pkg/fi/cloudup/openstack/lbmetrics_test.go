@@ -0,0 +1,209 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// newLBStatsOctavia serves the loadbalancers with the given IDs, named api.<id>, and their statistics
+func newLBStatsOctavia(t *testing.T, ids ...string) *mockOctavia {
+	octavia := newMockOctavia(t)
+	for _, id := range ids {
+		octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: id, Name: "api." + id})
+		octavia.SetStats(id, loadbalancers.Stats{BytesIn: len(id) * 100, BytesOut: 200, ActiveConnections: 3, TotalConnections: 40})
+	}
+	return octavia
+}
+
+// gatherLBStats returns the exported metrics as "name{id,name} value" lines
+func gatherLBStats(t *testing.T, collector *LBStatsCollector) []string {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("error registering collector: %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering metrics: %v", err)
+	}
+
+	var lines []string
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			value := metric.GetGauge().GetValue()
+			if metric.GetCounter() != nil {
+				value = metric.GetCounter().GetValue()
+			}
+			lines = append(lines, fmt.Sprintf("%s{%s,%s} %v", family.GetName(), labels["loadbalancer_id"], labels["loadbalancer_name"], value))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func Test_LBStatsCollector(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		readBackoff = backoff
+	}(readBackoff)
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Steps: 1}
+
+	octavia := newLBStatsOctavia(t, "lb1", "lb22")
+	collector := NewLBStatsCollector(octavia.cloud())
+
+	if err := collector.Refresh(loadbalancers.ListOpts{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"kops_openstack_loadbalancer_active_connections{lb1,api.lb1} 3",
+		"kops_openstack_loadbalancer_active_connections{lb22,api.lb22} 3",
+		"kops_openstack_loadbalancer_bytes_in_total{lb1,api.lb1} 300",
+		"kops_openstack_loadbalancer_bytes_in_total{lb22,api.lb22} 400",
+		"kops_openstack_loadbalancer_bytes_out_total{lb1,api.lb1} 200",
+		"kops_openstack_loadbalancer_bytes_out_total{lb22,api.lb22} 200",
+		"kops_openstack_loadbalancer_connections_total{lb1,api.lb1} 40",
+		"kops_openstack_loadbalancer_connections_total{lb22,api.lb22} 40",
+	}
+	if actual := gatherLBStats(t, collector); strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected metrics:\n%s\nexpected:\n%s", strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+	}
+
+	// a deleted loadbalancer is no longer exported, and one that fails keeps its last sample
+	if err := loadbalancers.Delete(context.TODO(), octavia.ServiceClient(), "lb1", nil).ExtractErr(); err != nil {
+		t.Fatalf("error deleting loadbalancer: %v", err)
+	}
+	octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+		if request != "GET /lbaas/loadbalancers/lb22/stats" {
+			return false
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	if err := collector.Refresh(loadbalancers.ListOpts{}); err == nil {
+		t.Errorf("expected error getting statistics")
+	}
+	actual := gatherLBStats(t, collector)
+	if len(actual) != 4 || !strings.Contains(strings.Join(actual, "\n"), "kops_openstack_loadbalancer_bytes_in_total{lb22,api.lb22} 400") {
+		t.Errorf("expected only the last sample of lb22, got:\n%s", strings.Join(actual, "\n"))
+	}
+}
+
+func Test_LBStatsCollectorRun(t *testing.T) {
+	octavia := newLBStatsOctavia(t, "lb1")
+	collector := NewLBStatsCollector(octavia.cloud())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collector.Run(ctx, loadbalancers.ListOpts{}, time.Millisecond)
+		close(done)
+	}()
+
+	err := wait.PollUntilContextTimeout(context.Background(), time.Millisecond, 10*time.Second, true, func(ctx context.Context) (bool, error) {
+		return octavia.count("GET /lbaas/loadbalancers") >= 3, nil
+	})
+	if err != nil {
+		t.Fatalf("expected statistics to be refreshed repeatedly: %v", err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected Run to return after the context is cancelled")
+	}
+	if len(gatherLBStats(t, collector)) != 4 {
+		t.Errorf("expected metrics of lb1")
+	}
+}
+
+func Test_EstimatedPoolLoad(t *testing.T) {
+	tests := []struct {
+		desc      string
+		listeners string
+		members   string
+		expected  map[string]int
+	}{
+		{
+			desc:      "shared by weight",
+			listeners: `[{"id": "https"}, {"id": "http"}]`,
+			members: `[
+				{"id": "a", "weight": 1, "admin_state_up": true, "operating_status": "ONLINE"},
+				{"id": "b", "weight": 1, "admin_state_up": true, "operating_status": "NO_MONITOR"},
+				{"id": "c", "weight": 2, "admin_state_up": true, "operating_status": "ONLINE"}
+			]`,
+			expected: map[string]int{"a": 3, "b": 3, "c": 5},
+		},
+		{
+			desc:      "members without traffic have no connections",
+			listeners: `[{"id": "https"}]`,
+			members: `[
+				{"id": "a", "weight": 1, "admin_state_up": true, "operating_status": "ONLINE"},
+				{"id": "down", "weight": 1, "admin_state_up": true, "operating_status": "ERROR"},
+				{"id": "disabled", "weight": 1, "admin_state_up": false, "operating_status": "OFFLINE"},
+				{"id": "drained", "weight": 0, "admin_state_up": true, "operating_status": "ONLINE"},
+				{"id": "backup", "weight": 1, "admin_state_up": true, "operating_status": "ONLINE", "backup": true}
+			]`,
+			expected: map[string]int{"a": 7, "down": 0, "disabled": 0, "drained": 0, "backup": 0},
+		},
+		{
+			desc:      "pool without listener",
+			listeners: `[]`,
+			members:   `[{"id": "a", "weight": 1, "admin_state_up": true, "operating_status": "ONLINE"}]`,
+			expected:  map[string]int{"a": 0},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			mux := http.NewServeMux()
+			fixture(mux, "/lbaas/pools/pool", http.MethodGet, fmt.Sprintf(`{"pool": {"id": "pool", "listeners": %s}}`, testCase.listeners), http.StatusOK)
+			fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, fmt.Sprintf(`{"members": %s}`, testCase.members), http.StatusOK)
+			fixture(mux, "/lbaas/listeners/https/stats", http.MethodGet, `{"stats": {"active_connections": 7, "total_connections": 100}}`, http.StatusOK)
+			fixture(mux, "/lbaas/listeners/http/stats", http.MethodGet, `{"stats": {"active_connections": 4, "total_connections": 20}}`, http.StatusOK)
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			load, err := cloud.EstimatedPoolLoad("pool")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(load, testCase.expected) {
+				t.Errorf("expected load %v, got %v", testCase.expected, load)
+			}
+		})
+	}
+}
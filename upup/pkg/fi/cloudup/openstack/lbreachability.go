@@ -0,0 +1,106 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"net"
+	"slices"
+
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/klog/v2"
+)
+
+func (c *openstackCloud) FindUnreachableMemberAddresses(poolID string, addresses []string) ([]string, error) {
+	return findUnreachableMemberAddresses(c, poolID, addresses)
+}
+
+// findUnreachableMemberAddresses returns the addresses, in the given order, that are in none of the subnets the loadbalancer
+// of the pool can reach: its VIP subnet, and the subnets of the members of the pool, which Octavia plugs the amphorae into.
+// The CIDRs of the subnets are read from Neutron. Members at such addresses would never pass their health checks,
+// so they are better refused before they are created.
+func findUnreachableMemberAddresses(c OpenstackCloud, poolID string, addresses []string) ([]string, error) {
+	for _, address := range addresses {
+		if net.ParseIP(address) == nil {
+			return nil, fmt.Errorf("invalid member address %q", address)
+		}
+	}
+
+	subnetIDs, err := lbReachableSubnetIDs(c, poolID)
+	if err != nil {
+		return nil, err
+	}
+	var cidrs []*net.IPNet
+	for _, subnetID := range subnetIDs {
+		subnet, err := c.GetSubnet(subnetID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting subnet %s: %w", subnetID, err)
+		}
+		_, cidr, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("subnet %s has invalid CIDR %q: %w", subnetID, subnet.CIDR, err)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
+	var unreachable []string
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		reachable := slices.ContainsFunc(cidrs, func(cidr *net.IPNet) bool { return cidr.Contains(ip) })
+		if !reachable {
+			unreachable = append(unreachable, address)
+		}
+	}
+	if len(unreachable) != 0 {
+		klog.V(2).Infof("%d of %d member addresses are not reachable from the loadbalancer of pool %s: %v", len(unreachable), len(addresses), poolID, unreachable)
+	}
+	return unreachable, nil
+}
+
+// lbReachableSubnetIDs returns the IDs of the VIP subnet of the loadbalancer of the pool, and of the subnets of the members of the pool
+func lbReachableSubnetIDs(c OpenstackCloud, poolID string) ([]string, error) {
+	pool, err := c.GetPool(poolID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pool %s: %w", poolID, err)
+	}
+	if len(pool.Loadbalancers) == 0 {
+		return nil, fmt.Errorf("pool %s does not belong to a loadbalancer", poolID)
+	}
+	lbID := pool.Loadbalancers[0].ID
+	lb, err := c.GetLB(lbID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting loadbalancer %s: %w", lbID, err)
+	}
+
+	var subnetIDs []string
+	if lb.VipSubnetID != "" {
+		subnetIDs = append(subnetIDs, lb.VipSubnetID)
+	}
+	members, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing members of pool %s: %w", poolID, err)
+	}
+	for _, member := range members {
+		if member.SubnetID != "" && !slices.Contains(subnetIDs, member.SubnetID) {
+			subnetIDs = append(subnetIDs, member.SubnetID)
+		}
+	}
+	if len(subnetIDs) == 0 {
+		return nil, fmt.Errorf("loadbalancer %s of pool %s has no VIP subnet", lbID, poolID)
+	}
+	return subnetIDs, nil
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_FindUnreachableMemberAddresses(t *testing.T) {
+	mux := http.NewServeMux()
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	fixture(mux, "/lbaas/pools/pool", http.MethodGet, `{"pool": {"id": "pool", "loadbalancers": [{"id": "lb"}]}}`, http.StatusOK)
+	fixture(mux, "/lbaas/loadbalancers/lb", http.MethodGet, `{"loadbalancer": {"id": "lb", "vip_subnet_id": "vip"}}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, `{"members": [
+		{"id": "a", "address": "10.1.0.5", "protocol_port": 443, "subnet_id": "nodes"},
+		{"id": "b", "address": "10.1.0.6", "protocol_port": 443}
+	]}`, http.StatusOK)
+	fixture(mux, "/subnets/vip", http.MethodGet, `{"subnet": {"id": "vip", "cidr": "10.0.0.0/24"}}`, http.StatusOK)
+	fixture(mux, "/subnets/nodes", http.MethodGet, `{"subnet": {"id": "nodes", "cidr": "10.1.0.0/16"}}`, http.StatusOK)
+
+	cloud := &openstackCloud{
+		lbClient:      serviceClient(testServer.URL),
+		neutronClient: serviceClient(testServer.URL),
+	}
+
+	// 10.0.0.10 is in the VIP subnet and 10.1.2.3 in the subnet of a member, while 192.168.0.1 is in neither
+	unreachable, err := cloud.FindUnreachableMemberAddresses("pool", []string{"10.0.0.10", "192.168.0.1", "10.1.2.3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"192.168.0.1"}; !reflect.DeepEqual(unreachable, expected) {
+		t.Errorf("expected unreachable addresses %v, got %v", expected, unreachable)
+	}
+
+	unreachable, err = cloud.FindUnreachableMemberAddresses("pool", []string{"10.0.0.10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(unreachable) != 0 {
+		t.Errorf("expected all addresses to be reachable, got %v", unreachable)
+	}
+
+	if _, err := cloud.FindUnreachableMemberAddresses("pool", []string{"node.example.com"}); err == nil || !strings.Contains(err.Error(), `invalid member address "node.example.com"`) {
+		t.Errorf("expected error for invalid address, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The operations reported to an LBMetricsRecorder
+const (
+	LBOperationCreateLoadBalancer = "create_loadbalancer"
+	LBOperationUpdateLoadBalancer = "update_loadbalancer"
+	LBOperationDeleteLoadBalancer = "delete_loadbalancer"
+	LBOperationCreateListener     = "create_listener"
+	LBOperationUpdateListener     = "update_listener"
+	LBOperationDeleteListener     = "delete_listener"
+	LBOperationCreatePool         = "create_pool"
+	LBOperationUpdatePool         = "update_pool"
+	LBOperationDeletePool         = "delete_pool"
+	LBOperationCreateMember       = "create_member"
+	LBOperationUpdateMember       = "update_member"
+	LBOperationDeleteMember       = "delete_member"
+	LBOperationCreateMonitor      = "create_monitor"
+	LBOperationUpdateMonitor      = "update_monitor"
+	LBOperationDeleteMonitor      = "delete_monitor"
+)
+
+// The results reported to an LBMetricsRecorder
+const (
+	LBResultSuccess = "success"
+	LBResultError   = "error"
+)
+
+// LBMetricsRecorder is told of every loadbalancer operation made by kOps, including its retries,
+// for example to export counters and latency histograms of the OpenStack API.
+type LBMetricsRecorder interface {
+	// IncOperation counts a finished operation, with result LBResultSuccess or LBResultError
+	IncOperation(operation, result string)
+	// ObserveLatency records how long a finished operation took, whether it succeeded or not
+	ObserveLatency(operation string, duration time.Duration)
+}
+
+// noopLBMetricsRecorder is the LBMetricsRecorder used until another one is set
+type noopLBMetricsRecorder struct{}
+
+func (noopLBMetricsRecorder) IncOperation(operation, result string) {}
+
+func (noopLBMetricsRecorder) ObserveLatency(operation string, duration time.Duration) {}
+
+// recordLBOperation reports an operation that started at start, and failed if *err is not nil, to the LBMetricsRecorder of the cloud.
+// It is meant to be deferred, with err pointing to the named error result of the operation.
+func recordLBOperation(c OpenstackCloud, operation string, start time.Time, err *error) {
+	recorder := c.LBMetricsRecorder()
+	result := LBResultSuccess
+	if *err != nil {
+		result = LBResultError
+	}
+	recorder.IncOperation(operation, result)
+	recorder.ObserveLatency(operation, time.Since(start))
+}
+
+// PrometheusLBMetricsRecorder is an LBMetricsRecorder exporting the loadbalancer operations as prometheus metrics.
+type PrometheusLBMetricsRecorder struct {
+	operations *prometheus.CounterVec
+	latencies  *prometheus.HistogramVec
+}
+
+var _ LBMetricsRecorder = &PrometheusLBMetricsRecorder{}
+
+// NewPrometheusLBMetricsRecorder returns a recorder whose metrics are registered with registerer,
+// for example prometheus.DefaultRegisterer, to serve them next to the other kOps metrics.
+func NewPrometheusLBMetricsRecorder(registerer prometheus.Registerer) (*PrometheusLBMetricsRecorder, error) {
+	r := &PrometheusLBMetricsRecorder{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kops_openstack_loadbalancer_operations_total",
+			Help: "Total OpenStack loadbalancer operations, by operation and result.",
+		}, []string{"operation", "result"}),
+		latencies: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "kops_openstack_loadbalancer_operation_duration_seconds",
+			Help:    "Duration of OpenStack loadbalancer operations, including retries.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{"operation"}),
+	}
+	for _, collector := range []prometheus.Collector{r.operations, r.latencies} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, fmt.Errorf("error registering OpenStack loadbalancer metrics: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// IncOperation implements LBMetricsRecorder.
+func (r *PrometheusLBMetricsRecorder) IncOperation(operation, result string) {
+	r.operations.WithLabelValues(operation, result).Inc()
+}
+
+// ObserveLatency implements LBMetricsRecorder.
+func (r *PrometheusLBMetricsRecorder) ObserveLatency(operation string, duration time.Duration) {
+	r.latencies.WithLabelValues(operation).Observe(duration.Seconds())
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// recordingLBMetricsRecorder records the operations it is told of as "operation result"
+type recordingLBMetricsRecorder struct {
+	mutex      sync.Mutex
+	operations []string
+	latencies  map[string]int
+}
+
+func (r *recordingLBMetricsRecorder) IncOperation(operation, result string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.operations = append(r.operations, operation+" "+result)
+}
+
+func (r *recordingLBMetricsRecorder) ObserveLatency(operation string, duration time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.latencies == nil {
+		r.latencies = make(map[string]int)
+	}
+	r.latencies[operation]++
+}
+
+func Test_LBMetricsRecorder(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		deleteBackoff = backoff
+	}(deleteBackoff)
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	mux := http.NewServeMux()
+	fixture(mux, "/lbaas/loadbalancers", http.MethodPost, `{"loadbalancer": {"id": "lb", "name": "api"}}`, http.StatusCreated)
+	fixture(mux, "/lbaas/listeners", http.MethodPost, `{"faultstring": "Invalid input"}`, http.StatusBadRequest)
+	fixture(mux, "/lbaas/pools/pool/members/member", http.MethodPut, `{"member": {"id": "member", "weight": 2}}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools/pool/members/invalid", http.MethodPut, `{"faultstring": "Invalid input"}`, http.StatusBadRequest)
+	// the loadbalancer is deleted by the first request, and is gone afterwards
+	deleted := false
+	mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if deleted {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	// without a recorder, operations are not recorded anywhere
+	if _, ok := cloud.LBMetricsRecorder().(noopLBMetricsRecorder); !ok {
+		t.Errorf("expected the no-op recorder by default, got %T", cloud.LBMetricsRecorder())
+	}
+
+	recorder := &recordingLBMetricsRecorder{}
+	cloud.SetLBMetricsRecorder(recorder)
+
+	if _, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"}); err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	if _, err := cloud.CreateListener(listeners.CreateOpts{Name: "api", Protocol: listeners.ProtocolTCP, ProtocolPort: 443}); err == nil {
+		t.Fatalf("expected error creating invalid listener")
+	}
+	weight := 2
+	if _, err := cloud.UpdateMemberInPool("pool", "member", v2pools.UpdateMemberOpts{Weight: &weight}); err != nil {
+		t.Fatalf("error updating member: %v", err)
+	}
+	if _, err := cloud.UpdateMemberInPool("pool", "invalid", v2pools.UpdateMemberOpts{Weight: &weight}); err == nil {
+		t.Fatalf("expected error updating invalid member")
+	}
+	if err := cloud.DeleteLB("lb", loadbalancers.DeleteOpts{}); err != nil {
+		t.Fatalf("error deleting loadbalancer: %v", err)
+	}
+
+	expected := []string{
+		"create_loadbalancer success",
+		"create_listener error",
+		"update_member success",
+		"update_member error",
+		"delete_loadbalancer success",
+	}
+	if actual := strings.Join(recorder.operations, "\n"); actual != strings.Join(expected, "\n") {
+		t.Errorf("unexpected operations:\n%s\nexpected:\n%s", actual, strings.Join(expected, "\n"))
+	}
+	expectedLatencies := map[string]int{
+		LBOperationCreateLoadBalancer: 1,
+		LBOperationCreateListener:     1,
+		LBOperationUpdateMember:       2,
+		LBOperationDeleteLoadBalancer: 1,
+	}
+	for operation, count := range expectedLatencies {
+		if recorder.latencies[operation] != count {
+			t.Errorf("expected %d latencies observed for %s, got %d", count, operation, recorder.latencies[operation])
+		}
+	}
+	if len(recorder.latencies) != len(expectedLatencies) {
+		t.Errorf("unexpected latencies observed: %v", recorder.latencies)
+	}
+
+	// removing the recorder stops recording
+	cloud.SetLBMetricsRecorder(nil)
+	if _, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"}); err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	if len(recorder.operations) != len(expected) {
+		t.Errorf("unexpected operations after removing the recorder: %v", recorder.operations[len(expected):])
+	}
+}
+
+func Test_PrometheusLBMetricsRecorder(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	recorder, err := NewPrometheusLBMetricsRecorder(registry)
+	if err != nil {
+		t.Fatalf("error creating recorder: %v", err)
+	}
+
+	recorder.IncOperation(LBOperationCreatePool, LBResultSuccess)
+	recorder.IncOperation(LBOperationCreatePool, LBResultSuccess)
+	recorder.IncOperation(LBOperationCreatePool, LBResultError)
+	recorder.ObserveLatency(LBOperationCreatePool, 300*time.Millisecond)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error gathering metrics: %v", err)
+	}
+	counts := make(map[string]float64)
+	observations := make(map[string]uint64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var labels []string
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, label.GetValue())
+			}
+			key := family.GetName() + "{" + strings.Join(labels, ",") + "}"
+			if metric.GetCounter() != nil {
+				counts[key] = metric.GetCounter().GetValue()
+			}
+			if metric.GetHistogram() != nil {
+				observations[key] = metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	if actual := counts["kops_openstack_loadbalancer_operations_total{create_pool,success}"]; actual != 2 {
+		t.Errorf("expected 2 successful create_pool operations, got %v", actual)
+	}
+	if actual := counts["kops_openstack_loadbalancer_operations_total{create_pool,error}"]; actual != 1 {
+		t.Errorf("expected 1 failed create_pool operation, got %v", actual)
+	}
+	if actual := observations["kops_openstack_loadbalancer_operation_duration_seconds{create_pool}"]; actual != 1 {
+		t.Errorf("expected 1 create_pool latency observation, got %v", actual)
+	}
+
+	// the metrics can only be registered once
+	if _, err := NewPrometheusLBMetricsRecorder(registry); err == nil {
+		t.Errorf("expected error registering the metrics twice")
+	}
+}
@@ -0,0 +1,396 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/klog/v2"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// ShadowSwapOptions describes the listener and pool a listener is migrated to by ShadowSwapListener.
+type ShadowSwapOptions struct {
+	// Protocol is the protocol of the listener after the migration; if empty the protocol of the listener is kept
+	Protocol listeners.Protocol
+	// Pool is the new default pool of the listener, which must be supported behind Protocol.
+	// It is created on the loadbalancer of the listener, so ListenerID and LoadbalancerID are ignored.
+	Pool v2pools.CreateOpts
+	// Members are the members of the new pool; if nil the members of the current default pool are copied
+	Members []MemberSpec
+	// Monitor is the health monitor of the new pool, if any
+	Monitor *MonitorSpec
+	// ShadowPort is the port the shadow listener is created on while the new pool is validated.
+	// It must not be used by another listener of the loadbalancer.
+	ShadowPort int
+	// Timeout bounds each wait for the loadbalancer to be ACTIVE, and the wait for the members to be ONLINE
+	Timeout time.Duration
+
+	// DefaultTLSContainerRef is the certificate of the listener after migrating it to TERMINATED_HTTPS, where it is required.
+	// It can only be set when the protocol changes to TERMINATED_HTTPS.
+	DefaultTLSContainerRef string
+	// SNIContainerRefs are the SNI certificates of the listener after migrating it to TERMINATED_HTTPS
+	SNIContainerRefs []string
+
+	// ValidateOnly stops once the shadow listener and pool are healthy, without touching the listener.
+	// The shadow resources are left in place, so that they can be checked before running the swap.
+	ValidateOnly bool
+	// KeepOldPool keeps the previous default pool of the listener instead of deleting it after the swap, for rolling back
+	KeepOldPool bool
+}
+
+// shadowListenerName is the name of the shadow listener created by ShadowSwapListener for the listener
+func shadowListenerName(name string) string {
+	return name + "-shadow"
+}
+
+func (c *openstackCloud) ShadowSwapListener(listenerID string, opts ShadowSwapOptions) (*listeners.Listener, error) {
+	return shadowSwapListener(c, listenerID, opts)
+}
+
+// shadowSwapListener migrates a listener to another protocol and pool, blue/green style:
+//
+//  1. the new pool is created on the loadbalancer, with its members and monitor;
+//  2. a shadow listener is created on ShadowPort, with the new protocol and the new pool as default pool;
+//  3. the members of the new pool must all come ONLINE, otherwise the listener is left untouched;
+//  4. the listener is repointed at the new pool. If the protocol is unchanged, this is a single update of its default pool.
+//     Otherwise the listener is deleted and created again with the same name and port, as Octavia cannot change the
+//     protocol of a listener nor have two listeners on one port; connections to the port fail until it is re-created;
+//  5. the shadow listener is deleted, followed by the old pool unless KeepOldPool is set.
+//
+// The loadbalancer is waited for to be ACTIVE after every change. With ValidateOnly, it stops after step 3.
+// It returns the listener serving the port at the end, or the shadow listener with ValidateOnly.
+//
+// If it fails before the listener is repointed, the shadow listener and pool are deleted again. If re-creating the listener
+// fails, the previous listener is restored with its previous pool, which is only deleted once the migration succeeded.
+func shadowSwapListener(c OpenstackCloud, listenerID string, opts ShadowSwapOptions) (listener *listeners.Listener, err error) {
+	ctx := context.TODO()
+
+	listenerList, err := c.ListListeners(listeners.ListOpts{ID: listenerID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting listener %s: %w", listenerID, err)
+	}
+	if len(listenerList) != 1 {
+		return nil, fmt.Errorf("listener %s not found", listenerID)
+	}
+	old := listenerList[0]
+	if len(old.Loadbalancers) != 1 {
+		return nil, fmt.Errorf("listener %s belongs to %d loadbalancers, expected 1", listenerID, len(old.Loadbalancers))
+	}
+	lbID := old.Loadbalancers[0].ID
+
+	protocol := opts.Protocol
+	if protocol == "" {
+		protocol = listeners.Protocol(old.Protocol)
+	}
+	if err := checkShadowSwap(c, old, lbID, protocol, opts); err != nil {
+		return nil, err
+	}
+	waitOpts := lbWaitOptions{Timeout: opts.Timeout}
+	if waitOpts.Timeout == 0 {
+		waitOpts.Timeout = defaultLBBuilderTimeout
+	}
+	waitForActive := func(step string) error {
+		if _, err := waitForLoadBalancerActive(ctx, c, lbID, waitOpts); err != nil {
+			return fmt.Errorf("error waiting for loadbalancer %s after %s: %w", lbID, step, err)
+		}
+		return nil
+	}
+
+	// the shadow resources are deleted again if the migration fails before the listener is repointed at the new pool
+	var shadowPoolID, shadowID string
+	swapped := false
+	defer func() {
+		if err != nil && !swapped {
+			deleteShadowResources(c, shadowID, shadowPoolID, waitForActive)
+		}
+	}()
+
+	members := opts.Members
+	if members == nil && old.DefaultPoolID != "" {
+		existing, err := c.ListPoolMembers(old.DefaultPoolID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return nil, fmt.Errorf("error listing members of pool %s: %w", old.DefaultPoolID, err)
+		}
+		for _, member := range existing {
+			members = append(members, NewMemberSpec(member))
+		}
+	}
+
+	poolOpts := opts.Pool
+	poolOpts.LoadbalancerID = lbID
+	poolOpts.ListenerID = ""
+	pool, err := c.CreatePool(poolOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating shadow pool for listener %s: %w", listenerID, err)
+	}
+	shadowPoolID = pool.ID
+	if err := waitForActive("creating pool " + pool.ID); err != nil {
+		return nil, err
+	}
+
+	if len(members) != 0 {
+		if err := batchUpdatePoolMembers(c, pool.ID, members); err != nil {
+			return nil, fmt.Errorf("error adding members to pool %s: %w", pool.ID, err)
+		}
+		if err := waitForActive("adding members to pool " + pool.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Monitor != nil {
+		spec := *opts.Monitor
+		spec.PoolID = pool.ID
+		spec.Type, err = MonitorTypeForPoolProtocol(pool.Protocol, spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		monitorOpts, err := BuildMonitorCreateOpts(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid monitor: %w", err)
+		}
+		if _, err := attachMonitorToPool(c, pool.ID, monitorOpts); err != nil {
+			return nil, fmt.Errorf("error creating monitor of pool %s: %w", pool.ID, err)
+		}
+		if err := waitForActive("creating monitor of pool " + pool.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	newListenerOpts := listenerCreateOptsFrom(old, lbID, protocol, pool.ID)
+	if opts.DefaultTLSContainerRef != "" {
+		newListenerOpts.DefaultTlsContainerRef = opts.DefaultTLSContainerRef
+		newListenerOpts.SniContainerRefs = opts.SNIContainerRefs
+	}
+	shadowOpts := newListenerOpts
+	shadowOpts.Name = shadowListenerName(old.Name)
+	shadowOpts.ProtocolPort = opts.ShadowPort
+	shadow, err := c.CreateListener(shadowOpts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating shadow listener for listener %s: %w", listenerID, err)
+	}
+	shadowID = shadow.ID
+	if err := waitForActive("creating listener " + shadow.ID); err != nil {
+		return nil, err
+	}
+
+	if err := c.WaitForMembersOnline(pool.ID, waitOpts.Timeout); err != nil {
+		return nil, fmt.Errorf("shadow pool %s of listener %s is not healthy, leaving the listener unchanged: %w", pool.ID, listenerID, err)
+	}
+	if opts.ValidateOnly {
+		klog.Infof("Shadow listener %s on port %d is healthy, not swapping listener %s", shadow.ID, shadow.ProtocolPort, listenerID)
+		return shadow, nil
+	}
+
+	if string(protocol) == old.Protocol {
+		if err := c.SwapListenerDefaultPool(old.ID, pool.ID); err != nil {
+			return nil, err
+		}
+		swapped = true
+		repointed := old
+		repointed.DefaultPoolID = pool.ID
+		listener = &repointed
+		if err := waitForActive("swapping default pool of listener " + old.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		klog.Infof("Re-creating listener %s on port %d with protocol %s", old.Name, old.ProtocolPort, protocol)
+		if err := c.DeleteListener(old.ID); err != nil {
+			return nil, fmt.Errorf("error deleting listener %s: %w", old.ID, err)
+		}
+		listener, err = recreateListener(c, old, newListenerOpts, waitForActive)
+		if err != nil {
+			return nil, restoreListener(c, old, lbID, waitForActive, err)
+		}
+		swapped = true
+		if err := waitForActive("creating listener " + listener.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.DeleteListener(shadow.ID); err != nil {
+		return listener, fmt.Errorf("error deleting shadow listener %s: %w", shadow.ID, err)
+	}
+	if err := waitForActive("deleting listener " + shadow.ID); err != nil {
+		return listener, err
+	}
+
+	if old.DefaultPoolID != "" && !opts.KeepOldPool {
+		if err := c.DeletePool(old.DefaultPoolID); err != nil {
+			return listener, fmt.Errorf("error deleting previous pool %s of listener %s: %w", old.DefaultPoolID, listener.ID, err)
+		}
+		if err := waitForActive("deleting pool " + old.DefaultPoolID); err != nil {
+			return listener, err
+		}
+	}
+
+	klog.Infof("Migrated listener %s on port %d to protocol %s and pool %s", listener.Name, listener.ProtocolPort, protocol, pool.ID)
+	return listener, nil
+}
+
+// recreateListener creates the listener again once the previous one is deleted
+func recreateListener(c OpenstackCloud, old listeners.Listener, opts listeners.CreateOpts, waitForActive func(step string) error) (*listeners.Listener, error) {
+	if err := waitForActive("deleting listener " + old.ID); err != nil {
+		return nil, err
+	}
+	listener, err := c.CreateListener(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error re-creating listener %s with protocol %s: %w", old.Name, opts.Protocol, err)
+	}
+	return listener, nil
+}
+
+// restoreListener creates the deleted listener again as it was, with its previous default pool, after failing to
+// re-create it with another protocol. It returns the error of the migration, telling whether the port is served again.
+func restoreListener(c OpenstackCloud, old listeners.Listener, lbID string, waitForActive func(step string) error, migrationErr error) error {
+	klog.Warningf("Restoring listener %s on port %d with protocol %s: %v", old.Name, old.ProtocolPort, old.Protocol, migrationErr)
+	if err := waitForActive("failing to re-create listener " + old.Name); err != nil {
+		klog.Warningf("Restoring listener %s anyway: %v", old.Name, err)
+	}
+	restored, err := c.CreateListener(listenerCreateOptsFrom(old, lbID, listeners.Protocol(old.Protocol), old.DefaultPoolID))
+	if err != nil {
+		return fmt.Errorf("%w; restoring the previous listener failed too, port %d has no listener: %v", migrationErr, old.ProtocolPort, err)
+	}
+	if err := waitForActive("restoring listener " + restored.ID); err != nil {
+		return fmt.Errorf("%w; the previous listener was restored as %s, but: %v", migrationErr, restored.ID, err)
+	}
+	return fmt.Errorf("%w; the previous listener was restored as %s", migrationErr, restored.ID)
+}
+
+// deleteShadowResources deletes the shadow listener and pool, if they were created, after a failed migration.
+// Deleting the pool deletes its members and monitor. Failures are only logged, as the migration already failed.
+func deleteShadowResources(c OpenstackCloud, shadowID string, shadowPoolID string, waitForActive func(step string) error) {
+	if shadowID != "" {
+		if err := waitForActive("failing to migrate listener"); err != nil {
+			klog.Warningf("Deleting shadow listener %s anyway: %v", shadowID, err)
+		}
+		if err := c.DeleteListener(shadowID); err != nil {
+			klog.Warningf("Error deleting shadow listener %s: %v", shadowID, err)
+			return
+		}
+	}
+	if shadowPoolID != "" {
+		if err := waitForActive("deleting shadow listener"); err != nil {
+			klog.Warningf("Deleting shadow pool %s anyway: %v", shadowPoolID, err)
+		}
+		if err := c.DeletePool(shadowPoolID); err != nil {
+			klog.Warningf("Error deleting shadow pool %s: %v", shadowPoolID, err)
+		}
+	}
+}
+
+// checkShadowSwap checks the options of ShadowSwapListener against the listener and the other listeners of its loadbalancer,
+// before anything is created.
+func checkShadowSwap(c OpenstackCloud, old listeners.Listener, lbID string, protocol listeners.Protocol, opts ShadowSwapOptions) error {
+	supported, known := listenerPoolProtocols[string(protocol)]
+	if !known {
+		return fmt.Errorf("unknown listener protocol %q", protocol)
+	}
+	if opts.Pool.Protocol == "" {
+		return fmt.Errorf("the protocol of the new pool is required")
+	}
+	if !slices.Contains(supported, string(opts.Pool.Protocol)) {
+		return fmt.Errorf("pool protocol %s is not supported behind a listener with protocol %s, must be one of %s",
+			opts.Pool.Protocol, protocol, strings.Join(supported, ", "))
+	}
+	if string(protocol) != old.Protocol && transportProtocol(string(protocol)) != transportProtocol(old.Protocol) {
+		return fmt.Errorf("cannot migrate listener %s from %s to %s, which is served over another transport protocol", old.ID, old.Protocol, protocol)
+	}
+	if string(protocol) != old.Protocol && len(old.L7Policies) != 0 {
+		return fmt.Errorf("cannot migrate listener %s to %s, its L7 policies would be lost when it is re-created", old.ID, protocol)
+	}
+	if opts.DefaultTLSContainerRef == "" && len(opts.SNIContainerRefs) != 0 {
+		return fmt.Errorf("SNI certificates require a default certificate")
+	}
+	if protocol == listeners.ProtocolTerminatedHTTPS && old.Protocol != string(protocol) && opts.DefaultTLSContainerRef == "" {
+		return fmt.Errorf("migrating listener %s to %s requires a default certificate", old.ID, protocol)
+	}
+	if opts.DefaultTLSContainerRef != "" && (protocol != listeners.ProtocolTerminatedHTTPS || old.Protocol == string(protocol)) {
+		return fmt.Errorf("certificates can only be set when migrating listener %s to %s", old.ID, listeners.ProtocolTerminatedHTTPS)
+	}
+
+	if opts.ShadowPort < 1 || opts.ShadowPort > 65535 {
+		return fmt.Errorf("shadow port must be between 1 and 65535, got %d", opts.ShadowPort)
+	}
+	others, err := c.ListListeners(listeners.ListOpts{LoadbalancerID: lbID})
+	if err != nil {
+		return fmt.Errorf("error listing listeners of loadbalancer %s: %w", lbID, err)
+	}
+	for _, other := range others {
+		if other.ProtocolPort == opts.ShadowPort && transportProtocol(other.Protocol) == transportProtocol(string(protocol)) {
+			return fmt.Errorf("shadow port %d is already used by listener %s", opts.ShadowPort, other.ID)
+		}
+	}
+	return nil
+}
+
+// listenerCreateOptsFrom returns the options to create a listener like the given one, with another protocol and default pool.
+// Settings that Octavia only accepts for some protocols, such as certificates and inserted headers, are only kept if the
+// protocol supports them. L7 policies are not kept.
+func listenerCreateOptsFrom(listener listeners.Listener, lbID string, protocol listeners.Protocol, poolID string) listeners.CreateOpts {
+	opts := listeners.CreateOpts{
+		LoadbalancerID: lbID,
+		Name:           listener.Name,
+		Description:    listener.Description,
+		Protocol:       protocol,
+		ProtocolPort:   listener.ProtocolPort,
+		DefaultPoolID:  poolID,
+		AdminStateUp:   fi.PtrTo(listener.AdminStateUp),
+		AllowedCIDRs:   listener.AllowedCIDRs,
+		Tags:           listener.Tags,
+	}
+	if listener.ConnLimit != 0 {
+		opts.ConnLimit = fi.PtrTo(listener.ConnLimit)
+	}
+	if listener.TimeoutClientData != 0 {
+		opts.TimeoutClientData = fi.PtrTo(listener.TimeoutClientData)
+	}
+	if listener.TimeoutMemberData != 0 {
+		opts.TimeoutMemberData = fi.PtrTo(listener.TimeoutMemberData)
+	}
+	if listener.TimeoutMemberConnect != 0 {
+		opts.TimeoutMemberConnect = fi.PtrTo(listener.TimeoutMemberConnect)
+	}
+	if listener.TimeoutTCPInspect != 0 {
+		opts.TimeoutTCPInspect = fi.PtrTo(listener.TimeoutTCPInspect)
+	}
+	if protocol == listeners.ProtocolHTTP || protocol == listeners.ProtocolTerminatedHTTPS {
+		opts.InsertHeaders = listener.InsertHeaders
+	}
+	if protocol == listeners.ProtocolTerminatedHTTPS {
+		opts.DefaultTlsContainerRef = listener.DefaultTlsContainerRef
+		opts.SniContainerRefs = listener.SniContainerRefs
+		opts.ClientAuthentication = listeners.ClientAuthentication(listener.ClientAuthentication)
+		opts.ClientCATLSContainerRef = listener.ClientCATLSContainerRef
+		opts.ClientCRLContainerRef = listener.ClientCRLContainerRef
+		opts.ALPNProtocols = listener.ALPNProtocols
+		opts.HSTSIncludeSubdomains = listener.HSTSIncludeSubdomains
+		opts.HSTSMaxAge = listener.HSTSMaxAge
+		opts.HSTSPreload = listener.HSTSPreload
+		opts.TLSCiphers = listener.TLSCiphers
+		for _, version := range listener.TLSVersions {
+			opts.TLSVersions = append(opts.TLSVersions, listeners.TLSVersion(version))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,350 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/l7policies"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// newShadowSwapOctavia returns an Octavia API with loadbalancer "lb" and its listener "api",
+// forwarding TCP port 443 to pool "old" with two members
+func newShadowSwapOctavia(t *testing.T) *mockOctavia {
+	octavia := newMockOctavia(t)
+	octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "lb", Name: "lb"})
+	octavia.AddPool(v2pools.Pool{ID: "old", Name: "api", Protocol: "TCP", LBMethod: "ROUND_ROBIN", Loadbalancers: []v2pools.LoadBalancerID{{ID: "lb"}}})
+	octavia.AddMember(v2pools.Member{ID: "member-1", PoolID: "old", Name: "control-plane-1", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1, OperatingStatus: operatingStatusOnline})
+	octavia.AddMember(v2pools.Member{ID: "member-2", PoolID: "old", Name: "control-plane-2", Address: "10.0.0.2", ProtocolPort: 443, Weight: 2, OperatingStatus: operatingStatusOnline})
+	octavia.AddListener(listeners.Listener{
+		ID: "api", Name: "api", Protocol: "TCP", ProtocolPort: 443, DefaultPoolID: "old",
+		ConnLimit: 1000, AdminStateUp: true, Loadbalancers: []listeners.LoadBalancerID{{ID: "lb"}},
+	})
+	return octavia
+}
+
+// shadowSwapTopology describes the listeners of the loadbalancer, with their default pool and its members
+func shadowSwapTopology(octavia *mockOctavia) string {
+	pools := make(map[string]v2pools.Pool)
+	for _, pool := range octavia.Pools() {
+		pools[pool.ID] = pool
+	}
+
+	var lines []string
+	for _, listener := range octavia.Listeners() {
+		line := fmt.Sprintf("%v %v:%v", listener.Name, listener.Protocol, listener.ProtocolPort)
+		if pool, found := pools[listener.DefaultPoolID]; found {
+			line += fmt.Sprintf(" -> %v %v", pool.Name, pool.Protocol)
+			if pool.MonitorID != "" {
+				line += " (monitored)"
+			}
+			members := octavia.Members(pool.ID)
+			sort.Slice(members, func(i, j int) bool { return members[i].Address < members[j].Address })
+			for _, member := range members {
+				line += fmt.Sprintf(" %v:%v/%v", member.Address, member.ProtocolPort, member.Weight)
+			}
+		}
+		lines = append(lines, line)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// shadowSwapPools returns the names of the pools, sorted
+func shadowSwapPools(octavia *mockOctavia) []string {
+	var names []string
+	for _, pool := range octavia.Pools() {
+		names = append(names, pool.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rejectListeners fails the creation of the listeners reject returns true for
+func rejectListeners(octavia *mockOctavia, reject func(listener listeners.CreateOpts) bool) {
+	octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+		var body struct {
+			Listener listeners.CreateOpts `json:"listener"`
+		}
+		if request != "POST /lbaas/listeners" || json.Unmarshal([]byte(octavia.body(request)), &body) != nil || !reject(body.Listener) {
+			return false
+		}
+		http.Error(w, `{"faultstring": "rejected"}`, http.StatusBadRequest)
+		return true
+	}
+}
+
+func Test_ShadowSwapListener(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		deleteBackoff = backoff
+	}(deleteBackoff)
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+	defer func(interval time.Duration) {
+		membersOnlinePollInterval = interval
+	}(membersOnlinePollInterval)
+	membersOnlinePollInterval = time.Millisecond
+
+	options := func() ShadowSwapOptions {
+		return ShadowSwapOptions{
+			Protocol:   listeners.ProtocolHTTPS,
+			Pool:       v2pools.CreateOpts{Name: "api-proxy", Protocol: v2pools.ProtocolPROXY, LBMethod: v2pools.LBMethodRoundRobin},
+			Monitor:    &MonitorSpec{Name: "api-proxy", Type: monitors.TypeTCP, Delay: 10, Timeout: 5, MaxRetries: 3},
+			ShadowPort: 8443,
+			Timeout:    time.Second,
+		}
+	}
+
+	tests := []struct {
+		desc             string
+		modify           func(opts *ShadowSwapOptions, octavia *mockOctavia)
+		expectedError    string
+		expectedListener string
+		// expectedTopology is the listeners of the loadbalancer at the end, see shadowSwapTopology
+		expectedTopology string
+		// expectedPools are the names of the pools left at the end
+		expectedPools []string
+	}{
+		{
+			desc:             "protocol change",
+			modify:           func(opts *ShadowSwapOptions, octavia *mockOctavia) {},
+			expectedListener: "api",
+			expectedTopology: "api HTTPS:443 -> api-proxy PROXY (monitored) 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api-proxy"},
+		},
+		{
+			desc: "same protocol swaps the pool in place",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.Protocol = ""
+				opts.Monitor = nil
+				opts.Members = []MemberSpec{{Name: "control-plane-3", Address: "10.0.0.3", ProtocolPort: 443}}
+			},
+			expectedListener: "api",
+			expectedTopology: "api TCP:443 -> api-proxy PROXY 10.0.0.3:443/1",
+			expectedPools:    []string{"api-proxy"},
+		},
+		{
+			desc: "old pool kept",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.KeepOldPool = true
+			},
+			expectedListener: "api",
+			expectedTopology: "api HTTPS:443 -> api-proxy PROXY (monitored) 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api", "api-proxy"},
+		},
+		{
+			desc: "validate only",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.ValidateOnly = true
+			},
+			expectedListener: "api-shadow",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2\n" +
+				"api-shadow HTTPS:8443 -> api-proxy PROXY (monitored) 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools: []string{"api", "api-proxy"},
+		},
+		{
+			desc: "unhealthy shadow pool",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				octavia.MemberOperatingStatus = "ERROR"
+			},
+			// the shadow listener and pool are deleted again
+			expectedError:    "is not healthy, leaving the listener unchanged",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "re-creating the listener fails",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				rejectListeners(octavia, func(listener listeners.CreateOpts) bool {
+					return listener.Name == "api" && listener.Protocol == listeners.ProtocolHTTPS
+				})
+			},
+			// the previous listener is restored with its pool, and the shadow resources are deleted
+			expectedError:    "the previous listener was restored as ",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "restoring the listener fails",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				rejectListeners(octavia, func(listener listeners.CreateOpts) bool {
+					return listener.Name == "api"
+				})
+			},
+			// the old pool is kept, so that the listener can be restored manually
+			expectedError:    "restoring the previous listener failed too, port 443 has no listener",
+			expectedTopology: "",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "TERMINATED_HTTPS without certificate",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.Protocol = listeners.ProtocolTerminatedHTTPS
+				opts.Pool.Protocol = v2pools.ProtocolHTTP
+			},
+			expectedError:    "migrating listener api to TERMINATED_HTTPS requires a default certificate",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "certificate without TERMINATED_HTTPS",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.DefaultTLSContainerRef = "https://barbican/containers/cert"
+			},
+			expectedError:    "certificates can only be set when migrating listener api to TERMINATED_HTTPS",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "L7 policies",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				octavia.AddL7Policy(l7policies.L7Policy{ID: "redirect", ListenerID: "api", Action: string(l7policies.ActionRedirectPrefix)})
+			},
+			expectedError:    "its L7 policies would be lost",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "shadow port in use",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.ShadowPort = 443
+			},
+			expectedError:    "shadow port 443 is already used by listener api",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "pool protocol not supported by listener protocol",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.Pool.Protocol = v2pools.ProtocolHTTP
+			},
+			expectedError:    "pool protocol HTTP is not supported behind a listener with protocol HTTPS",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+		{
+			desc: "transport protocol change",
+			modify: func(opts *ShadowSwapOptions, octavia *mockOctavia) {
+				opts.Protocol = listeners.ProtocolUDP
+				opts.Pool.Protocol = v2pools.ProtocolUDP
+				opts.Monitor = nil
+			},
+			expectedError:    "which is served over another transport protocol",
+			expectedTopology: "api TCP:443 -> api TCP 10.0.0.1:443/1 10.0.0.2:443/2",
+			expectedPools:    []string{"api"},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newShadowSwapOctavia(t)
+
+			opts := options()
+			testCase.modify(&opts, octavia)
+			listener, err := octavia.cloud().ShadowSwapListener("api", opts)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if listener.Name != testCase.expectedListener {
+				t.Errorf("expected listener %q to be returned, got %q", testCase.expectedListener, listener.Name)
+			}
+
+			if actual := shadowSwapTopology(octavia); actual != testCase.expectedTopology {
+				t.Errorf("unexpected topology:\n%s\nexpected:\n%s", actual, testCase.expectedTopology)
+			}
+			if actual := shadowSwapPools(octavia); !reflect.DeepEqual(actual, testCase.expectedPools) {
+				t.Errorf("expected pools %v, got %v", testCase.expectedPools, actual)
+			}
+		})
+	}
+}
+
+func Test_ShadowSwapListenerKeepsListenerSettings(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		deleteBackoff = backoff
+	}(deleteBackoff)
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+
+	octavia := newShadowSwapOctavia(t)
+	octavia.AddPool(v2pools.Pool{ID: "old", Name: "api", Protocol: "HTTP", LBMethod: "ROUND_ROBIN", Loadbalancers: []v2pools.LoadBalancerID{{ID: "lb"}}})
+	octavia.AddListener(listeners.Listener{
+		ID: "api", Name: "api", Protocol: "HTTP", ProtocolPort: 443, DefaultPoolID: "old",
+		ConnLimit: 1000, AdminStateUp: true, Loadbalancers: []listeners.LoadBalancerID{{ID: "lb"}},
+		TimeoutClientData: 60000, TimeoutMemberData: 70000, TimeoutMemberConnect: 5000, TimeoutTCPInspect: 10,
+		InsertHeaders: map[string]string{"X-Forwarded-For": "true"},
+		AllowedCIDRs:  []string{"10.0.0.0/8"},
+	})
+
+	listener, err := octavia.cloud().ShadowSwapListener("api", ShadowSwapOptions{
+		Protocol:               listeners.ProtocolTerminatedHTTPS,
+		Pool:                   v2pools.CreateOpts{Name: "api-http", Protocol: v2pools.ProtocolHTTP, LBMethod: v2pools.LBMethodRoundRobin},
+		ShadowPort:             8443,
+		Timeout:                time.Second,
+		DefaultTLSContainerRef: "https://barbican/containers/cert",
+		SNIContainerRefs:       []string{"https://barbican/containers/sni"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the listener is re-created, so it has a new ID but keeps its other settings
+	if listener.ID == "api" {
+		t.Errorf("expected listener to be re-created with a new ID")
+	}
+	if listener.ConnLimit != 1000 {
+		t.Errorf("expected connection limit 1000 to be kept, got %d", listener.ConnLimit)
+	}
+	if !listener.AdminStateUp {
+		t.Errorf("expected listener to be kept enabled")
+	}
+	if listener.TimeoutClientData != 60000 || listener.TimeoutMemberData != 70000 || listener.TimeoutMemberConnect != 5000 || listener.TimeoutTCPInspect != 10 {
+		t.Errorf("expected timeouts to be kept, got %d, %d, %d and %d", listener.TimeoutClientData, listener.TimeoutMemberData, listener.TimeoutMemberConnect, listener.TimeoutTCPInspect)
+	}
+	if !reflect.DeepEqual(listener.InsertHeaders, map[string]string{"X-Forwarded-For": "true"}) {
+		t.Errorf("expected inserted headers to be kept, got %v", listener.InsertHeaders)
+	}
+	if !reflect.DeepEqual(listener.AllowedCIDRs, []string{"10.0.0.0/8"}) {
+		t.Errorf("expected allowed CIDRs to be kept, got %v", listener.AllowedCIDRs)
+	}
+	if listener.DefaultTlsContainerRef != "https://barbican/containers/cert" || !reflect.DeepEqual(listener.SniContainerRefs, []string{"https://barbican/containers/sni"}) {
+		t.Errorf("expected the certificates of the migration, got %q and %v", listener.DefaultTlsContainerRef, listener.SniContainerRefs)
+	}
+	if len(listener.Loadbalancers) != 1 || listener.Loadbalancers[0].ID != "lb" {
+		t.Errorf("expected listener on loadbalancer lb, got %v", listener.Loadbalancers)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// maxLoadBalancerTagLength is the longest tag Octavia accepts on loadbalancers, listeners and pools
+const maxLoadBalancerTagLength = 255
+
+// ownershipTagKeys are the keys of the tags kops uses to identify its resources, which additional tags cannot set
+var ownershipTagKeys = []string{TagClusterName, TagKopsName, TagKopsInstanceGroup, TagKopsNetwork, TagKopsRole}
+
+// ValidateLoadBalancerTag checks that the tag key=value is accepted by Octavia, and can be used in tag filters.
+func ValidateLoadBalancerTag(key, value string) error {
+	if key == "" {
+		return fmt.Errorf("tag key cannot be empty")
+	}
+	if strings.Contains(key, "=") {
+		return fmt.Errorf("tag key %q cannot contain %q", key, "=")
+	}
+	// Octavia separates the tags of a filter with commas
+	if strings.Contains(key, ",") || strings.Contains(value, ",") {
+		return fmt.Errorf("tag %s=%s cannot contain %q", key, value, ",")
+	}
+	if l := len(key) + len("=") + len(value); l > maxLoadBalancerTagLength {
+		return fmt.Errorf("tag %s=%s is %d characters long, must be at most %d", key, value, l, maxLoadBalancerTagLength)
+	}
+	if slices.Contains(ownershipTagKeys, key) {
+		return fmt.Errorf("tag key %q is reserved for kops", key)
+	}
+	return nil
+}
+
+// LoadBalancerTags returns the tags to set on the loadbalancers, listeners and pools of a cluster.
+// The ownershipTags, in the key=value form, are those kops identifies its resources by; they take precedence over
+// the additionalTags with the same key, which are dropped.
+func LoadBalancerTags(ownershipTags []string, additionalTags map[string]string) []string {
+	owned := make(map[string]bool)
+	for _, tag := range ownershipTags {
+		key, _, _ := strings.Cut(tag, "=")
+		owned[key] = true
+	}
+
+	tags := slices.Clone(ownershipTags)
+	for _, key := range slices.Sorted(maps.Keys(additionalTags)) {
+		if owned[key] || slices.Contains(ownershipTagKeys, key) {
+			klog.Warningf("ignoring additional loadbalancer tag %q, which is reserved for kops", key)
+			continue
+		}
+		tags = append(tags, key+"="+additionalTags[key])
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_LoadBalancerTags(t *testing.T) {
+	ownership := []string{"KubernetesCluster=cluster.example.com"}
+
+	tests := []struct {
+		desc       string
+		additional map[string]string
+		expected   []string
+	}{
+		{
+			desc:     "ownership only",
+			expected: []string{"KubernetesCluster=cluster.example.com"},
+		},
+		{
+			desc: "merged and sorted",
+			additional: map[string]string{
+				"owner":       "team-a",
+				"cost-center": "1234",
+			},
+			expected: []string{"KubernetesCluster=cluster.example.com", "cost-center=1234", "owner=team-a"},
+		},
+		{
+			desc: "ownership tags win on conflict",
+			additional: map[string]string{
+				"KubernetesCluster": "other.example.com",
+				"owner":             "team-a",
+			},
+			expected: []string{"KubernetesCluster=cluster.example.com", "owner=team-a"},
+		},
+		{
+			desc: "reserved keys are not set",
+			additional: map[string]string{
+				"KopsRole": "control-plane",
+			},
+			expected: []string{"KubernetesCluster=cluster.example.com"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			actual := LoadBalancerTags(ownership, test.additional)
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("expected tags %v, got %v", test.expected, actual)
+			}
+		})
+	}
+
+	if ownership[0] != "KubernetesCluster=cluster.example.com" {
+		t.Errorf("ownership tags were modified: %v", ownership)
+	}
+}
+
+func Test_ValidateLoadBalancerTag(t *testing.T) {
+	tests := []struct {
+		key           string
+		value         string
+		expectedError string
+	}{
+		{key: "owner", value: "team-a"},
+		{key: "owner", value: ""},
+		{key: "", value: "team-a", expectedError: "cannot be empty"},
+		{key: "owner=x", value: "team-a", expectedError: `cannot contain "="`},
+		{key: "owner", value: "team-a,team-b", expectedError: `cannot contain ","`},
+		{key: "owner", value: strings.Repeat("a", 250), expectedError: "must be at most 255"},
+		{key: "KubernetesCluster", value: "other", expectedError: "reserved for kops"},
+	}
+
+	for _, test := range tests {
+		err := ValidateLoadBalancerTag(test.key, test.value)
+		if test.expectedError == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %s=%s: %v", test.key, test.value, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf("expected error containing %q for %s=%s, got %v", test.expectedError, test.key, test.value, err)
+		}
+	}
+}
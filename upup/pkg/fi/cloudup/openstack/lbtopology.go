@@ -0,0 +1,210 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+)
+
+// LBTopologySpec is the desired graph of a loadbalancer and its children, as checked by ValidateLBTopology.
+// Objects refer to each other by name, as their IDs are only known once they are created.
+type LBTopologySpec struct {
+	LoadBalancer loadbalancers.CreateOpts
+	Listeners    []listeners.CreateOpts
+	Pools        []LBPoolSpec
+}
+
+// LBPoolSpec is a pool of an LBTopologySpec, together with its monitor and members.
+type LBPoolSpec struct {
+	Pool v2pools.CreateOpts
+	// Listener is the name of the listener the pool is the default pool of; if empty the pool is attached to the loadbalancer
+	Listener string
+	Monitor  *MonitorSpec
+	Members  []MemberSpec
+}
+
+// listenerPoolProtocols lists the pool protocols Octavia accepts behind each listener protocol.
+var listenerPoolProtocols = map[string][]string{
+	string(listeners.ProtocolTCP):             {string(v2pools.ProtocolHTTP), string(v2pools.ProtocolHTTPS), string(v2pools.ProtocolPROXY), string(v2pools.ProtocolPROXYV2), string(v2pools.ProtocolTCP)},
+	string(listeners.ProtocolHTTP):            {string(v2pools.ProtocolHTTP), string(v2pools.ProtocolPROXY), string(v2pools.ProtocolPROXYV2)},
+	string(listeners.ProtocolHTTPS):           {string(v2pools.ProtocolHTTPS), string(v2pools.ProtocolPROXY), string(v2pools.ProtocolPROXYV2), string(v2pools.ProtocolTCP)},
+	string(listeners.ProtocolTerminatedHTTPS): {string(v2pools.ProtocolHTTP), string(v2pools.ProtocolPROXY), string(v2pools.ProtocolPROXYV2)},
+	string(listeners.ProtocolUDP):             {string(v2pools.ProtocolUDP)},
+	string(listeners.ProtocolSCTP):            {string(v2pools.ProtocolSCTP)},
+	// the prometheus listener serves the statistics of the loadbalancer itself, and has no pool
+	string(listeners.ProtocolPrometheus): nil,
+}
+
+// lbMethods lists the pool algorithms Octavia accepts
+var lbMethods = []string{
+	string(v2pools.LBMethodRoundRobin), string(v2pools.LBMethodLeastConnections),
+	string(v2pools.LBMethodSourceIp), string(v2pools.LBMethodSourceIpPort),
+}
+
+// ValidateLBTopology checks that the objects of the spec are complete and consistent with each other:
+// that pools refer to existing listeners with a compatible protocol, that monitors suit the protocol of their pool,
+// and that members are valid. It makes no API calls, so that a topology that Octavia would reject part way through
+// is refused before anything is created. All the problems found are returned together.
+func ValidateLBTopology(spec LBTopologySpec) error {
+	var errs []error
+
+	vips := 0
+	for _, id := range []string{spec.LoadBalancer.VipSubnetID, spec.LoadBalancer.VipNetworkID, spec.LoadBalancer.VipPortID} {
+		if id != "" {
+			vips++
+		}
+	}
+	if vips > 1 {
+		errs = append(errs, fmt.Errorf("only one of VipSubnetID, VipNetworkID and VipPortID can be set"))
+	}
+
+	listenersByName := make(map[string]listeners.CreateOpts)
+	ports := make(map[string]string)
+	for _, listener := range spec.Listeners {
+		if listener.Name == "" {
+			errs = append(errs, fmt.Errorf("listener on port %d: name is required, as pools refer to listeners by name", listener.ProtocolPort))
+			continue
+		}
+		if _, found := listenersByName[listener.Name]; found {
+			errs = append(errs, fmt.Errorf("listener %q: name is not unique", listener.Name))
+			continue
+		}
+		listenersByName[listener.Name] = listener
+
+		if _, known := listenerPoolProtocols[string(listener.Protocol)]; !known {
+			errs = append(errs, fmt.Errorf("listener %q: unknown protocol %q", listener.Name, listener.Protocol))
+		}
+		if listener.ProtocolPort < 1 || listener.ProtocolPort > 65535 {
+			errs = append(errs, fmt.Errorf("listener %q: port must be between 1 and 65535, got %d", listener.Name, listener.ProtocolPort))
+			continue
+		}
+		// UDP and SCTP listeners may share a port with a TCP based one
+		key := fmt.Sprintf("%s/%d", transportProtocol(string(listener.Protocol)), listener.ProtocolPort)
+		if other, found := ports[key]; found {
+			errs = append(errs, fmt.Errorf("listener %q: port %d is already used by listener %q", listener.Name, listener.ProtocolPort, other))
+		}
+		ports[key] = listener.Name
+	}
+
+	poolNames := make(map[string]bool)
+	defaultPools := make(map[string]string)
+	for _, pool := range spec.Pools {
+		name := pool.Pool.Name
+		if name != "" {
+			if poolNames[name] {
+				errs = append(errs, fmt.Errorf("pool %q: name is not unique", name))
+			}
+			poolNames[name] = true
+		}
+		for _, err := range validateLBPool(pool, listenersByName, defaultPools) {
+			errs = append(errs, fmt.Errorf("pool %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLBPool checks a pool of a topology, its monitor and members, and records it as the default pool of its listener.
+func validateLBPool(pool LBPoolSpec, listenersByName map[string]listeners.CreateOpts, defaultPools map[string]string) []error {
+	var errs []error
+
+	protocol := string(pool.Pool.Protocol)
+	if protocol == "" {
+		errs = append(errs, fmt.Errorf("protocol is required"))
+	} else if _, known := poolMonitorTypes[protocol]; !known {
+		errs = append(errs, fmt.Errorf("unknown protocol %q", protocol))
+		protocol = ""
+	}
+	if pool.Pool.LBMethod == "" {
+		errs = append(errs, fmt.Errorf("algorithm is required"))
+	} else if !slices.Contains(lbMethods, string(pool.Pool.LBMethod)) {
+		errs = append(errs, fmt.Errorf("unknown algorithm %q, must be one of %s", pool.Pool.LBMethod, strings.Join(lbMethods, ", ")))
+	}
+
+	if pool.Listener != "" {
+		listener, found := listenersByName[pool.Listener]
+		if !found {
+			errs = append(errs, fmt.Errorf("listener %q does not exist", pool.Listener))
+		} else {
+			if other, found := defaultPools[pool.Listener]; found {
+				errs = append(errs, fmt.Errorf("listener %q already has default pool %q", pool.Listener, other))
+			}
+			defaultPools[pool.Listener] = pool.Pool.Name
+
+			supported, known := listenerPoolProtocols[string(listener.Protocol)]
+			if known && protocol != "" && !slices.Contains(supported, protocol) {
+				if len(supported) == 0 {
+					errs = append(errs, fmt.Errorf("listener %q with protocol %s cannot have a pool", pool.Listener, listener.Protocol))
+				} else {
+					errs = append(errs, fmt.Errorf("protocol %s is not supported behind listener %q with protocol %s, must be one of %s",
+						protocol, pool.Listener, listener.Protocol, strings.Join(supported, ", ")))
+				}
+			}
+		}
+	}
+
+	if pool.Monitor != nil && protocol != "" {
+		monitor := *pool.Monitor
+		monitorType, err := MonitorTypeForPoolProtocol(protocol, monitor.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid monitor: %w", err))
+		} else {
+			monitor.Type = monitorType
+			if _, err := BuildMonitorCreateOpts(monitor); err != nil {
+				errs = append(errs, fmt.Errorf("invalid monitor: %w", err))
+			}
+		}
+	}
+
+	members := make(map[string]bool)
+	for _, member := range pool.Members {
+		key := PoolMemberKey(member.Address, member.ProtocolPort)
+		if net.ParseIP(member.Address) == nil {
+			errs = append(errs, fmt.Errorf("member %q: invalid address %q", member.Name, member.Address))
+		}
+		if member.ProtocolPort < 1 || member.ProtocolPort > 65535 {
+			errs = append(errs, fmt.Errorf("member %q: port must be between 1 and 65535, got %d", member.Name, member.ProtocolPort))
+		}
+		if member.Weight != nil && (*member.Weight < 0 || *member.Weight > maxMemberWeight) {
+			errs = append(errs, fmt.Errorf("member %q: weight must be between 0 and %d, got %d", member.Name, maxMemberWeight, *member.Weight))
+		}
+		if members[key] {
+			errs = append(errs, fmt.Errorf("member %q: %s is already a member", member.Name, key))
+		}
+		members[key] = true
+	}
+
+	return errs
+}
+
+// transportProtocol returns the transport protocol a listener protocol is served over
+func transportProtocol(protocol string) string {
+	switch protocol {
+	case string(listeners.ProtocolUDP), string(listeners.ProtocolSCTP):
+		return protocol
+	default:
+		return string(listeners.ProtocolTCP)
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// validLBTopologySpec returns an API loadbalancer with a TCP listener and pool, and a kubelet health monitor
+func validLBTopologySpec() LBTopologySpec {
+	return LBTopologySpec{
+		LoadBalancer: loadbalancers.CreateOpts{Name: "api", VipSubnetID: "subnet"},
+		Listeners: []listeners.CreateOpts{
+			{Name: "api-https", Protocol: listeners.ProtocolTCP, ProtocolPort: 443},
+			{Name: "stats", Protocol: listeners.ProtocolPrometheus, ProtocolPort: 9100},
+		},
+		Pools: []LBPoolSpec{
+			{
+				Pool:     v2pools.CreateOpts{Name: "api-https", Protocol: v2pools.ProtocolTCP, LBMethod: v2pools.LBMethodRoundRobin},
+				Listener: "api-https",
+				Monitor:  &MonitorSpec{Name: "api-https", Delay: 10, Timeout: 5, MaxRetries: 3},
+				Members: []MemberSpec{
+					{Name: "control-plane-1", Address: "10.0.0.1", ProtocolPort: 443},
+					{Name: "control-plane-2", Address: "10.0.0.2", ProtocolPort: 443, Weight: fi.PtrTo(2)},
+				},
+			},
+		},
+	}
+}
+
+func Test_ValidateLBTopology(t *testing.T) {
+	tests := []struct {
+		desc           string
+		modify         func(spec *LBTopologySpec)
+		expectedErrors []string
+	}{
+		{
+			desc:   "valid",
+			modify: func(spec *LBTopologySpec) {},
+		},
+		{
+			desc: "several VIPs",
+			modify: func(spec *LBTopologySpec) {
+				spec.LoadBalancer.VipNetworkID = "network"
+			},
+			expectedErrors: []string{"only one of VipSubnetID, VipNetworkID and VipPortID"},
+		},
+		{
+			desc: "listener without name",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[1].Name = ""
+			},
+			expectedErrors: []string{"listener on port 9100: name is required"},
+		},
+		{
+			desc: "duplicate listener",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[1].Name = "api-https"
+			},
+			expectedErrors: []string{`listener "api-https": name is not unique`},
+		},
+		{
+			desc: "listener port conflict",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[1].ProtocolPort = 443
+			},
+			expectedErrors: []string{`listener "stats": port 443 is already used by listener "api-https"`},
+		},
+		{
+			desc: "UDP listener may share a port with a TCP listener",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners = append(spec.Listeners, listeners.CreateOpts{Name: "dns", Protocol: listeners.ProtocolUDP, ProtocolPort: 443})
+			},
+		},
+		{
+			desc: "listener without port",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[0].ProtocolPort = 0
+			},
+			expectedErrors: []string{`listener "api-https": port must be between 1 and 65535`},
+		},
+		{
+			desc: "unknown listener protocol",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[1].Protocol = "QUIC"
+			},
+			expectedErrors: []string{`listener "stats": unknown protocol "QUIC"`},
+		},
+		{
+			desc: "pool referencing a missing listener",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Listener = "api-http"
+			},
+			expectedErrors: []string{`pool "api-https": listener "api-http" does not exist`},
+		},
+		{
+			desc: "pool protocol not supported by listener",
+			modify: func(spec *LBTopologySpec) {
+				spec.Listeners[0].Protocol = listeners.ProtocolHTTP
+			},
+			expectedErrors: []string{`pool "api-https": protocol TCP is not supported behind listener "api-https" with protocol HTTP`},
+		},
+		{
+			desc: "pool behind prometheus listener",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Listener = "stats"
+			},
+			expectedErrors: []string{`pool "api-https": listener "stats" with protocol PROMETHEUS cannot have a pool`},
+		},
+		{
+			desc: "two default pools for a listener",
+			modify: func(spec *LBTopologySpec) {
+				pool := spec.Pools[0]
+				pool.Pool.Name = "api-https-2"
+				pool.Members = nil
+				spec.Pools = append(spec.Pools, pool)
+			},
+			expectedErrors: []string{`pool "api-https-2": listener "api-https" already has default pool "api-https"`},
+		},
+		{
+			desc: "duplicate pool",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools = append(spec.Pools, LBPoolSpec{Pool: spec.Pools[0].Pool})
+			},
+			expectedErrors: []string{`pool "api-https": name is not unique`},
+		},
+		{
+			desc: "pool without protocol and algorithm",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Pool.Protocol = ""
+				spec.Pools[0].Pool.LBMethod = ""
+			},
+			expectedErrors: []string{`pool "api-https": protocol is required`, `pool "api-https": algorithm is required`},
+		},
+		{
+			desc: "unknown algorithm",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Pool.LBMethod = "WEIGHTED_ROUND_ROBIN"
+			},
+			expectedErrors: []string{`pool "api-https": unknown algorithm "WEIGHTED_ROUND_ROBIN"`},
+		},
+		{
+			desc: "monitor type incompatible with pool protocol",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Monitor.Type = monitors.TypeUDPConnect
+			},
+			expectedErrors: []string{`pool "api-https": invalid monitor: monitor type "UDP-CONNECT" is not supported for pool protocol "TCP"`},
+		},
+		{
+			desc: "monitor missing required fields",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Monitor.Type = monitors.TypeHTTP
+			},
+			expectedErrors: []string{`pool "api-https": invalid monitor: URLPath is required for HTTP monitors`},
+		},
+		{
+			desc: "invalid members",
+			modify: func(spec *LBTopologySpec) {
+				spec.Pools[0].Members = append(spec.Pools[0].Members,
+					MemberSpec{Name: "hostname", Address: "node.example.com", ProtocolPort: 443},
+					MemberSpec{Name: "no-port", Address: "10.0.0.3"},
+					MemberSpec{Name: "heavy", Address: "10.0.0.4", ProtocolPort: 443, Weight: fi.PtrTo(300)},
+					MemberSpec{Name: "again", Address: "10.0.0.1", ProtocolPort: 443},
+				)
+			},
+			expectedErrors: []string{
+				`member "hostname": invalid address "node.example.com"`,
+				`member "no-port": port must be between 1 and 65535`,
+				`member "heavy": weight must be between 0 and 256`,
+				`member "again": 10.0.0.1:443 is already a member`,
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			spec := validLBTopologySpec()
+			testCase.modify(&spec)
+
+			err := ValidateLBTopology(spec)
+			if len(testCase.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v", testCase.expectedErrors)
+			}
+			for _, expected := range testCase.expectedErrors {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected error containing %q, got %v", expected, err)
+				}
+			}
+		})
+	}
+}
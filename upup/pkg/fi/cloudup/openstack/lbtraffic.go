@@ -0,0 +1,104 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// maxMemberWeight is the highest weight Octavia accepts for a pool member
+const maxMemberWeight = 256
+
+// weightedLBMethods are the pool algorithms which balance traffic by member weight.
+// Octavia has no separate weighted round robin algorithm: its ROUND_ROBIN is weighted.
+// The SOURCE_IP algorithms pin clients to members by hash, so weights cannot split their traffic precisely.
+var weightedLBMethods = []string{string(v2pools.LBMethodRoundRobin), string(v2pools.LBMethodLeastConnections)}
+
+func (c *openstackCloud) SetPoolTrafficSplit(poolID string, weights map[string]int) error {
+	return setPoolTrafficSplit(c, poolID, weights)
+}
+
+// setPoolTrafficSplit sets the weights of the members of the pool, by member ID, in a single batch update,
+// for example to send a tenth of the traffic to a canary backend with weights of 9 and 1.
+// Members that are not in weights keep their weight.
+func setPoolTrafficSplit(c OpenstackCloud, poolID string, weights map[string]int) error {
+	for _, id := range slices.Sorted(maps.Keys(weights)) {
+		if weight := weights[id]; weight < 0 || weight > maxMemberWeight {
+			return fmt.Errorf("invalid weight %d for member %s of pool %s, must be between 0 and %d", weight, id, poolID, maxMemberWeight)
+		}
+	}
+
+	pool, err := c.GetPool(poolID)
+	if err != nil {
+		return fmt.Errorf("error getting pool %s: %w", poolID, err)
+	}
+	if !slices.Contains(weightedLBMethods, pool.LBMethod) {
+		return fmt.Errorf("pool %s uses the %s algorithm, which does not balance traffic by weight; use one of %v", poolID, pool.LBMethod, weightedLBMethods)
+	}
+
+	existing, err := c.ListPoolMembersMap(poolID)
+	if err != nil {
+		return err
+	}
+
+	desired := make([]MemberSpec, 0, len(existing))
+	missing := maps.Clone(weights)
+	totalWeight := 0
+	for _, key := range slices.Sorted(maps.Keys(existing)) {
+		member := existing[key]
+		weight, ok := weights[member.ID]
+		if ok {
+			delete(missing, member.ID)
+		} else {
+			weight = member.Weight
+		}
+		totalWeight += weight
+		spec := NewMemberSpec(member)
+		spec.Weight = fi.PtrTo(weight)
+		desired = append(desired, spec)
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("pool %s has no members %v", poolID, slices.Sorted(maps.Keys(missing)))
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("traffic split of pool %s would leave no member receiving traffic", poolID)
+	}
+
+	return convergePoolMembers(c, poolID, existing, desired, MemberPreservation{})
+}
+
+func (c *openstackCloud) GetPoolTrafficSplit(poolID string) (map[string]int, error) {
+	return getPoolTrafficSplit(c, poolID)
+}
+
+// getPoolTrafficSplit returns the weights of the members of the pool, by member ID, as set by SetPoolTrafficSplit.
+func getPoolTrafficSplit(c OpenstackCloud, poolID string) (map[string]int, error) {
+	members, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing members of pool %s: %w", poolID, err)
+	}
+	weights := make(map[string]int, len(members))
+	for _, member := range members {
+		weights[member.ID] = member.Weight
+	}
+	return weights, nil
+}
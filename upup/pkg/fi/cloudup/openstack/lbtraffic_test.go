@@ -0,0 +1,108 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+)
+
+func Test_PoolTrafficSplit(t *testing.T) {
+	tests := []struct {
+		desc          string
+		algorithm     string
+		weights       map[string]int
+		expected      map[string]int
+		expectedError string
+	}{
+		{
+			desc:      "canary gets a tenth of the traffic",
+			algorithm: "ROUND_ROBIN",
+			weights:   map[string]int{"stable": 9, "canary": 1},
+			expected:  map[string]int{"stable": 9, "canary": 1, "other": 3},
+		},
+		{
+			desc:      "least connections is weighted",
+			algorithm: "LEAST_CONNECTIONS",
+			weights:   map[string]int{"canary": 0},
+			expected:  map[string]int{"stable": 1, "canary": 0, "other": 3},
+		},
+		{
+			desc:          "source IP is not weighted",
+			algorithm:     "SOURCE_IP",
+			weights:       map[string]int{"canary": 1},
+			expectedError: "does not balance traffic by weight",
+		},
+		{
+			desc:          "unknown member",
+			algorithm:     "ROUND_ROBIN",
+			weights:       map[string]int{"missing": 1},
+			expectedError: "has no members [missing]",
+		},
+		{
+			desc:          "weight out of range",
+			algorithm:     "ROUND_ROBIN",
+			weights:       map[string]int{"canary": 257},
+			expectedError: "must be between 0 and 256",
+		},
+		{
+			desc:          "no traffic",
+			algorithm:     "ROUND_ROBIN",
+			weights:       map[string]int{"stable": 0, "canary": 0, "other": 0},
+			expectedError: "no member receiving traffic",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMemberPoolOctavia(t, testCase.algorithm,
+				v2pools.Member{ID: "stable", Name: "stable", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1},
+				v2pools.Member{ID: "canary", Name: "canary", Address: "10.0.0.2", ProtocolPort: 443, Weight: 1},
+				v2pools.Member{ID: "other", Name: "other", Address: "10.0.0.3", ProtocolPort: 443, Weight: 3},
+			)
+			cloud := octavia.cloud()
+
+			err := cloud.SetPoolTrafficSplit("pool", testCase.weights)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedError, err)
+				}
+				if batches := octavia.count("PUT /lbaas/pools/pool/members"); batches != 0 {
+					t.Errorf("expected no batch update, got %d", batches)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if batches := octavia.count("PUT /lbaas/pools/pool/members"); batches != 1 {
+				t.Errorf("expected the weights to be set in a single batch update, got %d", batches)
+			}
+
+			actual, err := cloud.GetPoolTrafficSplit("pool")
+			if err != nil {
+				t.Fatalf("unexpected error reading weights: %v", err)
+			}
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected weights %v, got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
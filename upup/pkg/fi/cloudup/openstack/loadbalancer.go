@@ -18,19 +18,35 @@ package openstack
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gophercloud/gophercloud/v2"
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/flavorprofiles"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/flavors"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/l7policies"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
 	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"golang.org/x/sync/errgroup"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/klog/v2"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/truncate"
+	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/utils/clock"
 )
 
 // memberBackoff is the backoff strategy for openstack updating members in loadbalancer pool
@@ -41,15 +57,183 @@ var memberBackoff = wait.Backoff{
 	Steps:    10,
 }
 
+// maxLBNameLength is the longest name Octavia accepts for loadbalancers, listeners, pools and monitors
+const maxLBNameLength = 255
+
+// NormalizeLBName returns a name for an Octavia object that fits within the name length limit.
+// Longer names are truncated and suffixed with a hash of the full name, so names sharing a long prefix stay distinct.
+func NormalizeLBName(name string) string {
+	return truncate.TruncateString(name, truncate.TruncateStringOptions{
+		MaxLength:  maxLBNameLength,
+		HashLength: 6,
+	})
+}
+
+// poolMonitorTypes lists the health monitor types Octavia accepts for each pool protocol.
+// The first entry is used as the default when no monitor type is requested.
+var poolMonitorTypes = map[string][]string{
+	string(v2pools.ProtocolTCP):     {monitors.TypeTCP, monitors.TypeHTTP, monitors.TypeHTTPS, monitors.TypeTLSHELLO, monitors.TypePING},
+	string(v2pools.ProtocolHTTP):    {monitors.TypeHTTP, monitors.TypeHTTPS, monitors.TypeTCP, monitors.TypeTLSHELLO, monitors.TypePING},
+	string(v2pools.ProtocolHTTPS):   {monitors.TypeHTTPS, monitors.TypeHTTP, monitors.TypeTCP, monitors.TypeTLSHELLO, monitors.TypePING},
+	string(v2pools.ProtocolPROXY):   {monitors.TypeTCP, monitors.TypeHTTP, monitors.TypeHTTPS, monitors.TypeTLSHELLO, monitors.TypePING},
+	string(v2pools.ProtocolPROXYV2): {monitors.TypeTCP, monitors.TypeHTTP, monitors.TypeHTTPS, monitors.TypeTLSHELLO, monitors.TypePING},
+	string(v2pools.ProtocolUDP):     {monitors.TypeUDPConnect, monitors.TypeTCP, monitors.TypeHTTP, monitors.TypeSCTP},
+	string(v2pools.ProtocolSCTP):    {monitors.TypeSCTP, monitors.TypeUDPConnect, monitors.TypeTCP, monitors.TypeHTTP},
+}
+
+// MonitorTypeForPoolProtocol returns the health monitor type to use for a pool with the given protocol.
+// When monitorType is empty a default matching the pool protocol is returned,
+// otherwise an error is returned if Octavia does not support the combination.
+func MonitorTypeForPoolProtocol(poolProtocol string, monitorType string) (string, error) {
+	supported, ok := poolMonitorTypes[strings.ToUpper(poolProtocol)]
+	if !ok {
+		return "", fmt.Errorf("unknown pool protocol %q", poolProtocol)
+	}
+	if monitorType == "" {
+		return supported[0], nil
+	}
+	for _, t := range supported {
+		if strings.EqualFold(t, monitorType) {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("monitor type %q is not supported for pool protocol %q, must be one of %s", monitorType, poolProtocol, strings.Join(supported, ", "))
+}
+
+// MonitorSpec describes a pool health monitor, see BuildMonitorCreateOpts.
+type MonitorSpec struct {
+	Name   string
+	PoolID string
+	// Type is the health monitor type, which must already be resolved against the pool protocol
+	Type string
+
+	Delay          int
+	Timeout        int
+	MaxRetries     int
+	MaxRetriesDown int
+
+	// The following fields are only valid for HTTP and HTTPS monitors
+	URLPath       string
+	HTTPMethod    string
+	HTTPVersion   string
+	ExpectedCodes string
+	DomainName    string
+}
+
+// monitorHTTPMethods lists the HTTP methods Octavia accepts for HTTP and HTTPS monitors
+var monitorHTTPMethods = []string{"CONNECT", "DELETE", "GET", "HEAD", "OPTIONS", "PATCH", "POST", "PUT", "TRACE"}
+
+// BuildMonitorCreateOpts validates that the fields of the spec are compatible with its monitor type,
+// and returns the options to create it with, so that mistakes are reported before Octavia is called.
+func BuildMonitorCreateOpts(spec MonitorSpec) (monitors.CreateOpts, error) {
+	opts := monitors.CreateOpts{
+		Name:           spec.Name,
+		PoolID:         spec.PoolID,
+		Type:           spec.Type,
+		Delay:          spec.Delay,
+		Timeout:        spec.Timeout,
+		MaxRetries:     spec.MaxRetries,
+		MaxRetriesDown: spec.MaxRetriesDown,
+	}
+
+	switch spec.Type {
+	case monitors.TypeHTTP, monitors.TypeHTTPS:
+		if spec.URLPath == "" {
+			return opts, fmt.Errorf("URLPath is required for %s monitors", spec.Type)
+		}
+		if !strings.HasPrefix(spec.URLPath, "/") {
+			return opts, fmt.Errorf("URLPath %q must start with /", spec.URLPath)
+		}
+		opts.URLPath = spec.URLPath
+
+		opts.HTTPMethod = "GET"
+		if spec.HTTPMethod != "" {
+			opts.HTTPMethod = strings.ToUpper(spec.HTTPMethod)
+			if !slices.Contains(monitorHTTPMethods, opts.HTTPMethod) {
+				return opts, fmt.Errorf("HTTPMethod %q is not supported, must be one of %s", spec.HTTPMethod, strings.Join(monitorHTTPMethods, ", "))
+			}
+		}
+
+		switch spec.HTTPVersion {
+		case "", "1.0", "1.1":
+			opts.HTTPVersion = spec.HTTPVersion
+		default:
+			return opts, fmt.Errorf("HTTPVersion %q is not supported, must be 1.0 or 1.1", spec.HTTPVersion)
+		}
+		if spec.DomainName != "" {
+			// The Host header is only sent with HTTP/1.1 requests
+			if spec.HTTPVersion != "1.1" {
+				return opts, fmt.Errorf("DomainName requires HTTPVersion 1.1")
+			}
+			opts.DomainName = spec.DomainName
+		}
+		opts.ExpectedCodes = spec.ExpectedCodes
+
+	case monitors.TypeTCP, monitors.TypePING, monitors.TypeTLSHELLO, monitors.TypeUDPConnect, monitors.TypeSCTP:
+		var httpFields []string
+		if spec.URLPath != "" {
+			httpFields = append(httpFields, "URLPath")
+		}
+		if spec.HTTPMethod != "" {
+			httpFields = append(httpFields, "HTTPMethod")
+		}
+		if spec.HTTPVersion != "" {
+			httpFields = append(httpFields, "HTTPVersion")
+		}
+		if spec.ExpectedCodes != "" {
+			httpFields = append(httpFields, "ExpectedCodes")
+		}
+		if spec.DomainName != "" {
+			httpFields = append(httpFields, "DomainName")
+		}
+		if len(httpFields) != 0 {
+			return opts, fmt.Errorf("%s can only be set on HTTP or HTTPS monitors, not %s", strings.Join(httpFields, ", "), spec.Type)
+		}
+
+	case "":
+		return opts, fmt.Errorf("monitor type is required")
+	default:
+		return opts, fmt.Errorf("unknown monitor type %q", spec.Type)
+	}
+
+	if spec.Delay <= 0 {
+		return opts, fmt.Errorf("Delay must be positive, got %d", spec.Delay)
+	}
+	if spec.Timeout <= 0 || spec.Timeout > spec.Delay {
+		return opts, fmt.Errorf("Timeout must be positive and no greater than Delay (%d), got %d", spec.Delay, spec.Timeout)
+	}
+	if spec.MaxRetries < 1 || spec.MaxRetries > 10 {
+		return opts, fmt.Errorf("MaxRetries must be between 1 and 10, got %d", spec.MaxRetries)
+	}
+	if spec.MaxRetriesDown != 0 && (spec.MaxRetriesDown < 1 || spec.MaxRetriesDown > 10) {
+		return opts, fmt.Errorf("MaxRetriesDown must be between 1 and 10, got %d", spec.MaxRetriesDown)
+	}
+
+	return opts, nil
+}
+
 func (c *openstackCloud) CreatePoolMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error) {
 	return createPoolMonitor(c, opts)
 }
 
 func createPoolMonitor(c OpenstackCloud, opts monitors.CreateOpts) (poolMonitor *monitors.Monitor, err error) {
+	defer recordLBOperation(c, LBOperationCreateMonitor, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
+	if opts.PoolID != "" {
+		pool, err := getPool(c, opts.PoolID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pool %s for monitor: %v", opts.PoolID, err)
+		}
+		opts.Type, err = MonitorTypeForPoolProtocol(pool.Protocol, opts.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
 		poolMonitor, err = monitors.Create(context.TODO(), c.LoadBalancerClient(), opts).Extract()
 		if err != nil {
@@ -74,31 +258,49 @@ func listMonitors(c OpenstackCloud, opts monitors.ListOpts) (monitorList []monit
 	if c.LoadBalancerClient() == nil {
 		return monitorList, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		allPages, err := monitors.List(c.LoadBalancerClient(), opts).AllPages(context.TODO())
-		if err != nil {
-			return false, fmt.Errorf("failed to list monitors: %s", err)
-		}
-		monitorList, err = monitors.ExtractMonitors(allPages)
+
+	return listAll("monitors", monitors.List(c.LoadBalancerClient(), opts), monitors.ExtractMonitors)
+}
+
+func (c *openstackCloud) DeleteMonitor(monitorID string) error {
+	return deleteMonitor(c, monitorID)
+}
+
+func (c *openstackCloud) UpdateMonitor(monitorID string, opts monitors.UpdateOpts) (*monitors.Monitor, error) {
+	return updateMonitor(c, monitorID, opts)
+}
+
+func updateMonitor(c OpenstackCloud, monitorID string, opts monitors.UpdateOpts) (monitor *monitors.Monitor, err error) {
+	defer recordLBOperation(c, LBOperationUpdateMonitor, time.Now(), &err)
+
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := monitors.Update(context.TODO(), c.LoadBalancerClient(), monitorID, opts).Extract()
 		if err != nil {
-			return false, fmt.Errorf("failed to extract monitor pages: %s", err)
+			// loadbalancer is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return false, nil
+			}
+			return true, fmt.Errorf("error updating pool monitor: %w", err)
 		}
+		monitor = v
 		return true, nil
 	})
 	if !done {
 		if err == nil {
 			err = wait.ErrWaitTimeout
 		}
-		return monitorList, err
+		return monitor, err
 	}
-	return monitorList, nil
+	return monitor, err
 }
 
-func (c *openstackCloud) DeleteMonitor(monitorID string) error {
-	return deleteMonitor(c, monitorID)
-}
+func deleteMonitor(c OpenstackCloud, monitorID string) (err error) {
+	defer recordLBOperation(c, LBOperationDeleteMonitor, time.Now(), &err)
 
-func deleteMonitor(c OpenstackCloud, monitorID string) error {
 	if c.LoadBalancerClient() == nil {
 		return fmt.Errorf("loadbalancer support not available in this deployment")
 	}
@@ -121,17 +323,110 @@ func deleteMonitor(c OpenstackCloud, monitorID string) error {
 	}
 }
 
+func (c *openstackCloud) AttachMonitorToPool(poolID string, opts monitors.CreateOpts) (*monitors.Monitor, error) {
+	return attachMonitorToPool(c, poolID, opts)
+}
+
+// attachMonitorToPool creates a monitor for the pool, and waits until the pool references it.
+// A pool can only have one monitor, so attaching to a pool which already has one is an error.
+func attachMonitorToPool(c OpenstackCloud, poolID string, opts monitors.CreateOpts) (*monitors.Monitor, error) {
+	pool, err := getPool(c, poolID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pool %s: %w", poolID, err)
+	}
+	if pool.MonitorID != "" {
+		return nil, fmt.Errorf("pool %s already has monitor %s", poolID, pool.MonitorID)
+	}
+
+	opts.PoolID = poolID
+	monitor, err := createPoolMonitor(c, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := waitForPoolMonitor(c, poolID, monitor.ID); err != nil {
+		return monitor, err
+	}
+	return monitor, nil
+}
+
+func (c *openstackCloud) DetachMonitorFromPool(poolID string) error {
+	return detachMonitorFromPool(c, poolID)
+}
+
+// detachMonitorFromPool deletes the monitor of the pool, and waits until the pool no longer references it.
+// Some providers keep the healthmonitor_id of the pool for a while after the monitor is deleted.
+func detachMonitorFromPool(c OpenstackCloud, poolID string) error {
+	pool, err := getPool(c, poolID)
+	if err != nil {
+		return fmt.Errorf("error getting pool %s: %w", poolID, err)
+	}
+	if pool.MonitorID == "" {
+		return nil
+	}
+
+	klog.V(2).Infof("Detaching monitor %s from pool %s", pool.MonitorID, poolID)
+	done, err := vfs.RetryWithBackoff(deleteBackoff, func() (bool, error) {
+		err := monitors.Delete(context.TODO(), c.LoadBalancerClient(), pool.MonitorID).ExtractErr()
+		if err == nil || isNotFound(err) {
+			return true, nil
+		}
+		// loadbalancer is currently in immutable state, try to retry
+		if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+			return false, nil
+		}
+		return true, fmt.Errorf("error deleting monitor %s of pool %s: %w", pool.MonitorID, poolID, err)
+	})
+	if err != nil {
+		return err
+	}
+	if !done {
+		return fmt.Errorf("error deleting monitor %s of pool %s: %w", pool.MonitorID, poolID, wait.ErrWaitTimeout)
+	}
+
+	return waitForPoolMonitor(c, poolID, "")
+}
+
+// waitForPoolMonitor waits until the pool references the monitor monitorID, or no monitor if monitorID is empty.
+func waitForPoolMonitor(c OpenstackCloud, poolID string, monitorID string) error {
+	var current string
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		pool, err := getPool(c, poolID)
+		if err != nil {
+			return true, fmt.Errorf("error getting pool %s: %w", poolID, err)
+		}
+		current = pool.MonitorID
+		return current == monitorID, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !done {
+		if monitorID == "" {
+			return fmt.Errorf("pool %s still references monitor %s after it was deleted", poolID, current)
+		}
+		return fmt.Errorf("pool %s does not reference monitor %s, got %q", poolID, monitorID, current)
+	}
+	return nil
+}
+
 func (c *openstackCloud) DeletePool(poolID string) error {
 	return deletePool(c, poolID)
 }
 
-func deletePool(c OpenstackCloud, poolID string) error {
+func deletePool(c OpenstackCloud, poolID string) (err error) {
+	defer recordLBOperation(c, LBOperationDeletePool, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
+	deleted := false
 	done, err := vfs.RetryWithBackoff(deleteBackoff, func() (bool, error) {
 		err := v2pools.Delete(context.TODO(), c.LoadBalancerClient(), poolID).ExtractErr()
+		if err == nil {
+			deleted = true
+		}
 		if err != nil && !isNotFound(err) {
 			return false, fmt.Errorf("error deleting pool: %v", err)
 		}
@@ -143,6 +438,9 @@ func deletePool(c OpenstackCloud, poolID string) error {
 	if err != nil {
 		return err
 	} else if done {
+		if deleted {
+			notifyLBDelete(c, LBResourcePool, poolID)
+		}
 		return nil
 	} else {
 		return wait.ErrWaitTimeout
@@ -153,13 +451,19 @@ func (c *openstackCloud) DeleteListener(listenerID string) error {
 	return deleteListener(c, listenerID)
 }
 
-func deleteListener(c OpenstackCloud, listenerID string) error {
+func deleteListener(c OpenstackCloud, listenerID string) (err error) {
+	defer recordLBOperation(c, LBOperationDeleteListener, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
+	deleted := false
 	done, err := vfs.RetryWithBackoff(deleteBackoff, func() (bool, error) {
 		err := listeners.Delete(context.TODO(), c.LoadBalancerClient(), listenerID).ExtractErr()
+		if err == nil {
+			deleted = true
+		}
 		if err != nil && !isNotFound(err) {
 			return false, fmt.Errorf("error deleting listener: %v", err)
 		}
@@ -171,6 +475,9 @@ func deleteListener(c OpenstackCloud, listenerID string) error {
 	if err != nil {
 		return err
 	} else if done {
+		if deleted {
+			notifyLBDelete(c, LBResourceListener, listenerID)
+		}
 		return nil
 	} else {
 		return wait.ErrWaitTimeout
@@ -181,13 +488,19 @@ func (c *openstackCloud) DeleteLB(lbID string, opts loadbalancers.DeleteOpts) er
 	return deleteLB(c, lbID, opts)
 }
 
-func deleteLB(c OpenstackCloud, lbID string, opts loadbalancers.DeleteOpts) error {
+func deleteLB(c OpenstackCloud, lbID string, opts loadbalancers.DeleteOpts) (err error) {
+	defer recordLBOperation(c, LBOperationDeleteLoadBalancer, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
+	deleted := false
 	done, err := vfs.RetryWithBackoff(deleteBackoff, func() (bool, error) {
 		err := loadbalancers.Delete(context.TODO(), c.LoadBalancerClient(), lbID, opts).ExtractErr()
+		if err == nil {
+			deleted = true
+		}
 		if err != nil && !isNotFound(err) {
 			return false, fmt.Errorf("error deleting loadbalancer: %v", err)
 		}
@@ -199,131 +512,612 @@ func deleteLB(c OpenstackCloud, lbID string, opts loadbalancers.DeleteOpts) erro
 	if err != nil {
 		return err
 	} else if done {
+		if deleted {
+			notifyLBDelete(c, LBResourceLoadBalancer, lbID)
+		}
 		return nil
 	} else {
 		return wait.ErrWaitTimeout
 	}
 }
 
-func (c *openstackCloud) CreateLB(opt loadbalancers.CreateOptsBuilder) (*loadbalancers.LoadBalancer, error) {
-	return createLB(c, opt)
+// loadbalancerDeleteStepTimeout is how long DeleteLBOrdered waits for the loadbalancer to become ACTIVE after each deletion
+var loadbalancerDeleteStepTimeout = 5 * time.Minute
+
+func (c *openstackCloud) DeleteLBOrdered(lbID string) error {
+	return deleteLBOrdered(c, lbID)
 }
 
-func createLB(c OpenstackCloud, opt loadbalancers.CreateOptsBuilder) (*loadbalancers.LoadBalancer, error) {
+// deleteLBOrdered deletes a loadbalancer without cascade delete, which some providers such as ovn reject.
+// Children are deleted in dependency order: the members, monitor and then the pool itself for every pool,
+// then the listeners, and finally the loadbalancer. Octavia locks the loadbalancer while it applies each deletion,
+// so every step waits for it to become ACTIVE again before the next one.
+func deleteLBOrdered(c OpenstackCloud, lbID string) error {
 	if c.LoadBalancerClient() == nil {
-		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+		return fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
-	var i *loadbalancers.LoadBalancer
-	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
-		v, err := loadbalancers.Create(context.TODO(), c.LoadBalancerClient(), opt).Extract()
+	lb, err := loadbalancers.Get(context.TODO(), c.LoadBalancerClient(), lbID).Extract()
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("error getting loadbalancer %s: %w", lbID, err)
+	}
+
+	waitForActive := func(step string) error {
+		if _, err := waitForLoadBalancerActive(context.TODO(), c, lbID, lbWaitOptions{Timeout: loadbalancerDeleteStepTimeout}); err != nil {
+			return fmt.Errorf("error waiting for loadbalancer %s after deleting %s: %w", lbID, step, err)
+		}
+		return nil
+	}
+
+	for _, p := range lb.Pools {
+		pool, err := v2pools.Get(context.TODO(), c.LoadBalancerClient(), p.ID).Extract()
 		if err != nil {
-			return false, fmt.Errorf("error creating loadbalancer: %v", err)
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("error getting pool %s: %w", p.ID, err)
+		}
+
+		members, err := c.ListPoolMembers(pool.ID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return fmt.Errorf("error listing members of pool %s: %w", pool.ID, err)
+		}
+		for _, member := range members {
+			if err := deletePoolMember(c, pool.ID, member.ID); err != nil {
+				return err
+			}
+			if err := waitForActive("member " + member.ID); err != nil {
+				return err
+			}
+		}
+		remaining, err := c.ListPoolMembers(pool.ID, v2pools.ListMembersOpts{})
+		if err != nil {
+			return fmt.Errorf("error listing members of pool %s: %w", pool.ID, err)
+		}
+		if len(remaining) != 0 {
+			return fmt.Errorf("%d members of pool %s remain after deleting them", len(remaining), pool.ID)
+		}
+
+		if pool.MonitorID != "" {
+			if err := c.DeleteMonitor(pool.MonitorID); err != nil {
+				return fmt.Errorf("error deleting monitor %s of pool %s: %w", pool.MonitorID, pool.ID, err)
+			}
+			if err := waitForActive("monitor " + pool.MonitorID); err != nil {
+				return err
+			}
+		}
+
+		if err := c.DeletePool(pool.ID); err != nil {
+			return fmt.Errorf("error deleting pool %s: %w", pool.ID, err)
+		}
+		if err := waitForActive("pool " + pool.ID); err != nil {
+			return err
+		}
+	}
+
+	for _, listener := range lb.Listeners {
+		if err := c.DeleteListener(listener.ID); err != nil {
+			return fmt.Errorf("error deleting listener %s: %w", listener.ID, err)
+		}
+		if err := waitForActive("listener " + listener.ID); err != nil {
+			return err
 		}
-		i = v
-		return true, nil
-	})
-	if err != nil {
-		return i, err
-	} else if done {
-		return i, nil
-	} else {
-		return i, wait.ErrWaitTimeout
 	}
+
+	return c.DeleteLB(lbID, loadbalancers.DeleteOpts{})
 }
 
-func (c *openstackCloud) GetLB(loadbalancerID string) (lb *loadbalancers.LoadBalancer, err error) {
-	return getLB(c, loadbalancerID)
+// DefaultLBTeardownConcurrency is the number of loadbalancers DeleteClusterLoadBalancers deletes at once by default
+const DefaultLBTeardownConcurrency = 4
+
+// DeleteClusterLoadBalancersOptions controls how DeleteClusterLoadBalancers tears down the loadbalancers of a cluster
+type DeleteClusterLoadBalancersOptions struct {
+	// Concurrency is the maximum number of loadbalancers deleted in parallel, DefaultLBTeardownConcurrency if not positive
+	Concurrency int
+	// Ordered deletes the children of every loadbalancer one by one, for providers without cascade delete
+	Ordered bool
+	// DrainTimeout, if positive, drains the members of every loadbalancer with DrainLBMembers before deleting it,
+	// waiting this long for in-flight connections to finish
+	DrainTimeout time.Duration
+	// LoadBalancerIDs, if set, are the loadbalancers to delete as found by the caller,
+	// instead of those named after the cluster
+	LoadBalancerIDs []string
 }
 
-func getLB(c OpenstackCloud, loadbalancerID string) (lb *loadbalancers.LoadBalancer, err error) {
-	if c.LoadBalancerClient() == nil {
-		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+func (c *openstackCloud) DeleteClusterLoadBalancers(clusterName string, opts DeleteClusterLoadBalancersOptions) error {
+	return deleteClusterLoadBalancers(c, clusterName, opts)
+}
+
+// isClusterLB returns true if the loadbalancer was created for the cluster,
+// either by kOps for the API or by the OpenStack cloud provider for a service
+func isClusterLB(lb loadbalancers.LoadBalancer, clusterName string) bool {
+	if lb.Name == NormalizeLBName("api."+clusterName) {
+		return true
 	}
+	return strings.HasPrefix(lb.Name, "kube_service_"+clusterName+"_")
+}
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		lb, err = loadbalancers.Get(context.TODO(), c.LoadBalancerClient(), loadbalancerID).Extract()
-		if err != nil {
-			return false, err
-		}
-		return true, nil
-	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
+// deleteClusterLoadBalancers deletes every loadbalancer of the cluster, several loadbalancers at a time.
+// The children of a single loadbalancer are still deleted one after the other, as Octavia locks the loadbalancer
+// while it applies each deletion. Errors from all loadbalancers are returned together.
+func deleteClusterLoadBalancers(c OpenstackCloud, clusterName string, opts DeleteClusterLoadBalancersOptions) error {
+	lbs, err := c.ListLBs(loadbalancers.ListOpts{})
+	if err != nil {
+		return fmt.Errorf("error listing loadbalancers: %w", err)
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultLBTeardownConcurrency
+	}
+
+	var mutex sync.Mutex
+	var errs []error
+	var eg errgroup.Group
+	eg.SetLimit(concurrency)
+	for _, lb := range lbs {
+		if len(opts.LoadBalancerIDs) > 0 {
+			if !slices.Contains(opts.LoadBalancerIDs, lb.ID) {
+				continue
+			}
+		} else if !isClusterLB(lb, clusterName) {
+			continue
 		}
-		return lb, err
+		eg.Go(func() error {
+			if opts.DrainTimeout > 0 {
+				klog.V(2).Infof("Draining loadbalancer %s (%s) for %v", lb.Name, lb.ID, opts.DrainTimeout)
+				if err := c.DrainLBMembers(context.TODO(), lb.ID, opts.DrainTimeout); err != nil {
+					// draining is best-effort, the loadbalancer is deleted anyway
+					klog.Warningf("failed to drain loadbalancer %s (%s): %v", lb.Name, lb.ID, err)
+				}
+			}
+			klog.V(2).Infof("Deleting loadbalancer %s (%s)", lb.Name, lb.ID)
+			var err error
+			if opts.Ordered {
+				err = c.DeleteLBOrdered(lb.ID)
+			} else {
+				err = c.DeleteLB(lb.ID, loadbalancers.DeleteOpts{Cascade: true})
+			}
+			if err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Errorf("error deleting loadbalancer %s (%s): %w", lb.Name, lb.ID, err))
+				mutex.Unlock()
+			}
+			return nil
+		})
 	}
-	return lb, nil
+	_ = eg.Wait()
+
+	return errors.Join(errs...)
 }
 
-// ListLBs will list load balancers
-func (c *openstackCloud) ListLBs(opt loadbalancers.ListOptsBuilder) (lbs []loadbalancers.LoadBalancer, err error) {
-	return listLBs(c, opt)
+func (c *openstackCloud) DrainLBMembers(ctx context.Context, lbID string, drain time.Duration) error {
+	return drainLBMembers(ctx, c, lbID, drain)
 }
 
-func listLBs(c OpenstackCloud, opt loadbalancers.ListOptsBuilder) (lbs []loadbalancers.LoadBalancer, err error) {
-	if c.LoadBalancerClient() == nil {
-		// skip error because cluster delete will otherwise fail
-		return lbs, nil
+// drainLBMembers sets the weight of every member of every pool of the loadbalancer to 0, so that they receive
+// no new connections but finish the existing ones, and then waits for drain, or until ctx is cancelled.
+// The members of a pool are updated with a single batch update where supported.
+func drainLBMembers(ctx context.Context, c OpenstackCloud, lbID string, drain time.Duration) error {
+	pools, err := c.ListPools(v2pools.ListOpts{LoadbalancerID: lbID})
+	if err != nil {
+		return fmt.Errorf("error listing pools of loadbalancer %s: %w", lbID, err)
 	}
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		allPages, err := loadbalancers.List(c.LoadBalancerClient(), opt).AllPages(context.TODO())
+	drained := 0
+	for _, pool := range pools {
+		members, err := c.ListPoolMembers(pool.ID, v2pools.ListMembersOpts{})
 		if err != nil {
-			return false, fmt.Errorf("failed to list loadbalancers: %s", err)
+			return fmt.Errorf("error listing members of pool %s: %w", pool.ID, err)
 		}
-		lbs, err = loadbalancers.ExtractLoadBalancers(allPages)
-		if err != nil {
-			return false, fmt.Errorf("failed to extract loadbalancer pages: %s", err)
+		if len(members) == 0 {
+			continue
 		}
-		return true, nil
-	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
+		if err := drainPoolMembers(c, pool.ID, members); err != nil {
+			return fmt.Errorf("error draining members of pool %s: %w", pool.ID, err)
 		}
-		return lbs, err
+		drained += len(members)
+	}
+	if drained == 0 {
+		return nil
 	}
-	return lbs, nil
-}
 
-func (c *openstackCloud) GetLBStats(loadbalancerID string) (stats *loadbalancers.Stats, err error) {
-	return getLBStats(c, loadbalancerID)
+	klog.V(2).Infof("drained %d members of loadbalancer %s, waiting %v for connections to finish", drained, lbID, drain)
+	timer := time.NewTimer(drain)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
-func getLBStats(c OpenstackCloud, loadbalancerID string) (stats *loadbalancers.Stats, err error) {
-	if c.LoadBalancerClient() == nil {
-		return stats, nil
+// drainPoolMembers sets the weight of the members of the pool to 0, keeping everything else about them
+func drainPoolMembers(c OpenstackCloud, poolID string, members []v2pools.Member) error {
+	specs := make([]MemberSpec, 0, len(members))
+	for _, member := range members {
+		spec := NewMemberSpec(member)
+		spec.Weight = fi.PtrTo(0)
+		specs = append(specs, spec)
+	}
+	err := batchUpdatePoolMembers(c, poolID, specs)
+	if err == nil {
+		return nil
+	}
+	if !gophercloud.ResponseCodeIs(err, http.StatusNotFound) &&
+		!gophercloud.ResponseCodeIs(err, http.StatusMethodNotAllowed) &&
+		!gophercloud.ResponseCodeIs(err, http.StatusNotImplemented) {
+		return err
 	}
+	klog.V(2).Infof("batch update of pool members not supported, draining members one by one: %v", err)
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		stats, err = loadbalancers.GetStats(context.TODO(), c.LoadBalancerClient(), loadbalancerID).Extract()
-		if err != nil {
-			return false, fmt.Errorf("Error getting load balancer stats %v", err)
+	for _, member := range members {
+		if member.Weight == 0 {
+			continue
 		}
-		return true, nil
-	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
+		if _, err := c.UpdateMemberInPool(poolID, member.ID, v2pools.UpdateMemberOpts{Weight: fi.PtrTo(0)}); err != nil {
+			return err
 		}
-		return stats, err
 	}
-	return stats, nil
+	return nil
 }
 
-func (c *openstackCloud) GetPool(poolID string) (pool *v2pools.Pool, err error) {
-	return getPool(c, poolID)
+func (c *openstackCloud) CreateLB(opt loadbalancers.CreateOptsBuilder) (*loadbalancers.LoadBalancer, error) {
+	return createLB(c, opt)
 }
 
-func getPool(c OpenstackCloud, poolID string) (pool *v2pools.Pool, err error) {
+func createLB(c OpenstackCloud, opt loadbalancers.CreateOptsBuilder) (lb *loadbalancers.LoadBalancer, err error) {
+	defer recordLBOperation(c, LBOperationCreateLoadBalancer, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		pool, err = v2pools.Get(context.TODO(), c.LoadBalancerClient(), poolID).Extract()
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := loadbalancers.Create(context.TODO(), c.LoadBalancerClient(), opt).Extract()
+		if err != nil {
+			// the request is not allowed, retrying won't help; a conflict is a requested VIP address which is already in use
+			if gophercloud.ResponseCodeIs(err, http.StatusForbidden) || gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return true, fmt.Errorf("error creating loadbalancer: %w", err)
+			}
+			return false, fmt.Errorf("error creating loadbalancer: %w", err)
+		}
+		lb = v
+		return true, nil
+	})
+	if err != nil {
+		return lb, err
+	} else if done {
+		notifyLBCreate(c, LBResourceLoadBalancer, lb.ID, lb.Name)
+		return lb, nil
+	} else {
+		return lb, wait.ErrWaitTimeout
+	}
+}
+
+// ErrLoadBalancerProvisioningFailed is returned, wrapped with the loadbalancer ID, as soon as a loadbalancer
+// being waited for goes into provisioning status ERROR, which it does not recover from.
+var ErrLoadBalancerProvisioningFailed = errors.New("loadbalancer provisioning failed")
+
+// LoadBalancerWaitTimeoutError is returned when a loadbalancer does not become ACTIVE in time.
+// It records the last status observed, to tell a stuck loadbalancer from a slow one.
+type LoadBalancerWaitTimeoutError struct {
+	LoadBalancerID     string
+	Elapsed            time.Duration
+	ProvisioningStatus string
+	OperatingStatus    string
+	// LastError is the error of the last poll, if getting the loadbalancer failed
+	LastError error
+}
+
+func (e *LoadBalancerWaitTimeoutError) Error() string {
+	msg := fmt.Sprintf("loadbalancer %s did not become ACTIVE after %s, last provisioning status %q, operating status %q",
+		e.LoadBalancerID, e.Elapsed.Round(time.Second), e.ProvisioningStatus, e.OperatingStatus)
+	if e.LastError != nil {
+		msg += fmt.Sprintf(", last error: %v", e.LastError)
+	}
+	return msg
+}
+
+// Unwrap allows callers to keep checking for wait.ErrWaitTimeout
+func (e *LoadBalancerWaitTimeoutError) Unwrap() error {
+	return wait.ErrWaitTimeout
+}
+
+// loadbalancerActivePollInterval is how often the provisioning status of a loadbalancer is checked while waiting for it
+// to become ACTIVE, unless a PollInterval is set. It is independent of readBackoff, which only retries failed requests.
+var loadbalancerActivePollInterval = 2 * time.Second
+
+// lbWaitClock is the clock used to wait for loadbalancers, replaced in tests
+var lbWaitClock clock.WithTicker = clock.RealClock{}
+
+// lbWaitOptions controls how long and how often to wait for a loadbalancer to become ACTIVE.
+type lbWaitOptions struct {
+	// Timeout is how long to wait before failing with a LoadBalancerWaitTimeoutError
+	Timeout time.Duration
+	// PollInterval is how often the provisioning status is checked, defaulting to loadbalancerActivePollInterval
+	PollInterval time.Duration
+}
+
+func (o lbWaitOptions) pollInterval() time.Duration {
+	if o.PollInterval <= 0 {
+		return loadbalancerActivePollInterval
+	}
+	return o.PollInterval
+}
+
+func (c *openstackCloud) CreateLBAndWait(opt loadbalancers.CreateOptsBuilder, timeout time.Duration) (*loadbalancers.LoadBalancer, error) {
+	return createLBAndWait(c, opt, lbWaitOptions{Timeout: timeout})
+}
+
+// createLBAndWait creates a loadbalancer and waits for it to become ACTIVE, returning the refreshed loadbalancer.
+// If the loadbalancer goes into ERROR it is deleted again, so that a failed create doesn't leave it behind.
+func createLBAndWait(c OpenstackCloud, opt loadbalancers.CreateOptsBuilder, waitOpts lbWaitOptions) (*loadbalancers.LoadBalancer, error) {
+	lb, err := c.CreateLB(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := waitForLoadBalancerActive(context.TODO(), c, lb.ID, waitOpts)
+	if current != nil {
+		lb = current
+	}
+	if err != nil {
+		if lb.ProvisioningStatus == "ERROR" {
+			klog.Warningf("deleting loadbalancer %s after failed create", lb.ID)
+			if deleteErr := c.DeleteLB(lb.ID, loadbalancers.DeleteOpts{Cascade: true}); deleteErr != nil {
+				return nil, fmt.Errorf("%w (deleting loadbalancer also failed: %v)", err, deleteErr)
+			}
+			return nil, err
+		}
+		return lb, err
+	}
+	return lb, nil
+}
+
+// waitForLoadBalancerActive waits for the loadbalancer to become ACTIVE, for example after a change to one of its children.
+// The provisioning status is checked straight away and then every PollInterval of the options.
+// It returns the last loadbalancer seen, and a LoadBalancerWaitTimeoutError if it did not become ACTIVE within the timeout.
+// A loadbalancer in ERROR fails the wait immediately with ErrLoadBalancerProvisioningFailed, and one that is not found
+// fails it too; other errors getting the loadbalancer are logged and polling goes on.
+func waitForLoadBalancerActive(ctx context.Context, c OpenstackCloud, loadbalancerID string, opts lbWaitOptions) (*loadbalancers.LoadBalancer, error) {
+	start := lbWaitClock.Now()
+	ticker := lbWaitClock.NewTicker(opts.pollInterval())
+	defer ticker.Stop()
+	deadline := lbWaitClock.NewTimer(opts.Timeout)
+	defer deadline.Stop()
+
+	var lb *loadbalancers.LoadBalancer
+	var lastErr error
+	for {
+		current, err := c.GetLB(loadbalancerID)
+		if err != nil {
+			if isNotFound(err) {
+				return lb, fmt.Errorf("error waiting for loadbalancer %s to be ACTIVE: %w", loadbalancerID, err)
+			}
+			// the API may be briefly unavailable, which says nothing about the loadbalancer
+			klog.Warningf("error getting loadbalancer %s while waiting for it to be ACTIVE, will retry: %v", loadbalancerID, err)
+			lastErr = err
+		} else {
+			lb = current
+			lastErr = nil
+			switch lb.ProvisioningStatus {
+			case "ACTIVE":
+				return lb, nil
+			case "ERROR":
+				return lb, fmt.Errorf("%w: loadbalancer %s has gone into ERROR state", ErrLoadBalancerProvisioningFailed, lb.ID)
+			}
+			klog.V(4).Infof("Waiting for loadbalancer %s to be ACTIVE, currently %s", lb.ID, lb.ProvisioningStatus)
+		}
+
+		select {
+		case <-ticker.C():
+		case <-deadline.C():
+			timeoutErr := &LoadBalancerWaitTimeoutError{
+				LoadBalancerID: loadbalancerID,
+				Elapsed:        lbWaitClock.Since(start),
+				LastError:      lastErr,
+			}
+			if lb != nil {
+				timeoutErr.ProvisioningStatus = lb.ProvisioningStatus
+				timeoutErr.OperatingStatus = lb.OperatingStatus
+			}
+			return lb, timeoutErr
+		case <-ctx.Done():
+			return lb, fmt.Errorf("error waiting for loadbalancer %s to be ACTIVE: %w", loadbalancerID, ctx.Err())
+		}
+	}
+}
+
+func (c *openstackCloud) UpdateLB(loadbalancerID string, opt loadbalancers.UpdateOpts) (*loadbalancers.LoadBalancer, error) {
+	return updateLB(c, loadbalancerID, opt)
+}
+
+func updateLB(c OpenstackCloud, loadbalancerID string, opt loadbalancers.UpdateOpts) (lb *loadbalancers.LoadBalancer, err error) {
+	defer recordLBOperation(c, LBOperationUpdateLoadBalancer, time.Now(), &err)
+
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := loadbalancers.Update(context.TODO(), c.LoadBalancerClient(), loadbalancerID, opt).Extract()
+		if err != nil {
+			// loadbalancer is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return false, nil
+			}
+			return true, fmt.Errorf("error updating loadbalancer: %w", err)
+		}
+		lb = v
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return lb, err
+	}
+	return lb, err
+}
+
+func (c *openstackCloud) GetLB(loadbalancerID string) (lb *loadbalancers.LoadBalancer, err error) {
+	return getLB(c, loadbalancerID)
+}
+
+func getLB(c OpenstackCloud, loadbalancerID string) (lb *loadbalancers.LoadBalancer, err error) {
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		lb, err = loadbalancers.Get(context.TODO(), c.LoadBalancerClient(), loadbalancerID).Extract()
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return lb, err
+	}
+	return lb, nil
+}
+
+// ListLBs will list load balancers
+func (c *openstackCloud) GetLBByName(name string) (*loadbalancers.LoadBalancer, error) {
+	return getLBByName(c, name)
+}
+
+// getLBByName returns the loadbalancer with the kops name, after NormalizeLBName, or nil if there is none.
+func getLBByName(c OpenstackCloud, name string) (*loadbalancers.LoadBalancer, error) {
+	lbName := NormalizeLBName(name)
+	lbs, err := c.ListLBs(loadbalancers.ListOpts{Name: lbName})
+	if err != nil {
+		return nil, err
+	}
+	// filter again, in case the name filter is not supported
+	lbs = slices.DeleteFunc(lbs, func(lb loadbalancers.LoadBalancer) bool {
+		return lb.Name != lbName
+	})
+	switch len(lbs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &lbs[0], nil
+	default:
+		return nil, fmt.Errorf("found %d loadbalancers with name %q", len(lbs), lbName)
+	}
+}
+
+func (c *openstackCloud) DeleteLBByName(name string, confirm func(lb *loadbalancers.LoadBalancer) bool) error {
+	return deleteLBByName(c, name, confirm)
+}
+
+// deleteLBByName deletes the loadbalancer with the kops name together with its children, if confirm accepts it.
+// A loadbalancer that does not exist is not an error, so cleanups can be repeated.
+func deleteLBByName(c OpenstackCloud, name string, confirm func(lb *loadbalancers.LoadBalancer) bool) error {
+	lb, err := c.GetLBByName(name)
+	if err != nil {
+		return err
+	}
+	if lb == nil {
+		klog.V(2).Infof("loadbalancer %q not found, nothing to delete", name)
+		return nil
+	}
+	if !confirm(lb) {
+		klog.Infof("not deleting loadbalancer %q (%s): not confirmed", lb.Name, lb.ID)
+		return nil
+	}
+
+	klog.V(2).Infof("Deleting loadbalancer %q (%s)", lb.Name, lb.ID)
+	return c.DeleteLB(lb.ID, loadbalancers.DeleteOpts{Cascade: true})
+}
+
+func (c *openstackCloud) ListLBs(opt loadbalancers.ListOptsBuilder) (lbs []loadbalancers.LoadBalancer, err error) {
+	return listLBs(c, opt)
+}
+
+func listLBs(c OpenstackCloud, opt loadbalancers.ListOptsBuilder) (lbs []loadbalancers.LoadBalancer, err error) {
+	if c.LoadBalancerClient() == nil {
+		// skip error because cluster delete will otherwise fail
+		return lbs, nil
+	}
+
+	return listAll("loadbalancers", loadbalancers.List(c.LoadBalancerClient(), opt), loadbalancers.ExtractLoadBalancers)
+}
+
+// ListLBsByStatus will list load balancers with the given provisioning status
+func (c *openstackCloud) ListLBsByStatus(status string) ([]loadbalancers.LoadBalancer, error) {
+	return listLBsByStatus(c, status)
+}
+
+// listLBsByStatus lists the loadbalancers in the given provisioning status, e.g. ERROR.
+// A trailing "*" matches any status with that prefix, so "PENDING_*" finds all loadbalancers with pending changes.
+// Not all deployments support filtering on the provisioning status, so the result is always filtered again client-side.
+func listLBsByStatus(c OpenstackCloud, status string) ([]loadbalancers.LoadBalancer, error) {
+	opts := loadbalancers.ListOpts{}
+	prefix, isPrefix := strings.CutSuffix(status, "*")
+	if !isPrefix {
+		opts.ProvisioningStatus = status
+	}
+
+	lbs, err := c.ListLBs(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []loadbalancers.LoadBalancer
+	for _, lb := range lbs {
+		if (isPrefix && strings.HasPrefix(lb.ProvisioningStatus, prefix)) || lb.ProvisioningStatus == status {
+			matches = append(matches, lb)
+		}
+	}
+	return matches, nil
+}
+
+func (c *openstackCloud) GetLBStats(loadbalancerID string) (stats *loadbalancers.Stats, err error) {
+	return getLBStats(c, loadbalancerID)
+}
+
+func getLBStats(c OpenstackCloud, loadbalancerID string) (stats *loadbalancers.Stats, err error) {
+	if c.LoadBalancerClient() == nil {
+		return stats, nil
+	}
+
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		stats, err = loadbalancers.GetStats(context.TODO(), c.LoadBalancerClient(), loadbalancerID).Extract()
+		if err != nil {
+			return false, fmt.Errorf("Error getting load balancer stats %v", err)
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return stats, err
+	}
+	return stats, nil
+}
+
+func (c *openstackCloud) GetPool(poolID string) (pool *v2pools.Pool, err error) {
+	return getPool(c, poolID)
+}
+
+func getPool(c OpenstackCloud, poolID string) (pool *v2pools.Pool, err error) {
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		pool, err = v2pools.Get(context.TODO(), c.LoadBalancerClient(), poolID).Extract()
 		if err != nil {
 			return false, err
 		}
@@ -338,6 +1132,57 @@ func getPool(c OpenstackCloud, poolID string) (pool *v2pools.Pool, err error) {
 	return pool, nil
 }
 
+// poolUpdateOpts sends the empty key manager references of a pool update as null, which removes them from the pool,
+// where gophercloud would send an empty reference
+type poolUpdateOpts v2pools.UpdateOpts
+
+func (opts poolUpdateOpts) ToPoolUpdateMap() (map[string]any, error) {
+	b, err := v2pools.UpdateOpts(opts).ToPoolUpdateMap()
+	if err != nil {
+		return nil, err
+	}
+	m := b["pool"].(map[string]any)
+	if opts.CATLSContainerRef != nil && *opts.CATLSContainerRef == "" {
+		m["ca_tls_container_ref"] = nil
+	}
+	if opts.CRLContainerRef != nil && *opts.CRLContainerRef == "" {
+		m["crl_container_ref"] = nil
+	}
+	return b, nil
+}
+
+func (c *openstackCloud) UpdatePool(poolID string, opts v2pools.UpdateOpts) (*v2pools.Pool, error) {
+	return updatePool(c, poolID, opts)
+}
+
+func updatePool(c OpenstackCloud, poolID string, opts v2pools.UpdateOpts) (pool *v2pools.Pool, err error) {
+	defer recordLBOperation(c, LBOperationUpdatePool, time.Now(), &err)
+
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := v2pools.Update(context.TODO(), c.LoadBalancerClient(), poolID, poolUpdateOpts(opts)).Extract()
+		if err != nil {
+			// loadbalancer is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return false, nil
+			}
+			return true, fmt.Errorf("error updating pool: %w", err)
+		}
+		pool = v
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return pool, err
+	}
+	return pool, err
+}
+
 func (c *openstackCloud) GetPoolMember(poolID string, memberID string) (member *v2pools.Member, err error) {
 	return getPoolMember(c, poolID, memberID)
 }
@@ -368,6 +1213,8 @@ func (c *openstackCloud) UpdateMemberInPool(poolID string, memberID string, opts
 }
 
 func updateMemberInPool(c OpenstackCloud, poolID string, memberID string, opts v2pools.UpdateMemberOptsBuilder) (association *v2pools.Member, err error) {
+	defer recordLBOperation(c, LBOperationUpdateMember, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
@@ -384,10 +1231,17 @@ func updateMemberInPool(c OpenstackCloud, poolID string, memberID string, opts v
 				klog.Infof("got error %v retrying...", http.StatusConflict)
 				return false, nil
 			}
+			// the request is rejected, retrying won't help
+			if gophercloud.ResponseCodeIs(err, http.StatusBadRequest) || gophercloud.ResponseCodeIs(err, http.StatusUnprocessableEntity) {
+				return true, fmt.Errorf("failed to update pool membership: %w", err)
+			}
 			return false, fmt.Errorf("failed to update pool membership: %v", err)
 		}
 		return true, nil
 	})
+	if err != nil && done {
+		return nil, err
+	}
 	if !done {
 		if err == nil {
 			err = wait.ErrWaitTimeout
@@ -402,9 +1256,15 @@ func (c *openstackCloud) AssociateToPool(server *servers.Server, poolID string,
 }
 
 func associateToPool(c OpenstackCloud, server *servers.Server, poolID string, opts v2pools.CreateMemberOpts) (association *v2pools.Member, err error) {
+	defer recordLBOperation(c, LBOperationCreateMember, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
+	if opts.Name == "" {
+		// name the member after its server, so it can be identified
+		opts.Name = server.Name
+	}
 
 	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
 		association, err = v2pools.GetMember(context.TODO(), c.LoadBalancerClient(), poolID, server.ID).Extract()
@@ -433,6 +1293,8 @@ func (c *openstackCloud) CreatePool(opts v2pools.CreateOpts) (pool *v2pools.Pool
 }
 
 func createPool(c OpenstackCloud, opts v2pools.CreateOpts) (pool *v2pools.Pool, err error) {
+	defer recordLBOperation(c, LBOperationCreatePool, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
@@ -450,6 +1312,7 @@ func createPool(c OpenstackCloud, opts v2pools.CreateOpts) (pool *v2pools.Pool,
 		}
 		return pool, err
 	}
+	notifyLBCreate(c, LBResourcePool, pool.ID, pool.Name)
 	return pool, nil
 }
 
@@ -462,82 +1325,171 @@ func listPoolMembers(c OpenstackCloud, poolID string, opts v2pools.ListMembersOp
 		return memberList, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		memberPage, err := v2pools.ListMembers(c.LoadBalancerClient(), poolID, opts).AllPages(context.TODO())
-		if err != nil {
-			return false, fmt.Errorf("failed to list members: %v", err)
-		}
-		memberList, err = v2pools.ExtractMembers(memberPage)
-		if err != nil {
-			return false, fmt.Errorf("failed to extract members: %v", err)
-		}
-		return true, nil
-	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
-		}
-		return memberList, err
-	}
-	return memberList, nil
+	return listAll("members", v2pools.ListMembers(c.LoadBalancerClient(), poolID, opts), v2pools.ExtractMembers)
 }
 
-func (c *openstackCloud) ListPools(opts v2pools.ListOpts) (poolList []v2pools.Pool, err error) {
-	return listPools(c, opts)
+func (c *openstackCloud) ListPoolMembersMap(poolID string) (map[string]v2pools.Member, error) {
+	return listPoolMembersMap(c, poolID)
 }
 
-func listPools(c OpenstackCloud, opts v2pools.ListOpts) (poolList []v2pools.Pool, err error) {
-	if c.LoadBalancerClient() == nil {
-		return poolList, fmt.Errorf("loadbalancer support not available in this deployment")
+// listPoolMembersMap returns the members of the pool keyed by "address:port", using a single list request.
+func listPoolMembersMap(c OpenstackCloud, poolID string) (map[string]v2pools.Member, error) {
+	memberList, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return nil, err
 	}
 
-	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		poolPage, err := v2pools.List(c.LoadBalancerClient(), opts).AllPages(context.TODO())
-		if err != nil {
-			return false, fmt.Errorf("failed to list pools: %v", err)
-		}
-		poolList, err = v2pools.ExtractPools(poolPage)
-		if err != nil {
-			return false, fmt.Errorf("failed to extract pools: %v", err)
-		}
-		return true, nil
-	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
-		}
-		return poolList, err
+	members := make(map[string]v2pools.Member, len(memberList))
+	for _, member := range memberList {
+		members[PoolMemberKey(member.Address, member.ProtocolPort)] = member
 	}
-	return poolList, nil
+	return members, nil
 }
 
-func (c *openstackCloud) ListListeners(opts listeners.ListOpts) (listenerList []listeners.Listener, err error) {
-	return listListeners(c, opts)
+// flavorDataMaxMembersPerPoolKey is the key in the loadbalancer flavor profile data
+// that advertises how many members a pool of that flavor can hold.
+// Octavia does not define a standard limit, so operators set it for providers that have one.
+const flavorDataMaxMembersPerPoolKey = "max_members_per_pool"
+
+func (c *openstackCloud) CheckPoolCapacity(poolID string, addingMembers int) error {
+	return checkPoolCapacity(c, poolID, addingMembers)
 }
 
-func listListeners(c OpenstackCloud, opts listeners.ListOpts) (listenerList []listeners.Listener, err error) {
-	if c.LoadBalancerClient() == nil {
-		return listenerList, fmt.Errorf("loadbalancer support not available in this deployment")
+// checkPoolCapacity returns an error if adding addingMembers members to the pool would exceed
+// the member limit of the flavor of its loadbalancer.
+// Pools whose flavor does not advertise a limit, or whose flavor profile cannot be read, are not checked.
+func checkPoolCapacity(c OpenstackCloud, poolID string, addingMembers int) error {
+	if addingMembers <= 0 {
+		return nil
+	}
+	if c.LoadBalancerClient() == nil {
+		return fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	limit, flavorName, err := poolMemberLimit(c, poolID)
+	if err != nil {
+		return err
+	}
+	if limit <= 0 {
+		return nil
+	}
+
+	members, err := c.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return err
+	}
+	return checkPoolMemberLimit(poolID, flavorName, limit, len(members), addingMembers)
+}
+
+func checkPoolMemberLimit(poolID string, flavorName string, limit int, currentMembers int, addingMembers int) error {
+	if currentMembers+addingMembers > limit {
+		return fmt.Errorf("cannot add %d members to pool %s: it has %d members and loadbalancer flavor %q allows at most %d members per pool", addingMembers, poolID, currentMembers, flavorName, limit)
+	}
+	return nil
+}
+
+// poolMemberLimit returns the maximum number of members of the pool, as advertised by the flavor
+// profile of its loadbalancer, along with the flavor name. A limit of 0 means no limit is known.
+func poolMemberLimit(c OpenstackCloud, poolID string) (int, string, error) {
+	pool, err := c.GetPool(poolID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get pool %s: %w", poolID, err)
+	}
+	if len(pool.Loadbalancers) == 0 {
+		return 0, "", nil
+	}
+
+	lb, err := c.GetLB(pool.Loadbalancers[0].ID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get loadbalancer %s: %w", pool.Loadbalancers[0].ID, err)
+	}
+	if lb.FlavorID == "" {
+		return 0, "", nil
 	}
 
+	var flavor *flavors.Flavor
+	var profile *flavorprofiles.FlavorProfile
 	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
-		listenerPage, err := listeners.List(c.LoadBalancerClient(), opts).AllPages(context.TODO())
+		flavor, err = flavors.Get(context.TODO(), c.LoadBalancerClient(), lb.FlavorID).Extract()
 		if err != nil {
-			return false, fmt.Errorf("failed to list listeners: %v", err)
+			if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+				return true, err
+			}
+			return false, err
 		}
-		listenerList, err = listeners.ExtractListeners(listenerPage)
+		profile, err = flavorprofiles.Get(context.TODO(), c.LoadBalancerClient(), flavor.FlavorProfileId).Extract()
 		if err != nil {
-			return false, fmt.Errorf("failed to extract listeners: %v", err)
+			// Flavor profiles are usually restricted to administrators
+			if gophercloud.ResponseCodeIs(err, http.StatusForbidden) || gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+				return true, err
+			}
+			return false, err
 		}
 		return true, nil
 	})
-	if !done {
-		if err == nil {
-			err = wait.ErrWaitTimeout
+	if err != nil {
+		if gophercloud.ResponseCodeIs(err, http.StatusForbidden) || gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+			klog.V(2).Infof("unable to read the flavor profile of loadbalancer %s, skipping pool capacity check: %v", lb.ID, err)
+			return 0, "", nil
 		}
-		return listenerList, err
+		return 0, "", fmt.Errorf("failed to get flavor of loadbalancer %s: %w", lb.ID, err)
+	}
+	if !done {
+		return 0, "", wait.ErrWaitTimeout
 	}
-	return listenerList, nil
+
+	limit, err := parseFlavorMemberLimit(profile.FlavorData)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse data of flavor profile %s: %w", profile.ID, err)
+	}
+	return limit, flavor.Name, nil
+}
+
+// parseFlavorMemberLimit reads the member limit from the JSON data of a flavor profile.
+func parseFlavorMemberLimit(flavorData string) (int, error) {
+	if flavorData == "" {
+		return 0, nil
+	}
+	data := make(map[string]any)
+	if err := json.Unmarshal([]byte(flavorData), &data); err != nil {
+		return 0, err
+	}
+	v, found := data[flavorDataMaxMembersPerPoolKey]
+	if !found {
+		return 0, nil
+	}
+	switch v := v.(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("unexpected type %T for %s", v, flavorDataMaxMembersPerPoolKey)
+	}
+}
+
+func (c *openstackCloud) ListPools(opts v2pools.ListOpts) (poolList []v2pools.Pool, err error) {
+	return listPools(c, opts)
+}
+
+func listPools(c OpenstackCloud, opts v2pools.ListOpts) (poolList []v2pools.Pool, err error) {
+	if c.LoadBalancerClient() == nil {
+		return poolList, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	return listAll("pools", v2pools.List(c.LoadBalancerClient(), opts), v2pools.ExtractPools)
+}
+
+func (c *openstackCloud) ListListeners(opts listeners.ListOpts) (listenerList []listeners.Listener, err error) {
+	return listListeners(c, opts)
+}
+
+func listListeners(c OpenstackCloud, opts listeners.ListOpts) (listenerList []listeners.Listener, err error) {
+	if c.LoadBalancerClient() == nil {
+		return listenerList, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	return listAll("listeners", listeners.List(c.LoadBalancerClient(), opts), listeners.ExtractListeners)
 }
 
 func (c *openstackCloud) CreateListener(opts listeners.CreateOpts) (listener *listeners.Listener, err error) {
@@ -545,6 +1497,8 @@ func (c *openstackCloud) CreateListener(opts listeners.CreateOpts) (listener *li
 }
 
 func createListener(c OpenstackCloud, opts listeners.CreateOpts) (listener *listeners.Listener, err error) {
+	defer recordLBOperation(c, LBOperationCreateListener, time.Now(), &err)
+
 	if c.LoadBalancerClient() == nil {
 		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
 	}
@@ -552,15 +1506,608 @@ func createListener(c OpenstackCloud, opts listeners.CreateOpts) (listener *list
 	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
 		listener, err = listeners.Create(context.TODO(), c.LoadBalancerClient(), opts).Extract()
 		if err != nil {
+			// the request is rejected, retrying won't help
+			if gophercloud.ResponseCodeIs(err, http.StatusBadRequest) {
+				return true, fmt.Errorf("unabled to create listener: %w", err)
+			}
 			return false, fmt.Errorf("unabled to create listener: %v", err)
 		}
 		return true, nil
 	})
+	if err != nil && done {
+		return nil, err
+	}
 	if !done {
 		if err == nil {
 			err = wait.ErrWaitTimeout
 		}
 		return listener, err
 	}
+	notifyLBCreate(c, LBResourceListener, listener.ID, listener.Name)
 	return listener, nil
 }
+
+func (c *openstackCloud) UpdateListener(listenerID string, opts listeners.UpdateOpts) (*listeners.Listener, error) {
+	return updateListener(c, listenerID, opts)
+}
+
+func updateListener(c OpenstackCloud, listenerID string, opts listeners.UpdateOpts) (listener *listeners.Listener, err error) {
+	defer recordLBOperation(c, LBOperationUpdateListener, time.Now(), &err)
+
+	if c.LoadBalancerClient() == nil {
+		return nil, fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := listeners.Update(context.TODO(), c.LoadBalancerClient(), listenerID, opts).Extract()
+		if err != nil {
+			// loadbalancer is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return false, nil
+			}
+			return true, fmt.Errorf("error updating listener: %w", err)
+		}
+		listener = v
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return listener, err
+	}
+	return listener, err
+}
+
+func (c *openstackCloud) SwapListenerDefaultPool(listenerID, newPoolID string) error {
+	return swapListenerDefaultPool(c, listenerID, newPoolID)
+}
+
+// swapListenerDefaultPool points the listener at newPoolID, for cutting over to another set of backends.
+// Changing the default pool of the listener is a single update, so traffic moves from the members of
+// the old pool to those of the new pool at once.
+func swapListenerDefaultPool(c OpenstackCloud, listenerID, newPoolID string) error {
+	listener, err := c.UpdateListener(listenerID, listeners.UpdateOpts{
+		DefaultPoolID: &newPoolID,
+	})
+	if err != nil {
+		return fmt.Errorf("error swapping default pool of listener %s to %s: %w", listenerID, newPoolID, err)
+	}
+	if listener.DefaultPoolID != newPoolID {
+		return fmt.Errorf("listener %s has default pool %q after swapping to %s", listenerID, listener.DefaultPoolID, newPoolID)
+	}
+	klog.V(2).Infof("Swapped default pool of listener %s to %s", listenerID, newPoolID)
+	return nil
+}
+
+func (c *openstackCloud) EnsureHTTPToHTTPSRedirect(httpListenerID, host string) error {
+	return ensureHTTPToHTTPSRedirect(c, httpListenerID, host)
+}
+
+// ensureHTTPToHTTPSRedirect creates a REDIRECT_PREFIX policy with a rule matching every path, as Octavia
+// cannot redirect to another scheme without a host, and a policy without rules never matches.
+func ensureHTTPToHTTPSRedirect(c OpenstackCloud, httpListenerID, host string) error {
+	if c.LoadBalancerClient() == nil {
+		return fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+	if host == "" {
+		return fmt.Errorf("a host is required to redirect listener %s to HTTPS", httpListenerID)
+	}
+
+	listenerList, err := c.ListListeners(listeners.ListOpts{ID: httpListenerID})
+	if err != nil {
+		return err
+	}
+	if len(listenerList) != 1 {
+		return fmt.Errorf("listener %s not found", httpListenerID)
+	}
+	if protocol := listenerList[0].Protocol; protocol != string(listeners.ProtocolHTTP) {
+		return fmt.Errorf("cannot redirect listener %s to HTTPS: it has protocol %s, not HTTP", httpListenerID, protocol)
+	}
+
+	redirectPrefix := "https://" + host
+	policies, err := listAll("l7policies", l7policies.List(c.LoadBalancerClient(), l7policies.ListOpts{
+		ListenerID: httpListenerID,
+		Action:     string(l7policies.ActionRedirectPrefix),
+	}), l7policies.ExtractL7Policies)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if policy.RedirectPrefix != redirectPrefix {
+			continue
+		}
+		rules, err := listAll("l7rules", l7policies.ListRules(c.LoadBalancerClient(), policy.ID, l7policies.ListRulesOpts{}), l7policies.ExtractRules)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(rules, isMatchAllPathRule) {
+			klog.V(4).Infof("Listener %s already redirects to %s with L7 policy %s", httpListenerID, redirectPrefix, policy.ID)
+			return nil
+		}
+	}
+
+	opts := l7policies.CreateOpts{
+		Name:             "redirect-to-https",
+		ListenerID:       httpListenerID,
+		Action:           l7policies.ActionRedirectPrefix,
+		RedirectPrefix:   redirectPrefix,
+		RedirectHttpCode: http.StatusMovedPermanently,
+		Rules: []l7policies.CreateRuleOpts{
+			{
+				RuleType:    l7policies.TypePath,
+				CompareType: l7policies.CompareTypeStartWith,
+				Value:       "/",
+			},
+		},
+	}
+	var policy *l7policies.L7Policy
+	done, err := vfs.RetryWithBackoff(writeBackoff, func() (bool, error) {
+		v, err := l7policies.Create(context.TODO(), c.LoadBalancerClient(), opts).Extract()
+		if err != nil {
+			// loadbalancer is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				return false, nil
+			}
+			return true, fmt.Errorf("error creating L7 policy: %w", err)
+		}
+		policy = v
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	klog.V(2).Infof("Created L7 policy %s redirecting listener %s to %s", policy.ID, httpListenerID, redirectPrefix)
+	return nil
+}
+
+// isMatchAllPathRule returns true if the rule matches the path of every request
+func isMatchAllPathRule(rule l7policies.Rule) bool {
+	return rule.RuleType == string(l7policies.TypePath) && rule.CompareType == string(l7policies.CompareTypeStartWith) &&
+		rule.Value == "/" && !rule.Invert
+}
+
+// MemberSpec describes a desired member of a loadbalancer pool.
+// Members are identified by their address and protocol port.
+type MemberSpec struct {
+	Name         string
+	Address      string
+	ProtocolPort int
+	SubnetID     string
+	Weight       *int
+	Backup       *bool
+	// MonitorPort is the port checked by the health monitor of the pool, if different from ProtocolPort
+	MonitorPort *int
+	Tags        []string
+}
+
+// NewMemberSpec returns the spec of an existing member, to keep it unchanged in a reconcile
+func NewMemberSpec(member v2pools.Member) MemberSpec {
+	spec := MemberSpec{
+		Name:         member.Name,
+		Address:      member.Address,
+		ProtocolPort: member.ProtocolPort,
+		SubnetID:     member.SubnetID,
+		Weight:       fi.PtrTo(member.Weight),
+		Backup:       fi.PtrTo(member.Backup),
+		Tags:         member.Tags,
+	}
+	if member.MonitorPort != 0 {
+		spec.MonitorPort = fi.PtrTo(member.MonitorPort)
+	}
+	return spec
+}
+
+// MemberPreservation selects pool members that were added outside of kops,
+// for example a bastion, which are kept even though they are not desired.
+type MemberPreservation struct {
+	// Tag preserves members carrying this tag
+	Tag string
+	// NamePrefix preserves members whose name starts with this prefix
+	NamePrefix string
+}
+
+// NewMemberPreservation returns the members to preserve configured for the loadbalancer.
+func NewMemberPreservation(spec *kops.OpenstackLoadbalancerConfig) MemberPreservation {
+	if spec == nil {
+		return MemberPreservation{}
+	}
+	return MemberPreservation{
+		Tag:        fi.ValueOf(spec.PreservedMemberTag),
+		NamePrefix: fi.ValueOf(spec.PreservedMemberNamePrefix),
+	}
+}
+
+// Preserves returns true if the member must not be removed from its pool.
+func (p MemberPreservation) Preserves(member v2pools.Member) bool {
+	if p.Tag != "" && slices.Contains(member.Tags, p.Tag) {
+		return true
+	}
+	return p.NamePrefix != "" && strings.HasPrefix(member.Name, p.NamePrefix)
+}
+
+// poolMemberPlan holds the changes needed to converge the members of a pool.
+type poolMemberPlan struct {
+	create []MemberSpec
+	update map[string]MemberSpec
+	remove []string
+	// preserve holds the members that are not desired but are kept
+	preserve []MemberSpec
+}
+
+func (p *poolMemberPlan) isEmpty() bool {
+	return len(p.create) == 0 && len(p.update) == 0 && len(p.remove) == 0
+}
+
+// PoolMemberKey returns the key identifying a pool member in ListPoolMembersMap.
+func PoolMemberKey(address string, port int) string {
+	return fmt.Sprintf("%s:%d", address, port)
+}
+
+// planPoolMembers computes the members to create, update and remove so that existing matches desired.
+// existing is keyed by PoolMemberKey. Members selected by preservation are never removed.
+func planPoolMembers(existing map[string]v2pools.Member, desired []MemberSpec, preservation MemberPreservation) *poolMemberPlan {
+	plan := &poolMemberPlan{
+		update: make(map[string]MemberSpec),
+	}
+
+	wanted := make(map[string]bool)
+	for _, spec := range desired {
+		key := PoolMemberKey(spec.Address, spec.ProtocolPort)
+		wanted[key] = true
+
+		member, found := existing[key]
+		if !found {
+			plan.create = append(plan.create, spec)
+			continue
+		}
+		// an empty name, like a nil weight, leaves the existing value alone
+		if (spec.Weight != nil && *spec.Weight != member.Weight) ||
+			(spec.Backup != nil && *spec.Backup != member.Backup) ||
+			(spec.MonitorPort != nil && *spec.MonitorPort != member.MonitorPort) ||
+			(spec.Name != "" && spec.Name != member.Name) {
+			plan.update[member.ID] = spec
+		}
+	}
+
+	for key, member := range existing {
+		if wanted[key] {
+			continue
+		}
+		if preservation.Preserves(member) {
+			klog.V(4).Infof("preserving member %s (%s) which was not added by kops", member.ID, key)
+			plan.preserve = append(plan.preserve, NewMemberSpec(member))
+			continue
+		}
+		plan.remove = append(plan.remove, member.ID)
+	}
+
+	return plan
+}
+
+func (c *openstackCloud) ReconcilePoolMembers(poolID string, desired []MemberSpec, preservation MemberPreservation) error {
+	return reconcilePoolMembers(c, poolID, desired, preservation)
+}
+
+// reconcilePoolMembers converges the members of the pool to desired.
+// Every change to a pool member locks the whole pool, so updating members one by one on a large
+// pool results in many conflicts. We therefore replace the member set with a single batch update,
+// and only fall back to ordered single calls if the batch API is not supported.
+// The batch update removes every member it is not given, so preserved members are sent along unchanged.
+func reconcilePoolMembers(c OpenstackCloud, poolID string, desired []MemberSpec, preservation MemberPreservation) error {
+	if c.LoadBalancerClient() == nil {
+		return fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	existing, err := c.ListPoolMembersMap(poolID)
+	if err != nil {
+		return err
+	}
+	return convergePoolMembers(c, poolID, existing, desired, preservation)
+}
+
+// convergePoolMembers converges the existing members of the pool, keyed by PoolMemberKey, to desired.
+func convergePoolMembers(c OpenstackCloud, poolID string, existing map[string]v2pools.Member, desired []MemberSpec, preservation MemberPreservation) error {
+	plan := planPoolMembers(existing, desired, preservation)
+	if plan.isEmpty() {
+		return nil
+	}
+	if len(desired) > len(existing) {
+		limit, flavorName, err := poolMemberLimit(c, poolID)
+		if err != nil {
+			return err
+		}
+		if limit > 0 {
+			if err := checkPoolMemberLimit(poolID, flavorName, limit, len(existing), len(desired)-len(existing)); err != nil {
+				return err
+			}
+		}
+	}
+	klog.V(2).Infof("reconciling members of pool %s: %d to create, %d to update, %d to remove", poolID, len(plan.create), len(plan.update), len(plan.remove))
+
+	err := batchUpdatePoolMembers(c, poolID, append(slices.Clone(desired), plan.preserve...))
+	if err == nil {
+		return nil
+	}
+	if !gophercloud.ResponseCodeIs(err, http.StatusNotFound) &&
+		!gophercloud.ResponseCodeIs(err, http.StatusMethodNotAllowed) &&
+		!gophercloud.ResponseCodeIs(err, http.StatusNotImplemented) {
+		return err
+	}
+	klog.V(2).Infof("batch update of pool members not supported, falling back to single member calls: %v", err)
+
+	return applyPoolMemberPlan(c, poolID, plan)
+}
+
+func (c *openstackCloud) ReplacePoolMembers(poolID string, desired []v2pools.BatchUpdateMemberOpts) error {
+	return replacePoolMembers(c, poolID, desired)
+}
+
+// replacePoolMembers makes the members of the pool exactly match desired, removing every other member.
+// It uses the same batch update as reconcilePoolMembers, so the pool is never left with a mix of old and new
+// members when the batch API is supported.
+func replacePoolMembers(c OpenstackCloud, poolID string, desired []v2pools.BatchUpdateMemberOpts) error {
+	specs := make([]MemberSpec, 0, len(desired))
+	for _, opt := range desired {
+		specs = append(specs, MemberSpec{
+			Name:         fi.ValueOf(opt.Name),
+			Address:      opt.Address,
+			ProtocolPort: opt.ProtocolPort,
+			SubnetID:     fi.ValueOf(opt.SubnetID),
+			Weight:       opt.Weight,
+			Backup:       opt.Backup,
+			MonitorPort:  opt.MonitorPort,
+			Tags:         opt.Tags,
+		})
+	}
+	return reconcilePoolMembers(c, poolID, specs, MemberPreservation{})
+}
+
+// ReplaceMemberOpts describes the member of a replacement node.
+// The fields that are not set are taken from the member of the node it replaces, except Weight, which defaults to 1,
+// so that the new node receives traffic even if the old one was drained.
+type ReplaceMemberOpts struct {
+	Name     string
+	SubnetID string
+	Weight   *int
+	Backup   *bool
+	Tags     []string
+}
+
+func (c *openstackCloud) ReplaceMemberForNode(poolID string, oldServerAddr string, newServerAddr string, opts ReplaceMemberOpts) error {
+	return replaceMemberForNode(c, poolID, oldServerAddr, newServerAddr, opts)
+}
+
+// replaceMemberForNode replaces the members of the pool for the node at oldServerAddr with members for the node at
+// newServerAddr, on the same protocol ports. Both happen in a single batch update, so that the pool never counts the
+// old and the new node at the same time; only if the batch API is not supported are the members replaced one by one.
+// Calling it again once the old members are gone makes no further changes.
+func replaceMemberForNode(c OpenstackCloud, poolID string, oldServerAddr string, newServerAddr string, opts ReplaceMemberOpts) error {
+	if oldServerAddr == "" || newServerAddr == "" {
+		return fmt.Errorf("old and new server addresses are required to replace a member of pool %s", poolID)
+	}
+	if oldServerAddr == newServerAddr {
+		return fmt.Errorf("cannot replace member %s of pool %s with itself", oldServerAddr, poolID)
+	}
+	if c.LoadBalancerClient() == nil {
+		return fmt.Errorf("loadbalancer support not available in this deployment")
+	}
+
+	existing, err := c.ListPoolMembersMap(poolID)
+	if err != nil {
+		return err
+	}
+
+	var desired []MemberSpec
+	replaced := 0
+	for _, key := range slices.Sorted(maps.Keys(existing)) {
+		member := existing[key]
+		if member.Address != oldServerAddr {
+			desired = append(desired, NewMemberSpec(member))
+			continue
+		}
+		replaced++
+		if _, found := existing[PoolMemberKey(newServerAddr, member.ProtocolPort)]; found {
+			// the new node is already a member on this port
+			continue
+		}
+		spec := MemberSpec{
+			Name:         opts.Name,
+			Address:      newServerAddr,
+			ProtocolPort: member.ProtocolPort,
+			SubnetID:     opts.SubnetID,
+			Weight:       opts.Weight,
+			Backup:       opts.Backup,
+			Tags:         opts.Tags,
+		}
+		if spec.Name == "" {
+			spec.Name = member.Name
+		}
+		if spec.SubnetID == "" {
+			spec.SubnetID = member.SubnetID
+		}
+		if spec.Backup == nil {
+			spec.Backup = fi.PtrTo(member.Backup)
+		}
+		if member.MonitorPort != 0 {
+			spec.MonitorPort = fi.PtrTo(member.MonitorPort)
+		}
+		if spec.Tags == nil {
+			spec.Tags = member.Tags
+		}
+		desired = append(desired, spec)
+	}
+
+	if replaced == 0 {
+		for _, member := range existing {
+			if member.Address == newServerAddr {
+				klog.V(4).Infof("member %s of pool %s was already replaced by %s", oldServerAddr, poolID, newServerAddr)
+				return nil
+			}
+		}
+		return fmt.Errorf("pool %s has no member with address %s to replace", poolID, oldServerAddr)
+	}
+
+	klog.V(2).Infof("replacing %d members of pool %s for node %s with node %s", replaced, poolID, oldServerAddr, newServerAddr)
+	return convergePoolMembers(c, poolID, existing, desired, MemberPreservation{})
+}
+
+func batchUpdatePoolMembers(c OpenstackCloud, poolID string, desired []MemberSpec) error {
+	opts := make([]v2pools.BatchUpdateMemberOpts, 0, len(desired))
+	for _, spec := range desired {
+		opt := v2pools.BatchUpdateMemberOpts{
+			Address:      spec.Address,
+			ProtocolPort: spec.ProtocolPort,
+			Weight:       spec.Weight,
+			Backup:       spec.Backup,
+			MonitorPort:  spec.MonitorPort,
+		}
+		if spec.Name != "" {
+			opt.Name = &spec.Name
+		}
+		if spec.SubnetID != "" {
+			opt.SubnetID = &spec.SubnetID
+		}
+		opt.Tags = spec.Tags
+		opts = append(opts, opt)
+	}
+
+	done, err := vfs.RetryWithBackoff(memberBackoff, func() (bool, error) {
+		err := v2pools.BatchUpdateMembers(context.TODO(), c.LoadBalancerClient(), poolID, opts).ExtractErr()
+		if err != nil {
+			// pool is currently in immutable state, try to retry
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				klog.Infof("got error %v retrying...", http.StatusConflict)
+				return false, nil
+			}
+			// other errors are returned immediately, so that callers can fall back
+			return true, err
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return err
+	}
+	return err
+}
+
+// applyPoolMemberPlan applies the plan one member at a time.
+// New members are added before old ones are removed, so the pool never loses capacity.
+func applyPoolMemberPlan(c OpenstackCloud, poolID string, plan *poolMemberPlan) error {
+	for _, spec := range plan.create {
+		opts := v2pools.CreateMemberOpts{
+			Name:         spec.Name,
+			Address:      spec.Address,
+			ProtocolPort: spec.ProtocolPort,
+			SubnetID:     spec.SubnetID,
+			Weight:       spec.Weight,
+			Backup:       spec.Backup,
+			MonitorPort:  spec.MonitorPort,
+		}
+		done, err := vfs.RetryWithBackoff(memberBackoff, func() (bool, error) {
+			_, err := v2pools.CreateMember(context.TODO(), c.LoadBalancerClient(), poolID, opts).Extract()
+			if err != nil {
+				if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+					klog.Infof("got error %v retrying...", http.StatusConflict)
+					return false, nil
+				}
+				return false, fmt.Errorf("failed to create pool member %s: %v", PoolMemberKey(spec.Address, spec.ProtocolPort), err)
+			}
+			return true, nil
+		})
+		if !done {
+			if err == nil {
+				err = wait.ErrWaitTimeout
+			}
+			return err
+		}
+	}
+
+	for memberID, spec := range plan.update {
+		opts := v2pools.UpdateMemberOpts{
+			Weight:      spec.Weight,
+			Backup:      spec.Backup,
+			MonitorPort: spec.MonitorPort,
+		}
+		if spec.Name != "" {
+			opts.Name = &spec.Name
+		}
+		_, err := c.UpdateMemberInPool(poolID, memberID, opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, memberID := range plan.remove {
+		if err := deletePoolMember(c, poolID, memberID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deletePoolMember deletes a member of a pool, retrying while the pool is locked by another change.
+func deletePoolMember(c OpenstackCloud, poolID string, memberID string) (err error) {
+	defer recordLBOperation(c, LBOperationDeleteMember, time.Now(), &err)
+
+	done, err := vfs.RetryWithBackoff(memberBackoff, func() (bool, error) {
+		err := v2pools.DeleteMember(context.TODO(), c.LoadBalancerClient(), poolID, memberID).ExtractErr()
+		if err != nil && !isNotFound(err) {
+			if gophercloud.ResponseCodeIs(err, http.StatusConflict) {
+				klog.Infof("got error %v retrying...", http.StatusConflict)
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to delete pool member %s: %v", memberID, err)
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *openstackCloud) EnsureLBVIPSecurityGroups(lbID string, sgIDs []string) error {
+	return ensureLBVIPSecurityGroups(c, lbID, sgIDs)
+}
+
+// ensureLBVIPSecurityGroups sets the security groups of the VIP port of the loadbalancer to exactly sgIDs.
+// The port is only updated if its security groups differ, in any order, so it also reverts changes made outside of kops.
+func ensureLBVIPSecurityGroups(c OpenstackCloud, lbID string, sgIDs []string) error {
+	portID, err := getLBVIPPortID(c, lbID)
+	if err != nil {
+		return err
+	}
+
+	port, err := c.GetPort(portID)
+	if err != nil {
+		return fmt.Errorf("error getting VIP port %s of loadbalancer %s: %v", portID, lbID, err)
+	}
+
+	desired := slices.Clone(sgIDs)
+	slices.Sort(desired)
+	desired = slices.Compact(desired)
+	if actual := slices.Sorted(slices.Values(port.SecurityGroups)); slices.Equal(actual, desired) {
+		klog.V(2).Infof("VIP port %s of loadbalancer %s already has security groups %v", portID, lbID, desired)
+		return nil
+	}
+
+	klog.V(2).Infof("Setting security groups of VIP port %s of loadbalancer %s to %v (was %v)", portID, lbID, desired, port.SecurityGroups)
+	if _, err := c.UpdatePort(portID, ports.UpdateOpts{SecurityGroups: &desired}); err != nil {
+		return fmt.Errorf("error setting security groups of VIP port %s of loadbalancer %s: %v", portID, lbID, err)
+	}
+	return nil
+}
@@ -0,0 +1,1973 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func Test_NormalizeLBName(t *testing.T) {
+	short := "api.my-cluster.k8s.local"
+	if actual := NormalizeLBName(short); actual != short {
+		t.Errorf("expected short name to be unchanged, got %q", actual)
+	}
+
+	prefix := strings.Repeat("a", 300)
+	first := NormalizeLBName(prefix + "-https")
+	second := NormalizeLBName(prefix + "-http")
+	for _, name := range []string{first, second} {
+		if len(name) > maxLBNameLength {
+			t.Errorf("expected at most %d characters, got %d", maxLBNameLength, len(name))
+		}
+		if !strings.HasPrefix(name, prefix[:200]) {
+			t.Errorf("expected %q to keep the start of the name", name)
+		}
+	}
+	if first == second {
+		t.Errorf("expected distinct names for long names sharing a prefix, got %q", first)
+	}
+	if again := NormalizeLBName(prefix + "-https"); again != first {
+		t.Errorf("expected a stable name, got %q and %q", first, again)
+	}
+}
+
+func Test_MonitorTypeForPoolProtocol(t *testing.T) {
+	tests := []struct {
+		protocol     string
+		monitorType  string
+		expectedType string
+		expectedErr  bool
+	}{
+		{protocol: "TCP", expectedType: monitors.TypeTCP},
+		{protocol: "UDP", expectedType: monitors.TypeUDPConnect},
+		{protocol: "HTTP", expectedType: monitors.TypeHTTP},
+		{protocol: "HTTPS", expectedType: monitors.TypeHTTPS},
+		{protocol: "SCTP", expectedType: monitors.TypeSCTP},
+		{protocol: "tcp", expectedType: monitors.TypeTCP},
+		{protocol: "TCP", monitorType: monitors.TypeHTTP, expectedType: monitors.TypeHTTP},
+		{protocol: "TCP", monitorType: monitors.TypePING, expectedType: monitors.TypePING},
+		{protocol: "TCP", monitorType: "http", expectedType: monitors.TypeHTTP},
+		{protocol: "TCP", monitorType: monitors.TypeUDPConnect, expectedErr: true},
+		{protocol: "HTTP", monitorType: monitors.TypeUDPConnect, expectedErr: true},
+		{protocol: "HTTP", monitorType: monitors.TypeSCTP, expectedErr: true},
+		{protocol: "UDP", monitorType: monitors.TypeTCP, expectedType: monitors.TypeTCP},
+		{protocol: "UDP", monitorType: monitors.TypeHTTPS, expectedErr: true},
+		{protocol: "UDP", monitorType: monitors.TypePING, expectedErr: true},
+		{protocol: "UDP", monitorType: monitors.TypeTLSHELLO, expectedErr: true},
+		{protocol: "SCTP", monitorType: monitors.TypeUDPConnect, expectedType: monitors.TypeUDPConnect},
+		{protocol: "TCP", monitorType: "BOGUS", expectedErr: true},
+		{protocol: "BOGUS", expectedErr: true},
+	}
+
+	for _, testCase := range tests {
+		t.Run(fmt.Sprintf("%s/%s", testCase.protocol, testCase.monitorType), func(t *testing.T) {
+			actual, err := MonitorTypeForPoolProtocol(testCase.protocol, testCase.monitorType)
+			if testCase.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, got monitor type %q", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != testCase.expectedType {
+				t.Errorf("expected monitor type %q, got %q", testCase.expectedType, actual)
+			}
+		})
+	}
+}
+
+func Test_CreatePoolMonitor_DefaultsTypeFromPool(t *testing.T) {
+	mux := http.NewServeMux()
+	fixture(mux, "/lbaas/pools/pool-udp", http.MethodGet, `{"pool": {"id": "pool-udp", "protocol": "UDP"}}`, http.StatusOK)
+
+	var created map[string]map[string]interface{}
+	mux.HandleFunc("/lbaas/healthmonitors", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"healthmonitor": {"id": "monitor", "type": %q}}`, created["healthmonitor"]["type"])
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	monitor, err := cloud.CreatePoolMonitor(monitors.CreateOpts{
+		PoolID:     "pool-udp",
+		Delay:      10,
+		Timeout:    5,
+		MaxRetries: 3,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if monitor.Type != monitors.TypeUDPConnect {
+		t.Errorf("expected monitor type %q, got %q", monitors.TypeUDPConnect, monitor.Type)
+	}
+
+	_, err = cloud.CreatePoolMonitor(monitors.CreateOpts{
+		PoolID:     "pool-udp",
+		Type:       monitors.TypeHTTPS,
+		Delay:      10,
+		Timeout:    5,
+		MaxRetries: 3,
+	})
+	if err == nil {
+		t.Errorf("expected error creating HTTPS monitor for UDP pool")
+	}
+}
+
+func Test_UpdateMonitor(t *testing.T) {
+	mux := http.NewServeMux()
+	var updated map[string]map[string]interface{}
+	mux.HandleFunc("/lbaas/healthmonitors/monitor", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+			t.Fatalf("error decoding request: %v", err)
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"healthmonitor": {"id": "monitor", "type": "HTTP", "url_path": %q}}`, updated["healthmonitor"]["url_path"])
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	monitor, err := cloud.UpdateMonitor("monitor", monitors.UpdateOpts{
+		URLPath: "/readyz",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated["healthmonitor"]["url_path"] != "/readyz" {
+		t.Errorf("expected url_path %q in request, got %v", "/readyz", updated["healthmonitor"]["url_path"])
+	}
+	if monitor.URLPath != "/readyz" {
+		t.Errorf("expected monitor url path %q, got %q", "/readyz", monitor.URLPath)
+	}
+}
+
+func Test_PlanPoolMembers(t *testing.T) {
+	current := []v2pools.Member{
+		{ID: "keep", Name: "keep", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1},
+		{ID: "reweight", Name: "reweight", Address: "10.0.0.2", ProtocolPort: 443, Weight: 1},
+		{ID: "gone", Name: "gone", Address: "10.0.0.3", ProtocolPort: 443, Weight: 1},
+		{ID: "otherport", Name: "otherport", Address: "10.0.0.1", ProtocolPort: 8443, Weight: 1},
+	}
+	desired := []MemberSpec{
+		{Name: "keep", Address: "10.0.0.1", ProtocolPort: 443},
+		{Name: "reweight", Address: "10.0.0.2", ProtocolPort: 443, Weight: fi.PtrTo(5)},
+		{Name: "new", Address: "10.0.0.4", ProtocolPort: 443},
+	}
+
+	existing := make(map[string]v2pools.Member)
+	for _, member := range current {
+		existing[PoolMemberKey(member.Address, member.ProtocolPort)] = member
+	}
+
+	plan := planPoolMembers(existing, desired, MemberPreservation{})
+
+	if len(plan.create) != 1 || plan.create[0].Name != "new" {
+		t.Errorf("expected to create member new, got %v", plan.create)
+	}
+	if _, found := plan.update["reweight"]; !found || len(plan.update) != 1 {
+		t.Errorf("expected to update member reweight, got %v", plan.update)
+	}
+	sort.Strings(plan.remove)
+	if strings.Join(plan.remove, ",") != "gone,otherport" {
+		t.Errorf("expected to remove members gone and otherport, got %v", plan.remove)
+	}
+
+	single := map[string]v2pools.Member{
+		PoolMemberKey("10.0.0.1", 443): current[0],
+	}
+	if plan := planPoolMembers(single, desired[:1], MemberPreservation{}); !plan.isEmpty() {
+		t.Errorf("expected empty plan, got %v", plan)
+	}
+
+	// a member without a desired name keeps the name it has
+	unnamed := []MemberSpec{{Address: "10.0.0.1", ProtocolPort: 443}}
+	if plan := planPoolMembers(single, unnamed, MemberPreservation{}); !plan.isEmpty() {
+		t.Errorf("expected empty plan for an unnamed member, got %v", plan)
+	}
+}
+
+func Test_BatchUpdatePoolMembersOmitsEmptyName(t *testing.T) {
+	octavia := newMemberPoolOctavia(t, "ROUND_ROBIN", reconcileMembers()...)
+	cloud := octavia.cloud()
+
+	desired := []MemberSpec{
+		{Address: "10.0.0.1", ProtocolPort: 443},
+		{Name: "new", Address: "10.0.0.4", ProtocolPort: 443},
+	}
+	if err := batchUpdatePoolMembers(cloud, "pool", desired); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body := octavia.body("PUT /lbaas/pools/pool/members"); strings.Count(body, `"name"`) != 1 || !strings.Contains(body, `"name":"new"`) {
+		t.Errorf("expected only the named member to have a name, got %s", body)
+	}
+}
+
+// newMemberPoolOctavia returns an Octavia API with the pool "pool" and its members
+func newMemberPoolOctavia(t testing.TB, algorithm string, members ...v2pools.Member) *mockOctavia {
+	octavia := newMockOctavia(t)
+	addMemberPool(octavia, algorithm, members...)
+	return octavia
+}
+
+func addMemberPool(octavia *mockOctavia, algorithm string, members ...v2pools.Member) {
+	octavia.AddPool(v2pools.Pool{ID: "pool", Name: "pool", LBMethod: algorithm})
+	for _, member := range members {
+		member.PoolID = "pool"
+		octavia.AddMember(member)
+	}
+}
+
+// failBatchUpdates answers the batch member updates of the pool with the status
+func failBatchUpdates(octavia *mockOctavia, status int) {
+	octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+		if status == http.StatusAccepted || request != "PUT /lbaas/pools/pool/members" {
+			return false
+		}
+		w.WriteHeader(status)
+		return true
+	}
+}
+
+const reconcileMemberListing = `{"members": [
+	{"id": "keep", "name": "keep", "address": "10.0.0.1", "protocol_port": 443, "weight": 1},
+	{"id": "reweight", "name": "reweight", "address": "10.0.0.2", "protocol_port": 443, "weight": 1},
+	{"id": "gone", "name": "gone", "address": "10.0.0.3", "protocol_port": 443, "weight": 1}
+]}`
+
+// reconcileMembers are the members of reconcileMemberListing
+func reconcileMembers() []v2pools.Member {
+	return []v2pools.Member{
+		{ID: "keep", Name: "keep", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1},
+		{ID: "reweight", Name: "reweight", Address: "10.0.0.2", ProtocolPort: 443, Weight: 1},
+		{ID: "gone", Name: "gone", Address: "10.0.0.3", ProtocolPort: 443, Weight: 1},
+	}
+}
+
+func reconcileDesiredMembers() []MemberSpec {
+	return []MemberSpec{
+		{Name: "keep", Address: "10.0.0.1", ProtocolPort: 443},
+		{Name: "reweight", Address: "10.0.0.2", ProtocolPort: 443, Weight: fi.PtrTo(5)},
+		{Name: "new", Address: "10.0.0.4", ProtocolPort: 443},
+	}
+}
+
+func Test_ReconcilePoolMembers(t *testing.T) {
+	tests := []struct {
+		desc             string
+		batchStatus      int
+		expectedRequests []string
+	}{
+		{
+			desc:        "batch update",
+			batchStatus: http.StatusAccepted,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+			},
+		},
+		{
+			desc:        "fallback to single member calls",
+			batchStatus: http.StatusNotFound,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+				"POST /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members/reweight",
+				"DELETE /lbaas/pools/pool/members/gone",
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMemberPoolOctavia(t, "ROUND_ROBIN", reconcileMembers()...)
+			failBatchUpdates(octavia, testCase.batchStatus)
+			cloud := octavia.cloud()
+
+			if err := cloud.ReconcilePoolMembers("pool", reconcileDesiredMembers(), MemberPreservation{}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := strings.Join(octavia.recorded(), "\n")
+			expected := strings.Join(testCase.expectedRequests, "\n")
+			if actual != expected {
+				t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+			}
+		})
+	}
+}
+
+func Test_ReplacePoolMembers(t *testing.T) {
+	tests := []struct {
+		desc             string
+		batchStatus      int
+		expectedRequests []string
+	}{
+		{
+			desc:        "batch update",
+			batchStatus: http.StatusAccepted,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+			},
+		},
+		{
+			desc:        "fallback to single member calls",
+			batchStatus: http.StatusMethodNotAllowed,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+				"DELETE /lbaas/pools/pool/members/gone",
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMemberPoolOctavia(t, "ROUND_ROBIN", reconcileMembers()...)
+			failBatchUpdates(octavia, testCase.batchStatus)
+			cloud := octavia.cloud()
+
+			// the node behind "gone" no longer exists
+			desired := []v2pools.BatchUpdateMemberOpts{
+				{Name: fi.PtrTo("keep"), Address: "10.0.0.1", ProtocolPort: 443},
+				{Name: fi.PtrTo("reweight"), Address: "10.0.0.2", ProtocolPort: 443},
+			}
+			if err := cloud.ReplacePoolMembers("pool", desired); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := strings.Join(octavia.recorded(), "\n")
+			expected := strings.Join(testCase.expectedRequests, "\n")
+			if actual != expected {
+				t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+			}
+			if body := octavia.body("PUT /lbaas/pools/pool/members"); testCase.batchStatus == http.StatusAccepted {
+				if strings.Contains(body, "10.0.0.3") {
+					t.Errorf("expected the absent node to be left out of the batch update, got %s", body)
+				}
+				if !strings.Contains(body, "10.0.0.1") || !strings.Contains(body, "10.0.0.2") {
+					t.Errorf("expected the desired members in the batch update, got %s", body)
+				}
+			}
+			var members []string
+			for _, member := range octavia.Members("pool") {
+				members = append(members, member.Address)
+			}
+			sort.Strings(members)
+			if expected := []string{"10.0.0.1", "10.0.0.2"}; !slices.Equal(members, expected) {
+				t.Errorf("expected members %v, got %v", expected, members)
+			}
+		})
+	}
+}
+
+// memberWeights describes the members of the pool as "name address:port weight=weight", sorted
+func memberWeights(octavia *mockOctavia) []string {
+	var members []string
+	for _, member := range octavia.Members("pool") {
+		members = append(members, fmt.Sprintf("%s %s:%d weight=%d", member.Name, member.Address, member.ProtocolPort, member.Weight))
+	}
+	sort.Strings(members)
+	return members
+}
+
+func Test_ReplaceMemberForNode(t *testing.T) {
+	octavia := newMemberPoolOctavia(t, "ROUND_ROBIN",
+		v2pools.Member{ID: "node-a", Name: "node-a", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1},
+		v2pools.Member{ID: "node-b", Name: "node-b", Address: "10.0.0.2", ProtocolPort: 443, Weight: 0},
+	)
+	cloud := octavia.cloud()
+
+	if err := cloud.ReplaceMemberForNode("pool", "10.0.0.2", "10.0.0.2", ReplaceMemberOpts{}); err == nil {
+		t.Errorf("expected error replacing a member with itself")
+	}
+
+	if err := cloud.ReplaceMemberForNode("pool", "10.0.0.2", "10.0.0.3", ReplaceMemberOpts{Name: "node-c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if batches := octavia.count("PUT /lbaas/pools/pool/members"); batches != 1 {
+		t.Errorf("expected the member to be replaced in a single batch update, got %d", batches)
+	}
+	actual := memberWeights(octavia)
+	expected := []string{"node-a 10.0.0.1:443 weight=1", "node-c 10.0.0.3:443 weight=1"}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected members %v, got %v", expected, actual)
+	}
+
+	// the old member is gone, so there is nothing left to do
+	if err := cloud.ReplaceMemberForNode("pool", "10.0.0.2", "10.0.0.3", ReplaceMemberOpts{Name: "node-c"}); err != nil {
+		t.Fatalf("unexpected error replacing again: %v", err)
+	}
+	if batches := octavia.count("PUT /lbaas/pools/pool/members"); batches != 1 {
+		t.Errorf("expected no further batch update, got %d", batches)
+	}
+
+	if err := cloud.ReplaceMemberForNode("pool", "10.0.0.8", "10.0.0.9", ReplaceMemberOpts{}); err == nil {
+		t.Errorf("expected error replacing a member that does not exist")
+	}
+}
+
+func Test_ReconcilePoolMembers_Preserved(t *testing.T) {
+	members := []v2pools.Member{
+		{ID: "keep", Name: "keep", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1},
+		{ID: "gone", Name: "gone", Address: "10.0.0.3", ProtocolPort: 443, Weight: 1},
+		{ID: "bastion", Name: "bastion", Address: "10.0.0.8", ProtocolPort: 443, Weight: 1, Tags: []string{"kops-preserve"}},
+		{ID: "manual", Name: "manual-debug", Address: "10.0.0.9", ProtocolPort: 443, Weight: 1},
+	}
+	preservation := MemberPreservation{Tag: "kops-preserve", NamePrefix: "manual-"}
+	desired := []MemberSpec{
+		{Name: "keep", Address: "10.0.0.1", ProtocolPort: 443},
+	}
+
+	tests := []struct {
+		desc             string
+		batchStatus      int
+		expectedRequests []string
+	}{
+		{
+			desc:        "batch update",
+			batchStatus: http.StatusAccepted,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+			},
+		},
+		{
+			desc:        "fallback to single member calls",
+			batchStatus: http.StatusNotFound,
+			expectedRequests: []string{
+				"GET /lbaas/pools/pool/members",
+				"PUT /lbaas/pools/pool/members",
+				"DELETE /lbaas/pools/pool/members/gone",
+			},
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMemberPoolOctavia(t, "ROUND_ROBIN", members...)
+			failBatchUpdates(octavia, testCase.batchStatus)
+			cloud := octavia.cloud()
+
+			if err := cloud.ReconcilePoolMembers("pool", desired, preservation); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual := strings.Join(octavia.recorded(), "\n")
+			expected := strings.Join(testCase.expectedRequests, "\n")
+			if actual != expected {
+				t.Errorf("unexpected requests:\n%s\nexpected:\n%s", actual, expected)
+			}
+
+			// the batch update removes every member it is not given
+			body := octavia.body("PUT /lbaas/pools/pool/members")
+			for _, address := range []string{"10.0.0.1", "10.0.0.8", "10.0.0.9"} {
+				if !strings.Contains(body, address) {
+					t.Errorf("expected batch update to keep member %s, got %s", address, body)
+				}
+			}
+			if strings.Contains(body, "10.0.0.3") {
+				t.Errorf("expected batch update to remove member 10.0.0.3, got %s", body)
+			}
+			var left []string
+			for _, member := range octavia.Members("pool") {
+				left = append(left, member.ID)
+			}
+			if expected := []string{"bastion", "keep", "manual"}; !slices.Equal(left, expected) {
+				t.Errorf("expected members %v to be left, got %v", expected, left)
+			}
+		})
+	}
+}
+
+func Benchmark_ReconcilePoolMembers(b *testing.B) {
+	octavia := newMockOctavia(b)
+	cloud := octavia.cloud()
+	desired := reconcileDesiredMembers()
+	// reset puts the members back, so that every iteration reconciles the same changes
+	reset := func(b *testing.B) {
+		b.StopTimer()
+		octavia.Reset()
+		addMemberPool(octavia, "ROUND_ROBIN", reconcileMembers()...)
+		b.StartTimer()
+	}
+
+	// The number of requests that lock the pool matters more than the time spent in the client,
+	// so report it alongside the timings.
+	b.Run("batch", func(b *testing.B) {
+		start := len(octavia.recorded())
+		for i := 0; i < b.N; i++ {
+			reset(b)
+			if err := cloud.ReconcilePoolMembers("pool", desired, MemberPreservation{}); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+		b.ReportMetric(float64(len(octavia.recorded())-start)/float64(b.N), "requests/op")
+	})
+
+	b.Run("per-member", func(b *testing.B) {
+		start := len(octavia.recorded())
+		for i := 0; i < b.N; i++ {
+			reset(b)
+			existing, err := cloud.ListPoolMembersMap("pool")
+			if err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+			if err := applyPoolMemberPlan(cloud, "pool", planPoolMembers(existing, desired, MemberPreservation{})); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+		b.ReportMetric(float64(len(octavia.recorded())-start)/float64(b.N), "requests/op")
+	})
+}
+
+func Test_ListLBsByStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	// the server ignores the provisioning_status filter, as some deployments do
+	fixture(mux, "/lbaas/loadbalancers", http.MethodGet, `{"loadbalancers": [
+		{"id": "active", "provisioning_status": "ACTIVE"},
+		{"id": "creating", "provisioning_status": "PENDING_CREATE"},
+		{"id": "updating", "provisioning_status": "PENDING_UPDATE"},
+		{"id": "error", "provisioning_status": "ERROR"}
+	]}`, http.StatusOK)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	tests := []struct {
+		status      string
+		expectedIDs []string
+	}{
+		{status: "ERROR", expectedIDs: []string{"error"}},
+		{status: "PENDING_CREATE", expectedIDs: []string{"creating"}},
+		{status: "PENDING_*", expectedIDs: []string{"creating", "updating"}},
+		{status: "DELETED"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.status, func(t *testing.T) {
+			lbs, err := cloud.ListLBsByStatus(testCase.status)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []string
+			for _, lb := range lbs {
+				ids = append(ids, lb.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(testCase.expectedIDs, ",") {
+				t.Errorf("expected loadbalancers %v, got %v", testCase.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func Test_ListPoolMembersMap(t *testing.T) {
+	mux := http.NewServeMux()
+	fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, reconcileMemberListing, http.StatusOK)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	members, err := cloud.ListPoolMembersMap("pool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 3 {
+		t.Errorf("expected 3 members, got %d", len(members))
+	}
+	if member, found := members["10.0.0.2:443"]; !found || member.ID != "reweight" {
+		t.Errorf("expected member reweight for 10.0.0.2:443, got %v", member)
+	}
+}
+
+func Test_AssociateToPool_NamesMemberAfterServer(t *testing.T) {
+	var created struct {
+		Member struct {
+			Name string `json:"name"`
+		} `json:"member"`
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lbaas/pools/pool/members/server-id", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/lbaas/pools/pool/members", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&created); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"member": {"id": "member", "name": %q}}`, created.Member.Name)
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	server := &servers.Server{ID: "server-id", Name: "control-plane-1-cluster"}
+	member, err := cloud.AssociateToPool(server, "pool", v2pools.CreateMemberOpts{
+		Address:      "10.0.0.1",
+		ProtocolPort: 443,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if created.Member.Name != server.Name {
+		t.Errorf("expected member to be created with name %q, got %q", server.Name, created.Member.Name)
+	}
+	if member.Name != server.Name {
+		t.Errorf("expected member name %q, got %q", server.Name, member.Name)
+	}
+}
+
+func Test_CheckPoolCapacity(t *testing.T) {
+	tests := []struct {
+		name              string
+		flavorProfile     string
+		flavorProfileCode int
+		addingMembers     int
+		expectError       bool
+	}{
+		{
+			name:              "within limit",
+			flavorProfile:     `{"flavorprofile": {"id": "profile", "flavor_data": "{\"max_members_per_pool\": 5}"}}`,
+			flavorProfileCode: http.StatusOK,
+			addingMembers:     2,
+		},
+		{
+			name:              "exceeds limit",
+			flavorProfile:     `{"flavorprofile": {"id": "profile", "flavor_data": "{\"max_members_per_pool\": 4}"}}`,
+			flavorProfileCode: http.StatusOK,
+			addingMembers:     2,
+			expectError:       true,
+		},
+		{
+			name:              "no limit in flavor",
+			flavorProfile:     `{"flavorprofile": {"id": "profile", "flavor_data": "{\"loadbalancer_topology\": \"SINGLE\"}"}}`,
+			flavorProfileCode: http.StatusOK,
+			addingMembers:     100,
+		},
+		{
+			name:              "flavor profile forbidden",
+			flavorProfile:     `{"forbidden": {}}`,
+			flavorProfileCode: http.StatusForbidden,
+			addingMembers:     100,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			fixture(mux, "/lbaas/pools/pool", http.MethodGet, `{"pool": {"id": "pool", "loadbalancers": [{"id": "lb"}]}}`, http.StatusOK)
+			fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, reconcileMemberListing, http.StatusOK)
+			fixture(mux, "/lbaas/loadbalancers/lb", http.MethodGet, `{"loadbalancer": {"id": "lb", "flavor_id": "flavor"}}`, http.StatusOK)
+			fixture(mux, "/lbaas/flavors/flavor", http.MethodGet, `{"flavor": {"id": "flavor", "name": "small", "flavor_profile_id": "profile"}}`, http.StatusOK)
+			fixture(mux, "/lbaas/flavorprofiles/profile", http.MethodGet, test.flavorProfile, test.flavorProfileCode)
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			err := cloud.CheckPoolCapacity("pool", test.addingMembers)
+			if test.expectError && err == nil {
+				t.Errorf("expected capacity error")
+			}
+			if !test.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Benchmark_ListPoolMembersMap(b *testing.B) {
+	var listing strings.Builder
+	listing.WriteString(`{"members": [`)
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			listing.WriteString(",")
+		}
+		fmt.Fprintf(&listing, `{"id": "member-%d", "address": "10.0.%d.%d", "protocol_port": 443}`, i, i/256, i%256)
+	}
+	listing.WriteString("]}")
+
+	mux := http.NewServeMux()
+	fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, listing.String(), http.StatusOK)
+	mux.HandleFunc("/lbaas/pools/pool/members/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"member": {"id": %q, "protocol_port": 443}}`, strings.TrimPrefix(r.URL.Path, "/lbaas/pools/pool/members/"))
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+	memberList, err := cloud.ListPoolMembers("pool", v2pools.ListMembersOpts{})
+	if err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+
+	b.Run("map", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := cloud.ListPoolMembersMap("pool"); err != nil {
+				b.Fatalf("unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("per-member", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, member := range memberList {
+				if _, err := cloud.GetPoolMember("pool", member.ID); err != nil {
+					b.Fatalf("unexpected error: %v", err)
+				}
+			}
+		}
+	})
+}
+
+func Test_CreateLBAndWait(t *testing.T) {
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+
+	tests := []struct {
+		desc            string
+		statuses        []string
+		timeout         time.Duration
+		expectedErr     bool
+		expectedErrText string
+		expectedDeleted bool
+	}{
+		{
+			desc:     "becomes active",
+			statuses: []string{"PENDING_CREATE", "PENDING_CREATE", "ACTIVE"},
+		},
+		{
+			desc:            "goes into error",
+			statuses:        []string{"PENDING_CREATE", "ERROR"},
+			expectedErr:     true,
+			expectedDeleted: true,
+		},
+		{
+			desc:            "times out",
+			statuses:        []string{"PENDING_CREATE"},
+			timeout:         20 * time.Millisecond,
+			expectedErr:     true,
+			expectedErrText: `last provisioning status "PENDING_CREATE", operating status "OFFLINE"`,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			polls := 0
+			deleted := false
+
+			mux := http.NewServeMux()
+			fixture(mux, "/lbaas/loadbalancers", http.MethodPost, `{"loadbalancer": {"id": "lb", "provisioning_status": "PENDING_CREATE"}}`, http.StatusAccepted)
+			mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				switch r.Method {
+				case http.MethodGet:
+					status := testCase.statuses[min(polls, len(testCase.statuses)-1)]
+					polls++
+					w.WriteHeader(http.StatusOK)
+					fmt.Fprintf(w, `{"loadbalancer": {"id": "lb", "provisioning_status": %q, "operating_status": "OFFLINE"}}`, status)
+				case http.MethodDelete:
+					deleted = true
+					w.WriteHeader(http.StatusNotFound)
+				}
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			timeout := testCase.timeout
+			if timeout == 0 {
+				timeout = time.Second
+			}
+			lb, err := cloud.CreateLBAndWait(loadbalancers.CreateOpts{Name: "lb"}, timeout)
+			if testCase.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, got loadbalancer %v", lb)
+				} else if !strings.Contains(err.Error(), testCase.expectedErrText) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedErrText, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if lb.ProvisioningStatus != "ACTIVE" {
+					t.Errorf("expected refreshed ACTIVE loadbalancer, got %q", lb.ProvisioningStatus)
+				}
+			}
+			if deleted != testCase.expectedDeleted {
+				t.Errorf("expected deleted=%v, got %v", testCase.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func Test_WaitForLoadBalancerActiveFailsFastOnError(t *testing.T) {
+	defer func(interval time.Duration) {
+		loadbalancerActivePollInterval = interval
+	}(loadbalancerActivePollInterval)
+	loadbalancerActivePollInterval = time.Millisecond
+
+	statuses := []string{"PENDING_CREATE", "PENDING_CREATE", "ERROR", "ACTIVE"}
+	polls := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[min(polls, len(statuses)-1)]
+		polls++
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"loadbalancer": {"id": "lb", "provisioning_status": %q, "operating_status": "OFFLINE"}}`, status)
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	start := time.Now()
+	lb, err := waitForLoadBalancerActive(context.TODO(), cloud, "lb", lbWaitOptions{Timeout: time.Minute})
+	if !errors.Is(err, ErrLoadBalancerProvisioningFailed) {
+		t.Fatalf("expected ErrLoadBalancerProvisioningFailed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "lb") {
+		t.Errorf("expected error to name the loadbalancer, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected to fail fast, waited %v", elapsed)
+	}
+	if polls != 3 {
+		t.Errorf("expected to stop polling at ERROR after 3 polls, polled %d times", polls)
+	}
+	if lb == nil || lb.ProvisioningStatus != "ERROR" {
+		t.Errorf("expected the loadbalancer in ERROR to be returned, got %v", lb)
+	}
+}
+
+func Test_WaitForLoadBalancerActiveRetriesErrors(t *testing.T) {
+	defer func(interval time.Duration, backoff wait.Backoff) {
+		loadbalancerActivePollInterval = interval
+		readBackoff = backoff
+	}(loadbalancerActivePollInterval, readBackoff)
+	loadbalancerActivePollInterval = time.Millisecond
+	// GetLB gives up after a single attempt, so that the wait sees the error
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Steps: 1}
+
+	t.Run("service unavailable", func(t *testing.T) {
+		statuses := []int{http.StatusOK, http.StatusServiceUnavailable, http.StatusOK}
+		polls := 0
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+			status := statuses[min(polls, len(statuses)-1)]
+			polls++
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(status)
+			if status != http.StatusOK {
+				return
+			}
+			provisioningStatus := "PENDING_UPDATE"
+			if polls == len(statuses) {
+				provisioningStatus = "ACTIVE"
+			}
+			fmt.Fprintf(w, `{"loadbalancer": {"id": "lb", "provisioning_status": %q}}`, provisioningStatus)
+		})
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		cloud := &openstackCloud{
+			lbClient: serviceClient(testServer.URL),
+		}
+
+		lb, err := waitForLoadBalancerActive(context.TODO(), cloud, "lb", lbWaitOptions{Timeout: time.Minute})
+		if err != nil {
+			t.Fatalf("expected the wait to go on after a 503, got %v", err)
+		}
+		if lb.ProvisioningStatus != "ACTIVE" || polls != 3 {
+			t.Errorf("expected ACTIVE after 3 polls, got %s after %d", lb.ProvisioningStatus, polls)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		polls := 0
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+			polls++
+			w.WriteHeader(http.StatusNotFound)
+		})
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		cloud := &openstackCloud{
+			lbClient: serviceClient(testServer.URL),
+		}
+
+		_, err := waitForLoadBalancerActive(context.TODO(), cloud, "lb", lbWaitOptions{Timeout: time.Minute})
+		if err == nil || !isNotFound(err) {
+			t.Fatalf("expected a not found error, got %v", err)
+		}
+		if polls != 1 {
+			t.Errorf("expected to stop polling at the first 404, polled %d times", polls)
+		}
+	})
+}
+
+func Test_WaitForLoadBalancerActivePollInterval(t *testing.T) {
+	defer func(c clock.WithTicker) {
+		lbWaitClock = c
+	}(lbWaitClock)
+
+	tests := []struct {
+		desc             string
+		pollInterval     time.Duration
+		expectedInterval time.Duration
+	}{
+		{
+			desc:             "default",
+			expectedInterval: 2 * time.Second,
+		},
+		{
+			desc:             "explicit",
+			pollInterval:     10 * time.Second,
+			expectedInterval: 10 * time.Second,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			fakeClock := testingclock.NewFakeClock(time.Now())
+			lbWaitClock = fakeClock
+
+			statuses := []string{"PENDING_UPDATE", "PENDING_UPDATE", "PENDING_UPDATE", "ACTIVE"}
+			polls := make(chan string, len(statuses))
+			var mutex sync.Mutex
+			count := 0
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				status := statuses[min(count, len(statuses)-1)]
+				count++
+				mutex.Unlock()
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				fmt.Fprintf(w, `{"loadbalancer": {"id": "lb", "provisioning_status": %q}}`, status)
+				polls <- status
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := waitForLoadBalancerActive(context.TODO(), cloud, "lb", lbWaitOptions{Timeout: time.Hour, PollInterval: testCase.pollInterval})
+				done <- err
+			}()
+
+			expectPoll := func() {
+				select {
+				case <-polls:
+				case <-time.After(10 * time.Second):
+					t.Fatalf("expected the provisioning status to be checked")
+				}
+			}
+
+			// the status is checked straight away, and then once per interval
+			expectPoll()
+			for range statuses[1:] {
+				fakeClock.Step(testCase.expectedInterval - time.Millisecond)
+				select {
+				case <-polls:
+					t.Fatalf("expected the provisioning status not to be checked before %v", testCase.expectedInterval)
+				case <-time.After(50 * time.Millisecond):
+				}
+				fakeClock.Step(time.Millisecond)
+				expectPoll()
+			}
+
+			if err := <-done; err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_WaitForLoadBalancerActiveTimeoutWithFakeClock(t *testing.T) {
+	defer func(c clock.WithTicker) {
+		lbWaitClock = c
+	}(lbWaitClock)
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	lbWaitClock = fakeClock
+
+	polls := make(chan struct{}, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lbaas/loadbalancers/lb", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"loadbalancer": {"id": "lb", "provisioning_status": "PENDING_UPDATE", "operating_status": "ONLINE"}}`)
+		polls <- struct{}{}
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := waitForLoadBalancerActive(context.TODO(), cloud, "lb", lbWaitOptions{Timeout: 25 * time.Second, PollInterval: 10 * time.Second})
+		done <- err
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-polls:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("expected poll %d", i+1)
+		}
+		if i < 2 {
+			fakeClock.Step(10 * time.Second)
+		}
+	}
+	fakeClock.Step(5 * time.Second)
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected the wait to time out")
+	}
+	var timeoutErr *LoadBalancerWaitTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected LoadBalancerWaitTimeoutError, got %v", err)
+	}
+	if timeoutErr.Elapsed != 25*time.Second {
+		t.Errorf("expected the timeout after 25s, got %v", timeoutErr.Elapsed)
+	}
+	if len(polls) != 0 {
+		t.Errorf("expected no poll after the timeout, got %d", len(polls))
+	}
+}
+
+func Test_LoadBalancerHelpersConcurrent(t *testing.T) {
+	mux := http.NewServeMux()
+	fixture(mux, "/", http.MethodGet, `{"versions": [{"id": "v2.15", "status": "CURRENT"}]}`, http.StatusOK)
+	fixture(mux, "/lbaas/pools/pool/members", http.MethodGet, reconcileMemberListing, http.StatusOK)
+	fixture(mux, "/lbaas/pools/pool/members/", http.MethodGet, `{"member": {"id": "keep", "address": "10.0.0.1", "protocol_port": 443}}`, http.StatusOK)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	// Run with -race to detect unsynchronized access to the shared client state
+	const workers = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*4)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if cloud.LoadBalancerClient() == nil {
+				errs <- fmt.Errorf("expected a load balancer client")
+			}
+			if use, err := cloud.UseLoadBalancerVIPACL(); err != nil {
+				errs <- err
+			} else if !use {
+				errs <- fmt.Errorf("expected VIP ACLs to be supported")
+			}
+			if _, err := cloud.ListPoolMembersMap("pool"); err != nil {
+				errs <- err
+			}
+			server := &servers.Server{ID: fmt.Sprintf("server-%d", i)}
+			if _, err := cloud.AssociateToPool(server, "pool", v2pools.CreateMemberOpts{Address: "10.0.0.1", ProtocolPort: 443}); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func Test_BuildMonitorCreateOpts(t *testing.T) {
+	base := func(monitorType string) MonitorSpec {
+		return MonitorSpec{
+			Name:       "monitor",
+			PoolID:     "pool",
+			Type:       monitorType,
+			Delay:      10,
+			Timeout:    5,
+			MaxRetries: 3,
+		}
+	}
+	withHTTP := func(spec MonitorSpec, f func(spec *MonitorSpec)) MonitorSpec {
+		f(&spec)
+		return spec
+	}
+
+	tests := []struct {
+		name          string
+		spec          MonitorSpec
+		expected      monitors.CreateOpts
+		expectedError string
+	}{
+		{
+			name:          "missing type",
+			spec:          base(""),
+			expectedError: "monitor type is required",
+		},
+		{
+			name:          "unknown type",
+			spec:          base("QUIC"),
+			expectedError: `unknown monitor type "QUIC"`,
+		},
+		{
+			name:     "TCP",
+			spec:     base(monitors.TypeTCP),
+			expected: monitors.CreateOpts{Name: "monitor", PoolID: "pool", Type: monitors.TypeTCP, Delay: 10, Timeout: 5, MaxRetries: 3},
+		},
+		{
+			name: "TCP with URL path",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.URLPath = "/healthz"
+			}),
+			expectedError: "URLPath can only be set on HTTP or HTTPS monitors, not TCP",
+		},
+		{
+			name: "TCP with HTTP fields",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.HTTPMethod = "GET"
+				spec.ExpectedCodes = "200"
+			}),
+			expectedError: "HTTPMethod, ExpectedCodes can only be set on HTTP or HTTPS monitors, not TCP",
+		},
+		{
+			name:     "PING",
+			spec:     base(monitors.TypePING),
+			expected: monitors.CreateOpts{Name: "monitor", PoolID: "pool", Type: monitors.TypePING, Delay: 10, Timeout: 5, MaxRetries: 3},
+		},
+		{
+			name: "TLS-HELLO with domain name",
+			spec: withHTTP(base(monitors.TypeTLSHELLO), func(spec *MonitorSpec) {
+				spec.DomainName = "example.com"
+			}),
+			expectedError: "DomainName can only be set on HTTP or HTTPS monitors, not TLS-HELLO",
+		},
+		{
+			name: "UDP-CONNECT with HTTP version",
+			spec: withHTTP(base(monitors.TypeUDPConnect), func(spec *MonitorSpec) {
+				spec.HTTPVersion = "1.1"
+			}),
+			expectedError: "HTTPVersion can only be set on HTTP or HTTPS monitors, not UDP-CONNECT",
+		},
+		{
+			name:          "HTTP without URL path",
+			spec:          base(monitors.TypeHTTP),
+			expectedError: "URLPath is required for HTTP monitors",
+		},
+		{
+			name: "HTTP with relative URL path",
+			spec: withHTTP(base(monitors.TypeHTTP), func(spec *MonitorSpec) {
+				spec.URLPath = "healthz"
+			}),
+			expectedError: `URLPath "healthz" must start with /`,
+		},
+		{
+			name: "HTTP defaults method",
+			spec: withHTTP(base(monitors.TypeHTTP), func(spec *MonitorSpec) {
+				spec.URLPath = "/healthz"
+			}),
+			expected: monitors.CreateOpts{Name: "monitor", PoolID: "pool", Type: monitors.TypeHTTP, Delay: 10, Timeout: 5, MaxRetries: 3, URLPath: "/healthz", HTTPMethod: "GET"},
+		},
+		{
+			name: "HTTPS fully populated",
+			spec: withHTTP(base(monitors.TypeHTTPS), func(spec *MonitorSpec) {
+				spec.MaxRetriesDown = 2
+				spec.URLPath = "/readyz"
+				spec.HTTPMethod = "head"
+				spec.HTTPVersion = "1.1"
+				spec.ExpectedCodes = "200-204"
+				spec.DomainName = "api.example.com"
+			}),
+			expected: monitors.CreateOpts{
+				Name: "monitor", PoolID: "pool", Type: monitors.TypeHTTPS, Delay: 10, Timeout: 5, MaxRetries: 3, MaxRetriesDown: 2,
+				URLPath: "/readyz", HTTPMethod: "HEAD", HTTPVersion: "1.1", ExpectedCodes: "200-204", DomainName: "api.example.com",
+			},
+		},
+		{
+			name: "HTTP with unsupported method",
+			spec: withHTTP(base(monitors.TypeHTTP), func(spec *MonitorSpec) {
+				spec.URLPath = "/healthz"
+				spec.HTTPMethod = "FETCH"
+			}),
+			expectedError: `HTTPMethod "FETCH" is not supported`,
+		},
+		{
+			name: "HTTP with unsupported version",
+			spec: withHTTP(base(monitors.TypeHTTP), func(spec *MonitorSpec) {
+				spec.URLPath = "/healthz"
+				spec.HTTPVersion = "2"
+			}),
+			expectedError: `HTTPVersion "2" is not supported`,
+		},
+		{
+			name: "HTTP domain name requires HTTP/1.1",
+			spec: withHTTP(base(monitors.TypeHTTP), func(spec *MonitorSpec) {
+				spec.URLPath = "/healthz"
+				spec.DomainName = "api.example.com"
+			}),
+			expectedError: "DomainName requires HTTPVersion 1.1",
+		},
+		{
+			name: "timeout exceeds delay",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.Timeout = 20
+			}),
+			expectedError: "Timeout must be positive and no greater than Delay (10), got 20",
+		},
+		{
+			name: "missing delay",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.Delay = 0
+			}),
+			expectedError: "Delay must be positive, got 0",
+		},
+		{
+			name: "too many retries",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.MaxRetries = 11
+			}),
+			expectedError: "MaxRetries must be between 1 and 10, got 11",
+		},
+		{
+			name: "too many retries down",
+			spec: withHTTP(base(monitors.TypeTCP), func(spec *MonitorSpec) {
+				spec.MaxRetriesDown = 11
+			}),
+			expectedError: "MaxRetriesDown must be between 1 and 10, got 11",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			opts, err := BuildMonitorCreateOpts(testCase.spec)
+			if testCase.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", testCase.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(opts, testCase.expected) {
+				t.Errorf("expected %+v, got %+v", testCase.expected, opts)
+			}
+		})
+	}
+}
+
+func Test_DeleteLBOrdered(t *testing.T) {
+	defer func(interval time.Duration, deleteBackoffs, memberBackoffs wait.Backoff) {
+		loadbalancerActivePollInterval = interval
+		deleteBackoff = deleteBackoffs
+		memberBackoff = memberBackoffs
+	}(loadbalancerActivePollInterval, deleteBackoff, memberBackoff)
+	loadbalancerActivePollInterval = time.Millisecond
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+	memberBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	// a loadbalancer with a listener, a pool, a monitor and two members, on a provider without cascade delete
+	octavia := newMockOctavia(t)
+	octavia.AsyncProvisioning = true
+	octavia.CascadeDeleteUnsupported = true
+	octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "lb"})
+	octavia.AddListener(listeners.Listener{ID: "listener", DefaultPoolID: "pool", Loadbalancers: []listeners.LoadBalancerID{{ID: "lb"}}})
+	octavia.AddPool(v2pools.Pool{ID: "pool", Loadbalancers: []v2pools.LoadBalancerID{{ID: "lb"}}})
+	octavia.AddMonitor(monitors.Monitor{ID: "monitor", Pools: []monitors.PoolID{{ID: "pool"}}})
+	octavia.AddMember(v2pools.Member{ID: "m1", PoolID: "pool", Address: "10.0.0.1", ProtocolPort: 443})
+	octavia.AddMember(v2pools.Member{ID: "m2", PoolID: "pool", Address: "10.0.0.2", ProtocolPort: 443})
+	// the first member delete is rejected because the pool is locked
+	conflictFirst(octavia, "DELETE /lbaas/pools/pool/members/m1", 1)
+	cloud := octavia.cloud()
+
+	if err := cloud.DeleteLBOrdered("lb"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var deletes []string
+	for _, request := range octavia.recorded() {
+		if strings.HasPrefix(request, http.MethodDelete) {
+			deletes = append(deletes, request)
+		}
+	}
+	// the monitor, pool, listener and loadbalancer are deleted again until they are gone
+	expected := []string{
+		"DELETE /lbaas/pools/pool/members/m1",
+		"DELETE /lbaas/pools/pool/members/m1",
+		"DELETE /lbaas/pools/pool/members/m2",
+		"DELETE /lbaas/healthmonitors/monitor",
+		"DELETE /lbaas/healthmonitors/monitor",
+		"DELETE /lbaas/pools/pool",
+		"DELETE /lbaas/pools/pool",
+		"DELETE /lbaas/listeners/listener",
+		"DELETE /lbaas/listeners/listener",
+		"DELETE /lbaas/loadbalancers/lb",
+		"DELETE /lbaas/loadbalancers/lb",
+	}
+	if actual := strings.Join(deletes, "\n"); actual != strings.Join(expected, "\n") {
+		t.Errorf("unexpected deletes:\n%s\nexpected:\n%s", actual, strings.Join(expected, "\n"))
+	}
+	if lbs := octavia.LoadBalancers(); len(lbs) != 0 {
+		t.Errorf("expected the loadbalancer to be deleted, got %v", lbs)
+	}
+
+	// deleting a loadbalancer that is already gone succeeds
+	if err := cloud.DeleteLBOrdered("lb"); err != nil {
+		t.Errorf("unexpected error deleting missing loadbalancer: %v", err)
+	}
+}
+
+func Test_EnsureLBVIPSecurityGroups(t *testing.T) {
+	networking := mocknetworking.CreateClient()
+	defer networking.Server.Close()
+	port := networking.AddPort(ports.Port{Name: "vip-port", SecurityGroups: []string{"default"}})
+	// updates are the security groups of the updates of the VIP port
+	var updates [][]string
+	var mutex sync.Mutex
+	neutron := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var body struct {
+				Port struct {
+					SecurityGroups []string `json:"security_groups"`
+				} `json:"port"`
+			}
+			data, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			if err := json.Unmarshal(data, &body); err == nil {
+				mutex.Lock()
+				updates = append(updates, body.Port.SecurityGroups)
+				mutex.Unlock()
+			}
+		}
+		networking.Mux.ServeHTTP(w, r)
+	}))
+	defer neutron.Close()
+
+	octavia := newMockOctavia(t)
+	octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "lb", VipPortID: port.ID})
+	cloud := octavia.cloud()
+	cloud.neutronClient = serviceClient(neutron.URL)
+
+	for i := 0; i < 2; i++ {
+		if err := cloud.EnsureLBVIPSecurityGroups("lb", []string{"sg-b", "sg-a"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if expected := [][]string{{"sg-a", "sg-b"}}; !reflect.DeepEqual(updates, expected) {
+		t.Errorf("expected updates %v, got %v", expected, updates)
+	}
+
+	// drift is reverted
+	if _, err := ports.Update(context.TODO(), networking.ServiceClient(), port.ID, ports.UpdateOpts{SecurityGroups: &[]string{"sg-a", "default"}}).Extract(); err != nil {
+		t.Fatalf("error changing the security groups: %v", err)
+	}
+	if err := cloud.EnsureLBVIPSecurityGroups("lb", []string{"sg-a", "sg-b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	actual, err := ports.Get(context.TODO(), networking.ServiceClient(), port.ID).Extract()
+	if err != nil {
+		t.Fatalf("error getting the VIP port: %v", err)
+	}
+	if expected := []string{"sg-a", "sg-b"}; !reflect.DeepEqual(actual.SecurityGroups, expected) {
+		t.Errorf("expected security groups %v, got %v", expected, actual.SecurityGroups)
+	}
+	if len(updates) != 2 {
+		t.Errorf("expected drift to be reverted with one update, got %v", updates)
+	}
+}
+
+func Test_UpdateMemberInPool_Retries(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		memberBackoff = backoff
+	}(memberBackoff)
+	memberBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	tests := []struct {
+		desc             string
+		statuses         []int
+		expectError      bool
+		expectedRequests int
+	}{
+		{desc: "bad request fails fast", statuses: []int{http.StatusBadRequest}, expectError: true, expectedRequests: 1},
+		{desc: "unprocessable entity fails fast", statuses: []int{http.StatusUnprocessableEntity}, expectError: true, expectedRequests: 1},
+		{desc: "conflict is retried", statuses: []int{http.StatusConflict, http.StatusConflict, http.StatusOK}, expectedRequests: 3},
+		{desc: "server error is retried", statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, expectedRequests: 2},
+		{desc: "missing member succeeds", statuses: []int{http.StatusNotFound}, expectedRequests: 1},
+		{desc: "persistent conflict times out", statuses: []int{http.StatusConflict}, expectError: true, expectedRequests: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var requests int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/lbaas/pools/pool/members/member", func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[min(requests, len(test.statuses)-1)]
+				requests++
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					fmt.Fprint(w, `{"member": {"id": "member"}}`)
+				}
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cloud := &openstackCloud{
+				lbClient: serviceClient(testServer.URL),
+			}
+
+			_, err := cloud.UpdateMemberInPool("pool", "member", v2pools.UpdateMemberOpts{Weight: fi.PtrTo(1)})
+			if test.expectError && err == nil {
+				t.Errorf("expected error")
+			}
+			if !test.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if requests != test.expectedRequests {
+				t.Errorf("expected %d requests, got %d", test.expectedRequests, requests)
+			}
+		})
+	}
+}
+
+// lbFleet is an Octavia API with many loadbalancers whose deletes take a while, recording how many run at once
+type lbFleet struct {
+	*mockOctavia
+
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+	// failing are the loadbalancers that cannot be deleted
+	failing map[string]bool
+}
+
+func newLBFleet(t *testing.T, clusterName string, count int, deleteDelay time.Duration) *lbFleet {
+	f := &lbFleet{
+		mockOctavia: newMockOctavia(t),
+		failing:     map[string]bool{},
+	}
+	f.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "other", Name: "api.other.example.com"})
+	f.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "api", Name: "api." + clusterName})
+	for i := 1; i < count; i++ {
+		f.AddLoadBalancer(loadbalancers.LoadBalancer{ID: fmt.Sprintf("svc%d", i), Name: fmt.Sprintf("kube_service_%s_default_svc%d", clusterName, i)})
+	}
+	f.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+		id, found := strings.CutPrefix(request, "DELETE /lbaas/loadbalancers/")
+		if !found {
+			return false
+		}
+		f.mutex.Lock()
+		f.inFlight++
+		f.maxInFlight = max(f.maxInFlight, f.inFlight)
+		failing := f.failing[id]
+		f.mutex.Unlock()
+
+		time.Sleep(deleteDelay)
+
+		f.mutex.Lock()
+		f.inFlight--
+		f.mutex.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+		} else {
+			f.Mux.ServeHTTP(w, r)
+		}
+		return true
+	}
+	return f
+}
+
+// remaining returns the IDs of the loadbalancers left
+func (f *lbFleet) remaining() []string {
+	var ids []string
+	for _, lb := range f.LoadBalancers() {
+		ids = append(ids, lb.ID)
+	}
+	return ids
+}
+
+func Test_DeleteClusterLoadBalancers(t *testing.T) {
+	defer func(deleteBackoffs wait.Backoff) {
+		deleteBackoff = deleteBackoffs
+	}(deleteBackoff)
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	const clusterName = "my.k8s"
+	const lbCount = 20
+
+	grid := []struct {
+		name        string
+		concurrency int
+	}{
+		{name: "serial", concurrency: 1},
+		{name: "default", concurrency: 0},
+		{name: "bounded", concurrency: 8},
+	}
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			fleet := newLBFleet(t, clusterName, lbCount, 10*time.Millisecond)
+
+			start := time.Now()
+			if err := fleet.cloud().DeleteClusterLoadBalancers(clusterName, DeleteClusterLoadBalancersOptions{Concurrency: g.concurrency}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			t.Logf("deleted %d loadbalancers in %v with at most %d at once", lbCount, time.Since(start), fleet.maxInFlight)
+
+			// only the loadbalancer of another cluster is left
+			if remaining := fleet.remaining(); !slices.Equal(remaining, []string{"other"}) {
+				t.Errorf("expected %d loadbalancers to be deleted, got %v left", lbCount, remaining)
+			}
+			limit := g.concurrency
+			if limit == 0 {
+				limit = DefaultLBTeardownConcurrency
+			}
+			if fleet.maxInFlight > limit {
+				t.Errorf("expected at most %d concurrent deletes, got %d", limit, fleet.maxInFlight)
+			}
+			if limit > 1 && fleet.maxInFlight < 2 {
+				t.Errorf("expected loadbalancers to be deleted concurrently")
+			}
+		})
+	}
+
+	t.Run("loadbalancers found by the caller", func(t *testing.T) {
+		fleet := newLBFleet(t, clusterName, lbCount, 0)
+
+		err := fleet.cloud().DeleteClusterLoadBalancers(clusterName, DeleteClusterLoadBalancersOptions{
+			LoadBalancerIDs: []string{"api", "svc2", "other"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		remaining := fleet.remaining()
+		if len(remaining) != lbCount-2 {
+			t.Errorf("expected 3 loadbalancers to be deleted, got %v left", remaining)
+		}
+		for _, id := range []string{"api", "svc2", "other"} {
+			if slices.Contains(remaining, id) {
+				t.Errorf("expected loadbalancer %s to be deleted", id)
+			}
+		}
+		if !slices.Contains(remaining, "svc1") {
+			t.Errorf("loadbalancer svc1 was deleted but not listed")
+		}
+	})
+
+	t.Run("errors are aggregated", func(t *testing.T) {
+		fleet := newLBFleet(t, clusterName, lbCount, 0)
+		fleet.failing["svc3"] = true
+		fleet.failing["svc7"] = true
+
+		err := fleet.cloud().DeleteClusterLoadBalancers(clusterName, DeleteClusterLoadBalancersOptions{})
+		if err == nil {
+			t.Fatalf("expected error")
+		}
+		for _, id := range []string{"svc3", "svc7"} {
+			if !strings.Contains(err.Error(), "("+id+")") {
+				t.Errorf("expected error for loadbalancer %s, got %v", id, err)
+			}
+		}
+		if remaining := fleet.remaining(); !slices.Equal(remaining, []string{"other", "svc3", "svc7"}) {
+			t.Errorf("expected the other %d loadbalancers to be deleted, got %v left", lbCount-2, remaining)
+		}
+	})
+}
+
+// conflictFirst makes Octavia reject the first count requests with a conflict, as it does while the loadbalancer is immutable
+func conflictFirst(octavia *mockOctavia, conflicting string, count int) {
+	octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+		if request != conflicting || count == 0 {
+			return false
+		}
+		count--
+		w.WriteHeader(http.StatusConflict)
+		return true
+	}
+}
+
+func Test_SwapListenerDefaultPool(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		writeBackoff = backoff
+	}(writeBackoff)
+	writeBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	octavia := newMockOctavia(t)
+	octavia.AddListener(listeners.Listener{ID: "listener", DefaultPoolID: "blue"})
+	conflictFirst(octavia, "PUT /lbaas/listeners/listener", 2)
+	cloud := octavia.cloud()
+
+	if err := cloud.SwapListenerDefaultPool("listener", "green"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updates := octavia.count("PUT /lbaas/listeners/listener"); updates != 3 {
+		t.Errorf("expected the update to be retried after conflicts, got %d updates", updates)
+	}
+
+	listener, err := listeners.Get(context.TODO(), cloud.LoadBalancerClient(), "listener").Extract()
+	if err != nil {
+		t.Fatalf("error getting listener: %v", err)
+	}
+	if listener.DefaultPoolID != "green" {
+		t.Errorf("expected listener to point at pool green, got %q", listener.DefaultPoolID)
+	}
+}
+
+func Test_EnsureHTTPToHTTPSRedirect(t *testing.T) {
+	defer func(read, write wait.Backoff) {
+		readBackoff = read
+		writeBackoff = write
+	}(readBackoff, writeBackoff)
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 2}
+	writeBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	octavia := newMockOctavia(t)
+	octavia.AddListener(listeners.Listener{ID: "http", Protocol: "HTTP"})
+	octavia.AddListener(listeners.Listener{ID: "https", Protocol: "TERMINATED_HTTPS"})
+	conflictFirst(octavia, "POST /lbaas/l7policies", 1)
+	cloud := octavia.cloud()
+
+	if err := cloud.EnsureHTTPToHTTPSRedirect("http", "api.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creates := octavia.count("POST /lbaas/l7policies"); creates != 2 {
+		t.Errorf("expected the creation to be retried after a conflict, got %d creations", creates)
+	}
+	policies := octavia.L7Policies()
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 L7 policy, got %d", len(policies))
+	}
+	policy := policies[0]
+	if policy.ListenerID != "http" || policy.Action != "REDIRECT_PREFIX" || policy.RedirectPrefix != "https://api.example.com" || policy.RedirectHttpCode != http.StatusMovedPermanently {
+		t.Errorf("unexpected L7 policy %+v", policy)
+	}
+	if rules := policy.Rules; len(rules) != 1 || !isMatchAllPathRule(rules[0]) {
+		t.Errorf("expected the L7 policy to match all paths, got rules %+v", rules)
+	}
+
+	// An equivalent policy is left alone
+	if err := cloud.EnsureHTTPToHTTPSRedirect("http", "api.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policies, creates := octavia.L7Policies(), octavia.count("POST /lbaas/l7policies"); len(policies) != 1 || creates != 2 {
+		t.Errorf("expected no new L7 policy, got %d policies after %d creations", len(policies), creates)
+	}
+
+	// A redirect to another host is not equivalent
+	if err := cloud.EnsureHTTPToHTTPSRedirect("http", "other.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if policies := octavia.L7Policies(); len(policies) != 2 {
+		t.Errorf("expected a new L7 policy for another host, got %d policies", len(policies))
+	}
+
+	if err := cloud.EnsureHTTPToHTTPSRedirect("https", "api.example.com"); err == nil || !strings.Contains(err.Error(), "not HTTP") {
+		t.Errorf("expected error redirecting an HTTPS listener, got %v", err)
+	}
+	if err := cloud.EnsureHTTPToHTTPSRedirect("missing", "api.example.com"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected error redirecting a missing listener, got %v", err)
+	}
+}
+
+func Test_DeleteLBByName(t *testing.T) {
+	fleet := newLBFleet(t, "my-cluster.k8s.local", 2, 0)
+	// the listener is only deleted with the loadbalancer by a cascade delete
+	fleet.AddListener(listeners.Listener{ID: "listener", Loadbalancers: []listeners.LoadBalancerID{{ID: "api"}}})
+	cloud := fleet.cloud()
+
+	var confirmed []string
+	refuse := func(lb *loadbalancers.LoadBalancer) bool {
+		confirmed = append(confirmed, lb.ID)
+		return false
+	}
+	if err := cloud.DeleteLBByName("api.my-cluster.k8s.local", refuse); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(confirmed, []string{"api"}) {
+		t.Errorf("expected confirmation to be asked for loadbalancer api, got %v", confirmed)
+	}
+	if remaining := fleet.remaining(); !slices.Equal(remaining, []string{"api", "other", "svc1"}) {
+		t.Errorf("expected nothing to be deleted without confirmation, got %v left", remaining)
+	}
+
+	accept := func(lb *loadbalancers.LoadBalancer) bool {
+		return lb.Name == "api.my-cluster.k8s.local"
+	}
+	if err := cloud.DeleteLBByName("api.my-cluster.k8s.local", accept); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining, listeners := fleet.remaining(), fleet.Listeners(); !slices.Equal(remaining, []string{"other", "svc1"}) || len(listeners) != 0 {
+		t.Errorf("expected loadbalancer api to be cascade deleted, got %v left with listeners %v", remaining, listeners)
+	}
+
+	// Deleting again finds nothing, without asking for confirmation
+	if err := cloud.DeleteLBByName("api.my-cluster.k8s.local", func(lb *loadbalancers.LoadBalancer) bool {
+		t.Errorf("unexpected confirmation for loadbalancer %s", lb.ID)
+		return true
+	}); err != nil {
+		t.Errorf("unexpected error deleting a missing loadbalancer: %v", err)
+	}
+}
+
+func Test_DetachMonitorFromPool(t *testing.T) {
+	defer func(read, del wait.Backoff) {
+		readBackoff = read
+		deleteBackoff = del
+	}(readBackoff, deleteBackoff)
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+	deleteBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 4}
+
+	// The monitor is immutable at first, and the pool keeps referencing it for one read after the delete
+	monitorID := "monitor"
+	deletes := 0
+	staleReads := 1
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/lbaas/pools/pool", func(w http.ResponseWriter, r *http.Request) {
+		id := monitorID
+		if deletes > 1 && staleReads > 0 {
+			staleReads--
+			id = "monitor"
+		}
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"pool": {"id": "pool", "protocol": "TCP", "healthmonitor_id": %q}}`, id)
+	})
+	mux.HandleFunc("/lbaas/healthmonitors/monitor", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("unexpected %s of monitor", r.Method)
+		}
+		deletes++
+		if deletes == 1 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		monitorID = ""
+		w.WriteHeader(http.StatusNoContent)
+	})
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		lbClient: serviceClient(testServer.URL),
+	}
+
+	if err := cloud.DetachMonitorFromPool("pool"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deletes != 2 {
+		t.Errorf("expected the delete to be retried after a conflict, got %d deletes", deletes)
+	}
+
+	pool, err := cloud.GetPool("pool")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pool.MonitorID != "" {
+		t.Errorf("expected pool to no longer reference a monitor, got %q", pool.MonitorID)
+	}
+
+	// Detaching again is a no-op
+	if err := cloud.DetachMonitorFromPool("pool"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deletes != 2 {
+		t.Errorf("expected no further deletes, got %d deletes", deletes)
+	}
+
+	// Attaching creates a monitor for the pool
+	mux.HandleFunc("/lbaas/healthmonitors", func(w http.ResponseWriter, r *http.Request) {
+		monitorID = "monitor"
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"healthmonitor": {"id": "monitor", "pools": [{"id": "pool"}]}}`)
+	})
+	monitor, err := cloud.AttachMonitorToPool("pool", monitors.CreateOpts{Type: "TCP", Delay: 10, Timeout: 5, MaxRetries: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if monitor.ID != "monitor" {
+		t.Errorf("unexpected monitor %v", monitor)
+	}
+
+	if _, err := cloud.AttachMonitorToPool("pool", monitors.CreateOpts{Type: "TCP", Delay: 10, Timeout: 5, MaxRetries: 3}); err == nil {
+		t.Errorf("expected error attaching a second monitor")
+	}
+}
+
+// newDrainPoolsOctavia serves loadbalancer lb with two pools of members and an empty pool
+func newDrainPoolsOctavia(t *testing.T) *mockOctavia {
+	octavia := newMockOctavia(t)
+	octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: "lb", Name: "lb"})
+	for _, id := range []string{"https", "http", "empty"} {
+		octavia.AddPool(v2pools.Pool{ID: id, Name: id, Loadbalancers: []v2pools.LoadBalancerID{{ID: "lb"}}})
+	}
+	octavia.AddMember(v2pools.Member{ID: "a", PoolID: "https", Name: "node-a", Address: "10.0.0.1", ProtocolPort: 443, Weight: 1})
+	octavia.AddMember(v2pools.Member{ID: "b", PoolID: "https", Name: "node-b", Address: "10.0.0.2", ProtocolPort: 443, Weight: 5})
+	octavia.AddMember(v2pools.Member{ID: "c", PoolID: "http", Name: "node-a", Address: "10.0.0.1", ProtocolPort: 80, Weight: 1})
+	return octavia
+}
+
+func Test_DrainLBMembers(t *testing.T) {
+	for _, batchUnsupported := range []bool{false, true} {
+		t.Run(fmt.Sprintf("batchUnsupported=%v", batchUnsupported), func(t *testing.T) {
+			octavia := newDrainPoolsOctavia(t)
+			if batchUnsupported {
+				octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+					if r.Method != http.MethodPut || !strings.HasSuffix(r.URL.Path, "/members") {
+						return false
+					}
+					w.WriteHeader(http.StatusNotFound)
+					return true
+				}
+			}
+
+			if err := octavia.cloud().DrainLBMembers(context.TODO(), "lb", time.Millisecond); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, poolID := range []string{"https", "http"} {
+				members := octavia.Members(poolID)
+				if len(members) == 0 {
+					t.Errorf("expected the members of pool %s to be kept", poolID)
+				}
+				for _, member := range members {
+					if member.Weight != 0 {
+						t.Errorf("expected member %s of pool %s to be drained, got weight %d", member.ID, poolID, member.Weight)
+					}
+				}
+			}
+			// Each pool with members gets a batch update, which falls back to updating the members one by one
+			batches := octavia.count("PUT /lbaas/pools/https/members") + octavia.count("PUT /lbaas/pools/http/members")
+			if batches != 2 || octavia.count("PUT /lbaas/pools/empty/members") != 0 {
+				t.Errorf("expected a batch update of each pool with members, got %v", octavia.recorded())
+			}
+			memberUpdates := 0
+			for _, request := range []string{"PUT /lbaas/pools/https/members/a", "PUT /lbaas/pools/https/members/b", "PUT /lbaas/pools/http/members/c"} {
+				memberUpdates += octavia.count(request)
+			}
+			if expected := map[bool]int{false: 0, true: 3}[batchUnsupported]; memberUpdates != expected {
+				t.Errorf("expected %d member updates, got %v", expected, octavia.recorded())
+			}
+		})
+	}
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		cloud := newDrainPoolsOctavia(t).cloud()
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		err := cloud.DrainLBMembers(ctx, "lb", time.Hour)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected the wait to end with the context, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Minute {
+			t.Errorf("expected the wait to be cut short, took %v", elapsed)
+		}
+	})
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package openstack
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
 
@@ -31,6 +33,7 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/recordsets"
 	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/zones"
 	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
@@ -70,6 +73,14 @@ type MockCloud struct {
 	extNetworkName    *string
 	extSubnetName     *string
 	floatingSubnet    *string
+	lbEventSink       LBEventSink
+	lbMetricsRecorder LBMetricsRecorder
+
+	// LBTLSPolicyUnsupported makes the mock behave like an Octavia older than 2.17, without the TLS versions and ciphers of listeners
+	LBTLSPolicyUnsupported bool
+
+	// LBVIPQosPolicyUnsupported makes the mock behave like an Octavia older than 2.1, which may not support the QoS policy of the VIP
+	LBVIPQosPolicyUnsupported bool
 }
 
 func InstallMockOpenstackCloud(region string) *MockCloud {
@@ -185,6 +196,14 @@ func (c *MockCloud) CreateL3FloatingIP(opts l3floatingip.CreateOpts) (fip *l3flo
 	return createL3FloatingIP(c, opts)
 }
 
+func (c *MockCloud) UpdateLB(loadbalancerID string, opt loadbalancers.UpdateOpts) (*loadbalancers.LoadBalancer, error) {
+	return updateLB(c, loadbalancerID, opt)
+}
+
+func (c *MockCloud) CreateLBAndWait(opt loadbalancers.CreateOptsBuilder, timeout time.Duration) (*loadbalancers.LoadBalancer, error) {
+	return createLBAndWait(c, opt, lbWaitOptions{Timeout: timeout})
+}
+
 func (c *MockCloud) CreateLB(opt loadbalancers.CreateOptsBuilder) (*loadbalancers.LoadBalancer, error) {
 	return createLB(c, opt)
 }
@@ -201,6 +220,10 @@ func (c *MockCloud) CreatePool(opts v2pools.CreateOpts) (pool *v2pools.Pool, err
 	return createPool(c, opts)
 }
 
+func (c *MockCloud) UpdatePool(poolID string, opts v2pools.UpdateOpts) (*v2pools.Pool, error) {
+	return updatePool(c, poolID, opts)
+}
+
 func (c *MockCloud) CreatePoolMonitor(opts monitors.CreateOpts) (*monitors.Monitor, error) {
 	return createPoolMonitor(c, opts)
 }
@@ -257,18 +280,86 @@ func (c *MockCloud) DeleteL3FloatingIP(id string) (err error) {
 	return deleteL3FloatingIP(c, id)
 }
 
+func (c *MockCloud) AssociateFloatingIPToLB(lbID string, floatingIPID string) error {
+	return associateFloatingIPToLB(c, lbID, floatingIPID)
+}
+
+func (c *MockCloud) DisassociateFloatingIPFromLB(lbID string, floatingIPID string) error {
+	return disassociateFloatingIPFromLB(c, lbID, floatingIPID)
+}
+
+func (c *MockCloud) EnsureLBVIPSecurityGroups(lbID string, sgIDs []string) error {
+	return ensureLBVIPSecurityGroups(c, lbID, sgIDs)
+}
+
 func (c *MockCloud) DeleteLB(lbID string, opts loadbalancers.DeleteOpts) error {
 	return deleteLB(c, lbID, opts)
 }
 
+func (c *MockCloud) GetLBByName(name string) (*loadbalancers.LoadBalancer, error) {
+	return getLBByName(c, name)
+}
+
+func (c *MockCloud) EnsureLoadBalancer(spec LoadBalancerSpec) (*loadbalancers.LoadBalancer, error) {
+	return ensureLoadBalancer(c, spec)
+}
+
+func (c *MockCloud) DeleteLBByName(name string, confirm func(lb *loadbalancers.LoadBalancer) bool) error {
+	return deleteLBByName(c, name, confirm)
+}
+
+func (c *MockCloud) DeleteLBOrdered(lbID string) error {
+	return deleteLBOrdered(c, lbID)
+}
+
+func (c *MockCloud) ListAmphoraeForLB(lbID string) ([]amphorae.Amphora, error) {
+	return listAmphoraeForLB(c, lbID)
+}
+
+func (c *MockCloud) DrainLBMembers(ctx context.Context, lbID string, drain time.Duration) error {
+	return drainLBMembers(ctx, c, lbID, drain)
+}
+
+func (c *MockCloud) DeleteClusterLoadBalancers(clusterName string, opts DeleteClusterLoadBalancersOptions) error {
+	return deleteClusterLoadBalancers(c, clusterName, opts)
+}
+
+func (c *MockCloud) UpdateListener(listenerID string, opts listeners.UpdateOpts) (*listeners.Listener, error) {
+	return updateListener(c, listenerID, opts)
+}
+
+func (c *MockCloud) SwapListenerDefaultPool(listenerID, newPoolID string) error {
+	return swapListenerDefaultPool(c, listenerID, newPoolID)
+}
+
+func (c *MockCloud) ShadowSwapListener(listenerID string, opts ShadowSwapOptions) (*listeners.Listener, error) {
+	return shadowSwapListener(c, listenerID, opts)
+}
+
+func (c *MockCloud) EnsureHTTPToHTTPSRedirect(httpListenerID, host string) error {
+	return ensureHTTPToHTTPSRedirect(c, httpListenerID, host)
+}
+
 func (c *MockCloud) DeleteListener(listenerID string) error {
 	return deleteListener(c, listenerID)
 }
 
+func (c *MockCloud) UpdateMonitor(monitorID string, opts monitors.UpdateOpts) (*monitors.Monitor, error) {
+	return updateMonitor(c, monitorID, opts)
+}
+
 func (c *MockCloud) DeleteMonitor(monitorID string) error {
 	return deleteMonitor(c, monitorID)
 }
 
+func (c *MockCloud) AttachMonitorToPool(poolID string, opts monitors.CreateOpts) (*monitors.Monitor, error) {
+	return attachMonitorToPool(c, poolID, opts)
+}
+
+func (c *MockCloud) DetachMonitorFromPool(poolID string) error {
+	return detachMonitorFromPool(c, poolID)
+}
+
 func (c *MockCloud) DeleteNetwork(networkID string) error {
 	return deleteNetwork(c, networkID)
 }
@@ -397,6 +488,10 @@ func (c *MockCloud) ListAvailabilityZones(serviceClient *gophercloud.ServiceClie
 	return listAvailabilityZones(c, serviceClient)
 }
 
+func (c *MockCloud) ListLBAvailabilityZones() ([]LBAvailabilityZone, error) {
+	return listLBAvailabilityZones(c)
+}
+
 func (c *MockCloud) ListDNSZones(opt zones.ListOptsBuilder) ([]zones.Zone, error) {
 	return listDNSZones(c, opt)
 }
@@ -429,10 +524,46 @@ func (c *MockCloud) GetLBStats(loadbalancerID string) (*loadbalancers.Stats, err
 	return getLBStats(c, loadbalancerID)
 }
 
+func (c *MockCloud) EstimatedPoolLoad(poolID string) (map[string]int, error) {
+	return estimatedPoolLoad(c, poolID)
+}
+
+func (c *MockCloud) SetPoolTrafficSplit(poolID string, weights map[string]int) error {
+	return setPoolTrafficSplit(c, poolID, weights)
+}
+
+func (c *MockCloud) GetPoolTrafficSplit(poolID string) (map[string]int, error) {
+	return getPoolTrafficSplit(c, poolID)
+}
+
+func (c *MockCloud) ReconcilePoolMembers(poolID string, desired []MemberSpec, preservation MemberPreservation) error {
+	return reconcilePoolMembers(c, poolID, desired, preservation)
+}
+
+func (c *MockCloud) ReplacePoolMembers(poolID string, desired []v2pools.BatchUpdateMemberOpts) error {
+	return replacePoolMembers(c, poolID, desired)
+}
+
+func (c *MockCloud) ReplaceMemberForNode(poolID string, oldServerAddr string, newServerAddr string, opts ReplaceMemberOpts) error {
+	return replaceMemberForNode(c, poolID, oldServerAddr, newServerAddr, opts)
+}
+
+func (c *MockCloud) CheckPoolCapacity(poolID string, addingMembers int) error {
+	return checkPoolCapacity(c, poolID, addingMembers)
+}
+
+func (c *MockCloud) ListPoolMembersMap(poolID string) (map[string]v2pools.Member, error) {
+	return listPoolMembersMap(c, poolID)
+}
+
 func (c *MockCloud) ListPoolMembers(poolID string, opts v2pools.ListMembersOpts) ([]v2pools.Member, error) {
 	return listPoolMembers(c, poolID, opts)
 }
 
+func (c *MockCloud) ListLBsByStatus(status string) ([]loadbalancers.LoadBalancer, error) {
+	return listLBsByStatus(c, status)
+}
+
 func (c *MockCloud) ListLBs(opt loadbalancers.ListOptsBuilder) (lbs []loadbalancers.LoadBalancer, err error) {
 	return listLBs(c, opt)
 }
@@ -457,6 +588,10 @@ func (c *MockCloud) ListPorts(opt ports.ListOptsBuilder) ([]ports.Port, error) {
 	return listPorts(c, opt)
 }
 
+func (c *MockCloud) DetectVIPSubnet(serverIDs []string) (string, error) {
+	return detectVIPSubnet(c, serverIDs)
+}
+
 func (c *MockCloud) ListRouters(opt routers.ListOpts) ([]routers.Router, error) {
 	return listRouters(c, opt)
 }
@@ -509,6 +644,45 @@ func (c *MockCloud) UseZones(zones []string) {
 	c.zones = zones
 }
 
+func (c *MockCloud) LBEventSink() LBEventSink {
+	return c.lbEventSink
+}
+
+func (c *MockCloud) SetLBEventSink(sink LBEventSink) {
+	c.lbEventSink = sink
+}
+
+func (c *MockCloud) LBMetricsRecorder() LBMetricsRecorder {
+	if c.lbMetricsRecorder == nil {
+		return noopLBMetricsRecorder{}
+	}
+	return c.lbMetricsRecorder
+}
+
+func (c *MockCloud) SetLBMetricsRecorder(recorder LBMetricsRecorder) {
+	c.lbMetricsRecorder = recorder
+}
+
+func (c *MockCloud) GetLBHealthSummary(lbID string) (*LBHealth, error) {
+	return getLBHealthSummary(c, lbID)
+}
+
+func (c *MockCloud) WaitForMembersOnline(poolID string, timeout time.Duration) error {
+	return waitForMembersOnline(c, poolID, timeout)
+}
+
+func (c *MockCloud) FindUnreachableMemberAddresses(poolID string, addresses []string) ([]string, error) {
+	return findUnreachableMemberAddresses(c, poolID, addresses)
+}
+
 func (c *MockCloud) UseLoadBalancerVIPACL() (bool, error) {
 	return true, nil
 }
+
+func (c *MockCloud) UseLoadBalancerTLSPolicy() (bool, error) {
+	return !c.LBTLSPolicyUnsupported, nil
+}
+
+func (c *MockCloud) UseLoadBalancerVIPQosPolicy() (bool, error) {
+	return !c.LBVIPQosPolicyUnsupported, nil
+}
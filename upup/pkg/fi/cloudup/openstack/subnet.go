@@ -19,7 +19,10 @@ package openstack
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
 	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kops/upup/pkg/fi"
@@ -169,3 +172,53 @@ func getLBFloatingSubnet(c OpenstackCloud, floatingSubnet *string) (subnet *subn
 	}
 	return nil, fmt.Errorf("did not find floatingsubnet for LB")
 }
+
+func (c *openstackCloud) DetectVIPSubnet(serverIDs []string) (string, error) {
+	return detectVIPSubnet(c, serverIDs)
+}
+
+// detectVIPSubnet returns the subnet that all the servers have a port in, to allocate a loadbalancer VIP from
+// when none is configured, so that the loadbalancer can reach the servers as its members.
+// It returns an error unless exactly one subnet is common to all servers.
+func detectVIPSubnet(c OpenstackCloud, serverIDs []string) (string, error) {
+	if len(serverIDs) == 0 {
+		return "", fmt.Errorf("cannot detect the VIP subnet without servers")
+	}
+
+	var common []string
+	for i, serverID := range serverIDs {
+		serverPorts, err := c.ListPorts(ports.ListOpts{DeviceID: serverID})
+		if err != nil {
+			return "", fmt.Errorf("error listing ports of server %s: %v", serverID, err)
+		}
+		var serverSubnets []string
+		for _, port := range serverPorts {
+			for _, fixedIP := range port.FixedIPs {
+				if !slices.Contains(serverSubnets, fixedIP.SubnetID) {
+					serverSubnets = append(serverSubnets, fixedIP.SubnetID)
+				}
+			}
+		}
+		if len(serverSubnets) == 0 {
+			return "", fmt.Errorf("server %s has no port with a fixed IP", serverID)
+		}
+
+		if i == 0 {
+			common = serverSubnets
+			continue
+		}
+		common = slices.DeleteFunc(common, func(subnetID string) bool {
+			return !slices.Contains(serverSubnets, subnetID)
+		})
+	}
+
+	switch len(common) {
+	case 0:
+		return "", fmt.Errorf("servers %s span multiple subnets and have none in common; VipSubnetID must be specified", strings.Join(serverIDs, ", "))
+	case 1:
+		return common[0], nil
+	default:
+		slices.Sort(common)
+		return "", fmt.Errorf("servers %s have multiple subnets in common (%s); VipSubnetID must be specified", strings.Join(serverIDs, ", "), strings.Join(common, ", "))
+	}
+}
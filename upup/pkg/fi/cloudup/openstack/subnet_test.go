@@ -0,0 +1,116 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+)
+
+// fakeServerPorts serves the ports of servers, filtered by device_id
+type fakeServerPorts map[string][]ports.Port
+
+func (f fakeServerPorts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || r.URL.Path != "/ports" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	result := []ports.Port{}
+	result = append(result, f[r.URL.Query().Get("device_id")]...)
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(mustJSONMarshal(json.Marshal(map[string]any{"ports": result})))
+}
+
+func serverPort(serverID string, subnetIDs ...string) ports.Port {
+	port := ports.Port{DeviceID: serverID}
+	for _, subnetID := range subnetIDs {
+		port.FixedIPs = append(port.FixedIPs, ports.IP{SubnetID: subnetID})
+	}
+	return port
+}
+
+func Test_DetectVIPSubnet(t *testing.T) {
+	fake := fakeServerPorts{
+		"cp-1":       {serverPort("cp-1", "nodes")},
+		"cp-2":       {serverPort("cp-2", "nodes"), serverPort("cp-2", "storage")},
+		"cp-3":       {serverPort("cp-3", "nodes")},
+		"other":      {serverPort("other", "other")},
+		"dual-1":     {serverPort("dual-1", "nodes", "storage")},
+		"dual-2":     {serverPort("dual-2", "storage"), serverPort("dual-2", "nodes")},
+		"unattached": {serverPort("unattached")},
+	}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+
+	cloud := &openstackCloud{
+		neutronClient: serviceClient(testServer.URL),
+	}
+
+	tests := []struct {
+		desc        string
+		serverIDs   []string
+		expected    string
+		expectedErr string
+	}{
+		{
+			desc:      "common subnet",
+			serverIDs: []string{"cp-1", "cp-2", "cp-3"},
+			expected:  "nodes",
+		},
+		{
+			desc:        "no common subnet",
+			serverIDs:   []string{"cp-1", "other"},
+			expectedErr: "servers cp-1, other span multiple subnets and have none in common",
+		},
+		{
+			desc:        "multiple common subnets",
+			serverIDs:   []string{"dual-1", "dual-2"},
+			expectedErr: "servers dual-1, dual-2 have multiple subnets in common (nodes, storage)",
+		},
+		{
+			desc:        "server without fixed IP",
+			serverIDs:   []string{"cp-1", "unattached"},
+			expectedErr: "server unattached has no port with a fixed IP",
+		},
+		{
+			desc:        "no servers",
+			expectedErr: "cannot detect the VIP subnet without servers",
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			actual, err := cloud.DetectVIPSubnet(testCase.serverIDs)
+			if testCase.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+					t.Errorf("expected error containing %q, got %v", testCase.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != testCase.expected {
+				t.Errorf("expected subnet %q, got %q", testCase.expected, actual)
+			}
+		})
+	}
+}
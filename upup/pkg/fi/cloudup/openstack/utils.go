@@ -23,7 +23,10 @@ import (
 
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
 )
 
 const (
@@ -32,6 +35,30 @@ const (
 	openstackAddress        = "addr"
 )
 
+// listAll reads every page of the pager and extracts the resources from them, retrying with readBackoff.
+// resource names the listed resources in errors.
+func listAll[T any](resource string, pager pagination.Pager, extract func(pagination.Page) ([]T, error)) ([]T, error) {
+	var items []T
+	done, err := vfs.RetryWithBackoff(readBackoff, func() (bool, error) {
+		allPages, err := pager.AllPages(context.TODO())
+		if err != nil {
+			return false, fmt.Errorf("failed to list %s: %v", resource, err)
+		}
+		items, err = extract(allPages)
+		if err != nil {
+			return false, fmt.Errorf("failed to extract %s: %v", resource, err)
+		}
+		return true, nil
+	})
+	if !done {
+		if err == nil {
+			err = wait.ErrWaitTimeout
+		}
+		return items, err
+	}
+	return items, nil
+}
+
 type flavorList []flavors.Flavor
 
 func (s flavorList) Len() int {
@@ -0,0 +1,118 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstack
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func Test_ListAll(t *testing.T) {
+	defer func(backoff wait.Backoff) {
+		readBackoff = backoff
+	}(readBackoff)
+	readBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+
+	extractIDs := func(page pagination.Page) ([]string, error) {
+		lbs, err := loadbalancers.ExtractLoadBalancers(page)
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, lb := range lbs {
+			ids = append(ids, lb.ID)
+		}
+		return ids, nil
+	}
+
+	tests := []struct {
+		desc        string
+		failures    int
+		extract     func(pagination.Page) ([]string, error)
+		expected    []string
+		expectedErr string
+	}{
+		{
+			desc:     "all pages",
+			extract:  extractIDs,
+			expected: []string{"lb1", "lb2", "lb3"},
+		},
+		{
+			desc:     "retried after failures",
+			failures: 2,
+			extract:  extractIDs,
+			expected: []string{"lb1", "lb2", "lb3"},
+		},
+		{
+			desc:        "failing",
+			failures:    3,
+			extract:     extractIDs,
+			expectedErr: "failed to list loadbalancers",
+		},
+		{
+			desc: "extract error",
+			extract: func(page pagination.Page) ([]string, error) {
+				return nil, fmt.Errorf("bad page")
+			},
+			expectedErr: "failed to extract loadbalancers: bad page",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			octavia := newMockOctavia(t)
+			for _, id := range []string{"lb1", "lb2", "lb3"} {
+				octavia.AddLoadBalancer(loadbalancers.LoadBalancer{ID: id})
+			}
+			failures := testCase.failures
+			octavia.intercept = func(w http.ResponseWriter, r *http.Request, request string) bool {
+				if failures == 0 {
+					return false
+				}
+				failures--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return true
+			}
+
+			// the loadbalancers are listed in two pages
+			pager := loadbalancers.List(octavia.cloud().LoadBalancerClient(), loadbalancers.ListOpts{Limit: 2})
+			actual, err := listAll("loadbalancers", pager, testCase.extract)
+			if testCase.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", testCase.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(actual, ",") != strings.Join(testCase.expected, ",") {
+				t.Errorf("expected %v, got %v", testCase.expected, actual)
+			}
+			if pages := octavia.count("GET /lbaas/loadbalancers"); pages != 2+testCase.failures {
+				t.Errorf("expected 2 pages after %d failures, got %d requests", testCase.failures, pages)
+			}
+		})
+	}
+}
@@ -19,8 +19,13 @@ package openstacktasks
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
+	"slices"
 	"time"
 
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/qos/policies"
 	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
 
 	"github.com/gophercloud/gophercloud/v2"
@@ -43,6 +48,23 @@ type LB struct {
 	SecurityGroup *SecurityGroup
 	Provider      *string
 	FlavorID      *string
+	// VipQosPolicyID is the QoS policy applied to the VIP port
+	VipQosPolicyID *string
+	// ProjectID is the project to create the loadbalancer in, if it differs from the project of the credentials.
+	// It is only used when creating the loadbalancer, and is not compared with the existing loadbalancer.
+	ProjectID *string
+	// DeleteUnmanagedListeners deletes listeners that are not managed by kops, instead of only warning about them.
+	// It is not compared with the existing loadbalancer.
+	DeleteUnmanagedListeners *bool
+	// VipNetworkID is the network to allocate the VIP from, letting Octavia pick the subnet.
+	// It cannot be combined with Subnet.
+	VipNetworkID *string
+	// VipAddress is the IP address to request for the VIP, which must be within the CIDR of Subnet.
+	// If unset, Octavia allocates an address from the subnet.
+	VipAddress *string
+	// Tags are set on the loadbalancer, for example to identify the cluster and owner it belongs to.
+	// If unset, the tags of the loadbalancer are left alone.
+	Tags []string
 }
 
 const (
@@ -53,6 +75,8 @@ const (
 	loadbalancerActiveInitDelay = 1 * time.Second
 	loadbalancerActiveFactor    = 1.2
 	loadbalancerActiveSteps     = 22
+	// loadbalancerCreateTimeout is how long to wait for a new loadbalancer to become ACTIVE
+	loadbalancerCreateTimeout = 5 * time.Minute
 
 	activeStatus = "ACTIVE"
 	errorStatus  = "ERROR"
@@ -65,13 +89,15 @@ func waitLoadbalancerActiveProvisioningStatus(client *gophercloud.ServiceClient,
 		Steps:    loadbalancerActiveSteps,
 	}
 
-	var provisioningStatus string
+	var provisioningStatus, operatingStatus string
+	start := time.Now()
 	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
 		loadbalancer, err := loadbalancers.Get(context.TODO(), client, loadbalancerID).Extract()
 		if err != nil {
 			return false, err
 		}
 		provisioningStatus = loadbalancer.ProvisioningStatus
+		operatingStatus = loadbalancer.OperatingStatus
 		if loadbalancer.ProvisioningStatus == activeStatus {
 			return true, nil
 		} else if loadbalancer.ProvisioningStatus == errorStatus {
@@ -82,12 +108,69 @@ func waitLoadbalancerActiveProvisioningStatus(client *gophercloud.ServiceClient,
 		}
 	})
 
-	if err == wait.ErrWaitTimeout {
-		err = fmt.Errorf("loadbalancer failed to go into ACTIVE provisioning status within allotted time")
+	if wait.Interrupted(err) {
+		err = &openstack.LoadBalancerWaitTimeoutError{
+			LoadBalancerID:     loadbalancerID,
+			Elapsed:            time.Since(start),
+			ProvisioningStatus: provisioningStatus,
+			OperatingStatus:    operatingStatus,
+		}
 	}
 	return provisioningStatus, err
 }
 
+// attachVipQosPolicy applies the QoS policy directly to the VIP port, for Octavia versions that don't manage it themselves
+func attachVipQosPolicy(cloud openstack.OpenstackCloud, portID string, qosPolicyID string) error {
+	opts := policies.PortUpdateOptsExt{
+		UpdateOptsBuilder: ports.UpdateOpts{},
+		QoSPolicyID:       &qosPolicyID,
+	}
+	_, err := ports.Update(context.TODO(), cloud.NetworkingClient(), portID, opts).Extract()
+	if err != nil {
+		return fmt.Errorf("Failed to attach QoS policy %s to port %s: %v", qosPolicyID, portID, err)
+	}
+	return nil
+}
+
+// isProjectForbidden returns true if the error is Octavia refusing to create a loadbalancer in another project
+func isProjectForbidden(err error) bool {
+	return gophercloud.ResponseCodeIs(err, http.StatusForbidden)
+}
+
+// getPortQosPolicyID returns the QoS policy attached to the port
+func getPortQosPolicyID(cloud openstack.OpenstackCloud, portID string) (string, error) {
+	var port struct {
+		ports.Port
+		policies.QoSPolicyExt
+	}
+	err := ports.Get(context.TODO(), cloud.NetworkingClient(), portID).ExtractInto(&port)
+	if err != nil {
+		return "", fmt.Errorf("Failed to get port with id %s: %v", portID, err)
+	}
+	return port.QoSPolicyID, nil
+}
+
+// isVipAddressInUse returns true if the error is Octavia refusing a requested VIP address which is already allocated
+func isVipAddressInUse(err error) bool {
+	return gophercloud.ResponseCodeIs(err, http.StatusConflict)
+}
+
+// validateVipAddress checks that the requested VIP address is within the CIDR of the subnet it is allocated from
+func validateVipAddress(vipAddress string, subnet *subnets.Subnet) error {
+	ip := net.ParseIP(vipAddress)
+	if ip == nil {
+		return fmt.Errorf("VIP address %q is not a valid IP address", vipAddress)
+	}
+	_, cidr, err := net.ParseCIDR(subnet.CIDR)
+	if err != nil {
+		return fmt.Errorf("failed to parse CIDR %q of subnet %s: %v", subnet.CIDR, subnet.Name, err)
+	}
+	if !cidr.Contains(ip) {
+		return fmt.Errorf("VIP address %s is not within the CIDR %s of subnet %s", vipAddress, subnet.CIDR, subnet.Name)
+	}
+	return nil
+}
+
 // GetDependencies returns the dependencies of the Instance task
 func (e *LB) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTask {
 	var deps []fi.CloudupTask
@@ -130,6 +213,30 @@ func NewLBTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lifecycle,
 		Provider:  fi.PtrTo(lb.Provider),
 		FlavorID:  fi.PtrTo(lb.FlavorID),
 	}
+	if lb.VipNetworkID != "" {
+		actual.VipNetworkID = fi.PtrTo(lb.VipNetworkID)
+	}
+	if lb.VipAddress != "" {
+		actual.VipAddress = fi.PtrTo(lb.VipAddress)
+		// Octavia may report the address in another notation, for example a canonical IPv6 address
+		if find != nil && find.VipAddress != nil && net.ParseIP(*find.VipAddress).Equal(net.ParseIP(lb.VipAddress)) {
+			actual.VipAddress = find.VipAddress
+		}
+	}
+
+	if find != nil && find.Tags != nil {
+		actual.Tags = normalizeTags(lb.Tags)
+	}
+
+	qosPolicyID := lb.VipQosPolicyID
+	if qosPolicyID == "" && find != nil && find.VipQosPolicyID != nil && lb.VipPortID != "" {
+		// older Octavia doesn't report the policy, so look at the port it was attached to
+		qosPolicyID, err = getPortQosPolicyID(cloud, lb.VipPortID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	actual.VipQosPolicyID = fi.PtrTo(qosPolicyID)
 
 	if secGroup {
 		sg, err := getSecurityGroupByName(&SecurityGroup{Name: fi.PtrTo(lb.Name)}, osCloud)
@@ -139,11 +246,16 @@ func NewLBTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lifecycle,
 		actual.SecurityGroup = sg
 	}
 	if find != nil {
+		// the project cannot be changed, so it is not compared
+		actual.ProjectID = find.ProjectID
+		actual.DeleteUnmanagedListeners = find.DeleteUnmanagedListeners
+
 		find.ID = actual.ID
 		find.PortID = actual.PortID
 		find.VipSubnet = actual.VipSubnet
 		find.Provider = actual.Provider
 		find.FlavorID = actual.FlavorID
+		find.Tags = normalizeTags(find.Tags)
 	}
 	return actual, nil
 }
@@ -155,7 +267,8 @@ func (s *LB) Find(context *fi.CloudupContext) (*LB, error) {
 
 	cloud := context.T.Cloud.(openstack.OpenstackCloud)
 	lbPage, err := loadbalancers.List(cloud.LoadBalancerClient(), loadbalancers.ListOpts{
-		Name: fi.ValueOf(s.Name),
+		Name:      fi.ValueOf(s.Name),
+		ProjectID: fi.ValueOf(s.ProjectID),
 	}).AllPages(context.Context())
 	if err != nil {
 		return nil, fmt.Errorf("Failed to retrieve loadbalancers for name %s: %v", fi.ValueOf(s.Name), err)
@@ -183,6 +296,20 @@ func (_ *LB) CheckChanges(a, e, changes *LB) error {
 		if e.Name == nil {
 			return fi.RequiredField("Name")
 		}
+		if e.VipNetworkID != nil && e.Subnet != nil {
+			return fmt.Errorf("LB %q cannot set both VipNetworkID and Subnet", fi.ValueOf(e.Name))
+		}
+		if e.VipNetworkID == nil && e.Subnet == nil {
+			return fi.RequiredField("Subnet")
+		}
+		if e.VipAddress != nil {
+			if e.Subnet == nil {
+				return fmt.Errorf("LB %q requires Subnet to set VipAddress", fi.ValueOf(e.Name))
+			}
+			if net.ParseIP(*e.VipAddress) == nil {
+				return fmt.Errorf("VipAddress %q of LB %q is not a valid IP address", *e.VipAddress, fi.ValueOf(e.Name))
+			}
+		}
 	} else {
 		if changes.ID != nil {
 			return fi.CannotChangeField("ID")
@@ -190,6 +317,12 @@ func (_ *LB) CheckChanges(a, e, changes *LB) error {
 		if changes.Name != nil {
 			return fi.CannotChangeField("Name")
 		}
+		if changes.VipNetworkID != nil {
+			return fi.CannotChangeField("VipNetworkID")
+		}
+		if changes.VipAddress != nil {
+			return fi.CannotChangeField("VipAddress")
+		}
 	}
 	return nil
 }
@@ -198,24 +331,55 @@ func (_ *LB) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *LB)
 	if a == nil {
 		klog.V(2).Infof("Creating LB with Name: %q", fi.ValueOf(e.Name))
 
-		subnets, err := t.Cloud.ListSubnets(subnets.ListOpts{
-			Name: fi.ValueOf(e.Subnet),
-		})
-		if err != nil {
-			return fmt.Errorf("Failed to retrieve subnet `%s` in loadbalancer creation: %v", fi.ValueOf(e.Subnet), err)
-		}
-		if len(subnets) != 1 {
-			return fmt.Errorf("Unexpected desired subnets for `%s`.  Expected 1, got %d", fi.ValueOf(e.Subnet), len(subnets))
-		}
-
 		lbopts := loadbalancers.CreateOpts{
-			Name:        fi.ValueOf(e.Name),
-			VipSubnetID: subnets[0].ID,
+			Name:      fi.ValueOf(e.Name),
+			ProjectID: fi.ValueOf(e.ProjectID),
+		}
+		if e.VipNetworkID != nil {
+			// Octavia picks the subnet of the network
+			lbopts.VipNetworkID = fi.ValueOf(e.VipNetworkID)
+		} else {
+			subnets, err := t.Cloud.ListSubnets(subnets.ListOpts{
+				Name: fi.ValueOf(e.Subnet),
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to retrieve subnet `%s` in loadbalancer creation: %v", fi.ValueOf(e.Subnet), err)
+			}
+			if len(subnets) != 1 {
+				return fmt.Errorf("Unexpected desired subnets for `%s`.  Expected 1, got %d", fi.ValueOf(e.Subnet), len(subnets))
+			}
+			lbopts.VipSubnetID = subnets[0].ID
+			if e.VipAddress != nil {
+				if err := validateVipAddress(*e.VipAddress, &subnets[0]); err != nil {
+					return err
+				}
+				lbopts.VipAddress = *e.VipAddress
+			}
 		}
 		if e.FlavorID != nil {
 			lbopts.FlavorID = fi.ValueOf(e.FlavorID)
 		}
-		lb, err := t.Cloud.CreateLB(lbopts)
+		attachQosPolicy := false
+		if e.VipQosPolicyID != nil {
+			useVIPQosPolicy, err := t.Cloud.UseLoadBalancerVIPQosPolicy()
+			if err != nil {
+				return fmt.Errorf("error checking the loadbalancer API version: %v", err)
+			}
+			if useVIPQosPolicy {
+				lbopts.VipQosPolicyID = fi.ValueOf(e.VipQosPolicyID)
+			} else {
+				klog.V(2).Infof("Octavia may not support vip_qos_policy_id, attaching QoS policy to the VIP port after creation")
+				attachQosPolicy = true
+			}
+		}
+		lbopts.Tags = e.Tags
+		lb, err := t.Cloud.CreateLBAndWait(lbopts, loadbalancerCreateTimeout)
+		if err != nil && lbopts.VipAddress != "" && isVipAddressInUse(err) {
+			return fmt.Errorf("error creating LB: VIP address %s is already in use in subnet %s: %v", lbopts.VipAddress, fi.ValueOf(e.Subnet), err)
+		}
+		if err != nil && lbopts.ProjectID != "" && isProjectForbidden(err) {
+			return fmt.Errorf("error creating LB in project %s: creating loadbalancers in another project requires admin rights: %v", lbopts.ProjectID, err)
+		}
 		if err != nil {
 			return fmt.Errorf("error creating LB: %v", err)
 		}
@@ -225,36 +389,146 @@ func (_ *LB) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *LB)
 		e.Provider = fi.PtrTo(lb.Provider)
 		e.FlavorID = fi.PtrTo(lb.FlavorID)
 
-		if e.SecurityGroup != nil {
-			opts := ports.UpdateOpts{
-				SecurityGroups: &[]string{fi.ValueOf(e.SecurityGroup.ID)},
+		if attachQosPolicy {
+			if err := attachVipQosPolicy(t.Cloud, lb.VipPortID, fi.ValueOf(e.VipQosPolicyID)); err != nil {
+				return err
 			}
-			_, err = ports.Update(context.TODO(), t.Cloud.NetworkingClient(), lb.VipPortID, opts).Extract()
-			if err != nil {
-				return fmt.Errorf("Failed to update security group for port %s: %v", lb.VipPortID, err)
+		}
+
+		if e.SecurityGroup != nil {
+			if err := t.Cloud.EnsureLBVIPSecurityGroups(lb.ID, []string{fi.ValueOf(e.SecurityGroup.ID)}); err != nil {
+				return err
 			}
 		}
 		return nil
 	}
-	// We may have failed to update the security groups on the load balancer
-	port, err := t.Cloud.GetPort(fi.ValueOf(a.PortID))
-	if err != nil {
-		return fmt.Errorf("Failed to get port with id %s: %v", fi.ValueOf(a.PortID), err)
+
+	if changes.VipQosPolicyID != nil {
+		klog.V(2).Infof("Updating QoS policy of LB %q", fi.ValueOf(a.Name))
+		useVIPQosPolicy, err := t.Cloud.UseLoadBalancerVIPQosPolicy()
+		if err != nil {
+			return fmt.Errorf("error checking the loadbalancer API version: %v", err)
+		}
+		if !useVIPQosPolicy {
+			if err := attachVipQosPolicy(t.Cloud, fi.ValueOf(a.PortID), fi.ValueOf(e.VipQosPolicyID)); err != nil {
+				return err
+			}
+		} else {
+			_, err := t.Cloud.UpdateLB(fi.ValueOf(a.ID), loadbalancers.UpdateOpts{
+				VipQosPolicyID: e.VipQosPolicyID,
+			})
+			if err != nil {
+				return fmt.Errorf("error updating LB: %v", err)
+			}
+			provisioningStatus, err := waitLoadbalancerActiveProvisioningStatus(t.Cloud.LoadBalancerClient(), fi.ValueOf(a.ID))
+			if err != nil {
+				return fmt.Errorf("error waiting for LB to be ACTIVE (%s): %v", provisioningStatus, err)
+			}
+		}
 	}
-	// Ensure the loadbalancer port has one security group and it is the one specified,
-	if e.SecurityGroup != nil &&
-		(len(port.SecurityGroups) < 1 || port.SecurityGroups[0] != fi.ValueOf(e.SecurityGroup.ID)) {
 
-		opts := ports.UpdateOpts{
-			SecurityGroups: &[]string{fi.ValueOf(e.SecurityGroup.ID)},
+	if changes.Tags != nil {
+		// the tags of the loadbalancer are replaced as a whole
+		tags := normalizeTags(e.Tags)
+		if tags == nil {
+			tags = []string{}
 		}
-		_, err = ports.Update(context.TODO(), t.Cloud.NetworkingClient(), fi.ValueOf(a.PortID), opts).Extract()
+		klog.V(2).Infof("Updating tags of LB %q to %v", fi.ValueOf(a.Name), tags)
+		if _, err := t.Cloud.UpdateLB(fi.ValueOf(a.ID), loadbalancers.UpdateOpts{Tags: &tags}); err != nil {
+			return fmt.Errorf("error updating LB tags: %v", err)
+		}
+		provisioningStatus, err := waitLoadbalancerActiveProvisioningStatus(t.Cloud.LoadBalancerClient(), fi.ValueOf(a.ID))
 		if err != nil {
-			return fmt.Errorf("Failed to update security group for port %s: %v", fi.ValueOf(a.PortID), err)
+			return fmt.Errorf("error waiting for LB to be ACTIVE (%s): %v", provisioningStatus, err)
 		}
-		return nil
+	}
+
+	// We may have failed to update the security groups on the load balancer, or they may have been changed since.
+	// Ensure the loadbalancer port has one security group and it is the one specified.
+	if e.SecurityGroup != nil {
+		return t.Cloud.EnsureLBVIPSecurityGroups(fi.ValueOf(a.ID), []string{fi.ValueOf(e.SecurityGroup.ID)})
 	}
 
 	klog.V(2).Infof("Openstack task LB::RenderOpenstack did nothing")
 	return nil
 }
+
+var _ fi.CloudupProducesDeletions = &LB{}
+
+// FindDeletions looks for listeners on the loadbalancer that are not managed by kops, for example ones added manually.
+// They are only reported, unless DeleteUnmanagedListeners is set. Listeners tagged with openstack.TagKopsIgnore are left alone.
+func (e *LB) FindDeletions(c *fi.CloudupContext) ([]fi.CloudupDeletion, error) {
+	if e.ID == nil {
+		return nil, nil
+	}
+
+	managed := make(map[string]bool)
+	for _, task := range c.AllTasks() {
+		if listener, ok := task.(*LBListener); ok && listener.Pool != nil && listener.Pool.Loadbalancer == e {
+			managed[fi.ValueOf(listener.Name)] = true
+		}
+	}
+
+	cloud := c.T.Cloud.(openstack.OpenstackCloud)
+	listenerList, err := cloud.ListListeners(listeners.ListOpts{
+		LoadbalancerID: fi.ValueOf(e.ID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing listeners of loadbalancer %s: %v", fi.ValueOf(e.ID), err)
+	}
+
+	var removals []fi.CloudupDeletion
+	for i := range listenerList {
+		listener := &listenerList[i]
+		if managed[listener.Name] || slices.Contains(listener.Tags, openstack.TagKopsIgnore) {
+			continue
+		}
+		if !fi.ValueOf(e.DeleteUnmanagedListeners) {
+			klog.Warningf("loadbalancer %q has listener %q (%s) on port %d that is not managed by kops; tag it %s to silence this warning",
+				fi.ValueOf(e.Name), listener.Name, listener.ID, listener.ProtocolPort, openstack.TagKopsIgnore)
+			continue
+		}
+		removals = append(removals, &deleteLBListener{listener: listener})
+	}
+	return removals, nil
+}
+
+// deleteLBListener tracks a listener that is not managed by kops, and should be deleted.
+// It implements fi.CloudupDeletion
+type deleteLBListener struct {
+	listener *listeners.Listener
+}
+
+var _ fi.CloudupDeletion = &deleteLBListener{}
+
+// TaskName returns the task name
+func (d *deleteLBListener) TaskName() string {
+	return "LBListener"
+}
+
+// Item returns the listener name and ID
+func (d *deleteLBListener) Item() string {
+	return fmt.Sprintf("%s (%s)", d.listener.Name, d.listener.ID)
+}
+
+func (d *deleteLBListener) Delete(t fi.CloudupTarget) error {
+	openstackTarget, ok := t.(*openstack.OpenstackAPITarget)
+	if !ok {
+		return fmt.Errorf("unexpected target type for deletion: %T", t)
+	}
+
+	klog.V(2).Infof("Deleting listener %s not managed by kops", d.Item())
+	if err := openstackTarget.Cloud.DeleteListener(d.listener.ID); err != nil {
+		return fmt.Errorf("error deleting listener %s: %v", d.Item(), err)
+	}
+	return nil
+}
+
+// String returns a string representation of the task
+func (d *deleteLBListener) String() string {
+	return d.TaskName() + "-" + d.Item()
+}
+
+func (d *deleteLBListener) DeferDeletion() bool {
+	return false
+}
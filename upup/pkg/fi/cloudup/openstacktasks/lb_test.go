@@ -0,0 +1,394 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstacktasks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func Test_LB_VipQosPolicy(t *testing.T) {
+	for _, unsupported := range []bool{false, true} {
+		t.Run(fmt.Sprintf("unsupported=%v", unsupported), func(t *testing.T) {
+			cloud := openstack.BuildMockOpenstackCloud("us-test1")
+			cloud.MockNeutronClient = mocknetworking.CreateClient()
+			cloud.MockLBClient = mockloadbalancer.CreateClient()
+			cloud.MockLBClient.VipPorts = cloud.MockNeutronClient
+			// an older Octavia rejects the field with 400 Bad Request
+			cloud.MockLBClient.VipQosPolicyUnsupported = unsupported
+			cloud.LBVIPQosPolicyUnsupported = unsupported
+
+			network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+			if err != nil {
+				t.Fatalf("error creating network: %v", err)
+			}
+			if _, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)}); err != nil {
+				t.Fatalf("error creating subnet: %v", err)
+			}
+			target := openstack.NewOpenstackAPITarget(cloud)
+
+			e := &LB{
+				Name:           fi.PtrTo("api"),
+				Subnet:         fi.PtrTo("cluster"),
+				VipQosPolicyID: fi.PtrTo("qos-1"),
+				Lifecycle:      fi.LifecycleSync,
+			}
+			if err := (&LB{}).RenderOpenstack(target, nil, e, e); err != nil {
+				t.Fatalf("error creating loadbalancer: %v", err)
+			}
+
+			lb, err := cloud.GetLB(fi.ValueOf(e.ID))
+			if err != nil {
+				t.Fatalf("error getting loadbalancer: %v", err)
+			}
+			portQosPolicyID, err := getPortQosPolicyID(cloud, lb.VipPortID)
+			if err != nil {
+				t.Fatalf("error getting the QoS policy of the VIP port: %v", err)
+			}
+			if unsupported {
+				if lb.VipQosPolicyID != "" || portQosPolicyID != "qos-1" {
+					t.Errorf("expected the QoS policy on the VIP port only, got %q on the loadbalancer and %q on the port", lb.VipQosPolicyID, portQosPolicyID)
+				}
+			} else {
+				if lb.VipQosPolicyID != "qos-1" || portQosPolicyID != "" {
+					t.Errorf("expected the QoS policy on the loadbalancer only, got %q on the loadbalancer and %q on the port", lb.VipQosPolicyID, portQosPolicyID)
+				}
+			}
+
+			a, err := NewLBTaskFromCloud(cloud, fi.LifecycleSync, lb, e)
+			if err != nil {
+				t.Fatalf("error building actual loadbalancer: %v", err)
+			}
+			changes := &LB{}
+			if fi.BuildChanges(a, e, changes) {
+				t.Errorf("expected no changes after creation, got %+v", changes)
+			}
+
+			// the policy is changed where it was set
+			e.VipQosPolicyID = fi.PtrTo("qos-2")
+			changes = &LB{}
+			if !fi.BuildChanges(a, e, changes) || changes.VipQosPolicyID == nil {
+				t.Fatalf("expected the QoS policy to change, got %+v", changes)
+			}
+			if err := (&LB{}).RenderOpenstack(target, a, e, changes); err != nil {
+				t.Fatalf("error updating loadbalancer: %v", err)
+			}
+			lb, err = cloud.GetLB(fi.ValueOf(e.ID))
+			if err != nil {
+				t.Fatalf("error getting loadbalancer: %v", err)
+			}
+			a, err = NewLBTaskFromCloud(cloud, fi.LifecycleSync, lb, e)
+			if err != nil {
+				t.Fatalf("error building actual loadbalancer: %v", err)
+			}
+			if fi.ValueOf(a.VipQosPolicyID) != "qos-2" {
+				t.Errorf("expected QoS policy qos-2, got %q", fi.ValueOf(a.VipQosPolicyID))
+			}
+		})
+	}
+}
+
+func Test_LB_ProjectIDIsNotCompared(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+
+	e := &LB{
+		Name:      fi.PtrTo("api"),
+		Subnet:    fi.PtrTo("cluster"),
+		ProjectID: fi.PtrTo("tenant"),
+	}
+	a, err := NewLBTaskFromCloud(cloud, fi.LifecycleSync, lb, e)
+	if err != nil {
+		t.Fatalf("error building actual loadbalancer: %v", err)
+	}
+	if fi.ValueOf(a.ProjectID) != "tenant" {
+		t.Errorf("expected the desired ProjectID to be kept, got %q", fi.ValueOf(a.ProjectID))
+	}
+}
+
+func Test_IsProjectForbidden(t *testing.T) {
+	forbidden := fmt.Errorf("error creating loadbalancer: %w", gophercloud.ErrUnexpectedResponseCode{
+		Actual: http.StatusForbidden,
+		Body:   []byte(`{"faultstring": "Policy does not allow this request to be performed."}`),
+	})
+	if !isProjectForbidden(forbidden) {
+		t.Errorf("expected 403 to be recognized")
+	}
+	if isProjectForbidden(gophercloud.ErrUnexpectedResponseCode{Actual: http.StatusBadRequest}) {
+		t.Errorf("expected 400 not to be recognized")
+	}
+}
+
+func Test_LB_FindDeletions_UnmanagedListeners(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	other, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "other"})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	for _, opts := range []listeners.CreateOpts{
+		{Name: "api-listener", LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 443},
+		{Name: "manual", LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 8443},
+		{Name: "ignored", LoadbalancerID: lb.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 9443, Tags: []string{openstack.TagKopsIgnore}},
+		{Name: "elsewhere", LoadbalancerID: other.ID, Protocol: listeners.ProtocolTCP, ProtocolPort: 443},
+	} {
+		if _, err := cloud.CreateListener(opts); err != nil {
+			t.Fatalf("error creating listener %s: %v", opts.Name, err)
+		}
+	}
+
+	findDeletions := func(e *LB) []fi.CloudupDeletion {
+		pool := &LBPool{Name: fi.PtrTo("api-pool"), Loadbalancer: e}
+		allTasks := map[string]fi.CloudupTask{
+			"LB/api":                  e,
+			"LBPool/api-pool":         pool,
+			"LBListener/api-listener": &LBListener{Name: fi.PtrTo("api-listener"), Pool: pool},
+		}
+		c, err := fi.NewCloudupContext(context.TODO(), fi.DeletionProcessingModeDeleteIncludingDeferred, openstack.NewOpenstackAPITarget(cloud), nil, cloud, nil, nil, nil, allTasks)
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		deletions, err := e.FindDeletions(c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return deletions
+	}
+
+	t.Run("warns by default", func(t *testing.T) {
+		deletions := findDeletions(&LB{ID: fi.PtrTo(lb.ID), Name: fi.PtrTo("api")})
+		if len(deletions) != 0 {
+			t.Errorf("expected no deletions, got %v", deletions)
+		}
+	})
+
+	t.Run("deletes unmanaged listeners when asked to", func(t *testing.T) {
+		deletions := findDeletions(&LB{ID: fi.PtrTo(lb.ID), Name: fi.PtrTo("api"), DeleteUnmanagedListeners: fi.PtrTo(true)})
+		if len(deletions) != 1 {
+			t.Fatalf("expected one deletion, got %v", deletions)
+		}
+		deletion := deletions[0].(*deleteLBListener)
+		if deletion.listener.Name != "manual" {
+			t.Fatalf("expected listener manual to be deleted, got %s", deletion.listener.Name)
+		}
+		if err := deletion.Delete(openstack.NewOpenstackAPITarget(cloud)); err != nil {
+			t.Fatalf("error deleting listener: %v", err)
+		}
+
+		remaining, err := cloud.ListListeners(listeners.ListOpts{LoadbalancerID: lb.ID})
+		if err != nil {
+			t.Fatalf("error listing listeners: %v", err)
+		}
+		var names []string
+		for _, listener := range remaining {
+			names = append(names, listener.Name)
+		}
+		slices.Sort(names)
+		if expected := []string{"api-listener", "ignored"}; !slices.Equal(names, expected) {
+			t.Errorf("expected listeners %v, got %v", expected, names)
+		}
+	})
+}
+
+func Test_LB_VipNetworkID(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	// the subnet is picked by Octavia
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipNetworkID: network.ID, VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+
+	e := &LB{
+		Name:         fi.PtrTo("api"),
+		VipNetworkID: fi.PtrTo(network.ID),
+		Lifecycle:    fi.LifecycleSync,
+	}
+	a, err := NewLBTaskFromCloud(cloud, fi.LifecycleSync, lb, e)
+	if err != nil {
+		t.Fatalf("error building actual loadbalancer: %v", err)
+	}
+	changes := &LB{}
+	if fi.BuildChanges(a, e, changes) {
+		t.Errorf("expected no changes when only the network is specified, got %+v", changes)
+	}
+	if err := (&LB{}).CheckChanges(a, e, changes); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := &LB{Name: fi.PtrTo("api"), VipNetworkID: fi.PtrTo(network.ID), Subnet: fi.PtrTo("cluster")}
+	if err := (&LB{}).CheckChanges(nil, invalid, invalid); err == nil {
+		t.Errorf("expected error combining VipNetworkID and Subnet")
+	}
+}
+
+func Test_ValidateVipAddress(t *testing.T) {
+	tests := []struct {
+		desc        string
+		vipAddress  string
+		cidr        string
+		expectedErr string
+	}{
+		{
+			desc:       "address in subnet",
+			vipAddress: "192.168.0.10",
+			cidr:       "192.168.0.0/24",
+		},
+		{
+			desc:       "IPv6 address in subnet",
+			vipAddress: "2001:db8::10",
+			cidr:       "2001:db8::/64",
+		},
+		{
+			desc:        "address outside subnet",
+			vipAddress:  "192.168.1.10",
+			cidr:        "192.168.0.0/24",
+			expectedErr: "VIP address 192.168.1.10 is not within the CIDR 192.168.0.0/24 of subnet cluster",
+		},
+		{
+			desc:        "invalid address",
+			vipAddress:  "192.168.0",
+			cidr:        "192.168.0.0/24",
+			expectedErr: `VIP address "192.168.0" is not a valid IP address`,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			err := validateVipAddress(testCase.vipAddress, &subnets.Subnet{Name: "cluster", CIDR: testCase.cidr})
+			if testCase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != testCase.expectedErr {
+				t.Errorf("expected error %q, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func Test_LB_VipAddress(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	if _, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)}); err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	target := openstack.NewOpenstackAPITarget(cloud)
+
+	e := &LB{
+		Name:       fi.PtrTo("api"),
+		Subnet:     fi.PtrTo("cluster"),
+		VipAddress: fi.PtrTo("192.168.0.10"),
+		Lifecycle:  fi.LifecycleSync,
+	}
+	if err := (&LB{}).CheckChanges(nil, e, e); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := (&LB{}).RenderOpenstack(target, nil, e, e); err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+
+	lb, err := cloud.GetLB(fi.ValueOf(e.ID))
+	if err != nil {
+		t.Fatalf("error getting loadbalancer: %v", err)
+	}
+	if lb.VipAddress != "192.168.0.10" {
+		t.Errorf("expected VIP address 192.168.0.10, got %q", lb.VipAddress)
+	}
+
+	a, err := NewLBTaskFromCloud(cloud, fi.LifecycleSync, lb, e)
+	if err != nil {
+		t.Fatalf("error building actual loadbalancer: %v", err)
+	}
+	changes := &LB{}
+	if fi.BuildChanges(a, e, changes) {
+		t.Errorf("expected no changes when the VIP address was assigned as requested, got %+v", changes)
+	}
+
+	t.Run("address in use", func(t *testing.T) {
+		other := &LB{Name: fi.PtrTo("other"), Subnet: fi.PtrTo("cluster"), VipAddress: fi.PtrTo("192.168.0.10")}
+		err := (&LB{}).RenderOpenstack(target, nil, other, other)
+		if err == nil || !strings.Contains(err.Error(), "VIP address 192.168.0.10 is already in use in subnet cluster") {
+			t.Errorf("expected error about the address in use, got %v", err)
+		}
+	})
+
+	t.Run("address outside subnet", func(t *testing.T) {
+		other := &LB{Name: fi.PtrTo("other"), Subnet: fi.PtrTo("cluster"), VipAddress: fi.PtrTo("10.0.0.10")}
+		err := (&LB{}).RenderOpenstack(target, nil, other, other)
+		if err == nil || !strings.Contains(err.Error(), "is not within the CIDR") {
+			t.Errorf("expected error about the CIDR, got %v", err)
+		}
+	})
+
+	t.Run("without subnet", func(t *testing.T) {
+		other := &LB{Name: fi.PtrTo("other"), VipNetworkID: fi.PtrTo(network.ID), VipAddress: fi.PtrTo("192.168.0.11")}
+		if err := (&LB{}).CheckChanges(nil, other, other); err == nil {
+			t.Errorf("expected error setting VipAddress without Subnet")
+		}
+	})
+}
@@ -17,11 +17,12 @@ limitations under the License.
 package openstacktasks
 
 import (
-	"context"
 	"fmt"
+	"slices"
 	"sort"
 
 	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
 	"k8s.io/klog/v2"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
@@ -35,9 +36,34 @@ type LBListener struct {
 	Pool         *LBPool
 	Lifecycle    fi.Lifecycle
 	AllowedCIDRs []string
+	// Tags are set on the listener so that it can be identified without walking from the loadbalancer.
+	Tags []string
+	// Protocol is the protocol of the listener, defaulting to TCP
+	Protocol *string
+	// DefaultTLSContainerRef is the key manager reference of the certificate served by TERMINATED_HTTPS listeners
+	DefaultTLSContainerRef *string
+	// ClientAuthentication is the TLS client authentication mode (NONE, OPTIONAL or MANDATORY) of TERMINATED_HTTPS listeners
+	ClientAuthentication *string
+	// ClientCATLSContainerRef is the key manager reference of the CA certificates that client certificates are verified against
+	ClientCATLSContainerRef *string
+	// TLSCiphers, TLSVersions and AllowDeprecatedTLSVersions only apply to TERMINATED_HTTPS listeners, so they are not set
+	// from the cluster spec: the API listener passes TLS through to kube-apiserver over TCP.
+	//
+	// TLSCiphers is the OpenSSL cipher string of TERMINATED_HTTPS listeners; Octavia applies its default when it is not set
+	TLSCiphers *string
+	// TLSVersions are the TLS versions accepted by TERMINATED_HTTPS listeners, such as TLSv1.2 and TLSv1.3.
+	// Octavia applies its default versions when they are not set, and they are then not compared.
+	TLSVersions []string
+	// AllowDeprecatedTLSVersions allows TLSVersions to include SSLv3, TLSv1 and TLSv1.1. It is not compared with the existing listener.
+	AllowDeprecatedTLSVersions *bool
+	// ForceRecreate deletes and recreates the listener when its protocol changes, which Octavia cannot update.
+	// Traffic through the listener is interrupted until it is recreated. It is not compared with the existing listener.
+	ForceRecreate *bool
 }
 
-// GetDependencies returns the dependencies of the Instance task
+// GetDependencies returns the dependencies of the Instance task.
+// The pool is created before the listener, so that the listener can be created with its default pool
+// in a single call and never exists without one.
 func (e *LBListener) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTask {
 	var deps []fi.CloudupTask
 	for _, task := range tasks {
@@ -65,8 +91,28 @@ func NewLBListenerTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lif
 		Name:         fi.PtrTo(listener.Name),
 		Port:         fi.PtrTo(listener.ProtocolPort),
 		AllowedCIDRs: listener.AllowedCIDRs,
+		Tags:         normalizeTags(listener.Tags),
 		Lifecycle:    lifecycle,
 	}
+	if listener.Protocol != "" {
+		listenerTask.Protocol = fi.PtrTo(listener.Protocol)
+	}
+	if listener.DefaultTlsContainerRef != "" {
+		listenerTask.DefaultTLSContainerRef = fi.PtrTo(listener.DefaultTlsContainerRef)
+	}
+	if listener.ClientAuthentication != "" {
+		listenerTask.ClientAuthentication = fi.PtrTo(listener.ClientAuthentication)
+	}
+	if listener.ClientCATLSContainerRef != "" {
+		listenerTask.ClientCATLSContainerRef = fi.PtrTo(listener.ClientCATLSContainerRef)
+	}
+	if listener.TLSCiphers != "" {
+		listenerTask.TLSCiphers = fi.PtrTo(listener.TLSCiphers)
+	}
+	// the default versions Octavia applies are only compared when versions are set
+	if find == nil || find.TLSVersions != nil {
+		listenerTask.TLSVersions = normalizeTLSVersions(listener.TLSVersions)
+	}
 
 	if len(listener.Pools) > 0 {
 		for _, pool := range listener.Pools {
@@ -79,7 +125,7 @@ func NewLBListenerTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lif
 				break
 			}
 		}
-	} else {
+	} else if listener.DefaultPoolID != "" {
 		pool, err := cloud.GetPool(listener.DefaultPoolID)
 		if err != nil {
 			return nil, fmt.Errorf("Fail to get pool with ID: %s: %v", listener.DefaultPoolID, err)
@@ -91,14 +137,100 @@ func NewLBListenerTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lif
 		listenerTask.Pool = poolTask
 	}
 	if find != nil {
+		listenerTask.ForceRecreate = find.ForceRecreate
+		listenerTask.AllowDeprecatedTLSVersions = find.AllowDeprecatedTLSVersions
+
 		// Update all search terms
 		find.ID = listenerTask.ID
 		find.Name = listenerTask.Name
-		find.Pool = listenerTask.Pool
+		find.Tags = normalizeTags(find.Tags)
+		find.TLSVersions = normalizeTLSVersions(find.TLSVersions)
+		// keep the desired pool if the listener lost its default pool, so that it gets linked again
+		if listenerTask.Pool != nil {
+			find.Pool = listenerTask.Pool
+		}
 	}
 	return listenerTask, nil
 }
 
+// normalizeTags returns the tags sorted and without duplicates, for consistent comparison.
+func normalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !fi.ArrayContains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// normalizeTLSVersions returns the TLS versions sorted, for consistent comparison.
+func normalizeTLSVersions(versions []string) []string {
+	if versions == nil {
+		return nil
+	}
+	normalized := append([]string{}, versions...)
+	sort.Strings(normalized)
+	return normalized
+}
+
+// deprecatedTLSVersions are the TLS versions Octavia accepts, but which are only used with AllowDeprecatedTLSVersions
+var deprecatedTLSVersions = []listeners.TLSVersion{listeners.TLSVersionSSLv3, listeners.TLSVersionTLSv1, listeners.TLSVersionTLSv1_1}
+
+// supportedTLSVersions are the TLS versions listeners may use
+var supportedTLSVersions = []listeners.TLSVersion{listeners.TLSVersionTLSv1_2, listeners.TLSVersionTLSv1_3}
+
+// validateTLSVersions checks that the TLS versions of the listener are known, and not deprecated unless allowDeprecated is set.
+func validateTLSVersions(name string, versions []string, allowDeprecated bool) error {
+	if versions != nil && len(versions) == 0 {
+		return fmt.Errorf("TLSVersions of listener %q cannot be empty", name)
+	}
+	seen := make(map[string]bool)
+	for _, version := range versions {
+		if seen[version] {
+			return fmt.Errorf("TLS version %s of listener %q is listed more than once", version, name)
+		}
+		seen[version] = true
+
+		switch {
+		case slices.Contains(supportedTLSVersions, listeners.TLSVersion(version)):
+		case slices.Contains(deprecatedTLSVersions, listeners.TLSVersion(version)):
+			if !allowDeprecated {
+				return fmt.Errorf("TLS version %s of listener %q is deprecated; set AllowDeprecatedTLSVersions to use it", version, name)
+			}
+		default:
+			return fmt.Errorf("unknown TLS version %q for listener %q, must be one of %s or %s", version, name,
+				listeners.TLSVersionTLSv1_2, listeners.TLSVersionTLSv1_3)
+		}
+	}
+	return nil
+}
+
+// checkTLSPolicySupported returns an error if the listener has TLS versions or ciphers, which the loadbalancer API does not support
+func checkTLSPolicySupported(t *openstack.OpenstackAPITarget, e *LBListener) error {
+	supported, err := t.Cloud.UseLoadBalancerTLSPolicy()
+	if err != nil {
+		return fmt.Errorf("error checking support for the TLS versions and ciphers of LB listener %q: %v", fi.ValueOf(e.Name), err)
+	}
+	if !supported {
+		return fmt.Errorf("TLSCiphers and TLSVersions of LB listener %q require Octavia API version 2.17 or later", fi.ValueOf(e.Name))
+	}
+	return nil
+}
+
+// tlsVersions converts the TLS versions of the listener for the listeners API
+func tlsVersions(versions []string) []listeners.TLSVersion {
+	converted := make([]listeners.TLSVersion, 0, len(versions))
+	for _, version := range versions {
+		converted = append(converted, listeners.TLSVersion(version))
+	}
+	return converted
+}
+
 func (s *LBListener) Find(context *fi.CloudupContext) (*LBListener, error) {
 	if s.Name == nil {
 		return nil, nil
@@ -138,10 +270,109 @@ func (_ *LBListener) CheckChanges(a, e, changes *LBListener) error {
 		if changes.Name != nil {
 			return fi.CannotChangeField("Name")
 		}
+		if changes.Protocol != nil && !fi.ValueOf(e.ForceRecreate) {
+			return fmt.Errorf("cannot change the protocol of LB listener %q from %s to %s, because Octavia requires the listener to be recreated; set ForceRecreate to delete and recreate it, which briefly interrupts traffic through it",
+				fi.ValueOf(e.Name), listenerProtocol(a), listenerProtocol(e))
+		}
+	}
+	protocol := listenerProtocol(e)
+	if protocol == listeners.ProtocolTerminatedHTTPS {
+		if e.DefaultTLSContainerRef == nil {
+			return fmt.Errorf("DefaultTLSContainerRef is required for %s listener %q", protocol, fi.ValueOf(e.Name))
+		}
+		if e.Pool != nil && poolProtocol(e.Pool) != v2pools.ProtocolHTTP {
+			return fmt.Errorf("%s listener %q requires an HTTP pool, not %s", protocol, fi.ValueOf(e.Name), poolProtocol(e.Pool))
+		}
+	} else if e.DefaultTLSContainerRef != nil {
+		return fmt.Errorf("DefaultTLSContainerRef can only be set on %s listeners", listeners.ProtocolTerminatedHTTPS)
+	}
+	if e.ClientAuthentication != nil || e.ClientCATLSContainerRef != nil {
+		if protocol != listeners.ProtocolTerminatedHTTPS {
+			return fmt.Errorf("ClientAuthentication and ClientCATLSContainerRef can only be set on %s listeners", listeners.ProtocolTerminatedHTTPS)
+		}
+		switch clientAuthentication := listeners.ClientAuthentication(fi.ValueOf(e.ClientAuthentication)); clientAuthentication {
+		case "", listeners.ClientAuthenticationNone, listeners.ClientAuthenticationOptional:
+		case listeners.ClientAuthenticationMandatory:
+			if e.ClientCATLSContainerRef == nil {
+				return fmt.Errorf("ClientCATLSContainerRef is required for listener %q with %s client authentication", fi.ValueOf(e.Name), clientAuthentication)
+			}
+		default:
+			return fmt.Errorf("unknown ClientAuthentication %q for listener %q, must be one of %s, %s or %s", clientAuthentication, fi.ValueOf(e.Name),
+				listeners.ClientAuthenticationNone, listeners.ClientAuthenticationOptional, listeners.ClientAuthenticationMandatory)
+		}
+	}
+	if e.TLSCiphers != nil || e.TLSVersions != nil {
+		if protocol != listeners.ProtocolTerminatedHTTPS {
+			return fmt.Errorf("TLSCiphers and TLSVersions can only be set on %s listeners", listeners.ProtocolTerminatedHTTPS)
+		}
+		if e.TLSCiphers != nil && *e.TLSCiphers == "" {
+			return fmt.Errorf("TLSCiphers of listener %q cannot be empty", fi.ValueOf(e.Name))
+		}
+		if err := validateTLSVersions(fi.ValueOf(e.Name), e.TLSVersions, fi.ValueOf(e.AllowDeprecatedTLSVersions)); err != nil {
+			return err
+		}
+	}
+	if e.Pool != nil && fi.ValueOf(e.Pool.TLSEnabled) && protocol != listeners.ProtocolTerminatedHTTPS {
+		return fmt.Errorf("pool %q of listener %q re-encrypts to its members with TLSEnabled, which requires a %s listener", fi.ValueOf(e.Pool.Name), fi.ValueOf(e.Name), listeners.ProtocolTerminatedHTTPS)
 	}
 	return nil
 }
 
+// createLBListener creates the listener together with the link to its default pool
+func createLBListener(t *openstack.OpenstackAPITarget, e *LBListener, useVIPACL bool) error {
+	if e.Pool == nil || e.Pool.ID == nil {
+		return fmt.Errorf("default pool for LB listener %q has not been created", fi.ValueOf(e.Name))
+	}
+
+	// creating the pool, or deleting a listener, puts the loadbalancer into PENDING_UPDATE
+	provisioningStatus, err := waitLoadbalancerActiveProvisioningStatus(t.Cloud.LoadBalancerClient(), fi.ValueOf(e.Pool.Loadbalancer.ID))
+	if err != nil {
+		return fmt.Errorf("failed to loadbalancer ACTIVE provisioning status %v: %v", provisioningStatus, err)
+	}
+
+	klog.V(2).Infof("Creating LB with Name: %q", fi.ValueOf(e.Name))
+	listeneropts := listeners.CreateOpts{
+		Name:           fi.ValueOf(e.Name),
+		DefaultPoolID:  fi.ValueOf(e.Pool.ID),
+		LoadbalancerID: fi.ValueOf(e.Pool.Loadbalancer.ID),
+		Protocol:       listenerProtocol(e),
+		ProtocolPort:   fi.ValueOf(e.Port),
+		Tags:           normalizeTags(e.Tags),
+
+		DefaultTlsContainerRef:  fi.ValueOf(e.DefaultTLSContainerRef),
+		ClientAuthentication:    listeners.ClientAuthentication(fi.ValueOf(e.ClientAuthentication)),
+		ClientCATLSContainerRef: fi.ValueOf(e.ClientCATLSContainerRef),
+	}
+	if e.TLSCiphers != nil || e.TLSVersions != nil {
+		if err := checkTLSPolicySupported(t, e); err != nil {
+			return err
+		}
+		listeneropts.TLSCiphers = fi.ValueOf(e.TLSCiphers)
+		if e.TLSVersions != nil {
+			listeneropts.TLSVersions = tlsVersions(e.TLSVersions)
+		}
+	}
+
+	if useVIPACL && (fi.ValueOf(e.Pool.Loadbalancer.Provider) != "ovn") {
+		listeneropts.AllowedCIDRs = e.AllowedCIDRs
+	}
+
+	listener, err := t.Cloud.CreateListener(listeneropts)
+	if err != nil {
+		return fmt.Errorf("error creating LB listener: %v", err)
+	}
+	e.ID = fi.PtrTo(listener.ID)
+	return nil
+}
+
+// listenerProtocol returns the protocol of the listener, defaulting to TCP.
+func listenerProtocol(listener *LBListener) listeners.Protocol {
+	if listener.Protocol != nil {
+		return listeners.Protocol(*listener.Protocol)
+	}
+	return listeners.ProtocolTCP
+}
+
 func (_ *LBListener) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *LBListener) error {
 	useVIPACL, err := t.Cloud.UseLoadBalancerVIPACL()
 	if err != nil {
@@ -149,39 +380,108 @@ func (_ *LBListener) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, chan
 	}
 
 	if a == nil {
-		klog.V(2).Infof("Creating LB with Name: %q", fi.ValueOf(e.Name))
-		listeneropts := listeners.CreateOpts{
-			Name:           fi.ValueOf(e.Name),
-			DefaultPoolID:  fi.ValueOf(e.Pool.ID),
-			LoadbalancerID: fi.ValueOf(e.Pool.Loadbalancer.ID),
-			Protocol:       listeners.ProtocolTCP,
-			ProtocolPort:   fi.ValueOf(e.Port),
-		}
+		return createLBListener(t, e, useVIPACL)
+	}
 
-		if useVIPACL && (fi.ValueOf(e.Pool.Loadbalancer.Provider) != "ovn") {
-			listeneropts.AllowedCIDRs = e.AllowedCIDRs
+	if changes.Protocol != nil {
+		// CheckChanges only allows this with ForceRecreate. The pool, and so its monitor and members,
+		// belong to the loadbalancer and survive the listener; the new listener is linked to it again.
+		klog.Warningf("Recreating LB listener %q to change its protocol from %s to %s; traffic through it is interrupted until it is recreated",
+			fi.ValueOf(a.Name), listenerProtocol(a), listenerProtocol(e))
+		if err := t.Cloud.DeleteListener(fi.ValueOf(a.ID)); err != nil {
+			return fmt.Errorf("error deleting LB listener %q to recreate it: %v", fi.ValueOf(a.Name), err)
 		}
+		return createLBListener(t, e, useVIPACL)
+	}
 
-		listener, err := t.Cloud.CreateListener(listeneropts)
+	if changes.Pool != nil {
+		klog.V(2).Infof("Linking LB listener %q to default pool %q", fi.ValueOf(a.Name), fi.ValueOf(e.Pool.Name))
+		opts := listeners.UpdateOpts{
+			DefaultPoolID: e.Pool.ID,
+		}
+		_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
 		if err != nil {
-			return fmt.Errorf("error creating LB listener: %v", err)
+			return fmt.Errorf("error updating LB listener default pool: %v", err)
 		}
-		e.ID = fi.PtrTo(listener.ID)
-		return nil
-	} else if len(changes.AllowedCIDRs) > 0 {
-		if useVIPACL && (fi.ValueOf(a.Pool.Loadbalancer.Provider) != "ovn") {
+	}
+
+	if len(changes.AllowedCIDRs) > 0 {
+		if useVIPACL && (fi.ValueOf(e.Pool.Loadbalancer.Provider) != "ovn") {
 			opts := listeners.UpdateOpts{
 				AllowedCIDRs: &changes.AllowedCIDRs,
 			}
-			_, err := listeners.Update(context.TODO(), t.Cloud.LoadBalancerClient(), fi.ValueOf(a.ID), opts).Extract()
+			_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
 			if err != nil {
 				return fmt.Errorf("error updating LB listener: %v", err)
 			}
 		} else {
 			klog.V(2).Infof("Openstack Octavia VIPACLs not supported")
 		}
-		return nil
 	}
-	klog.V(2).Infof("Openstack task LB::RenderOpenstack did nothing")
+
+	if changes.DefaultTLSContainerRef != nil {
+		klog.V(2).Infof("Updating certificate of LB listener %q", fi.ValueOf(a.Name))
+		opts := listeners.UpdateOpts{
+			DefaultTlsContainerRef: e.DefaultTLSContainerRef,
+		}
+		_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
+		if err != nil {
+			return fmt.Errorf("error updating LB listener certificate: %v", err)
+		}
+	}
+
+	if changes.ClientAuthentication != nil || changes.ClientCATLSContainerRef != nil {
+		klog.V(2).Infof("Updating client authentication of LB listener %q", fi.ValueOf(a.Name))
+		// the CA is updated together with the mode, so that MANDATORY never applies without a CA
+		opts := listeners.UpdateOpts{
+			ClientCATLSContainerRef: e.ClientCATLSContainerRef,
+		}
+		if e.ClientAuthentication != nil {
+			opts.ClientAuthentication = fi.PtrTo(listeners.ClientAuthentication(*e.ClientAuthentication))
+		}
+		_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
+		if err != nil {
+			return fmt.Errorf("error updating LB listener client authentication: %v", err)
+		}
+	}
+
+	if changes.TLSCiphers != nil || changes.TLSVersions != nil {
+		if err := checkTLSPolicySupported(t, e); err != nil {
+			return err
+		}
+		klog.V(2).Infof("Updating TLS versions and ciphers of LB listener %q", fi.ValueOf(a.Name))
+		opts := listeners.UpdateOpts{
+			TLSCiphers: changes.TLSCiphers,
+		}
+		if changes.TLSVersions != nil {
+			versions := tlsVersions(changes.TLSVersions)
+			opts.TLSVersions = &versions
+		}
+		_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
+		if err != nil {
+			return fmt.Errorf("error updating LB listener TLS versions and ciphers: %v", err)
+		}
+	}
+
+	if changes.Tags != nil {
+		// the tags of the listener are replaced as a whole
+		tags := normalizeTags(e.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		klog.V(2).Infof("Updating tags of LB listener %q to %v", fi.ValueOf(a.Name), tags)
+		opts := listeners.UpdateOpts{
+			Tags: &tags,
+		}
+		_, err := t.Cloud.UpdateListener(fi.ValueOf(a.ID), opts)
+		if err != nil {
+			return fmt.Errorf("error updating LB listener tags: %v", err)
+		}
+	}
+
+	if changes.Pool == nil && len(changes.AllowedCIDRs) == 0 && changes.Tags == nil && changes.DefaultTLSContainerRef == nil &&
+		changes.ClientAuthentication == nil && changes.ClientCATLSContainerRef == nil && changes.TLSCiphers == nil && changes.TLSVersions == nil {
+		klog.V(2).Infof("Openstack task LB::RenderOpenstack did nothing")
+	}
 	return nil
 }
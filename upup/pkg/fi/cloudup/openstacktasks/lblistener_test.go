@@ -0,0 +1,591 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstacktasks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func Test_LBListener_CreatedWithDefaultPool(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolTCP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+
+	e := &LBListener{
+		Name: fi.PtrTo("api"),
+		Port: fi.PtrTo(443),
+		Pool: &LBPool{
+			ID:   fi.PtrTo(pool.ID),
+			Name: fi.PtrTo(pool.Name),
+			Loadbalancer: &LB{
+				ID: fi.PtrTo(lb.ID),
+			},
+		},
+	}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBListener{}).RenderOpenstack(target, nil, e, e); err != nil {
+		t.Fatalf("unexpected error rendering listener: %v", err)
+	}
+
+	found, err := cloud.ListListeners(listeners.ListOpts{ID: fi.ValueOf(e.ID)})
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(found))
+	}
+	if found[0].DefaultPoolID != pool.ID {
+		t.Errorf("expected listener to be created with default pool %q, got %q", pool.ID, found[0].DefaultPoolID)
+	}
+}
+
+func Test_LBListener_RequiresCreatedPool(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+
+	e := &LBListener{
+		Name: fi.PtrTo("api"),
+		Port: fi.PtrTo(443),
+		Pool: &LBPool{
+			Name: fi.PtrTo("api-https"),
+		},
+	}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBListener{}).RenderOpenstack(target, nil, e, e); err == nil {
+		t.Errorf("expected error creating listener before its pool")
+	}
+}
+
+func Test_LBListener_NormalizeTags(t *testing.T) {
+	tests := []struct {
+		tags     []string
+		expected []string
+	}{
+		{tags: nil, expected: nil},
+		{tags: []string{}, expected: nil},
+		{tags: []string{"b", "a"}, expected: []string{"a", "b"}},
+		{tags: []string{"b", "a", "b"}, expected: []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		actual := normalizeTags(test.tags)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("normalizeTags(%v): expected %v, got %v", test.tags, test.expected, actual)
+		}
+	}
+}
+
+func Test_LBListener_ReconcilesTags(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolTCP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	listener, err := cloud.CreateListener(listeners.CreateOpts{
+		Name:           "api",
+		DefaultPoolID:  pool.ID,
+		LoadbalancerID: lb.ID,
+		Protocol:       listeners.ProtocolTCP,
+		ProtocolPort:   443,
+		Tags:           []string{"stale"},
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	poolTask := &LBPool{
+		ID:   fi.PtrTo(pool.ID),
+		Name: fi.PtrTo(pool.Name),
+		Loadbalancer: &LB{
+			ID: fi.PtrTo(lb.ID),
+		},
+	}
+	e := &LBListener{
+		Name: fi.PtrTo("api"),
+		Port: fi.PtrTo(443),
+		Pool: poolTask,
+		Tags: []string{"KubernetesCluster=a", "KubernetesCluster=b"},
+	}
+	a := &LBListener{
+		ID:   fi.PtrTo(listener.ID),
+		Name: fi.PtrTo(listener.Name),
+		Port: fi.PtrTo(listener.ProtocolPort),
+		Pool: poolTask,
+		Tags: normalizeTags(listener.Tags),
+	}
+
+	changes := &LBListener{Tags: e.Tags}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBListener{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering listener: %v", err)
+	}
+
+	found, err := cloud.ListListeners(listeners.ListOpts{ID: listener.ID})
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(found))
+	}
+	if !reflect.DeepEqual(found[0].Tags, e.Tags) {
+		t.Errorf("expected listener tags %v, got %v", e.Tags, found[0].Tags)
+	}
+}
+
+func Test_LBListener_ProtocolChange(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api"})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolHTTP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	listener, err := cloud.CreateListener(listeners.CreateOpts{Name: "api", LoadbalancerID: lb.ID, DefaultPoolID: pool.ID, Protocol: listeners.ProtocolTerminatedHTTPS, ProtocolPort: 443, DefaultTlsContainerRef: "cert"})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	newExpected := func(forceRecreate *bool) *LBListener {
+		return &LBListener{
+			Name:     fi.PtrTo("api"),
+			Port:     fi.PtrTo(443),
+			Protocol: fi.PtrTo(string(listeners.ProtocolHTTP)),
+			Pool: &LBPool{
+				ID:           fi.PtrTo(pool.ID),
+				Name:         fi.PtrTo(pool.Name),
+				Protocol:     fi.PtrTo(string(v2pools.ProtocolHTTP)),
+				Loadbalancer: &LB{ID: fi.PtrTo(lb.ID)},
+			},
+			ForceRecreate: forceRecreate,
+		}
+	}
+	a := &LBListener{
+		ID:                     fi.PtrTo(listener.ID),
+		Name:                   fi.PtrTo("api"),
+		Port:                   fi.PtrTo(443),
+		Protocol:               fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+		DefaultTLSContainerRef: fi.PtrTo("cert"),
+	}
+	changes := &LBListener{Protocol: fi.PtrTo(string(listeners.ProtocolHTTP))}
+
+	t.Run("without ForceRecreate", func(t *testing.T) {
+		err := (&LBListener{}).CheckChanges(a, newExpected(nil), changes)
+		if err == nil || !strings.Contains(err.Error(), "ForceRecreate") {
+			t.Errorf("expected error suggesting ForceRecreate, got %v", err)
+		}
+	})
+
+	t.Run("with ForceRecreate", func(t *testing.T) {
+		e := newExpected(fi.PtrTo(true))
+		if err := (&LBListener{}).CheckChanges(a, e, changes); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		target := &openstack.OpenstackAPITarget{Cloud: cloud}
+		if err := (&LBListener{}).RenderOpenstack(target, a, e, changes); err != nil {
+			t.Fatalf("unexpected error rendering listener: %v", err)
+		}
+
+		found, err := cloud.ListListeners(listeners.ListOpts{Name: "api"})
+		if err != nil {
+			t.Fatalf("error listing listeners: %v", err)
+		}
+		if len(found) != 1 {
+			t.Fatalf("expected 1 listener, got %d", len(found))
+		}
+		if found[0].ID == listener.ID || found[0].ID != fi.ValueOf(e.ID) {
+			t.Errorf("expected listener to be recreated, got %q", found[0].ID)
+		}
+		if found[0].Protocol != string(listeners.ProtocolHTTP) {
+			t.Errorf("expected protocol %s, got %s", listeners.ProtocolHTTP, found[0].Protocol)
+		}
+		if found[0].DefaultPoolID != pool.ID {
+			t.Errorf("expected recreated listener to be linked to pool %q, got %q", pool.ID, found[0].DefaultPoolID)
+		}
+	})
+}
+
+func Test_LBListener_CheckChanges_ClientAuthentication(t *testing.T) {
+	tests := []struct {
+		desc        string
+		listener    *LBListener
+		expectedErr string
+	}{
+		{
+			desc: "mandatory with CA",
+			listener: &LBListener{
+				ClientAuthentication:    fi.PtrTo(string(listeners.ClientAuthenticationMandatory)),
+				ClientCATLSContainerRef: fi.PtrTo("https://barbican/containers/client-ca"),
+			},
+		},
+		{
+			desc: "optional without CA",
+			listener: &LBListener{
+				ClientAuthentication: fi.PtrTo(string(listeners.ClientAuthenticationOptional)),
+			},
+		},
+		{
+			desc: "mandatory without CA",
+			listener: &LBListener{
+				ClientAuthentication: fi.PtrTo(string(listeners.ClientAuthenticationMandatory)),
+			},
+			expectedErr: "ClientCATLSContainerRef is required",
+		},
+		{
+			desc: "unknown mode",
+			listener: &LBListener{
+				ClientAuthentication: fi.PtrTo("REQUIRED"),
+			},
+			expectedErr: "unknown ClientAuthentication",
+		},
+		{
+			desc: "TCP listener",
+			listener: &LBListener{
+				Protocol:             fi.PtrTo(string(listeners.ProtocolTCP)),
+				ClientAuthentication: fi.PtrTo(string(listeners.ClientAuthenticationOptional)),
+			},
+			expectedErr: "can only be set on TERMINATED_HTTPS listeners",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			e := testCase.listener
+			e.Name = fi.PtrTo("api")
+			if e.Protocol == nil {
+				e.Protocol = fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS))
+				e.DefaultTLSContainerRef = fi.PtrTo("https://barbican/containers/cert")
+			}
+			err := (&LBListener{}).CheckChanges(nil, e, e)
+			if testCase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func Test_LBListener_ReconcilesClientAuthentication(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolHTTP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	listener, err := cloud.CreateListener(listeners.CreateOpts{
+		Name:                   "api",
+		DefaultPoolID:          pool.ID,
+		LoadbalancerID:         lb.ID,
+		Protocol:               listeners.ProtocolTerminatedHTTPS,
+		ProtocolPort:           443,
+		DefaultTlsContainerRef: "https://barbican/containers/cert",
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	e := &LBListener{
+		Name:                    fi.PtrTo("api"),
+		Port:                    fi.PtrTo(443),
+		Lifecycle:               fi.LifecycleSync,
+		Protocol:                fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+		DefaultTLSContainerRef:  fi.PtrTo("https://barbican/containers/cert"),
+		ClientAuthentication:    fi.PtrTo(string(listeners.ClientAuthenticationMandatory)),
+		ClientCATLSContainerRef: fi.PtrTo("https://barbican/containers/client-ca"),
+		Pool: &LBPool{
+			ID:        fi.PtrTo(pool.ID),
+			Name:      fi.PtrTo(pool.Name),
+			Lifecycle: fi.LifecycleSync,
+			Loadbalancer: &LB{
+				ID: fi.PtrTo(lb.ID),
+			},
+		},
+	}
+	a, err := NewLBListenerTaskFromCloud(cloud, fi.LifecycleSync, listener, e)
+	if err != nil {
+		t.Fatalf("error building actual listener: %v", err)
+	}
+	if fi.ValueOf(a.ClientAuthentication) != string(listeners.ClientAuthenticationNone) {
+		t.Errorf("expected actual ClientAuthentication to be read back as NONE, got %v", a.ClientAuthentication)
+	}
+
+	changes := &LBListener{}
+	fi.BuildChanges(a, e, changes)
+	if changes.ClientAuthentication == nil || changes.ClientCATLSContainerRef == nil {
+		t.Fatalf("expected changes of the client authentication, got %v", fi.DebugAsJsonString(changes))
+	}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBListener{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering listener: %v", err)
+	}
+
+	found, err := cloud.ListListeners(listeners.ListOpts{ID: listener.ID})
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(found))
+	}
+	if found[0].ClientAuthentication != string(listeners.ClientAuthenticationMandatory) {
+		t.Errorf("expected client authentication MANDATORY, got %q", found[0].ClientAuthentication)
+	}
+	if found[0].ClientCATLSContainerRef != fi.ValueOf(e.ClientCATLSContainerRef) {
+		t.Errorf("expected client CA %q, got %q", fi.ValueOf(e.ClientCATLSContainerRef), found[0].ClientCATLSContainerRef)
+	}
+}
+
+func Test_LBListener_CheckChanges_TLSPolicy(t *testing.T) {
+	tests := []struct {
+		desc        string
+		listener    *LBListener
+		expectedErr string
+	}{
+		{
+			desc: "modern versions and ciphers",
+			listener: &LBListener{
+				TLSCiphers:  fi.PtrTo("ECDHE-RSA-AES256-GCM-SHA384"),
+				TLSVersions: []string{"TLSv1.3", "TLSv1.2"},
+			},
+		},
+		{
+			desc: "deprecated version",
+			listener: &LBListener{
+				TLSVersions: []string{"TLSv1.1", "TLSv1.2"},
+			},
+			expectedErr: "TLS version TLSv1.1 of listener \"api\" is deprecated",
+		},
+		{
+			desc: "deprecated version explicitly allowed",
+			listener: &LBListener{
+				TLSVersions:                []string{"TLSv1.1", "TLSv1.2"},
+				AllowDeprecatedTLSVersions: fi.PtrTo(true),
+			},
+		},
+		{
+			desc: "unknown version",
+			listener: &LBListener{
+				TLSVersions: []string{"TLSv2"},
+			},
+			expectedErr: "unknown TLS version \"TLSv2\"",
+		},
+		{
+			desc: "duplicate version",
+			listener: &LBListener{
+				TLSVersions: []string{"TLSv1.3", "TLSv1.3"},
+			},
+			expectedErr: "listed more than once",
+		},
+		{
+			desc: "empty versions",
+			listener: &LBListener{
+				TLSVersions: []string{},
+			},
+			expectedErr: "TLSVersions of listener \"api\" cannot be empty",
+		},
+		{
+			desc: "empty ciphers",
+			listener: &LBListener{
+				TLSCiphers: fi.PtrTo(""),
+			},
+			expectedErr: "TLSCiphers of listener \"api\" cannot be empty",
+		},
+		{
+			desc: "TCP listener",
+			listener: &LBListener{
+				Protocol:    fi.PtrTo(string(listeners.ProtocolTCP)),
+				TLSVersions: []string{"TLSv1.3"},
+			},
+			expectedErr: "can only be set on TERMINATED_HTTPS listeners",
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			e := testCase.listener
+			e.Name = fi.PtrTo("api")
+			if e.Protocol == nil {
+				e.Protocol = fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS))
+				e.DefaultTLSContainerRef = fi.PtrTo("https://barbican/containers/cert")
+			}
+			err := (&LBListener{}).CheckChanges(nil, e, e)
+			if testCase.expectedErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), testCase.expectedErr) {
+				t.Errorf("expected error containing %q, got %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func Test_LBListener_ReconcilesTLSPolicy(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolHTTP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	listener, err := cloud.CreateListener(listeners.CreateOpts{
+		Name:                   "api",
+		DefaultPoolID:          pool.ID,
+		LoadbalancerID:         lb.ID,
+		Protocol:               listeners.ProtocolTerminatedHTTPS,
+		ProtocolPort:           443,
+		DefaultTlsContainerRef: "https://barbican/containers/cert",
+	})
+	if err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+
+	e := &LBListener{
+		Name:                   fi.PtrTo("api"),
+		Port:                   fi.PtrTo(443),
+		Lifecycle:              fi.LifecycleSync,
+		Protocol:               fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+		DefaultTLSContainerRef: fi.PtrTo("https://barbican/containers/cert"),
+		Pool: &LBPool{
+			ID:        fi.PtrTo(pool.ID),
+			Name:      fi.PtrTo(pool.Name),
+			Lifecycle: fi.LifecycleSync,
+			Loadbalancer: &LB{
+				ID: fi.PtrTo(lb.ID),
+			},
+		},
+	}
+	// the defaults Octavia applies are not drift while no TLS policy is set
+	a, err := NewLBListenerTaskFromCloud(cloud, fi.LifecycleSync, listener, e)
+	if err != nil {
+		t.Fatalf("error building actual listener: %v", err)
+	}
+	changes := &LBListener{}
+	fi.BuildChanges(a, e, changes)
+	if changes.TLSCiphers != nil || changes.TLSVersions != nil {
+		t.Errorf("expected no changes of the TLS policy, got %v", fi.DebugAsJsonString(changes))
+	}
+
+	e.TLSCiphers = fi.PtrTo("ECDHE-RSA-AES256-GCM-SHA384")
+	e.TLSVersions = []string{"TLSv1.3"}
+	a, err = NewLBListenerTaskFromCloud(cloud, fi.LifecycleSync, listener, e)
+	if err != nil {
+		t.Fatalf("error building actual listener: %v", err)
+	}
+	if !reflect.DeepEqual(a.TLSVersions, []string{"TLSv1.2", "TLSv1.3"}) {
+		t.Errorf("expected actual TLS versions to be the defaults, got %v", a.TLSVersions)
+	}
+	changes = &LBListener{}
+	fi.BuildChanges(a, e, changes)
+	if changes.TLSCiphers == nil || changes.TLSVersions == nil {
+		t.Fatalf("expected changes of the TLS policy, got %v", fi.DebugAsJsonString(changes))
+	}
+
+	// the policy cannot be updated through older Octavia APIs
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	cloud.LBTLSPolicyUnsupported = true
+	if err := (&LBListener{}).RenderOpenstack(target, a, e, changes); err == nil || !strings.Contains(err.Error(), "require Octavia API version 2.17") {
+		t.Errorf("expected unsupported error, got %v", err)
+	}
+
+	cloud.LBTLSPolicyUnsupported = false
+	if err := (&LBListener{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering listener: %v", err)
+	}
+
+	found, err := cloud.ListListeners(listeners.ListOpts{ID: listener.ID})
+	if err != nil {
+		t.Fatalf("error listing listeners: %v", err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("expected 1 listener, got %d", len(found))
+	}
+	if found[0].TLSCiphers != fi.ValueOf(e.TLSCiphers) {
+		t.Errorf("expected TLS ciphers %q, got %q", fi.ValueOf(e.TLSCiphers), found[0].TLSCiphers)
+	}
+	if !reflect.DeepEqual(found[0].TLSVersions, e.TLSVersions) {
+		t.Errorf("expected TLS versions %v, got %v", e.TLSVersions, found[0].TLSVersions)
+	}
+
+	// once applied, the policy is not drift anymore
+	a, err = NewLBListenerTaskFromCloud(cloud, fi.LifecycleSync, &found[0], e)
+	if err != nil {
+		t.Fatalf("error building actual listener: %v", err)
+	}
+	changes = &LBListener{}
+	fi.BuildChanges(a, e, changes)
+	if changes.TLSCiphers != nil || changes.TLSVersions != nil {
+		t.Errorf("expected no changes of the TLS policy after rendering, got %v", fi.DebugAsJsonString(changes))
+	}
+}
@@ -31,6 +31,20 @@ type LBPool struct {
 	Name         *string
 	Lifecycle    fi.Lifecycle
 	Loadbalancer *LB
+	// Protocol is the protocol used to reach the members, defaulting to TCP.
+	// Pools behind a TERMINATED_HTTPS listener must use HTTP.
+	Protocol *string
+	// TLSEnabled re-encrypts the traffic to the members, for pools behind a TERMINATED_HTTPS listener
+	TLSEnabled *bool
+	// CATLSContainerRef is the key manager reference of the CA certificates used to verify the members.
+	// An empty reference removes it from the pool, while nil leaves the pool alone.
+	CATLSContainerRef *string
+	// CRLContainerRef is the key manager reference of the revocation list used to verify the members.
+	// An empty reference removes it from the pool, while nil leaves the pool alone.
+	CRLContainerRef *string
+	// Tags are set on the pool, for example to identify the cluster and owner it belongs to.
+	// If unset, the tags of the pool are left alone.
+	Tags []string
 }
 
 // GetDependencies returns the dependencies of the Instance task
@@ -46,6 +60,26 @@ func (e *LBPool) GetDependencies(tasks map[string]fi.CloudupTask) []fi.CloudupTa
 
 var _ fi.CompareWithID = &LBPool{}
 
+// poolLBMethod returns the balancing algorithm used for pools of the given loadbalancer.
+// The ovn provider only implements SOURCE_IP_PORT.
+func poolLBMethod(lb *LB) v2pools.LBMethod {
+	if lb != nil && fi.ValueOf(lb.Provider) == "ovn" {
+		return v2pools.LBMethodSourceIpPort
+	}
+	return v2pools.LBMethodRoundRobin
+}
+
+// lbMethodSupportsBackupMembers returns true if the algorithm honours members flagged as backup,
+// sending traffic to them only once all primary members are down.
+func lbMethodSupportsBackupMembers(method v2pools.LBMethod) bool {
+	switch method {
+	case v2pools.LBMethodRoundRobin, v2pools.LBMethodLeastConnections, v2pools.LBMethodSourceIp:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *LBPool) CompareWithID() *string {
 	return s.ID
 }
@@ -56,9 +90,16 @@ func NewLBPoolTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lifecyc
 	}
 
 	a := &LBPool{
-		ID:        fi.PtrTo(pool.ID),
-		Name:      fi.PtrTo(pool.Name),
-		Lifecycle: lifecycle,
+		ID:         fi.PtrTo(pool.ID),
+		Name:       fi.PtrTo(pool.Name),
+		Lifecycle:  lifecycle,
+		TLSEnabled: fi.PtrTo(pool.TLSEnabled),
+		// unset references are reported as empty, so that removing a reference is seen as a change
+		CATLSContainerRef: fi.PtrTo(pool.CATLSContainerRef),
+		CRLContainerRef:   fi.PtrTo(pool.CRLContainerRef),
+	}
+	if pool.Protocol != "" {
+		a.Protocol = fi.PtrTo(pool.Protocol)
 	}
 	if len(pool.Loadbalancers) == 1 {
 		lbID := pool.Loadbalancers[0]
@@ -73,9 +114,14 @@ func NewLBPoolTaskFromCloud(cloud openstack.OpenstackCloud, lifecycle fi.Lifecyc
 		a.Loadbalancer = loadbalancerTask
 	}
 	if find != nil {
+		if find.Tags != nil {
+			a.Tags = normalizeTags(pool.Tags)
+		}
+
 		// Update all search terms
 		find.ID = a.ID
 		find.Name = a.Name
+		find.Tags = normalizeTags(find.Tags)
 	}
 	return a, nil
 }
@@ -119,10 +165,27 @@ func (_ *LBPool) CheckChanges(a, e, changes *LBPool) error {
 		if changes.Name != nil {
 			return fi.CannotChangeField("Name")
 		}
+		if changes.Protocol != nil {
+			return fi.CannotChangeField("Protocol")
+		}
+	}
+	if !fi.ValueOf(e.TLSEnabled) && (fi.ValueOf(e.CATLSContainerRef) != "" || fi.ValueOf(e.CRLContainerRef) != "") {
+		return fmt.Errorf("CATLSContainerRef and CRLContainerRef can only be set on pools with TLSEnabled")
+	}
+	if fi.ValueOf(e.CRLContainerRef) != "" && fi.ValueOf(e.CATLSContainerRef) == "" {
+		return fmt.Errorf("CRLContainerRef requires CATLSContainerRef to be set")
 	}
 	return nil
 }
 
+// poolProtocol returns the protocol of the pool, defaulting to TCP.
+func poolProtocol(pool *LBPool) v2pools.Protocol {
+	if pool.Protocol != nil {
+		return v2pools.Protocol(*pool.Protocol)
+	}
+	return v2pools.ProtocolTCP
+}
+
 func (_ *LBPool) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *LBPool) error {
 	if a == nil {
 
@@ -132,15 +195,17 @@ func (_ *LBPool) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes
 			return fmt.Errorf("failed to loadbalancer ACTIVE provisioning status %v: %v", provisioningStatus, err)
 		}
 
-		LbMethod := v2pools.LBMethodRoundRobin
-		if fi.ValueOf(e.Loadbalancer.Provider) == "ovn" {
-			LbMethod = v2pools.LBMethodSourceIpPort
-		}
 		poolopts := v2pools.CreateOpts{
 			Name:           fi.ValueOf(e.Name),
-			LBMethod:       LbMethod,
-			Protocol:       v2pools.ProtocolTCP,
+			LBMethod:       poolLBMethod(e.Loadbalancer),
+			Protocol:       poolProtocol(e),
 			LoadbalancerID: fi.ValueOf(e.Loadbalancer.ID),
+
+			TLSEnabled:        fi.ValueOf(e.TLSEnabled),
+			CATLSContainerRef: fi.ValueOf(e.CATLSContainerRef),
+			CRLContainerRef:   fi.ValueOf(e.CRLContainerRef),
+
+			Tags: e.Tags,
 		}
 		pool, err := t.Cloud.CreatePool(poolopts)
 		if err != nil {
@@ -151,6 +216,32 @@ func (_ *LBPool) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes
 		return nil
 	}
 
-	klog.V(2).Infof("Openstack task LB::RenderOpenstack did nothing")
+	if changes.TLSEnabled != nil || changes.CATLSContainerRef != nil || changes.CRLContainerRef != nil {
+		klog.V(2).Infof("Updating TLS settings of LB pool %q", fi.ValueOf(a.Name))
+		opts := v2pools.UpdateOpts{
+			TLSEnabled:        changes.TLSEnabled,
+			CATLSContainerRef: changes.CATLSContainerRef,
+			CRLContainerRef:   changes.CRLContainerRef,
+		}
+		if _, err := t.Cloud.UpdatePool(fi.ValueOf(a.ID), opts); err != nil {
+			return fmt.Errorf("error updating LB pool: %v", err)
+		}
+	}
+
+	if changes.Tags != nil {
+		// the tags of the pool are replaced as a whole
+		tags := normalizeTags(e.Tags)
+		if tags == nil {
+			tags = []string{}
+		}
+		klog.V(2).Infof("Updating tags of LB pool %q to %v", fi.ValueOf(a.Name), tags)
+		if _, err := t.Cloud.UpdatePool(fi.ValueOf(a.ID), v2pools.UpdateOpts{Tags: &tags}); err != nil {
+			return fmt.Errorf("error updating LB pool tags: %v", err)
+		}
+	}
+
+	if changes.TLSEnabled == nil && changes.CATLSContainerRef == nil && changes.CRLContainerRef == nil && changes.Tags == nil {
+		klog.V(2).Infof("Openstack task LB::RenderOpenstack did nothing")
+	}
 	return nil
 }
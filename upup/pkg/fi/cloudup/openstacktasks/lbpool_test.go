@@ -0,0 +1,266 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstacktasks
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/listeners"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func Test_LBPool_CheckChanges_TLS(t *testing.T) {
+	tests := []struct {
+		desc        string
+		pool        *LBPool
+		listener    *LBListener
+		expectedErr bool
+	}{
+		{
+			desc: "plain TCP pool and listener",
+			pool: &LBPool{},
+		},
+		{
+			desc: "re-encrypting pool behind a TERMINATED_HTTPS listener",
+			pool: &LBPool{
+				Protocol:          fi.PtrTo(string(v2pools.ProtocolHTTP)),
+				TLSEnabled:        fi.PtrTo(true),
+				CATLSContainerRef: fi.PtrTo("https://barbican/containers/ca"),
+				CRLContainerRef:   fi.PtrTo("https://barbican/containers/crl"),
+			},
+			listener: &LBListener{
+				Protocol:               fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+				DefaultTLSContainerRef: fi.PtrTo("https://barbican/containers/cert"),
+			},
+		},
+		{
+			desc: "re-encrypting pool behind a TCP listener",
+			pool: &LBPool{
+				Protocol:   fi.PtrTo(string(v2pools.ProtocolHTTP)),
+				TLSEnabled: fi.PtrTo(true),
+			},
+			listener:    &LBListener{},
+			expectedErr: true,
+		},
+		{
+			desc: "CA reference without TLSEnabled",
+			pool: &LBPool{
+				CATLSContainerRef: fi.PtrTo("https://barbican/containers/ca"),
+			},
+			expectedErr: true,
+		},
+		{
+			desc: "CRL reference without CA reference",
+			pool: &LBPool{
+				TLSEnabled:      fi.PtrTo(true),
+				CRLContainerRef: fi.PtrTo("https://barbican/containers/crl"),
+			},
+			expectedErr: true,
+		},
+		{
+			desc: "TERMINATED_HTTPS listener without certificate",
+			pool: &LBPool{
+				Protocol: fi.PtrTo(string(v2pools.ProtocolHTTP)),
+			},
+			listener: &LBListener{
+				Protocol: fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+			},
+			expectedErr: true,
+		},
+		{
+			desc: "TERMINATED_HTTPS listener with a TCP pool",
+			pool: &LBPool{},
+			listener: &LBListener{
+				Protocol:               fi.PtrTo(string(listeners.ProtocolTerminatedHTTPS)),
+				DefaultTLSContainerRef: fi.PtrTo("https://barbican/containers/cert"),
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			testCase.pool.Name = fi.PtrTo("api-https")
+			err := (&LBPool{}).CheckChanges(nil, testCase.pool, &LBPool{})
+			if err == nil && testCase.listener != nil {
+				testCase.listener.Name = fi.PtrTo("api")
+				testCase.listener.Pool = testCase.pool
+				err = (&LBListener{}).CheckChanges(nil, testCase.listener, &LBListener{})
+			}
+			if testCase.expectedErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !testCase.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_LBPool_ReconcilesTLSContainerRefs(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{
+		Name:              "api-https",
+		LoadbalancerID:    lb.ID,
+		LBMethod:          v2pools.LBMethodRoundRobin,
+		Protocol:          v2pools.ProtocolHTTP,
+		TLSEnabled:        true,
+		CATLSContainerRef: "https://barbican/containers/old-ca",
+	})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+
+	e := &LBPool{
+		Name:              fi.PtrTo("api-https"),
+		Protocol:          fi.PtrTo(string(v2pools.ProtocolHTTP)),
+		TLSEnabled:        fi.PtrTo(true),
+		CATLSContainerRef: fi.PtrTo("https://barbican/containers/new-ca"),
+		CRLContainerRef:   fi.PtrTo("https://barbican/containers/crl"),
+		Loadbalancer: &LB{
+			ID: fi.PtrTo(lb.ID),
+		},
+	}
+	a, err := NewLBPoolTaskFromCloud(cloud, fi.LifecycleSync, pool, e)
+	if err != nil {
+		t.Fatalf("error building actual pool: %v", err)
+	}
+	if fi.ValueOf(a.CATLSContainerRef) != "https://barbican/containers/old-ca" {
+		t.Errorf("expected actual CATLSContainerRef to be read back, got %q", fi.ValueOf(a.CATLSContainerRef))
+	}
+
+	changes := &LBPool{
+		CATLSContainerRef: e.CATLSContainerRef,
+		CRLContainerRef:   e.CRLContainerRef,
+	}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBPool{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering pool: %v", err)
+	}
+
+	found, err := cloud.GetPool(pool.ID)
+	if err != nil {
+		t.Fatalf("error getting pool: %v", err)
+	}
+	if !found.TLSEnabled {
+		t.Errorf("expected pool to keep TLS enabled")
+	}
+	if found.CATLSContainerRef != fi.ValueOf(e.CATLSContainerRef) {
+		t.Errorf("expected CATLSContainerRef %q, got %q", fi.ValueOf(e.CATLSContainerRef), found.CATLSContainerRef)
+	}
+	if found.CRLContainerRef != fi.ValueOf(e.CRLContainerRef) {
+		t.Errorf("expected CRLContainerRef %q, got %q", fi.ValueOf(e.CRLContainerRef), found.CRLContainerRef)
+	}
+}
+
+func Test_LBPool_RemovesTLSContainerRefs(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.0.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{
+		Name:              "api-https",
+		LoadbalancerID:    lb.ID,
+		LBMethod:          v2pools.LBMethodRoundRobin,
+		Protocol:          v2pools.ProtocolHTTP,
+		TLSEnabled:        true,
+		CATLSContainerRef: "https://barbican/containers/ca",
+		CRLContainerRef:   "https://barbican/containers/crl",
+	})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+
+	e := &LBPool{
+		Name:              fi.PtrTo("api-https"),
+		Lifecycle:         fi.LifecycleSync,
+		Protocol:          fi.PtrTo(string(v2pools.ProtocolHTTP)),
+		TLSEnabled:        fi.PtrTo(true),
+		CATLSContainerRef: fi.PtrTo(""),
+		CRLContainerRef:   fi.PtrTo(""),
+		Loadbalancer: &LB{
+			ID: fi.PtrTo(lb.ID),
+		},
+	}
+	if err := (&LBPool{}).CheckChanges(nil, e, &LBPool{}); err != nil {
+		t.Fatalf("unexpected error checking pool: %v", err)
+	}
+	a, err := NewLBPoolTaskFromCloud(cloud, fi.LifecycleSync, pool, e)
+	if err != nil {
+		t.Fatalf("error building actual pool: %v", err)
+	}
+
+	changes := &LBPool{}
+	if !fi.BuildChanges(a, e, changes) || changes.CATLSContainerRef == nil || changes.CRLContainerRef == nil {
+		t.Fatalf("expected the removal of the references to be a change, got %+v", changes)
+	}
+	target := &openstack.OpenstackAPITarget{Cloud: cloud}
+	if err := (&LBPool{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering pool: %v", err)
+	}
+
+	found, err := cloud.GetPool(pool.ID)
+	if err != nil {
+		t.Fatalf("error getting pool: %v", err)
+	}
+	if found.CATLSContainerRef != "" || found.CRLContainerRef != "" {
+		t.Errorf("expected references to be removed, got CA %q and CRL %q", found.CATLSContainerRef, found.CRLContainerRef)
+	}
+
+	a, err = NewLBPoolTaskFromCloud(cloud, fi.LifecycleSync, found, e)
+	if err != nil {
+		t.Fatalf("error building actual pool: %v", err)
+	}
+	changes = &LBPool{}
+	if fi.BuildChanges(a, e, changes) {
+		t.Errorf("expected no changes once the references are removed, got %+v", changes)
+	}
+}
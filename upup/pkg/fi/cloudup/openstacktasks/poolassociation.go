@@ -23,6 +23,8 @@ import (
 
 	"github.com/gophercloud/gophercloud/v2"
 	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
 	"k8s.io/kops/util/pkg/vfs"
@@ -39,6 +41,19 @@ type PoolAssociation struct {
 	InterfaceName *string
 	ProtocolPort  *int
 	Weight        *int
+	// Backup marks the members as standby, only receiving traffic if all other members are down
+	Backup *bool
+	// MonitorPort is the port checked by the health monitor of the pool, if different from ProtocolPort
+	MonitorPort *int
+	// MembersNamedByServer is whether the members carry the name of their server.
+	// Members created before they were named after their server carry the name of the association, and are renamed.
+	MembersNamedByServer *bool
+	// PreservedMembers selects the members that were added outside of kops, which are kept when stale members are pruned.
+	// It is not compared with the existing members.
+	PreservedMembers *openstack.MemberPreservation
+	// StaleMembersRemoved is whether the pool has no members left that belong to no server of the cluster,
+	// such as the members of deleted control-plane servers. Stale members are removed, except for PreservedMembers.
+	StaleMembersRemoved *bool
 }
 
 // GetDependencies returns the dependencies of the Instance task
@@ -85,14 +100,30 @@ func (p *PoolAssociation) Find(context *fi.CloudupContext) (*PoolAssociation, er
 	a := rs[0]
 	// check is member already created
 	var found *v2pools.Member
-	for _, member := range a.Members {
-		poolMember, err := cloud.GetPoolMember(a.ID, member.ID)
+	namedByServer := true
+	if len(a.Members) > 0 {
+		memberServers, err := p.listMemberServers(cloud)
 		if err != nil {
 			return nil, err
 		}
-		if fi.ValueOf(p.Name) == poolMember.Name {
-			found = poolMember
-			break
+		serverNames := sets.New[string]()
+		for _, server := range memberServers {
+			serverNames.Insert(server.Name)
+		}
+
+		members, err := cloud.ListPoolMembersMap(a.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			if member.Name == fi.ValueOf(p.Name) {
+				namedByServer = false
+			} else if !serverNames.Has(member.Name) {
+				continue
+			}
+			if found == nil {
+				found = &member
+			}
 		}
 	}
 	// if not found it is created by returning nil, nil
@@ -107,7 +138,7 @@ func (p *PoolAssociation) Find(context *fi.CloudupContext) (*PoolAssociation, er
 
 	actual := &PoolAssociation{
 		ID:            fi.PtrTo(found.ID),
-		Name:          fi.PtrTo(found.Name),
+		Name:          p.Name,
 		Pool:          pool,
 		ServerPrefix:  p.ServerPrefix,
 		ClusterName:   p.ClusterName,
@@ -115,8 +146,27 @@ func (p *PoolAssociation) Find(context *fi.CloudupContext) (*PoolAssociation, er
 		ProtocolPort:  p.ProtocolPort,
 		Lifecycle:     p.Lifecycle,
 		Weight:        fi.PtrTo(found.Weight),
+		Backup:        fi.PtrTo(found.Backup),
+
+		MembersNamedByServer: fi.PtrTo(namedByServer),
 	}
+	if found.MonitorPort != 0 {
+		actual.MonitorPort = fi.PtrTo(found.MonitorPort)
+	}
+	_, stale, err := p.findStaleMembers(cloud, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	actual.PreservedMembers = p.PreservedMembers
+	actual.StaleMembersRemoved = fi.PtrTo(len(stale) == 0)
+
 	p.ID = actual.ID
+	if p.MembersNamedByServer == nil {
+		p.MembersNamedByServer = fi.PtrTo(true)
+	}
+	if p.StaleMembersRemoved == nil {
+		p.StaleMembersRemoved = fi.PtrTo(true)
+	}
 	return actual, nil
 }
 
@@ -137,6 +187,12 @@ func (_ *PoolAssociation) CheckChanges(a, e, changes *PoolAssociation) error {
 			return fi.CannotChangeField("Name")
 		}
 	}
+	if fi.ValueOf(e.Backup) && e.Pool != nil {
+		method := poolLBMethod(e.Pool.Loadbalancer)
+		if !lbMethodSupportsBackupMembers(method) {
+			return fmt.Errorf("backup members are not supported with pool algorithm %s", method)
+		}
+	}
 	return nil
 }
 
@@ -155,31 +211,111 @@ func GetServerFixedIP(client *gophercloud.ServiceClient, server *servers.Server,
 	return memberAddress, err
 }
 
+// listMemberServers returns the servers of the cluster that are members of the association
+func (e *PoolAssociation) listMemberServers(cloud openstack.OpenstackCloud) ([]servers.Server, error) {
+	serverList, err := cloud.ListInstances(servers.ListOpts{
+		Name: fmt.Sprintf("^%s", fi.ValueOf(e.ServerPrefix)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing servers: %v", err)
+	}
+
+	var memberServers []servers.Server
+	for _, server := range serverList {
+		val, ok := server.Metadata["k8s"]
+		if !ok || val != fi.ValueOf(e.ClusterName) {
+			continue
+		}
+		memberServers = append(memberServers, server)
+	}
+	return memberServers, nil
+}
+
+// findStaleMembers splits the members of the pool into the members of servers of the cluster, which are returned
+// unchanged as specs, and the stale members, which belong to no server of the cluster and are not preserved.
+// Members are matched to servers by their address or, while a server has no address yet, by their name.
+func (e *PoolAssociation) findStaleMembers(cloud openstack.OpenstackCloud, poolID string) ([]openstack.MemberSpec, []v2pools.Member, error) {
+	serverList, err := cloud.ListInstances(servers.ListOpts{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing servers: %v", err)
+	}
+	serverNames := sets.New[string]()
+	serverAddresses := sets.New[string]()
+	for _, server := range serverList {
+		if server.Metadata["k8s"] != fi.ValueOf(e.ClusterName) {
+			continue
+		}
+		serverNames.Insert(server.Name)
+		if address, err := openstack.GetServerFixedIP(&server, fi.ValueOf(e.InterfaceName)); err == nil {
+			serverAddresses.Insert(address)
+		}
+	}
+
+	members, err := cloud.ListPoolMembers(poolID, v2pools.ListMembersOpts{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing members of pool %s: %v", poolID, err)
+	}
+	var live []openstack.MemberSpec
+	var stale []v2pools.Member
+	for _, member := range members {
+		if serverAddresses.Has(member.Address) || serverNames.Has(member.Name) {
+			live = append(live, openstack.NewMemberSpec(member))
+			continue
+		}
+		if e.PreservedMembers != nil && e.PreservedMembers.Preserves(member) {
+			continue
+		}
+		stale = append(stale, member)
+	}
+	return live, stale, nil
+}
+
+// removeStaleMembers removes the stale members of the pool, keeping the members of servers of the cluster and the preserved members
+func (e *PoolAssociation) removeStaleMembers(cloud openstack.OpenstackCloud, poolID string) error {
+	live, stale, err := e.findStaleMembers(cloud, poolID)
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	for _, member := range stale {
+		klog.V(2).Infof("Removing stale member %s (%s:%d) of pool %s", member.Name, member.Address, member.ProtocolPort, poolID)
+	}
+	preservation := openstack.MemberPreservation{}
+	if e.PreservedMembers != nil {
+		preservation = *e.PreservedMembers
+	}
+	if err := cloud.ReconcilePoolMembers(poolID, live, preservation); err != nil {
+		return fmt.Errorf("error removing stale members of pool %s: %v", poolID, err)
+	}
+	return nil
+}
+
 func (_ *PoolAssociation) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *PoolAssociation) error {
+	memberServers, err := e.listMemberServers(t.Cloud)
+	if err != nil {
+		return err
+	}
+
 	if a == nil {
-		serverList, err := t.Cloud.ListInstances(servers.ListOpts{
-			Name: fmt.Sprintf("^%s", fi.ValueOf(e.ServerPrefix)),
-		})
-		if err != nil {
-			return fmt.Errorf("error listing servers: %v", err)
+		if err := t.Cloud.CheckPoolCapacity(fi.ValueOf(e.Pool.ID), len(memberServers)); err != nil {
+			return err
 		}
 
-		for _, server := range serverList {
-			val, ok := server.Metadata["k8s"]
-			if !ok || val != fi.ValueOf(e.ClusterName) {
-				continue
-			}
-
+		for _, server := range memberServers {
 			memberAddress, err := GetServerFixedIP(t.Cloud.ComputeClient(), &server, fi.ValueOf(e.InterfaceName))
 			if err != nil {
 				return err
 			}
 
 			member, err := t.Cloud.AssociateToPool(&server, fi.ValueOf(e.Pool.ID), v2pools.CreateMemberOpts{
-				Name:         fi.ValueOf(e.Name),
+				Name:         server.Name,
 				ProtocolPort: fi.ValueOf(e.ProtocolPort),
 				SubnetID:     fi.ValueOf(e.Pool.Loadbalancer.VipSubnet),
 				Address:      memberAddress,
+				Backup:       e.Backup,
+				MonitorPort:  e.MonitorPort,
 			})
 			if err != nil {
 				return fmt.Errorf("Failed to create member: %v", err)
@@ -187,12 +323,40 @@ func (_ *PoolAssociation) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e,
 			e.ID = fi.PtrTo(member.ID)
 		}
 	} else {
-		_, err := t.Cloud.UpdateMemberInPool(fi.ValueOf(a.Pool.ID), fi.ValueOf(a.ID), v2pools.UpdateMemberOpts{
-			Weight: e.Weight,
-		})
+		// the members of the association are named after its servers, or after the association
+		// if they were created before members were named after their server
+		serverNames := sets.New[string]()
+		serversByAddress := make(map[string]string)
+		for _, server := range memberServers {
+			serverNames.Insert(server.Name)
+			if address, err := openstack.GetServerFixedIP(&server, fi.ValueOf(e.InterfaceName)); err == nil {
+				serversByAddress[address] = server.Name
+			}
+		}
+
+		members, err := t.Cloud.ListPoolMembers(fi.ValueOf(a.Pool.ID), v2pools.ListMembersOpts{})
 		if err != nil {
-			return fmt.Errorf("Failed to update member: %v", err)
+			return fmt.Errorf("Failed to list members: %v", err)
+		}
+		for _, member := range members {
+			opts := v2pools.UpdateMemberOpts{
+				Weight:      e.Weight,
+				Backup:      e.Backup,
+				MonitorPort: e.MonitorPort,
+			}
+			if member.Name == fi.ValueOf(a.Name) {
+				if serverName, found := serversByAddress[member.Address]; found {
+					klog.V(2).Infof("Renaming member %s of pool %s to %s", member.ID, fi.ValueOf(a.Pool.ID), serverName)
+					opts.Name = fi.PtrTo(serverName)
+				}
+			} else if !serverNames.Has(member.Name) {
+				continue
+			}
+			_, err := t.Cloud.UpdateMemberInPool(fi.ValueOf(a.Pool.ID), member.ID, opts)
+			if err != nil {
+				return fmt.Errorf("Failed to update member: %v", err)
+			}
 		}
 	}
-	return nil
+	return e.removeStaleMembers(t.Cloud, fi.ValueOf(e.Pool.ID))
 }
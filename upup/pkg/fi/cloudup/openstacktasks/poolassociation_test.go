@@ -0,0 +1,196 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstacktasks
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"k8s.io/kops/cloudmock/openstack/mockcompute"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+	"k8s.io/kops/cloudmock/openstack/mocknetworking"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func Test_PoolAssociation_CheckChanges_Backup(t *testing.T) {
+	tests := []struct {
+		desc        string
+		provider    string
+		backup      *bool
+		expectedErr bool
+	}{
+		{
+			desc:     "unset backup is always allowed",
+			provider: "ovn",
+		},
+		{
+			desc:     "backup with amphora provider",
+			provider: "amphora",
+			backup:   fi.PtrTo(true),
+		},
+		{
+			desc:        "backup with ovn provider",
+			provider:    "ovn",
+			backup:      fi.PtrTo(true),
+			expectedErr: true,
+		},
+		{
+			desc:     "explicitly disabled backup with ovn provider",
+			provider: "ovn",
+			backup:   fi.PtrTo(false),
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			e := &PoolAssociation{
+				Name:   fi.PtrTo("member"),
+				Backup: testCase.backup,
+				Pool: &LBPool{
+					Loadbalancer: &LB{
+						Provider: fi.PtrTo(testCase.provider),
+					},
+				},
+			}
+
+			err := (&PoolAssociation{}).CheckChanges(nil, e, &PoolAssociation{})
+			if testCase.expectedErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !testCase.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PoolAssociation_RemovesStaleMembers(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	cloud.MockNeutronClient = mocknetworking.CreateClient()
+	cloud.MockNovaClient = mockcompute.CreateClient(cloud.NetworkingClient())
+	target := openstack.NewOpenstackAPITarget(cloud)
+
+	// the mock gives every server the address 192.168.1.1
+	if _, err := cloud.CreateInstance(servers.CreateOpts{
+		Name:     "master-a-1",
+		Networks: []servers.Network{{Port: "port"}},
+		Metadata: map[string]string{"k8s": "cluster"},
+	}, nil, "port"); err != nil {
+		t.Fatalf("error creating server: %v", err)
+	}
+	network, err := cloud.CreateNetwork(networks.CreateOpts{Name: "cluster"})
+	if err != nil {
+		t.Fatalf("error creating network: %v", err)
+	}
+	subnet, err := cloud.CreateSubnet(subnets.CreateOpts{Name: "cluster", NetworkID: network.ID, CIDR: "192.168.1.0/24", IPVersion: 4, EnableDHCP: fi.PtrTo(true)})
+	if err != nil {
+		t.Fatalf("error creating subnet: %v", err)
+	}
+	lb, err := cloud.CreateLB(loadbalancers.CreateOpts{Name: "api", VipSubnetID: subnet.ID})
+	if err != nil {
+		t.Fatalf("error creating loadbalancer: %v", err)
+	}
+	pool, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api-https", LoadbalancerID: lb.ID, LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolTCP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	for _, opts := range []v2pools.CreateMemberOpts{
+		{Name: "master-a-1", Address: "192.168.1.1", ProtocolPort: 443, MonitorPort: fi.PtrTo(3990)},
+		// the server of this member was deleted
+		{Name: "master-a-0", Address: "192.168.1.7", ProtocolPort: 443},
+		// this member was added manually
+		{Name: "bastion", Address: "192.168.1.8", ProtocolPort: 443, Tags: []string{"kops-preserve"}},
+	} {
+		if _, err := v2pools.CreateMember(context.TODO(), cloud.LoadBalancerClient(), pool.ID, opts).Extract(); err != nil {
+			t.Fatalf("error creating member %s: %v", opts.Name, err)
+		}
+	}
+
+	e := &PoolAssociation{
+		Name:          fi.PtrTo("cluster-master-a"),
+		ServerPrefix:  fi.PtrTo("master-a"),
+		ClusterName:   fi.PtrTo("cluster"),
+		InterfaceName: fi.PtrTo("private"),
+		ProtocolPort:  fi.PtrTo(443),
+		MonitorPort:   fi.PtrTo(3990),
+		Weight:        fi.PtrTo(1),
+		Lifecycle:     fi.LifecycleSync,
+		Pool: &LBPool{
+			ID:           fi.PtrTo(pool.ID),
+			Name:         fi.PtrTo(pool.Name),
+			Lifecycle:    fi.LifecycleSync,
+			Loadbalancer: &LB{ID: fi.PtrTo(lb.ID)},
+		},
+		PreservedMembers: &openstack.MemberPreservation{Tag: "kops-preserve"},
+	}
+	find := func() *PoolAssociation {
+		t.Helper()
+		c, err := fi.NewCloudupContext(context.TODO(), fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, map[string]fi.CloudupTask{"association": e})
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		a, err := e.Find(c)
+		if err != nil {
+			t.Fatalf("unexpected error finding association: %v", err)
+		}
+		if a == nil {
+			t.Fatalf("expected to find association %s", fi.ValueOf(e.Name))
+		}
+		return a
+	}
+
+	a := find()
+	if fi.ValueOf(a.StaleMembersRemoved) {
+		t.Errorf("expected the stale member to be found")
+	}
+	changes := &PoolAssociation{}
+	if !fi.BuildChanges(a, e, changes) {
+		t.Fatalf("expected changes for the stale member")
+	}
+	if err := (&PoolAssociation{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error rendering association: %v", err)
+	}
+
+	members, err := cloud.ListPoolMembers(pool.ID, v2pools.ListMembersOpts{})
+	if err != nil {
+		t.Fatalf("error listing members: %v", err)
+	}
+	var names []string
+	for _, member := range members {
+		names = append(names, member.Name)
+		if member.Name == "master-a-1" && member.MonitorPort != 3990 {
+			t.Errorf("expected member master-a-1 to keep monitor port 3990, got %d", member.MonitorPort)
+		}
+	}
+	sort.Strings(names)
+	if expected := []string{"bastion", "master-a-1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected members %v, got %v", expected, names)
+	}
+
+	if changes := (&PoolAssociation{}); fi.BuildChanges(find(), e, changes) {
+		t.Errorf("expected no changes after removing the stale member, got %v", fi.DebugAsJsonString(changes))
+	}
+}
@@ -31,6 +31,24 @@ type PoolMonitor struct {
 	Name      *string
 	Lifecycle fi.Lifecycle
 	Pool      *LBPool
+	// Type is the health monitor type, defaulting to one matching the pool protocol
+	Type *string
+	// URLPath is the path requested by HTTP and HTTPS monitors, defaulting to /healthz
+	URLPath *string
+	// MaxRetriesDown is the number of failed checks before a member is marked DOWN, defaulting to Octavia's default of 3.
+	// Raising it keeps members of flaky networks from being removed prematurely.
+	MaxRetriesDown *int
+}
+
+// defaultMonitorURLPath is the path requested by HTTP and HTTPS monitors when no URLPath is set
+const defaultMonitorURLPath = "/healthz"
+
+// defaultMonitorMaxRetriesDown is the max_retries_down Octavia uses when it is not set
+const defaultMonitorMaxRetriesDown = 3
+
+// isHTTPMonitorType returns true if the monitor type requests a URL path
+func isHTTPMonitorType(monitorType string) bool {
+	return monitorType == monitors.TypeHTTP || monitorType == monitors.TypeHTTPS
 }
 
 // GetDependencies returns the dependencies of the Instance task
@@ -40,6 +58,11 @@ func (p *PoolMonitor) GetDependencies(tasks map[string]fi.CloudupTask) []fi.Clou
 		if _, ok := task.(*LBPool); ok {
 			deps = append(deps, task)
 		}
+		// members must be configured before a monitor starts checking them,
+		// for example when they are checked on a different monitor port
+		if _, ok := task.(*PoolAssociation); ok {
+			deps = append(deps, task)
+		}
 	}
 	return deps
 }
@@ -73,6 +96,13 @@ func (p *PoolMonitor) Find(context *fi.CloudupContext) (*PoolMonitor, error) {
 		Name:      fi.PtrTo(found.Name),
 		Pool:      p.Pool,
 		Lifecycle: p.Lifecycle,
+		Type:      fi.PtrTo(found.Type),
+	}
+	if found.MaxRetriesDown != 0 {
+		actual.MaxRetriesDown = fi.PtrTo(found.MaxRetriesDown)
+	}
+	if isHTTPMonitorType(found.Type) {
+		actual.URLPath = fi.PtrTo(found.URLPath)
 	}
 	p.ID = actual.ID
 	return actual, nil
@@ -95,26 +125,71 @@ func (_ *PoolMonitor) CheckChanges(a, e, changes *PoolMonitor) error {
 			return fi.CannotChangeField("Name")
 		}
 	}
+	if e.URLPath != nil && e.Type != nil && !isHTTPMonitorType(*e.Type) {
+		return fmt.Errorf("URLPath can only be set on HTTP or HTTPS monitors, not %s", fi.ValueOf(e.Type))
+	}
+	if e.MaxRetriesDown != nil && (*e.MaxRetriesDown < 1 || *e.MaxRetriesDown > 10) {
+		return fmt.Errorf("MaxRetriesDown must be between 1 and 10, got %d", *e.MaxRetriesDown)
+	}
 	return nil
 }
 
 func (_ *PoolMonitor) RenderOpenstack(t *openstack.OpenstackAPITarget, a, e, changes *PoolMonitor) error {
+	// The type of a monitor cannot be updated, and a pool can only have one monitor
+	if a != nil && changes.Type != nil {
+		klog.V(2).Infof("Replacing PoolMonitor %q to change its type from %s to %s", fi.ValueOf(a.Name), fi.ValueOf(a.Type), fi.ValueOf(e.Type))
+		if err := t.Cloud.DeleteMonitor(fi.ValueOf(a.ID)); err != nil {
+			return fmt.Errorf("error deleting PoolMonitor: %v", err)
+		}
+		a = nil
+	}
+
 	if a == nil {
 		klog.V(2).Infof("Creating PoolMonitor with Name: %q", fi.ValueOf(e.Name))
 
-		poolMonitor, err := t.Cloud.CreatePoolMonitor(monitors.CreateOpts{
+		monitorType, err := openstack.MonitorTypeForPoolProtocol(string(poolProtocol(e.Pool)), fi.ValueOf(e.Type))
+		if err != nil {
+			return err
+		}
+		spec := openstack.MonitorSpec{
 			Name:           fi.ValueOf(e.Name),
 			PoolID:         fi.ValueOf(e.Pool.ID),
-			Type:           monitors.TypeTCP,
+			Type:           monitorType,
 			Delay:          10,
 			Timeout:        5,
 			MaxRetries:     3,
-			MaxRetriesDown: 3,
-		})
+			MaxRetriesDown: defaultMonitorMaxRetriesDown,
+		}
+		if e.MaxRetriesDown != nil {
+			spec.MaxRetriesDown = *e.MaxRetriesDown
+		}
+		if isHTTPMonitorType(monitorType) {
+			spec.URLPath = fi.ValueOf(e.URLPath)
+			if spec.URLPath == "" {
+				spec.URLPath = defaultMonitorURLPath
+			}
+		}
+		opts, err := openstack.BuildMonitorCreateOpts(spec)
+		if err != nil {
+			return fmt.Errorf("invalid PoolMonitor %q: %w", fi.ValueOf(e.Name), err)
+		}
+		poolMonitor, err := t.Cloud.CreatePoolMonitor(opts)
 		if err != nil {
 			return fmt.Errorf("error creating PoolMonitor: %v", err)
 		}
 		e.ID = fi.PtrTo(poolMonitor.ID)
+		return nil
+	}
+
+	if changes.URLPath != nil || changes.MaxRetriesDown != nil {
+		klog.V(2).Infof("Updating PoolMonitor %q: %v", fi.ValueOf(a.Name), changes)
+		_, err := t.Cloud.UpdateMonitor(fi.ValueOf(a.ID), monitors.UpdateOpts{
+			URLPath:        fi.ValueOf(changes.URLPath),
+			MaxRetriesDown: fi.ValueOf(changes.MaxRetriesDown),
+		})
+		if err != nil {
+			return fmt.Errorf("error updating PoolMonitor: %v", err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package openstacktasks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/monitors"
+	v2pools "github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/pools"
+	"k8s.io/kops/cloudmock/openstack/mockloadbalancer"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
+)
+
+func Test_PoolMonitor_CheckChanges_URLPath(t *testing.T) {
+	tests := []struct {
+		desc        string
+		monitorType *string
+		urlPath     *string
+		expectedErr bool
+	}{
+		{
+			desc:        "unset path with TCP monitor",
+			monitorType: fi.PtrTo(monitors.TypeTCP),
+		},
+		{
+			desc:        "path with HTTP monitor",
+			monitorType: fi.PtrTo(monitors.TypeHTTP),
+			urlPath:     fi.PtrTo("/readyz"),
+		},
+		{
+			desc:        "path with HTTPS monitor",
+			monitorType: fi.PtrTo(monitors.TypeHTTPS),
+			urlPath:     fi.PtrTo("/healthz"),
+		},
+		{
+			desc:        "path with TCP monitor",
+			monitorType: fi.PtrTo(monitors.TypeTCP),
+			urlPath:     fi.PtrTo("/readyz"),
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.desc, func(t *testing.T) {
+			e := &PoolMonitor{
+				Name:    fi.PtrTo("monitor"),
+				Type:    testCase.monitorType,
+				URLPath: testCase.urlPath,
+			}
+
+			err := (&PoolMonitor{}).CheckChanges(nil, e, &PoolMonitor{})
+			if testCase.expectedErr && err == nil {
+				t.Errorf("expected error, got nil")
+			}
+			if !testCase.expectedErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_PoolMonitor_CheckChanges_MaxRetriesDown(t *testing.T) {
+	for _, maxRetriesDown := range []int{0, 11} {
+		e := &PoolMonitor{
+			Name:           fi.PtrTo("monitor"),
+			MaxRetriesDown: fi.PtrTo(maxRetriesDown),
+		}
+		if err := (&PoolMonitor{}).CheckChanges(nil, e, &PoolMonitor{}); err == nil {
+			t.Errorf("expected error for MaxRetriesDown %d", maxRetriesDown)
+		}
+	}
+}
+
+func Test_PoolMonitor_MaxRetriesDown(t *testing.T) {
+	cloud := openstack.BuildMockOpenstackCloud("us-test1")
+	cloud.MockLBClient = mockloadbalancer.CreateClient()
+	target := openstack.NewOpenstackAPITarget(cloud)
+
+	created, err := cloud.CreatePool(v2pools.CreateOpts{Name: "api", LoadbalancerID: "lb", LBMethod: v2pools.LBMethodRoundRobin, Protocol: v2pools.ProtocolTCP})
+	if err != nil {
+		t.Fatalf("error creating pool: %v", err)
+	}
+	pool := &LBPool{ID: fi.PtrTo(created.ID)}
+	find := func(e *PoolMonitor) *PoolMonitor {
+		t.Helper()
+		c, err := fi.NewCloudupContext(context.TODO(), fi.DeletionProcessingModeDeleteIncludingDeferred, target, nil, cloud, nil, nil, nil, map[string]fi.CloudupTask{"monitor": e})
+		if err != nil {
+			t.Fatalf("error building context: %v", err)
+		}
+		a, err := e.Find(c)
+		if err != nil {
+			t.Fatalf("unexpected error finding monitor: %v", err)
+		}
+		if a == nil {
+			t.Fatalf("expected to find monitor %s", fi.ValueOf(e.Name))
+		}
+		return a
+	}
+
+	// Octavia's default is used when unset
+	defaulted := &PoolMonitor{Name: fi.PtrTo("defaulted"), Pool: pool, Lifecycle: fi.LifecycleSync}
+	if err := (&PoolMonitor{}).RenderOpenstack(target, nil, defaulted, defaulted); err != nil {
+		t.Fatalf("unexpected error creating monitor: %v", err)
+	}
+	if a := find(defaulted); fi.ValueOf(a.MaxRetriesDown) != defaultMonitorMaxRetriesDown {
+		t.Errorf("expected MaxRetriesDown %d, got %v", defaultMonitorMaxRetriesDown, a.MaxRetriesDown)
+	}
+
+	e := &PoolMonitor{Name: fi.PtrTo("monitor"), Pool: pool, Lifecycle: fi.LifecycleSync, MaxRetriesDown: fi.PtrTo(5)}
+	if err := (&PoolMonitor{}).RenderOpenstack(target, nil, e, e); err != nil {
+		t.Fatalf("unexpected error creating monitor: %v", err)
+	}
+	a := find(e)
+	if fi.ValueOf(a.MaxRetriesDown) != 5 {
+		t.Errorf("expected MaxRetriesDown 5 after create, got %v", a.MaxRetriesDown)
+	}
+	if changes := (&PoolMonitor{}); fi.BuildChanges(a, e, changes) {
+		t.Errorf("expected no changes after create, got %v", fi.DebugAsJsonString(changes))
+	}
+
+	e.MaxRetriesDown = fi.PtrTo(8)
+	changes := &PoolMonitor{}
+	if !fi.BuildChanges(a, e, changes) || fi.ValueOf(changes.MaxRetriesDown) != 8 {
+		t.Fatalf("expected a change of MaxRetriesDown, got %v", fi.DebugAsJsonString(changes))
+	}
+	if err := (&PoolMonitor{}).RenderOpenstack(target, a, e, changes); err != nil {
+		t.Fatalf("unexpected error updating monitor: %v", err)
+	}
+	if a := find(e); fi.ValueOf(a.MaxRetriesDown) != 8 {
+		t.Errorf("expected MaxRetriesDown 8 after update, got %v", a.MaxRetriesDown)
+	}
+}
@@ -67,6 +67,22 @@ func (t *TerraformTarget) DefaultCheckExisting() bool {
 	return false
 }
 
+// ProviderRegion returns the region the generated provider block applies to resources by default.
+func (t *TerraformTarget) ProviderRegion() string {
+	if region, ok := tfGetProviderExtraConfig(t.clusterSpecTarget)["region"]; ok {
+		return region
+	}
+	return t.Cloud.Region()
+}
+
+// ProviderProject returns the project the generated provider block applies to resources by default.
+func (t *TerraformTarget) ProviderProject() string {
+	if project, ok := tfGetProviderExtraConfig(t.clusterSpecTarget)["project"]; ok {
+		return project
+	}
+	return t.Project
+}
+
 // tfGetProviderExtraConfig is a helper function to get extra config with safety checks on the pointers.
 func tfGetProviderExtraConfig(c *kops.TargetSpec) map[string]string {
 	if c != nil &&
@@ -81,6 +81,13 @@ func LiteralProperty(resourceType, resourceName, prop string) *Literal {
 	}
 }
 
+// LiteralResource references a resource as a whole, as in depends_on.
+func LiteralResource(resourceType, resourceName string) *Literal {
+	return &Literal{
+		String: resourceType + "." + sanitizeName(resourceName),
+	}
+}
+
 func LiteralTokens(tokens ...string) *Literal {
 	return &Literal{
 		String: strings.Join(tokens, "."),
@@ -67,6 +67,12 @@ func BuildCloud(cluster *kops.Cluster) (fi.Cloud, error) {
 			if err != nil {
 				return nil, err
 			}
+			if networkTier := cluster.Spec.CloudProvider.GCE.NetworkTier; networkTier != nil {
+				gceCloud, err = gce.WithDefaultNetworkTier(gceCloud, *networkTier)
+				if err != nil {
+					return nil, err
+				}
+			}
 
 			cloud = gceCloud
 		}
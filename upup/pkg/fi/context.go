@@ -142,6 +142,16 @@ func (c *Context[T]) Render(a, e, changes Task[T]) error {
 		lifecycle = hl.GetLifecycle()
 	}
 
+	if lifecycle == LifecycleReferenceOnly {
+		if s, ok := e.(SupportsReferenceOnly); !ok || !s.SupportsReferenceOnly() {
+			return fmt.Errorf("lifecycle %s is not supported by task %s", lifecycle, getTaskName(e))
+		}
+		if _, ok := c.Target.(*DryRunTarget[T]); ok {
+			// The object is never changed, so there is nothing to preview
+			return nil
+		}
+	}
+
 	if lifecycle != "" {
 		if reflect.ValueOf(a).IsNil() {
 			switch lifecycle {
@@ -35,8 +35,18 @@ const (
 
 	// LifecycleExistsAndWarnIfChanges will check that the task exists and will warn on changes, but then ignore them
 	LifecycleExistsAndWarnIfChanges Lifecycle = "ExistsAndWarnIfChanges"
+
+	// LifecycleReferenceOnly will never create, change or delete the object, which is managed outside of kops,
+	// but lets the task render a reference to it, for example as a terraform data source
+	LifecycleReferenceOnly Lifecycle = "ReferenceOnly"
 )
 
+// SupportsReferenceOnly is implemented by tasks that can be used with LifecycleReferenceOnly.
+// Their renderers are still called, and must only render a reference to the existing object.
+type SupportsReferenceOnly interface {
+	SupportsReferenceOnly() bool
+}
+
 // HasLifecycle indicates that the task has a Lifecycle
 type HasLifecycle interface {
 	GetLifecycle() Lifecycle
@@ -53,6 +63,7 @@ var Lifecycles = sets.NewString(
 	string(LifecycleWarnIfInsufficientAccess),
 	string(LifecycleExistsAndValidates),
 	string(LifecycleExistsAndWarnIfChanges),
+	string(LifecycleReferenceOnly),
 )
 
 // LifecycleNameMap is used to validate in the UX.  When a user provides a lifecycle name
@@ -63,4 +74,5 @@ var LifecycleNameMap = map[string]Lifecycle{
 	"WarnIfInsufficientAccess": LifecycleWarnIfInsufficientAccess,
 	"ExistsAndValidates":       LifecycleExistsAndValidates,
 	"ExistsAndWarnIfChanges":   LifecycleExistsAndWarnIfChanges,
+	"ReferenceOnly":            LifecycleReferenceOnly,
 }